@@ -12,6 +12,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/iamarpitzala/aca-reca-backend/config"
 	"github.com/iamarpitzala/aca-reca-backend/internal/middleware"
+	"github.com/iamarpitzala/aca-reca-backend/internal/money"
 	"github.com/iamarpitzala/aca-reca-backend/route"
 	"github.com/joho/godotenv"
 )
@@ -24,6 +25,11 @@ func InitServer() {
 
 	// Load configuration
 	cfg := config.Load()
+	rounding, err := money.ParseRounding(cfg.Money.Rounding)
+	if err != nil {
+		log.Fatalf("Invalid MONEY_ROUNDING: %v", err)
+	}
+	money.SetRounding(rounding)
 
 	// Initialize database
 	db, err := config.NewConnection(cfg.DB)
@@ -48,7 +54,12 @@ func InitServer() {
 
 	// Gin engine
 	r := gin.New()
-	r.Use(gin.Logger(), gin.Recovery(), middleware.CorsMiddleware())
+	if len(cfg.Server.TrustedProxies) > 0 {
+		if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+			log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+		}
+	}
+	r.Use(gin.Logger(), gin.Recovery(), middleware.CorsMiddleware(), middleware.ClientInfoMiddleware())
 
 	// Routes
 	route.InitRouter(r)
@@ -19,16 +19,26 @@ type RedisConfig struct {
 
 // Config struct
 type Config struct {
-	Server ServerConfig
-	DB     DBConfig
-	JWT    JWTConfig
-	OAuth  OAuthConfig
+	Server  ServerConfig
+	DB      DBConfig
+	JWT     JWTConfig
+	OAuth   OAuthConfig
+	Mail    MailConfig
+	Invite  InvitationConfig
+	Account AccountConfig
+	Storage StorageConfig
+	Redis   RedisConfig
+	Login   LoginLimitConfig
+	Money   MoneyConfig
 }
 
 type ServerConfig struct {
 	Port        string
 	Env         string
 	CORSOrigins []string // Allowed origins for CORS (required when using credentials)
+	// TrustedProxies may set X-Forwarded-For. Client addresses feed login rate limiting, so production
+	// should list its load balancers; when empty every proxy is trusted.
+	TrustedProxies []string
 }
 
 type DBConfig struct {
@@ -57,6 +67,62 @@ type OAuthConfig struct {
 	Providers   map[string]OAuthProviderConfig
 }
 
+// MailConfig selects how outgoing email is delivered. Driver "file" writes each message to OutboxDir
+// (a local SMTP sink for development and tests); "smtp" relays through SMTPHost. It must be set.
+type MailConfig struct {
+	Driver       string
+	From         string
+	OutboxDir    string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// StorageConfig selects where uploaded files (clinic logos) are kept. Driver "local" stores them under
+// LocalDir; "memory" keeps them in process and is meant for tests.
+type StorageConfig struct {
+	Driver   string
+	LocalDir string
+}
+
+// MoneyConfig sets how amounts are rounded to the cent when scaled by a percentage other than GST
+// (service fees, outwork charges, commissions): "half_up" or "half_even" (banker's rounding). GST is
+// always rounded half up, as the ATO requires.
+type MoneyConfig struct {
+	Rounding string
+}
+
+type InvitationConfig struct {
+	TTL       time.Duration
+	AcceptURL string // Frontend page that receives ?token=
+}
+
+// AccountConfig controls the emailed password reset and email verification links, and whether users
+// are emailed when their account is signed in to from a new device.
+type AccountConfig struct {
+	PasswordResetTTL     time.Duration
+	PasswordResetURL     string // Frontend page that receives ?token=
+	EmailVerificationTTL time.Duration
+	EmailVerificationURL string // Frontend page that receives ?token=
+	NewDeviceAlerts      bool
+}
+
+// LoginLimitConfig controls brute-force protection on login and registration. Driver "memory" keeps
+// failure counters in process; "redis" shares them between instances through Redis. After
+// FreeAttempts failures each attempt waits BaseDelay, doubling up to MaxDelay; an account or address
+// reaching its LockoutAfter count is locked for LockoutDuration. Failures older than Window are forgotten.
+type LoginLimitConfig struct {
+	Driver              string
+	FreeAttempts        int
+	BaseDelay           time.Duration
+	MaxDelay            time.Duration
+	AccountLockoutAfter int
+	IPLockoutAfter      int // Higher than the account limit: many users can share an address
+	LockoutDuration     time.Duration
+	Window              time.Duration
+}
+
 type OAuthProviderConfig struct {
 	ClientID     string
 	ClientSecret string
@@ -64,14 +130,18 @@ type OAuthProviderConfig struct {
 	TokenURL     string
 	UserInfoURL  string
 	Scopes       []string
+	// Issuer is the expected "iss" of the provider's id_token. "{tenantid}" stands for the token's
+	// "tid" claim, for multi-tenant providers that issue from a per-tenant URL.
+	Issuer string
 }
 
 func Load() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port:        getEnv("SERVER_PORT", "8080"),
-			Env:         getEnv("ENV", "development"),
-			CORSOrigins: getCORSOrigins(),
+			Port:           getEnv("SERVER_PORT", "8080"),
+			Env:            getEnv("ENV", "development"),
+			CORSOrigins:    getCORSOrigins(),
+			TrustedProxies: getEnvAsList("TRUSTED_PROXIES"),
 		},
 		DB: DBConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -100,6 +170,7 @@ func Load() *Config {
 					TokenURL:     "https://oauth2.googleapis.com/token",
 					UserInfoURL:  "https://www.googleapis.com/oauth2/v2/userinfo",
 					Scopes:       []string{"openid", "profile", "email"},
+					Issuer:       "https://accounts.google.com",
 				},
 				"microsoft": {
 					ClientID:     getEnv("MICROSOFT_CLIENT_ID", ""),
@@ -108,9 +179,54 @@ func Load() *Config {
 					TokenURL:     "https://login.microsoftonline.com/common/oauth2/v2.0/token",
 					UserInfoURL:  "https://graph.microsoft.com/v1.0/me",
 					Scopes:       []string{"openid", "profile", "email"},
+					Issuer:       "https://login.microsoftonline.com/{tenantid}/v2.0",
 				},
 			},
 		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", ""),
+			From:         getEnv("MAIL_FROM", "no-reply@acareca.local"),
+			OutboxDir:    getEnv("MAIL_OUTBOX_DIR", "tmp/mail"),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnv("SMTP_PORT", "1025"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		},
+		Invite: InvitationConfig{
+			TTL:       getEnvAsDuration("INVITATION_TTL", 7*24*time.Hour),
+			AcceptURL: getEnv("INVITATION_ACCEPT_URL", getEnv("FRONTEND_URL", "http://localhost:5173")+"/invitations/accept"),
+		},
+		Account: AccountConfig{
+			PasswordResetTTL:     getEnvAsDuration("PASSWORD_RESET_TTL", time.Hour),
+			PasswordResetURL:     getEnv("PASSWORD_RESET_URL", getEnv("FRONTEND_URL", "http://localhost:5173")+"/reset-password"),
+			EmailVerificationTTL: getEnvAsDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+			EmailVerificationURL: getEnv("EMAIL_VERIFICATION_URL", getEnv("FRONTEND_URL", "http://localhost:5173")+"/verify-email"),
+			NewDeviceAlerts:      getEnvAsBool("NEW_DEVICE_ALERTS", false),
+		},
+		Storage: StorageConfig{
+			Driver:   getEnv("STORAGE_DRIVER", "local"),
+			LocalDir: getEnv("STORAGE_LOCAL_DIR", "tmp/storage"),
+		},
+		Redis: RedisConfig{
+			Host:       getEnv("REDIS_HOST", "localhost"),
+			Port:       getEnv("REDIS_PORT", "6379"),
+			Password:   getEnv("REDIS_PASSWORD", ""),
+			DB:         getEnvAsInt("REDIS_DB", 0),
+			TLSEnabled: getEnvAsBool("REDIS_TLS_ENABLED", false),
+		},
+		Login: LoginLimitConfig{
+			Driver:              getEnv("LOGIN_LIMIT_DRIVER", "memory"),
+			FreeAttempts:        getEnvAsInt("LOGIN_FREE_ATTEMPTS", 3),
+			BaseDelay:           getEnvAsDuration("LOGIN_BASE_DELAY", time.Second),
+			MaxDelay:            getEnvAsDuration("LOGIN_MAX_DELAY", 30*time.Second),
+			AccountLockoutAfter: getEnvAsInt("LOGIN_ACCOUNT_LOCKOUT_AFTER", 10),
+			IPLockoutAfter:      getEnvAsInt("LOGIN_IP_LOCKOUT_AFTER", 50),
+			LockoutDuration:     getEnvAsDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+			Window:              getEnvAsDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		},
+		Money: MoneyConfig{
+			Rounding: getEnv("MONEY_ROUNDING", "half_up"),
+		},
 	}
 }
 
@@ -144,6 +260,17 @@ func getCORSOrigins() []string {
 	return origins
 }
 
+// getEnvAsList splits a comma-separated variable, dropping blanks. It returns nil when unset.
+func getEnvAsList(key string) []string {
+	var out []string
+	for _, p := range strings.Split(os.Getenv(key), ",") {
+		if v := strings.TrimSpace(p); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 func getEnvAsInt(key string, defaultValue int) int {
 	valueStr := getEnv(key, "")
 	if value, ok := util.ToInt(valueStr); ok {
@@ -0,0 +1,315 @@
+package calculation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+)
+
+// maxConditionDepth bounds how deeply condition groups may nest.
+const maxConditionDepth = 5
+
+// FieldState is what a form's conditional logic decides about one field for an entry.
+type FieldState struct {
+	Active   bool // Shown; inactive fields are left out of validation and calculations
+	Required bool // Required by the field itself or by a matching require rule
+}
+
+// EntryEvaluation is what a form's conditional logic and formulas work out for one entry.
+type EntryEvaluation struct {
+	States   map[string]FieldState
+	Formulas map[string]float64 // Results of active formula fields, rounded to cents
+	Errors   map[string]string  // Formula fields that could not be computed, and why
+}
+
+// EvaluateEntry decides, from an entry's values keyed by field ID, which fields are active and
+// which are required, and computes the formula fields. A condition or formula referring to an
+// inactive field sees it as empty, so hiding a field also settles what depends on it. Published
+// forms have no circular references; if a draft does, the fields on the cycle are treated as
+// active and the formulas on it fail.
+func EvaluateEntry(fields []domain.CustomFormField, values map[string]interface{}) *EntryEvaluation {
+	e := &entryEvaluator{
+		fields:    make(map[string]domain.CustomFormField, len(fields)),
+		order:     fields,
+		values:    values,
+		states:    make(map[string]FieldState, len(fields)),
+		visiting:  make(map[string]bool),
+		results:   make(map[string]float64),
+		errs:      make(map[string]string),
+		computing: make(map[string]bool),
+	}
+	for _, f := range fields {
+		e.fields[f.ID] = f
+	}
+	for _, f := range fields {
+		e.state(f.ID)
+	}
+	formulas := make(map[string]float64)
+	for _, f := range fields {
+		if f.Type == domain.FieldTypeFormula && e.states[f.ID].Active {
+			formulas[f.ID] = e.formulaValue(f.ID)
+		}
+	}
+	return &EntryEvaluation{States: e.states, Formulas: formulas, Errors: e.errs}
+}
+
+type entryEvaluator struct {
+	fields   map[string]domain.CustomFormField
+	order    []domain.CustomFormField
+	values   map[string]interface{}
+	states   map[string]FieldState
+	visiting map[string]bool
+
+	// Formula results, failures and the formulas being computed, see formula.go
+	results   map[string]float64
+	errs      map[string]string
+	computing map[string]bool
+}
+
+func (e *entryEvaluator) state(id string) FieldState {
+	if st, ok := e.states[id]; ok {
+		return st
+	}
+	f := e.fields[id]
+	st := FieldState{Active: true, Required: f.Required}
+	if e.visiting[id] {
+		return st
+	}
+	e.visiting[id] = true
+	defer delete(e.visiting, id)
+
+	hasShow, shown := false, false
+	for _, rule := range f.ConditionalLogic {
+		if !rule.IsEnabled() {
+			continue
+		}
+		matched := e.matchAll(rule.Match, rule.Conditions)
+		switch rule.Action {
+		case domain.RuleActionShow:
+			hasShow = true
+			shown = shown || matched
+		case domain.RuleActionHide:
+			if matched {
+				st.Active = false
+			}
+		case domain.RuleActionRequire:
+			st.Required = st.Required || matched
+		}
+	}
+	if hasShow && !shown {
+		st.Active = false
+	}
+	e.states[id] = st
+	return st
+}
+
+func (e *entryEvaluator) matchAll(match string, conditions []domain.RuleCondition) bool {
+	if match == domain.RuleMatchAny {
+		for _, c := range conditions {
+			if e.match(c) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, c := range conditions {
+		if !e.match(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *entryEvaluator) match(c domain.RuleCondition) bool {
+	if len(c.Conditions) > 0 {
+		return e.matchAll(c.Match, c.Conditions)
+	}
+	var value interface{}
+	if f, ok := e.fields[c.FieldID]; ok && e.state(c.FieldID).Active {
+		if f.Type == domain.FieldTypeFormula {
+			value = e.formulaValue(f.ID)
+		} else {
+			value = e.values[c.FieldID]
+		}
+	}
+	return compareCondition(normalizeOperator(c.Operator), value, c.Value)
+}
+
+// normalizeOperator accepts the operators in snake_case, camelCase or kebab-case.
+func normalizeOperator(op string) string {
+	switch strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(op)) {
+	case "equals", "eq":
+		return domain.RuleOpEquals
+	case "notequals", "ne", "neq":
+		return domain.RuleOpNotEquals
+	case "greaterthan", "gt":
+		return domain.RuleOpGreaterThan
+	case "contains":
+		return domain.RuleOpContains
+	}
+	return ""
+}
+
+func compareCondition(op string, value, target interface{}) bool {
+	switch op {
+	case domain.RuleOpEquals:
+		return valuesEqual(value, target)
+	case domain.RuleOpNotEquals:
+		return !valuesEqual(value, target)
+	case domain.RuleOpGreaterThan:
+		if a, ok := parseNumber(value); ok {
+			b, ok := parseNumber(target)
+			return ok && a > b
+		}
+		a, errA := parseConditionDate(value)
+		b, errB := parseConditionDate(target)
+		return errA == nil && errB == nil && a.After(b)
+	case domain.RuleOpContains:
+		if list, ok := value.([]interface{}); ok {
+			for _, item := range list {
+				if valuesEqual(item, target) {
+					return true
+				}
+			}
+			return false
+		}
+		s, ok := scalarString(value)
+		t, tok := scalarString(target)
+		return ok && tok && t != "" && strings.Contains(strings.ToLower(s), strings.ToLower(t))
+	}
+	return false
+}
+
+// valuesEqual compares numbers numerically and anything else as trimmed text; a missing value
+// equals an empty one.
+func valuesEqual(a, b interface{}) bool {
+	if x, ok := parseNumber(a); ok {
+		if y, ok := parseNumber(b); ok {
+			return x == y
+		}
+	}
+	s, _ := scalarString(a)
+	t, _ := scalarString(b)
+	return strings.TrimSpace(s) == strings.TrimSpace(t)
+}
+
+func parseConditionDate(v interface{}) (time.Time, error) {
+	s, _ := v.(string)
+	return time.Parse(fieldDateLayout, strings.TrimSpace(s))
+}
+
+// validateConditionalLogic checks the enabled rules of every field, known actions and operators and
+// conditions that reference other fields of the form, recording what each field depends on in deps.
+func validateConditionalLogic(fields []domain.CustomFormField, byID map[string]domain.CustomFormField, deps map[string][]string, add func(domain.CustomFormField, string)) {
+	for _, f := range fields {
+		for _, rule := range f.ConditionalLogic {
+			if !rule.IsEnabled() {
+				continue
+			}
+			switch rule.Action {
+			case domain.RuleActionShow, domain.RuleActionHide, domain.RuleActionRequire:
+			default:
+				add(f, fmt.Sprintf("conditional logic action %q must be show, hide or require", rule.Action))
+			}
+			if msg := validateMatch(rule.Match, rule.Conditions); msg != "" {
+				add(f, msg)
+			}
+			for _, c := range rule.Conditions {
+				validateCondition(f, c, 1, byID, add, func(ref string) { deps[f.ID] = append(deps[f.ID], ref) })
+			}
+		}
+	}
+}
+
+// reportCycle adds an error for the first circular reference in deps, which maps each field to the
+// fields its conditional logic and formula depend on.
+func reportCycle(fields []domain.CustomFormField, byID map[string]domain.CustomFormField, deps map[string][]string, add func(domain.CustomFormField, string)) {
+	// Depth-first search; reaching a field still on the path closes a cycle
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	mark := make(map[string]int)
+	var path []string
+	var visit func(id string) bool
+	visit = func(id string) bool {
+		mark[id] = onPath
+		path = append(path, id)
+		for _, ref := range deps[id] {
+			switch mark[ref] {
+			case onPath:
+				start := 0
+				for path[start] != ref {
+					start++
+				}
+				names := make([]string, 0, len(path)-start+1)
+				for _, p := range append(path[start:], ref) {
+					names = append(names, byID[p].Name)
+				}
+				add(byID[ref], "circular reference: "+strings.Join(names, " → "))
+				return true
+			case unvisited:
+				if visit(ref) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		mark[id] = done
+		return false
+	}
+	for _, f := range fields {
+		if mark[f.ID] == unvisited && visit(f.ID) {
+			return
+		}
+	}
+}
+
+func validateCondition(f domain.CustomFormField, c domain.RuleCondition, depth int, byID map[string]domain.CustomFormField, add func(domain.CustomFormField, string), ref func(string)) {
+	if depth > maxConditionDepth {
+		add(f, fmt.Sprintf("conditional logic groups can be nested at most %d deep", maxConditionDepth))
+		return
+	}
+	if len(c.Conditions) > 0 {
+		if c.FieldID != "" {
+			add(f, "a condition cannot have both a fieldId and nested conditions")
+		}
+		if msg := validateMatch(c.Match, c.Conditions); msg != "" {
+			add(f, msg)
+		}
+		for _, nested := range c.Conditions {
+			validateCondition(f, nested, depth+1, byID, add, ref)
+		}
+		return
+	}
+	switch {
+	case strings.TrimSpace(c.FieldID) == "":
+		add(f, "conditional logic condition needs a fieldId")
+	case c.FieldID == f.ID:
+		add(f, "conditional logic cannot refer to the field itself")
+	default:
+		if _, ok := byID[c.FieldID]; !ok {
+			add(f, "conditional logic refers to unknown field "+c.FieldID)
+		} else {
+			ref(c.FieldID)
+		}
+	}
+	if normalizeOperator(c.Operator) == "" {
+		add(f, fmt.Sprintf("conditional logic operator %q must be equals, not_equals, greater_than or contains", c.Operator))
+	}
+}
+
+func validateMatch(match string, conditions []domain.RuleCondition) string {
+	switch match {
+	case "", domain.RuleMatchAll, domain.RuleMatchAny:
+	default:
+		return fmt.Sprintf("conditional logic match %q must be all or any", match)
+	}
+	if len(conditions) == 0 {
+		return "conditional logic needs at least one condition"
+	}
+	return ""
+}
@@ -2,119 +2,104 @@ package calculation
 
 import (
 	"encoding/json"
-	"math"
-	"strconv"
 	"strings"
-)
 
-// Field definitions for parsing form.Fields JSONB (subset needed for calculation)
-type calcField struct {
-	ID             string  `json:"id"`
-	Name           string  `json:"name"`
-	Type           string  `json:"type"`
-	Section        string  `json:"section"`
-	IncludeInTotal bool    `json:"includeInTotal"`
-	GstConfig      *gstCfg `json:"gstConfig"`
-	PaymentResp    string  `json:"paymentResponsibility"`
-}
-type gstCfg struct {
-	Enabled bool    `json:"enabled"`
-	Rate    float64 `json:"rate"`
-	Type    string  `json:"type"` // inclusive, exclusive, manual
-}
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/money"
+)
 
 // Value from client: fieldId, value, optional manualGstAmount
 type entryValue struct {
-	FieldID         string      `json:"fieldId"`
-	FieldName       string      `json:"fieldName"`
-	Value           interface{} `json:"value"`
-	ManualGstAmount *float64    `json:"manualGstAmount"`
+	FieldID         string        `json:"fieldId"`
+	FieldName       string        `json:"fieldName"`
+	Value           interface{}   `json:"value"`
+	ManualGstAmount *money.Amount `json:"manualGstAmount"`
 }
 
 // Deductions from request (for service fee % and override; entry-level payment responsibility overrides per-field when set)
 type deductionsInput struct {
-	ServiceFacilityFeePercent  *float64 `json:"serviceFacilityFeePercent"`
-	ServiceFeeOverride         *float64 `json:"serviceFeeOverride"`
-	EntryPaymentResponsibility *string  `json:"entryPaymentResponsibility"`
+	ServiceFacilityFeePercent  *float64      `json:"serviceFacilityFeePercent"`
+	ServiceFeeOverride         *money.Amount `json:"serviceFeeOverride"`
+	EntryPaymentResponsibility *string       `json:"entryPaymentResponsibility"`
 }
 
 // Output structures (match frontend EntryCalculations)
 type fieldCalc struct {
-	FieldID     string  `json:"fieldId"`
-	FieldName   string  `json:"fieldName"`
-	BaseAmount  float64 `json:"baseAmount"`
-	GstAmount   float64 `json:"gstAmount"`
-	TotalAmount float64 `json:"totalAmount"`
-	GstRate     float64 `json:"gstRate"`
-	GstType     string  `json:"gstType"`
+	FieldID      string       `json:"fieldId"`
+	FieldName    string       `json:"fieldName"`
+	BaseAmount   money.Amount `json:"baseAmount"`
+	GstAmount    money.Amount `json:"gstAmount"`
+	TotalAmount  money.Amount `json:"totalAmount"`
+	GstRate      float64      `json:"gstRate"`
+	GstType      string       `json:"gstType"`
+	TaxCode      string       `json:"taxCode,omitempty"`      // Tax code of the linked account
+	TaxTreatment string       `json:"taxTreatment,omitempty"` // taxable, gst_free, input_taxed or bas_excluded
 }
 type basMapping struct {
-	GstOnSales1A float64 `json:"gstOnSales1A"`
-	GstCredit1B  float64 `json:"gstCredit1B"`
-	TotalSalesG1 float64 `json:"totalSalesG1"`
-	ExpensesG11  float64 `json:"expensesG11"`
+	GstOnSales1A money.Amount `json:"gstOnSales1A"`
+	GstCredit1B  money.Amount `json:"gstCredit1B"`
+	TotalSalesG1 money.Amount `json:"totalSalesG1"`
+	ExpensesG11  money.Amount `json:"expensesG11"`
 }
 type calculationsOutput struct {
-	FieldTotals             []fieldCalc `json:"fieldTotals"`
-	TotalBaseAmount         float64     `json:"totalBaseAmount"`
-	TotalGSTAmount          float64     `json:"totalGSTAmount"`
-	TotalAmount             float64     `json:"totalAmount"`
-	NetPayable              float64     `json:"netPayable"`
-	NetReceivable           float64     `json:"netReceivable"`
-	BasMapping              basMapping  `json:"basMapping"`
-	NetFee                  *float64    `json:"netFee,omitempty"`
-	ServiceFeeBase          *float64    `json:"serviceFeeBase,omitempty"`
-	GstOnServiceFee         *float64    `json:"gstOnServiceFee,omitempty"`
-	TotalServiceFee         *float64    `json:"totalServiceFee,omitempty"`
-	TotalReductions         *float64    `json:"totalReductions,omitempty"`
-	TotalReimbursements     *float64    `json:"totalReimbursements,omitempty"`
-	ReductionBreakdown      []fieldCalc `json:"reductionBreakdown,omitempty"`
-	ReimbursementBreakdown  []fieldCalc `json:"reimbursementBreakdown,omitempty"`
-	SubtotalAfterDeductions *float64    `json:"subtotalAfterDeductions,omitempty"`
-	RemittedAmount          *float64    `json:"remittedAmount,omitempty"`
-	OutworkChargeBase       *float64    `json:"outworkChargeBase,omitempty"`
-	OutworkChargeGst        *float64    `json:"outworkChargeGst,omitempty"`
-	OutworkChargeTotal      *float64    `json:"outworkChargeTotal,omitempty"`
+	FieldTotals             []fieldCalc             `json:"fieldTotals"`
+	TotalBaseAmount         money.Amount            `json:"totalBaseAmount"`
+	TotalGSTAmount          money.Amount            `json:"totalGSTAmount"`
+	TotalAmount             money.Amount            `json:"totalAmount"`
+	NetPayable              money.Amount            `json:"netPayable"`
+	NetReceivable           money.Amount            `json:"netReceivable"`
+	BasMapping              basMapping              `json:"basMapping"`
+	GstRate                 float64                 `json:"gstRate"` // Clinic rate for the entry date, used on the service fee and outwork charge
+	NetFee                  *money.Amount           `json:"netFee,omitempty"`
+	ServiceFeeBase          *money.Amount           `json:"serviceFeeBase,omitempty"`
+	GstOnServiceFee         *money.Amount           `json:"gstOnServiceFee,omitempty"`
+	TotalServiceFee         *money.Amount           `json:"totalServiceFee,omitempty"`
+	TotalReductions         *money.Amount           `json:"totalReductions,omitempty"`
+	TotalReimbursements     *money.Amount           `json:"totalReimbursements,omitempty"`
+	ReductionBreakdown      []fieldCalc             `json:"reductionBreakdown,omitempty"`
+	ReimbursementBreakdown  []fieldCalc             `json:"reimbursementBreakdown,omitempty"`
+	SubtotalAfterDeductions *money.Amount           `json:"subtotalAfterDeductions,omitempty"`
+	RemittedAmount          *money.Amount           `json:"remittedAmount,omitempty"`
+	OutworkChargeBase       *money.Amount           `json:"outworkChargeBase,omitempty"`
+	OutworkChargeGst        *money.Amount           `json:"outworkChargeGst,omitempty"`
+	OutworkChargeTotal      *money.Amount           `json:"outworkChargeTotal,omitempty"`
+	FormulaResults          map[string]money.Amount `json:"formulaResults,omitempty"`
 }
 
-func round2(n float64) float64 { return math.Round(n*100) / 100 }
-
-// parseFloat parses a numeric value from JSON (number or string).
-func parseFloat(v interface{}) float64 {
+// parseAmount parses an amount from JSON (number or string); anything else is 0.
+func parseAmount(v interface{}) money.Amount {
 	switch x := v.(type) {
 	case float64:
-		return x
+		return money.FromFloat(x)
 	case int:
-		return float64(x)
+		return money.Cents(int64(x) * 100)
 	case int64:
-		return float64(x)
+		return money.Cents(x * 100)
 	case string:
-		f, _ := strconv.ParseFloat(x, 64)
-		return f
+		a, _ := money.Parse(x)
+		return a
 	}
 	return 0
 }
 
-func calcGST(amount, rate float64, gstType string, manualGst *float64) (base, gst, total float64) {
+// calcGST splits an amount into base, GST and total. Base plus GST is always exactly the total.
+func calcGST(amount money.Amount, rate float64, gstType string, manualGst *money.Amount) (base, gst, total money.Amount) {
 	if gstType == "manual" {
-		m := 0.0
+		var m money.Amount
 		if manualGst != nil {
 			m = *manualGst
 		}
-		return round2(amount), round2(m), round2(amount + m)
+		return amount, m, amount + m
 	}
 	if rate == 0 {
 		return amount, 0, amount
 	}
-	rateDec := rate / 100
 	if gstType == "inclusive" {
-		gst = amount - (amount / (1 + rateDec))
-		base = amount - gst
-		return round2(base), round2(gst), amount
+		gst = amount.GSTIncluded(rate)
+		return amount - gst, gst, amount
 	}
-	gst = amount * rateDec
-	total = amount + gst
-	return amount, round2(gst), round2(total)
+	gst = amount.GST(rate)
+	return amount, gst, amount + gst
 }
 
 // getSection returns "expense" if section starts with "expense" (e.g. expense, expenses, Expenses 1), else "income"
@@ -126,12 +111,21 @@ func getSection(s string) string {
 	return "income"
 }
 
+// IsExpenseSection reports whether a field section is treated as an expense section.
+func IsExpenseSection(section string) bool {
+	return getSection(section) == "expense"
+}
+
 const defaultServiceFeePct = 50.0
 
 // RunEntryCalculation computes field totals, NET FEE, and deductions from form definition and raw values.
+// Fields hidden by the form's conditional logic are left out. Formula fields are computed and then
+// treated like currency fields; every active formula's result is also reported in formulaResults.
 // formFieldsJSON and valuesJSON are the raw JSONB from DB; formType, formServiceFeePct from form row.
 // deductionsJSON can be nil; if present it may contain serviceFacilityFeePercent and serviceFeeOverride.
 // When formOutworkEnabled is true and formOutworkRatePercent > 0, expense GST is consolidated into a single outwork charge.
+// tax holds the GST rates for the entry date; see TaxSettings. BAS-excluded fields count towards the
+// totals but not the BAS mapping.
 func RunEntryCalculation(
 	formFieldsJSON []byte,
 	formType string,
@@ -140,8 +134,9 @@ func RunEntryCalculation(
 	formOutworkRatePercent *float64,
 	valuesJSON []byte,
 	deductionsJSON []byte,
+	tax TaxSettings,
 ) ([]byte, error) {
-	var fields []calcField
+	var fields []domain.CustomFormField
 	if err := json.Unmarshal(formFieldsJSON, &fields); err != nil {
 		return nil, err
 	}
@@ -158,60 +153,76 @@ func RunEntryCalculation(
 	}
 
 	fieldTotals := make([]fieldCalc, 0)
-	var totalBase, totalGst, totalAmount float64
-	var incomeBase, incomeGst, incomeTotal float64
-	var expenseBase, expenseGst, expenseTotal float64
+	var totalBase, totalGst, totalAmount money.Amount
+	var incomeBase, incomeGst, incomeTotal money.Amount
+	var expenseBase, expenseGst, expenseTotal money.Amount
+	var excludedSales, excludedExpenses money.Amount // BAS-excluded income total and expense base
 
 	valueByID := make(map[string]entryValue)
+	raw := make(map[string]interface{})
 	for _, v := range values {
 		if v.FieldID != "" {
 			valueByID[v.FieldID] = v
+			raw[v.FieldID] = v.Value
 		}
 	}
+	ev := EvaluateEntry(fields, raw)
+	if err := formulaError(fields, ev); err != nil {
+		return nil, err
+	}
 
 	for _, f := range fields {
-		if f.Type != "number" && f.Type != "currency" {
+		if f.Type != domain.FieldTypeNumber && f.Type != domain.FieldTypeCurrency && f.Type != domain.FieldTypeFormula {
 			continue
 		}
+		if !ev.States[f.ID].Active {
+			continue // hidden by conditional logic
+		}
 		if !f.IncludeInTotal {
 			continue
 		}
 		v, ok := valueByID[f.ID]
-		if !ok {
+		var numVal money.Amount
+		switch {
+		case f.Type == domain.FieldTypeFormula:
+			numVal = money.FromFloat(ev.Formulas[f.ID])
+		case !ok:
 			continue
+		default:
+			numVal = parseAmount(v.Value)
 		}
-		numVal := parseFloat(v.Value)
-		var manual *float64
+		var manual *money.Amount
 		if v.ManualGstAmount != nil {
 			manual = v.ManualGstAmount
 		}
-		rate := 0.0
-		gstType := "exclusive"
-		if f.GstConfig != nil {
-			rate = f.GstConfig.Rate
-			gstType = f.GstConfig.Type
-			if gstType == "" {
-				gstType = "exclusive"
-			}
-		}
-		var base, gst, total float64
-		if f.GstConfig != nil && f.GstConfig.Enabled {
-			base, gst, total = calcGST(numVal, rate, gstType, manual)
+		ft := tax.forField(f)
+		var base, gst, total money.Amount
+		if ft.charged {
+			base, gst, total = calcGST(numVal, ft.rate, ft.gstType, manual)
 		} else {
 			base, gst, total = numVal, 0, numVal
 		}
 		fieldTotals = append(fieldTotals, fieldCalc{
-			FieldID:     f.ID,
-			FieldName:   f.Name,
-			BaseAmount:  base,
-			GstAmount:   gst,
-			TotalAmount: total,
-			GstRate:     rate,
-			GstType:     gstType,
+			FieldID:      f.ID,
+			FieldName:    f.Name,
+			BaseAmount:   base,
+			GstAmount:    gst,
+			TotalAmount:  total,
+			GstRate:      ft.rate,
+			GstType:      ft.gstType,
+			TaxCode:      ft.code,
+			TaxTreatment: ft.treatment,
 		})
+		expense := formType == "expense" || (formType == "both" && getSection(f.Section) == "expense")
+		if ft.treatment == domain.TaxTreatmentBASExcluded {
+			if expense {
+				excludedExpenses += base
+			} else {
+				excludedSales += total
+			}
+		}
 		if formType == "both" {
-			sec := getSection(f.Section)
-			if sec == "expense" {
+			if expense {
 				expenseBase += base
 				expenseGst += gst
 				expenseTotal += total
@@ -236,35 +247,42 @@ func RunEntryCalculation(
 	var bas basMapping
 	switch formType {
 	case "income":
-		bas = basMapping{GstOnSales1A: totalGst, TotalSalesG1: totalAmount}
+		bas = basMapping{GstOnSales1A: totalGst, TotalSalesG1: totalAmount - excludedSales}
 	case "expense":
-		bas = basMapping{GstCredit1B: totalGst, ExpensesG11: totalBase}
+		bas = basMapping{GstCredit1B: totalGst, ExpensesG11: totalBase - excludedExpenses}
 	default:
 		bas = basMapping{
-			GstOnSales1A: round2(incomeGst),
-			GstCredit1B:  round2(expenseGst),
-			TotalSalesG1: round2(incomeTotal),
-			ExpensesG11:  round2(expenseBase),
+			GstOnSales1A: incomeGst,
+			GstCredit1B:  expenseGst,
+			TotalSalesG1: incomeTotal - excludedSales,
+			ExpensesG11:  expenseBase - excludedExpenses,
 		}
 	}
 
 	out := calculationsOutput{
 		FieldTotals:     fieldTotals,
-		TotalBaseAmount: round2(totalBase),
-		TotalGSTAmount:  round2(totalGst),
-		TotalAmount:     round2(totalAmount),
+		TotalBaseAmount: totalBase,
+		TotalGSTAmount:  totalGst,
+		TotalAmount:     totalAmount,
 		NetPayable:      0,
 		NetReceivable:   0,
 		BasMapping:      bas,
+		GstRate:         tax.GSTRate,
+	}
+	if len(ev.Formulas) > 0 {
+		out.FormulaResults = make(map[string]money.Amount, len(ev.Formulas))
+		for id, v := range ev.Formulas {
+			out.FormulaResults[id] = money.FromFloat(v)
+		}
 	}
 	if formType == "expense" {
-		out.NetPayable = round2(totalAmount)
+		out.NetPayable = totalAmount
 	}
 	if formType == "income" {
-		out.NetReceivable = round2(totalAmount)
+		out.NetReceivable = totalAmount
 	}
 	if formType == "income" || formType == "both" {
-		nf := round2(totalBase)
+		nf := totalBase
 		out.NetFee = &nf
 	}
 
@@ -278,20 +296,19 @@ func RunEntryCalculation(
 	}
 	if hasIncome && pct > 0 {
 		netFee := out.TotalBaseAmount
-		serviceBase := netFee * (pct / 100.0)
+		serviceBase := netFee.Percent(pct, money.DefaultRounding())
 		if deductions != nil && deductions.ServiceFeeOverride != nil {
 			serviceBase = *deductions.ServiceFeeOverride
 		}
-		serviceBase = round2(serviceBase)
-		gstOnSvc := round2(serviceBase * 0.1)
-		totalSvc := round2(serviceBase + gstOnSvc)
+		gstOnSvc := serviceBase.GST(tax.GSTRate)
+		totalSvc := serviceBase + gstOnSvc
 		out.ServiceFeeBase = &serviceBase
 		out.GstOnServiceFee = &gstOnSvc
 		out.TotalServiceFee = &totalSvc
 
 		// Additional Reductions: expense-section fields only (never income). Only the GST portion is shown and applied.
 		// When entryPaymentResponsibility is set in deductions, use it for all expense fields (so e.g. Lab Fee shows when entry is "Pay by clinic").
-		var totalRedGst, totalReimb float64
+		var totalRedGst, totalReimb money.Amount
 		var redBreak, reimbBreak []fieldCalc
 		entryPayResp := ""
 		if deductions != nil && deductions.EntryPaymentResponsibility != nil {
@@ -314,7 +331,7 @@ func RunEntryCalculation(
 			}
 			payResp := entryPayResp
 			if payResp == "" {
-				payResp = strings.ToLower(f.PaymentResp)
+				payResp = strings.ToLower(f.PaymentResponsibility)
 			}
 			if payResp == "" {
 				payResp = "owner"
@@ -327,8 +344,6 @@ func RunEntryCalculation(
 				reimbBreak = append(reimbBreak, *ft)
 			}
 		}
-		totalRedGst = round2(totalRedGst)
-		totalReimb = round2(totalReimb)
 		out.TotalReimbursements = &totalReimb
 		out.ReductionBreakdown = redBreak
 		out.ReimbursementBreakdown = reimbBreak
@@ -338,15 +353,15 @@ func RunEntryCalculation(
 		if formOutworkEnabled && formOutworkRatePercent != nil && *formOutworkRatePercent > 0 {
 			outworkRate = *formOutworkRatePercent
 		}
-		var effectiveReductions float64
+		var effectiveReductions money.Amount
 		if outworkRate > 0 {
-			var totalOutworkCosts float64
+			var totalOutworkCosts money.Amount
 			for _, r := range redBreak {
 				totalOutworkCosts += r.BaseAmount
 			}
-			outworkChargeBase := round2(totalOutworkCosts * (outworkRate / 100.0))
-			outworkChargeGst := round2(outworkChargeBase * 0.1)
-			outworkChargeTotal := round2(outworkChargeBase + outworkChargeGst)
+			outworkChargeBase := totalOutworkCosts.Percent(outworkRate, money.DefaultRounding())
+			outworkChargeGst := outworkChargeBase.GST(tax.GSTRate)
+			outworkChargeTotal := outworkChargeBase + outworkChargeGst
 			out.OutworkChargeBase = &outworkChargeBase
 			out.OutworkChargeGst = &outworkChargeGst
 			out.OutworkChargeTotal = &outworkChargeTotal
@@ -356,8 +371,8 @@ func RunEntryCalculation(
 		}
 		out.TotalReductions = &effectiveReductions
 
-		sub := round2(netFee - serviceBase)
-		rem := round2(netFee - totalSvc + totalReimb - effectiveReductions)
+		sub := netFee - serviceBase
+		rem := netFee - totalSvc + totalReimb - effectiveReductions
 		out.SubtotalAfterDeductions = &sub
 		out.RemittedAmount = &rem
 	}
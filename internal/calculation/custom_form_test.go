@@ -0,0 +1,132 @@
+package calculation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/money"
+)
+
+// randomEntry builds a form of currency fields with assorted GST settings and an entry for it.
+func randomEntry(rng *rand.Rand) (formType string, fieldsJSON, valuesJSON []byte) {
+	formType = []string{"income", "expense", "both"}[rng.Intn(3)]
+	var fields []domain.CustomFormField
+	var values []map[string]interface{}
+	for i := 0; i < 1+rng.Intn(6); i++ {
+		f := domain.CustomFormField{
+			ID:             fmt.Sprintf("f%d", i),
+			Name:           fmt.Sprintf("Field %d", i),
+			Type:           domain.FieldTypeCurrency,
+			Section:        []string{"income", "expense"}[rng.Intn(2)],
+			IncludeInTotal: true,
+		}
+		if rng.Intn(4) > 0 {
+			f.GstConfig = &domain.FieldGSTConfig{
+				Enabled: rng.Intn(5) > 0,
+				Type:    []string{"inclusive", "exclusive", "manual", ""}[rng.Intn(4)],
+			}
+			if rng.Intn(2) == 0 {
+				rate := float64(rng.Intn(2001)) / 100
+				f.GstConfig.Rate = &rate
+			}
+		}
+		fields = append(fields, f)
+		v := map[string]interface{}{
+			"fieldId": f.ID,
+			"value":   fmt.Sprintf("%d.%02d", rng.Intn(200000)-20000, rng.Intn(100)),
+		}
+		if rng.Intn(2) == 0 {
+			v["manualGstAmount"] = fmt.Sprintf("%d.%02d", rng.Intn(500), rng.Intn(100))
+		}
+		values = append(values, v)
+	}
+	fieldsJSON, _ = json.Marshal(fields)
+	valuesJSON, _ = json.Marshal(values)
+	return formType, fieldsJSON, valuesJSON
+}
+
+// Every entry's totals are exactly the sum of its field totals, base plus GST is the total at every
+// level, and the BAS mapping carries the same figures.
+func TestEntryTotalsReconcile(t *testing.T) {
+	f := func(seed int64) bool {
+		rng := rand.New(rand.NewSource(seed))
+		formType, fieldsJSON, valuesJSON := randomEntry(rng)
+		out, err := RunEntryCalculation(fieldsJSON, formType, nil, false, nil, valuesJSON, nil, TaxSettings{GSTRate: 10})
+		if err != nil {
+			t.Logf("seed %d: %v", seed, err)
+			return false
+		}
+		var calc calculationsOutput
+		if err := json.Unmarshal(out, &calc); err != nil {
+			return false
+		}
+
+		var fields []domain.CustomFormField
+		_ = json.Unmarshal(fieldsJSON, &fields)
+		section := make(map[string]string, len(fields))
+		for _, f := range fields {
+			section[f.ID] = getSection(f.Section)
+		}
+		var base, gst, total, expenseBase, expenseGST money.Amount
+		for _, ft := range calc.FieldTotals {
+			if ft.BaseAmount+ft.GstAmount != ft.TotalAmount {
+				return false
+			}
+			sign := money.Amount(1)
+			if formType == "both" && section[ft.FieldID] == "expense" {
+				sign = -1
+				expenseBase += ft.BaseAmount
+				expenseGST += ft.GstAmount
+			}
+			base += sign * ft.BaseAmount
+			gst += sign * ft.GstAmount
+			total += sign * ft.TotalAmount
+		}
+		if calc.TotalBaseAmount != base || calc.TotalGSTAmount != gst || calc.TotalAmount != total {
+			return false
+		}
+		if calc.TotalBaseAmount+calc.TotalGSTAmount != calc.TotalAmount {
+			return false
+		}
+		bas := calc.BasMapping
+		switch formType {
+		case "income":
+			return bas.GstOnSales1A == gst && bas.TotalSalesG1 == total && bas.GstCredit1B == 0
+		case "expense":
+			return bas.GstCredit1B == gst && bas.ExpensesG11 == base && bas.GstOnSales1A == 0
+		default:
+			return bas.GstOnSales1A-bas.GstCredit1B == gst && bas.GstCredit1B == expenseGST && bas.ExpensesG11 == expenseBase
+		}
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCalcGSTBasePlusGSTIsTotal(t *testing.T) {
+	f := func(c int64, r uint16, manual int32, kind uint8) bool {
+		amount := money.Cents(c % 1_000_000_000_000)
+		rate := float64(r%10001) / 100
+		m := money.Cents(int64(manual))
+		gstType := []string{"inclusive", "exclusive", "manual"}[kind%3]
+		base, gst, total := calcGST(amount, rate, gstType, &m)
+		if base+gst != total {
+			return false
+		}
+		switch gstType {
+		case "inclusive":
+			return total == amount && gst == amount.GSTIncluded(rate)
+		case "exclusive":
+			return base == amount && gst == amount.GST(rate)
+		default:
+			return base == amount && gst == m
+		}
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+}
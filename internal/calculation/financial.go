@@ -0,0 +1,314 @@
+package calculation
+
+import (
+	"errors"
+
+	constants "github.com/iamarpitzala/aca-reca-backend/constant"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/money"
+)
+
+// Financial form calculation variants (dental service-fee statements)
+const (
+	VariantNetA1   = "A1" // Net method without super holding
+	VariantNetA2   = "A2" // Net method with super holding
+	VariantGrossB1 = "B1" // Gross method, lab fee paid by clinic
+	VariantGrossB2 = "B2" // Gross method with GST on lab fee
+	VariantGrossB3 = "B3" // Gross method with merchant / bank fee
+	VariantGrossB4 = "B4" // Gross method, GST on patient fee + lab fee paid by dentist
+	VariantGrossB5 = "B5" // Gross method with outwork charge rate
+)
+
+const defaultOutworkRatePercent = 40.0
+
+// commissionAmount applies a PERCENTAGE share to base, or returns the FIXED amount as is.
+func commissionAmount(commissionType string, share float64, base money.Amount) money.Amount {
+	if commissionType == constants.FIXED {
+		return money.FromFloat(share)
+	}
+	return base.Percent(share, money.DefaultRounding())
+}
+
+// CalculateNetMethod evaluates the net method: the clinic pays the dentist their commission
+// (OwnerCommission) on the net patient fee, with GST on top (A1) or split into a
+// commission and super component first when super holding is enabled (A2). gstRate is the
+// clinic's GST rate in percent on the calculation date.
+func CalculateNetMethod(cfg domain.NetMethodConfig, in domain.CalculationInput, gstRate float64) (*domain.CalculationResult, *domain.BASMapping, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	result := &domain.CalculationResult{}
+	bas := &domain.BASMapping{}
+
+	// A = Gross Patient Fee
+	A := in.GrossPatientFee
+	// B = Lab Fee (if enabled)
+	var B money.Amount
+	if cfg.LabFees {
+		B = in.LabFee
+	}
+	// C = Net Patient Fee
+	C := A - B
+	// D = Commission for Dentist
+	D := commissionAmount(cfg.CommissionType, cfg.OwnerCommission, C)
+
+	if cfg.SuperHoldingEnabled {
+		if cfg.SuperPercent == nil {
+			return nil, nil, errors.New("superPercent required when superHoldingEnabled")
+		}
+		superPct := *cfg.SuperPercent
+		// F = Commission Component = D ÷ (1 + super%)
+		F := D.Ratio(100, 100+superPct, money.DefaultRounding())
+		// E = Super Component = F × super%, taken as the rest of D so that E + F = D
+		E := D - F
+		// G = Total for Reconciliation = E + F
+		G := E + F
+		// H = GST on Commission = F × GST%
+		H := F.GST(gstRate)
+		// I = Total Payment to Dentist = F + H
+		I := F + H
+
+		result.Variant = VariantNetA2
+		result.CommissionForDentist = D
+		result.CommissionComponent = F
+		result.SuperComponent = E
+		result.TotalForReconciliation = G
+		result.GSTOnCommission = H
+		result.TotalCommission = I
+
+		bas.Field1A = H
+		bas.FieldG1 = I
+	} else {
+		// E = GST on Commission = D × GST%
+		E := D.GST(gstRate)
+		// F = Total Commission = D + E
+		F := D + E
+
+		result.Variant = VariantNetA1
+		result.CommissionForDentist = D
+		result.GSTOnCommission = E
+		result.TotalCommission = F
+
+		bas.Field1A = E
+		bas.FieldG1 = F
+	}
+	result.NetPatientFee = C
+	result.GSTRate = gstRate
+	return result, bas, nil
+}
+
+// CalculateGrossMethod evaluates the gross method: the dentist collects the patient fee and
+// pays the clinic a service & facility fee (ClinicCommission). The variant is chosen from
+// the configuration, falling back to the merchant-fee variant when such fees are supplied.
+// gstRate is the clinic's GST rate in percent on the calculation date.
+func CalculateGrossMethod(cfg domain.GrossMethodConfig, in domain.CalculationInput, gstRate float64) (*domain.CalculationResult, *domain.BASMapping, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	A := in.GrossPatientFee
+	switch {
+	case cfg.OutworkRateEnabled:
+		r, b := grossB5(cfg, in, A, gstRate)
+		return r, b, nil
+	case cfg.PaidBy == constants.PAID_BY_OWNER:
+		r, b := grossB4(cfg, in, A, gstRate)
+		return r, b, nil
+	case in.MerchantFeeInclGST > 0 || in.BankFee > 0:
+		r, b := grossB3(cfg, in, A, gstRate)
+		return r, b, nil
+	case cfg.GSTOnLabFee:
+		r, b := grossB2(cfg, in, A, gstRate)
+		return r, b, nil
+	default:
+		r, b := grossB1(cfg, in, A, gstRate)
+		return r, b, nil
+	}
+}
+
+// B1. Standard (Lab Fee Paid by Clinic)
+func grossB1(cfg domain.GrossMethodConfig, in domain.CalculationInput, A money.Amount, gstRate float64) (*domain.CalculationResult, *domain.BASMapping) {
+	// B = Lab Fee
+	B := in.LabFee
+	// C = Net Patient Fee
+	C := A - B
+	// D = Service & Facility Fee
+	D := commissionAmount(cfg.CommissionType, cfg.ClinicCommission, C)
+	// E = GST on Service Fee
+	E := D.GST(gstRate)
+	// F = Total Service Fee
+	F := D + E
+	// G = Amount Remitted to Dentist
+	G := C - F
+
+	result := &domain.CalculationResult{
+		Variant:                 VariantGrossB1,
+		GSTRate:                 gstRate,
+		NetPatientFee:           C,
+		ServiceFacilityFee:      D,
+		GSTOnServiceFee:         E,
+		TotalServiceFee:         F,
+		AmountRemittedToDentist: G,
+	}
+	bas := &domain.BASMapping{
+		Field1B:  E,
+		FieldG1:  A,
+		FieldG11: B + F,
+	}
+	return result, bas
+}
+
+// B2. With GST on Lab Fee
+func grossB2(cfg domain.GrossMethodConfig, in domain.CalculationInput, A money.Amount, gstRate float64) (*domain.CalculationResult, *domain.BASMapping) {
+	// B = Lab Fee
+	B := in.LabFee
+	// G = GST on Lab Fee
+	G := in.GSTOnLabFee
+	// C = Net Patient Fee
+	C := A - B
+	// D = Service & Facility Fee
+	D := commissionAmount(cfg.CommissionType, cfg.ClinicCommission, C)
+	// E = GST on Service Fee
+	E := D.GST(gstRate)
+	// F = Total Service Fee
+	F := D + E
+	// I = Amount Remitted to Dentist
+	I := C - F - G
+
+	result := &domain.CalculationResult{
+		Variant:                 VariantGrossB2,
+		GSTRate:                 gstRate,
+		NetPatientFee:           C,
+		ServiceFacilityFee:      D,
+		GSTOnServiceFee:         E,
+		TotalServiceFee:         F,
+		AmountRemittedToDentist: I,
+	}
+	bas := &domain.BASMapping{
+		Field1B:  E + G,
+		FieldG1:  A,
+		FieldG11: B + F + G,
+	}
+	return result, bas
+}
+
+// B3. With Merchant Fee / Bank Fee
+func grossB3(cfg domain.GrossMethodConfig, in domain.CalculationInput, A money.Amount, gstRate float64) (*domain.CalculationResult, *domain.BASMapping) {
+	// B = Lab Fee
+	B := in.LabFee
+	// G = Merchant Fee (Incl GST)
+	G := in.MerchantFeeInclGST
+	// K = Bank Fee
+	K := in.BankFee
+	// C = Net Patient Fee
+	C := A - B
+	// D = Service & Facility Fee
+	D := commissionAmount(cfg.CommissionType, cfg.ClinicCommission, C)
+	// E = GST on Service Fee
+	E := D.GST(gstRate)
+	// F = Total Service Fee
+	F := D + E
+	// I = GST component of the merchant fee = G × GST% / (100 + GST%)
+	I := G.GSTIncluded(gstRate)
+	// J = Net Merchant Fee
+	J := G - I
+	// H = Amount Remitted to Dentist
+	H := C - F - G - K
+
+	result := &domain.CalculationResult{
+		Variant:                 VariantGrossB3,
+		GSTRate:                 gstRate,
+		NetPatientFee:           C,
+		ServiceFacilityFee:      D,
+		GSTOnServiceFee:         E,
+		TotalServiceFee:         F,
+		MerchantFeeGSTComponent: I,
+		NetMerchantFee:          J,
+		AmountRemittedToDentist: H,
+	}
+	bas := &domain.BASMapping{
+		Field1B:  E + I,
+		FieldG1:  A,
+		FieldG11: B + F + G + K,
+	}
+	return result, bas
+}
+
+// B4. GST on Patient Fee + Lab Fee Paid by Dentist
+func grossB4(cfg domain.GrossMethodConfig, in domain.CalculationInput, A money.Amount, gstRate float64) (*domain.CalculationResult, *domain.BASMapping) {
+	// B = GST on Patient Fee
+	B := in.GSTOnPatientFee
+	// I = Lab Fee Paid by Dentist
+	I := in.LabFeePaidByDentist
+	// C = Patient Fee Excl GST
+	C := A - B
+	// E = Net Patient Fee = C - I
+	E := C - I
+	// F = Service & Facility Fee
+	F := commissionAmount(cfg.CommissionType, cfg.ClinicCommission, E)
+	// G = GST on Service Fee
+	G := F.GST(gstRate)
+	// H = Total Service Fee
+	H := F + G
+	// J = Amount Remitted to Dentist
+	J := E - H + I + B
+
+	result := &domain.CalculationResult{
+		Variant:                 VariantGrossB4,
+		GSTRate:                 gstRate,
+		NetPatientFee:           E,
+		ServiceFacilityFee:      F,
+		GSTOnServiceFee:         G,
+		TotalServiceFee:         H,
+		AmountRemittedToDentist: J,
+	}
+	bas := &domain.BASMapping{
+		Field1A:  B,
+		Field1B:  G,
+		FieldG1:  A,
+		FieldG11: H + I,
+	}
+	return result, bas
+}
+
+// B5. Outwork Charge Rate
+func grossB5(cfg domain.GrossMethodConfig, in domain.CalculationInput, A money.Amount, gstRate float64) (*domain.CalculationResult, *domain.BASMapping) {
+	outworkPct := defaultOutworkRatePercent
+	if cfg.OutworkRatePercent != nil {
+		outworkPct = *cfg.OutworkRatePercent
+	}
+	// B = Total Outwork Charge = w + x + y + z
+	B := in.OutworkLabFee + in.OutworkMerchantFee + in.OutworkGSTOnLabFee + in.OutworkGSTOnMerchantFee
+	// C = Net Patient Fee
+	C := A - B
+	// D = Service & Facility Fee
+	D := commissionAmount(cfg.CommissionType, cfg.ClinicCommission, C)
+	// E = Lab & Other Cost Charge
+	E := B.Percent(outworkPct, money.DefaultRounding())
+	// F = Total Service Fee + Other Charges
+	F := D + E
+	// G = GST on Service Fee
+	G := F.GST(gstRate)
+	// H = Total Charges incl GST
+	H := F + G
+	// I = Amount Remitted to Dentist
+	I := A - H
+
+	result := &domain.CalculationResult{
+		Variant:                     VariantGrossB5,
+		GSTRate:                     gstRate,
+		NetPatientFee:               C,
+		ServiceFacilityFee:          D,
+		TotalOutworkCharge:          B,
+		LabOtherCostCharge:          E,
+		TotalServiceFeeOtherCharges: F,
+		GSTOnServiceFee:             G,
+		TotalChargesInclGST:         H,
+		AmountRemittedToDentist:     I,
+	}
+	bas := &domain.BASMapping{
+		Field1B:  G,
+		FieldG1:  A,
+		FieldG11: H,
+	}
+	return result, bas
+}
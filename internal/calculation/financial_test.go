@@ -0,0 +1,325 @@
+package calculation
+
+import (
+	"reflect"
+	"testing"
+
+	constants "github.com/iamarpitzala/aca-reca-backend/constant"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/money"
+)
+
+func amt(t *testing.T, s string) money.Amount {
+	t.Helper()
+	a, err := money.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func pct(v float64) *float64 { return &v }
+
+// Worked examples from the dental service-fee statements: a $1,000.00 patient fee with a $200.00 lab
+// fee and a 40% commission, at 10% GST unless stated.
+func TestCalculateNetMethod(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     domain.NetMethodConfig
+		in      func(t *testing.T) domain.CalculationInput
+		gstRate float64
+		want    func(t *testing.T) (domain.CalculationResult, domain.BASMapping)
+	}{
+		{
+			name: "A1 without super holding",
+			cfg:  domain.NetMethodConfig{CommissionType: constants.PERCENTAGE, OwnerCommission: 40, ClinicCommission: 60, LabFees: true},
+			in: func(t *testing.T) domain.CalculationInput {
+				return domain.CalculationInput{GrossPatientFee: amt(t, "1000.00"), LabFee: amt(t, "200.00")}
+			},
+			gstRate: 10,
+			want: func(t *testing.T) (domain.CalculationResult, domain.BASMapping) {
+				return domain.CalculationResult{
+					Variant:              VariantNetA1,
+					GSTRate:              10,
+					NetPatientFee:        amt(t, "800.00"),
+					CommissionForDentist: amt(t, "320.00"),
+					GSTOnCommission:      amt(t, "32.00"),
+					TotalCommission:      amt(t, "352.00"),
+				}, domain.BASMapping{
+					Field1A: amt(t, "32.00"),
+					FieldG1: amt(t, "352.00"),
+				}
+			},
+		},
+		{
+			name: "A1 with the lab fee not deducted",
+			cfg:  domain.NetMethodConfig{CommissionType: constants.PERCENTAGE, OwnerCommission: 40, ClinicCommission: 60},
+			in: func(t *testing.T) domain.CalculationInput {
+				return domain.CalculationInput{GrossPatientFee: amt(t, "1000.00"), LabFee: amt(t, "200.00")}
+			},
+			gstRate: 10,
+			want: func(t *testing.T) (domain.CalculationResult, domain.BASMapping) {
+				return domain.CalculationResult{
+					Variant:              VariantNetA1,
+					GSTRate:              10,
+					NetPatientFee:        amt(t, "1000.00"),
+					CommissionForDentist: amt(t, "400.00"),
+					GSTOnCommission:      amt(t, "40.00"),
+					TotalCommission:      amt(t, "440.00"),
+				}, domain.BASMapping{
+					Field1A: amt(t, "40.00"),
+					FieldG1: amt(t, "440.00"),
+				}
+			},
+		},
+		{
+			name: "A1 with a fixed commission and no GST",
+			cfg:  domain.NetMethodConfig{CommissionType: constants.FIXED, OwnerCommission: 300, LabFees: true},
+			in: func(t *testing.T) domain.CalculationInput {
+				return domain.CalculationInput{GrossPatientFee: amt(t, "1000.00"), LabFee: amt(t, "200.00")}
+			},
+			gstRate: 0,
+			want: func(t *testing.T) (domain.CalculationResult, domain.BASMapping) {
+				return domain.CalculationResult{
+					Variant:              VariantNetA1,
+					NetPatientFee:        amt(t, "800.00"),
+					CommissionForDentist: amt(t, "300.00"),
+					TotalCommission:      amt(t, "300.00"),
+				}, domain.BASMapping{
+					FieldG1: amt(t, "300.00"),
+				}
+			},
+		},
+		{
+			name: "A2 with 12% super holding",
+			cfg: domain.NetMethodConfig{CommissionType: constants.PERCENTAGE, OwnerCommission: 40, ClinicCommission: 60, LabFees: true,
+				SuperHoldingEnabled: true, SuperPercent: pct(12)},
+			in: func(t *testing.T) domain.CalculationInput {
+				return domain.CalculationInput{GrossPatientFee: amt(t, "1000.00"), LabFee: amt(t, "200.00")}
+			},
+			gstRate: 10,
+			want: func(t *testing.T) (domain.CalculationResult, domain.BASMapping) {
+				// F = 320 / 1.12 = 285.714..., so the super component is the remaining 34.29
+				return domain.CalculationResult{
+					Variant:                VariantNetA2,
+					GSTRate:                10,
+					NetPatientFee:          amt(t, "800.00"),
+					CommissionForDentist:   amt(t, "320.00"),
+					CommissionComponent:    amt(t, "285.71"),
+					SuperComponent:         amt(t, "34.29"),
+					TotalForReconciliation: amt(t, "320.00"),
+					GSTOnCommission:        amt(t, "28.57"),
+					TotalCommission:        amt(t, "314.28"),
+				}, domain.BASMapping{
+					Field1A: amt(t, "28.57"),
+					FieldG1: amt(t, "314.28"),
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, bas, err := CalculateNetMethod(tt.cfg, tt.in(t), tt.gstRate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantResult, wantBAS := tt.want(t)
+			if !reflect.DeepEqual(*result, wantResult) {
+				t.Errorf("result = %+v, want %+v", *result, wantResult)
+			}
+			if !reflect.DeepEqual(*bas, wantBAS) {
+				t.Errorf("BAS = %+v, want %+v", *bas, wantBAS)
+			}
+		})
+	}
+}
+
+func TestCalculateNetMethodRequiresSuperPercent(t *testing.T) {
+	cfg := domain.NetMethodConfig{CommissionType: constants.FIXED, OwnerCommission: 300, SuperHoldingEnabled: true}
+	if _, _, err := CalculateNetMethod(cfg, domain.CalculationInput{}, 10); err == nil {
+		t.Fatal("expected an error without superPercent")
+	}
+}
+
+func TestCalculateGrossMethod(t *testing.T) {
+	clinicPaid := domain.GrossMethodConfig{CommissionType: constants.PERCENTAGE, ClinicCommission: 40, OwnerCommission: 60, PaidBy: constants.PAID_BY_CLINIC}
+	withGSTOnLabFee := clinicPaid
+	withGSTOnLabFee.GSTOnLabFee = true
+	ownerPaid := clinicPaid
+	ownerPaid.PaidBy = constants.PAID_BY_OWNER
+	outwork := clinicPaid
+	outwork.OutworkRateEnabled = true
+	outwork.OutworkRatePercent = pct(50)
+
+	tests := []struct {
+		name    string
+		cfg     domain.GrossMethodConfig
+		in      func(t *testing.T) domain.CalculationInput
+		gstRate float64
+		want    func(t *testing.T) (domain.CalculationResult, domain.BASMapping)
+	}{
+		{
+			name: "B1 lab fee paid by clinic",
+			cfg:  clinicPaid,
+			in: func(t *testing.T) domain.CalculationInput {
+				return domain.CalculationInput{GrossPatientFee: amt(t, "1000.00"), LabFee: amt(t, "200.00")}
+			},
+			gstRate: 10,
+			want: func(t *testing.T) (domain.CalculationResult, domain.BASMapping) {
+				return domain.CalculationResult{
+					Variant:                 VariantGrossB1,
+					GSTRate:                 10,
+					NetPatientFee:           amt(t, "800.00"),
+					ServiceFacilityFee:      amt(t, "320.00"),
+					GSTOnServiceFee:         amt(t, "32.00"),
+					TotalServiceFee:         amt(t, "352.00"),
+					AmountRemittedToDentist: amt(t, "448.00"),
+				}, domain.BASMapping{
+					Field1B:  amt(t, "32.00"),
+					FieldG1:  amt(t, "1000.00"),
+					FieldG11: amt(t, "552.00"),
+				}
+			},
+		},
+		{
+			name: "B2 with GST on the lab fee",
+			cfg:  withGSTOnLabFee,
+			in: func(t *testing.T) domain.CalculationInput {
+				return domain.CalculationInput{GrossPatientFee: amt(t, "1000.00"), LabFee: amt(t, "200.00"), GSTOnLabFee: amt(t, "20.00")}
+			},
+			gstRate: 10,
+			want: func(t *testing.T) (domain.CalculationResult, domain.BASMapping) {
+				return domain.CalculationResult{
+					Variant:                 VariantGrossB2,
+					GSTRate:                 10,
+					NetPatientFee:           amt(t, "800.00"),
+					ServiceFacilityFee:      amt(t, "320.00"),
+					GSTOnServiceFee:         amt(t, "32.00"),
+					TotalServiceFee:         amt(t, "352.00"),
+					AmountRemittedToDentist: amt(t, "428.00"),
+				}, domain.BASMapping{
+					Field1B:  amt(t, "52.00"),
+					FieldG1:  amt(t, "1000.00"),
+					FieldG11: amt(t, "572.00"),
+				}
+			},
+		},
+		{
+			name: "B3 with merchant and bank fees",
+			cfg:  clinicPaid,
+			in: func(t *testing.T) domain.CalculationInput {
+				return domain.CalculationInput{GrossPatientFee: amt(t, "1000.00"), LabFee: amt(t, "200.00"),
+					MerchantFeeInclGST: amt(t, "22.00"), BankFee: amt(t, "5.00")}
+			},
+			gstRate: 10,
+			want: func(t *testing.T) (domain.CalculationResult, domain.BASMapping) {
+				return domain.CalculationResult{
+					Variant:                 VariantGrossB3,
+					GSTRate:                 10,
+					NetPatientFee:           amt(t, "800.00"),
+					ServiceFacilityFee:      amt(t, "320.00"),
+					GSTOnServiceFee:         amt(t, "32.00"),
+					TotalServiceFee:         amt(t, "352.00"),
+					MerchantFeeGSTComponent: amt(t, "2.00"),
+					NetMerchantFee:          amt(t, "20.00"),
+					AmountRemittedToDentist: amt(t, "421.00"),
+				}, domain.BASMapping{
+					Field1B:  amt(t, "34.00"),
+					FieldG1:  amt(t, "1000.00"),
+					FieldG11: amt(t, "579.00"),
+				}
+			},
+		},
+		{
+			name: "B4 GST on patient fee, lab fee paid by dentist",
+			cfg:  ownerPaid,
+			in: func(t *testing.T) domain.CalculationInput {
+				return domain.CalculationInput{GrossPatientFee: amt(t, "1100.00"), GSTOnPatientFee: amt(t, "100.00"),
+					LabFeePaidByDentist: amt(t, "150.00")}
+			},
+			gstRate: 10,
+			want: func(t *testing.T) (domain.CalculationResult, domain.BASMapping) {
+				return domain.CalculationResult{
+					Variant:                 VariantGrossB4,
+					GSTRate:                 10,
+					NetPatientFee:           amt(t, "850.00"),
+					ServiceFacilityFee:      amt(t, "340.00"),
+					GSTOnServiceFee:         amt(t, "34.00"),
+					TotalServiceFee:         amt(t, "374.00"),
+					AmountRemittedToDentist: amt(t, "726.00"),
+				}, domain.BASMapping{
+					Field1A:  amt(t, "100.00"),
+					Field1B:  amt(t, "34.00"),
+					FieldG1:  amt(t, "1100.00"),
+					FieldG11: amt(t, "524.00"),
+				}
+			},
+		},
+		{
+			name: "B5 with a 50% outwork charge rate",
+			cfg:  outwork,
+			in: func(t *testing.T) domain.CalculationInput {
+				return domain.CalculationInput{GrossPatientFee: amt(t, "1000.00"),
+					OutworkLabFee: amt(t, "100.00"), OutworkMerchantFee: amt(t, "20.00"),
+					OutworkGSTOnLabFee: amt(t, "10.00"), OutworkGSTOnMerchantFee: amt(t, "2.00")}
+			},
+			gstRate: 10,
+			want: func(t *testing.T) (domain.CalculationResult, domain.BASMapping) {
+				return domain.CalculationResult{
+					Variant:                     VariantGrossB5,
+					GSTRate:                     10,
+					NetPatientFee:               amt(t, "868.00"),
+					ServiceFacilityFee:          amt(t, "347.20"),
+					TotalOutworkCharge:          amt(t, "132.00"),
+					LabOtherCostCharge:          amt(t, "66.00"),
+					TotalServiceFeeOtherCharges: amt(t, "413.20"),
+					GSTOnServiceFee:             amt(t, "41.32"),
+					TotalChargesInclGST:         amt(t, "454.52"),
+					AmountRemittedToDentist:     amt(t, "545.48"),
+				}, domain.BASMapping{
+					Field1B:  amt(t, "41.32"),
+					FieldG1:  amt(t, "1000.00"),
+					FieldG11: amt(t, "454.52"),
+				}
+			},
+		},
+		{
+			name: "B1 at a 15% GST rate",
+			cfg:  clinicPaid,
+			in: func(t *testing.T) domain.CalculationInput {
+				return domain.CalculationInput{GrossPatientFee: amt(t, "1000.00"), LabFee: amt(t, "200.00")}
+			},
+			gstRate: 15,
+			want: func(t *testing.T) (domain.CalculationResult, domain.BASMapping) {
+				return domain.CalculationResult{
+					Variant:                 VariantGrossB1,
+					GSTRate:                 15,
+					NetPatientFee:           amt(t, "800.00"),
+					ServiceFacilityFee:      amt(t, "320.00"),
+					GSTOnServiceFee:         amt(t, "48.00"),
+					TotalServiceFee:         amt(t, "368.00"),
+					AmountRemittedToDentist: amt(t, "432.00"),
+				}, domain.BASMapping{
+					Field1B:  amt(t, "48.00"),
+					FieldG1:  amt(t, "1000.00"),
+					FieldG11: amt(t, "568.00"),
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, bas, err := CalculateGrossMethod(tt.cfg, tt.in(t), tt.gstRate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantResult, wantBAS := tt.want(t)
+			if !reflect.DeepEqual(*result, wantResult) {
+				t.Errorf("result = %+v, want %+v", *result, wantResult)
+			}
+			if !reflect.DeepEqual(*bas, wantBAS) {
+				t.Errorf("BAS = %+v, want %+v", *bas, wantBAS)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package calculation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/formula"
+	"github.com/iamarpitzala/aca-reca-backend/internal/money"
+)
+
+// formulaValue computes a formula field, rounded to cents. A formula that fails counts as 0 and its
+// reason is kept in errs.
+func (e *entryEvaluator) formulaValue(id string) float64 {
+	if v, ok := e.results[id]; ok {
+		return v
+	}
+	if _, failed := e.errs[id]; failed {
+		return 0
+	}
+	if e.computing[id] {
+		e.errs[id] = "circular reference"
+		return 0
+	}
+	e.computing[id] = true
+	defer delete(e.computing, id)
+
+	expr, err := formula.Parse(e.fields[id].Formula)
+	var v float64
+	if err == nil {
+		v, err = expr.Eval(formulaEnv{e})
+	}
+	if err != nil {
+		e.errs[id] = err.Error()
+		return 0
+	}
+	v = money.FromFloat(v).Float64()
+	e.results[id] = v
+	return v
+}
+
+// numericValue is a field's value as a formula sees it: 0 when inactive or empty, 1 or 0 for a
+// checkbox, and the number entered (or computed) otherwise.
+func (e *entryEvaluator) numericValue(id string) (float64, error) {
+	f, ok := e.fields[id]
+	if !ok {
+		return 0, fmt.Errorf("unknown field %s", id)
+	}
+	if !e.state(id).Active {
+		return 0, nil
+	}
+	if f.Type == domain.FieldTypeFormula {
+		v := e.formulaValue(id)
+		if msg, failed := e.errs[id]; failed {
+			return 0, fmt.Errorf("%s: %s", f.Name, msg)
+		}
+		return v, nil
+	}
+	switch v := e.values[id].(type) {
+	case bool:
+		return boolNumber(v), nil
+	case string:
+		if f.Type == domain.FieldTypeCheckbox {
+			return boolNumber(v == "true"), nil
+		}
+	}
+	n, _ := parseNumber(e.values[id])
+	return n, nil
+}
+
+func boolNumber(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type formulaEnv struct{ e *entryEvaluator }
+
+func (env formulaEnv) Field(id string) (float64, error) { return env.e.numericValue(id) }
+
+// Section adds up the active number and currency fields of a section.
+func (env formulaEnv) Section(name string) (float64, error) {
+	var total float64
+	for _, f := range sectionFields(env.e.order, name) {
+		v, err := env.e.numericValue(f.ID)
+		if err != nil {
+			return 0, err
+		}
+		total += v
+	}
+	return total, nil
+}
+
+// sectionFields returns the number and currency fields of the named section, matched ignoring case.
+// Formula fields are not summed, so a formula can total the section it sits in.
+func sectionFields(fields []domain.CustomFormField, name string) []domain.CustomFormField {
+	var out []domain.CustomFormField
+	for _, f := range fields {
+		if (f.Type == domain.FieldTypeNumber || f.Type == domain.FieldTypeCurrency) &&
+			strings.EqualFold(strings.TrimSpace(f.Section), strings.TrimSpace(name)) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// validateFormulas checks that each formula field has an expression that parses and refers only to
+// fields that hold numbers and to sections that exist, recording its dependencies in deps.
+func validateFormulas(fields []domain.CustomFormField, byID map[string]domain.CustomFormField, deps map[string][]string, add func(domain.CustomFormField, string)) {
+	for _, f := range fields {
+		if f.Type != domain.FieldTypeFormula {
+			if strings.TrimSpace(f.Formula) != "" {
+				add(f, "formula only applies to formula fields")
+			}
+			continue
+		}
+		expr, err := formula.Parse(f.Formula)
+		if err != nil {
+			add(f, "invalid formula: "+err.Error())
+			continue
+		}
+		for _, ref := range expr.Refs() {
+			target, ok := byID[ref]
+			switch {
+			case !ok:
+				add(f, "formula refers to unknown field "+ref)
+				continue
+			case target.Type == domain.FieldTypeText || target.Type == domain.FieldTypeTextarea || target.Type == domain.FieldTypeDate:
+				add(f, fmt.Sprintf("formula cannot use %s, a %s field", target.Name, target.Type))
+			}
+			deps[f.ID] = append(deps[f.ID], ref)
+		}
+		for _, section := range expr.Sections() {
+			members := sectionFields(fields, section)
+			if len(members) == 0 {
+				add(f, fmt.Sprintf("formula sums section %q, which has no number or currency fields", section))
+			}
+			for _, m := range members {
+				deps[f.ID] = append(deps[f.ID], m.ID)
+			}
+		}
+	}
+}
+
+// formulaError reports the formula fields of an entry that could not be computed.
+func formulaError(fields []domain.CustomFormField, ev *EntryEvaluation) error {
+	var errs []domain.FieldError
+	for _, f := range fields {
+		if msg, failed := ev.Errors[f.ID]; failed && ev.States[f.ID].Active {
+			errs = append(errs, domain.FieldError{FieldID: f.ID, FieldName: f.Name, Message: "formula failed: " + msg})
+		}
+	}
+	if len(errs) > 0 {
+		return &domain.FieldValidationError{Errors: errs}
+	}
+	return nil
+}
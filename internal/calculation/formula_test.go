@@ -0,0 +1,89 @@
+package calculation
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+)
+
+func TestValidateFormFieldsFormulas(t *testing.T) {
+	number := func(id, section string) domain.CustomFormField {
+		return domain.CustomFormField{ID: id, Name: strings.ToUpper(id), Type: domain.FieldTypeNumber, Section: section}
+	}
+	formula := func(id, expr string) domain.CustomFormField {
+		return domain.CustomFormField{ID: id, Name: strings.ToUpper(id), Type: domain.FieldTypeFormula, Formula: expr}
+	}
+	tests := []struct {
+		name    string
+		fields  []domain.CustomFormField
+		wantErr string
+	}{
+		{
+			name:   "chain of formulas",
+			fields: []domain.CustomFormField{number("fee", "income"), formula("net", "fee * 0.6"), formula("gross", `net + sum("income")`)},
+		},
+		{
+			name:    "refers to itself",
+			fields:  []domain.CustomFormField{formula("x", "x + 1")},
+			wantErr: "circular reference: X → X",
+		},
+		{
+			name:    "cycle through another formula",
+			fields:  []domain.CustomFormField{number("fee", ""), formula("a", "b + fee"), formula("b", "{c} * 2"), formula("c", "if(fee > 0, a, 0)")},
+			wantErr: "circular reference: A → B → C → A",
+		},
+		{
+			name:    "unknown reference",
+			fields:  []domain.CustomFormField{number("fee", ""), formula("net", "fee - lab")},
+			wantErr: "formula refers to unknown field lab",
+		},
+		{
+			name:    "unknown braced reference",
+			fields:  []domain.CustomFormField{formula("net", "{lab-fee} * 2")},
+			wantErr: "formula refers to unknown field lab-fee",
+		},
+		{
+			name:    "text field",
+			fields:  []domain.CustomFormField{{ID: "note", Name: "NOTE", Type: domain.FieldTypeText}, formula("net", "note + 1")},
+			wantErr: "formula cannot use NOTE, a text field",
+		},
+		{
+			name:    "empty section",
+			fields:  []domain.CustomFormField{number("fee", "income"), formula("net", `sum("expense")`)},
+			wantErr: `sums section "expense", which has no number or currency fields`,
+		},
+		{
+			name:    "parse error",
+			fields:  []domain.CustomFormField{number("fee", ""), formula("net", "0 < fee < 10")},
+			wantErr: "invalid formula: comparisons cannot be chained",
+		},
+	}
+	for _, tt := range tests {
+		fieldsJSON, err := json.Marshal(tt.fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ValidateFormFields(fieldsJSON)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		var verr *domain.FieldValidationError
+		if !errors.As(err, &verr) {
+			t.Errorf("%s: error = %v, want a field validation error", tt.name, err)
+			continue
+		}
+		var found bool
+		for _, fe := range verr.Errors {
+			found = found || strings.Contains(fe.Message, tt.wantErr)
+		}
+		if !found {
+			t.Errorf("%s: errors = %+v, want %q", tt.name, verr.Errors, tt.wantErr)
+		}
+	}
+}
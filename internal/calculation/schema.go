@@ -0,0 +1,370 @@
+package calculation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+)
+
+const fieldDateLayout = "2006-01-02"
+
+// ParseFields decodes the form's fields JSONB into the typed field model.
+func ParseFields(formFieldsJSON []byte) ([]domain.CustomFormField, error) {
+	var fields []domain.CustomFormField
+	if len(formFieldsJSON) == 0 {
+		return fields, nil
+	}
+	if err := json.Unmarshal(formFieldsJSON, &fields); err != nil {
+		return nil, errors.New("fields must be an array of field definitions")
+	}
+	return fields, nil
+}
+
+// ValidateFormFields checks that the field definitions themselves are well formed
+// (unique IDs, known types, dropdown options, consistent validation rules, account links,
+// formulas that parse, and conditional logic and formulas that refer to other fields without
+// circular references).
+// Returns *domain.FieldValidationError when one or more definitions are invalid.
+func ValidateFormFields(formFieldsJSON []byte) error {
+	fields, err := ParseFields(formFieldsJSON)
+	if err != nil {
+		return err
+	}
+	var errs []domain.FieldError
+	add := func(f domain.CustomFormField, msg string) {
+		errs = append(errs, domain.FieldError{FieldID: f.ID, FieldName: f.Name, Message: msg})
+	}
+
+	seen := make(map[string]bool)
+	for _, f := range fields {
+		if strings.TrimSpace(f.ID) == "" {
+			add(f, "field id is required")
+		} else if seen[f.ID] {
+			add(f, "duplicate field id "+f.ID)
+		}
+		seen[f.ID] = true
+		if strings.TrimSpace(f.Name) == "" {
+			add(f, "field name is required")
+		}
+
+		switch f.Type {
+		case domain.FieldTypeText, domain.FieldTypeTextarea, domain.FieldTypeNumber, domain.FieldTypeCurrency,
+			domain.FieldTypeDate, domain.FieldTypeDropdown, domain.FieldTypeCheckbox, domain.FieldTypeFormula:
+		default:
+			add(f, fmt.Sprintf("unknown field type %q", f.Type))
+		}
+
+		if f.GstConfig != nil {
+			if r := f.GstConfig.Rate; r != nil && (*r < 0 || *r > 100) {
+				add(f, "GST rate must be between 0 and 100")
+			}
+			switch f.GstConfig.Type {
+			case "", "inclusive", "exclusive", "manual":
+			default:
+				add(f, "GST type must be inclusive, exclusive or manual")
+			}
+		}
+
+		if f.AccountID != "" {
+			if f.Type != domain.FieldTypeNumber && f.Type != domain.FieldTypeCurrency && f.Type != domain.FieldTypeFormula {
+				add(f, "accountId only applies to number, currency and formula fields")
+			}
+			if _, err := uuid.Parse(f.AccountID); err != nil {
+				add(f, "accountId must be a valid account ID")
+			}
+		}
+
+		if f.Type == domain.FieldTypeDropdown {
+			if len(f.Options) == 0 {
+				add(f, "dropdown must define at least one option")
+			}
+			optSeen := make(map[string]bool)
+			for _, o := range f.Options {
+				if strings.TrimSpace(o.Value) == "" {
+					add(f, "dropdown option value is required")
+					continue
+				}
+				if optSeen[o.Value] {
+					add(f, "duplicate dropdown option "+o.Value)
+				}
+				optSeen[o.Value] = true
+			}
+		}
+
+		if v := f.Validation; v != nil {
+			numeric := f.Type == domain.FieldTypeNumber || f.Type == domain.FieldTypeCurrency
+			textual := f.Type == domain.FieldTypeText || f.Type == domain.FieldTypeTextarea
+			if (v.Min != nil || v.Max != nil) && !numeric {
+				add(f, "min/max only apply to number and currency fields")
+			}
+			if v.Min != nil && v.Max != nil && *v.Min > *v.Max {
+				add(f, "min cannot be greater than max")
+			}
+			if (v.MinLength != nil || v.MaxLength != nil || v.Pattern != "") && !textual {
+				add(f, "length and pattern rules only apply to text fields")
+			}
+			if (v.MinLength != nil && *v.MinLength < 0) || (v.MaxLength != nil && *v.MaxLength < 0) {
+				add(f, "length limits cannot be negative")
+			}
+			if v.MinLength != nil && v.MaxLength != nil && *v.MinLength > *v.MaxLength {
+				add(f, "minLength cannot be greater than maxLength")
+			}
+			if v.Pattern != "" {
+				if _, err := regexp.Compile(v.Pattern); err != nil {
+					add(f, "invalid pattern: "+err.Error())
+				}
+			}
+			if (v.MinDate != "" || v.MaxDate != "") && f.Type != domain.FieldTypeDate {
+				add(f, "date range only applies to date fields")
+			}
+			minDate, minErr := parseOptionalDate(v.MinDate)
+			maxDate, maxErr := parseOptionalDate(v.MaxDate)
+			if minErr != nil {
+				add(f, "minDate must be YYYY-MM-DD")
+			}
+			if maxErr != nil {
+				add(f, "maxDate must be YYYY-MM-DD")
+			}
+			if minDate != nil && maxDate != nil && minDate.After(*maxDate) {
+				add(f, "minDate cannot be after maxDate")
+			}
+		}
+	}
+
+	byID := make(map[string]domain.CustomFormField, len(fields))
+	for _, f := range fields {
+		byID[f.ID] = f
+	}
+	deps := make(map[string][]string)
+	validateConditionalLogic(fields, byID, deps, add)
+	validateFormulas(fields, byID, deps, add)
+	reportCycle(fields, byID, deps, add)
+
+	if len(errs) > 0 {
+		return &domain.FieldValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// ValidateEntryValues checks submitted entry values against the form's field schema:
+// unknown or duplicate field IDs, required fields, type conformance, min/max, length,
+// pattern, dropdown options and date ranges. Fields hidden by conditional logic are not
+// checked, require rules add to the required fields, and formulas that cannot be computed
+// (say, dividing by an empty field) are reported against their field.
+// Returns *domain.FieldValidationError when one or more values are invalid.
+func ValidateEntryValues(formFieldsJSON []byte, valuesJSON []byte) error {
+	fields, err := ParseFields(formFieldsJSON)
+	if err != nil {
+		return err
+	}
+	var values []entryValue
+	if len(valuesJSON) > 0 {
+		if err := json.Unmarshal(valuesJSON, &values); err != nil {
+			return errors.New("values must be an array of {fieldId, value}")
+		}
+	}
+
+	fieldByID := make(map[string]domain.CustomFormField, len(fields))
+	for _, f := range fields {
+		fieldByID[f.ID] = f
+	}
+
+	var errs []domain.FieldError
+	valueByID := make(map[string]entryValue, len(values))
+	for _, v := range values {
+		if v.FieldID == "" {
+			errs = append(errs, domain.FieldError{FieldName: v.FieldName, Message: "fieldId is required"})
+			continue
+		}
+		if _, ok := fieldByID[v.FieldID]; !ok {
+			errs = append(errs, domain.FieldError{FieldID: v.FieldID, FieldName: v.FieldName, Message: "unknown field"})
+			continue
+		}
+		if _, dup := valueByID[v.FieldID]; dup {
+			errs = append(errs, domain.FieldError{FieldID: v.FieldID, FieldName: v.FieldName, Message: "duplicate value for field"})
+			continue
+		}
+		valueByID[v.FieldID] = v
+	}
+
+	raw := make(map[string]interface{}, len(valueByID))
+	for id, v := range valueByID {
+		raw[id] = v.Value
+	}
+	ev := EvaluateEntry(fields, raw)
+
+	for _, f := range fields {
+		state := ev.States[f.ID]
+		if !state.Active {
+			continue
+		}
+		if f.Type == domain.FieldTypeFormula {
+			// Computed, so any submitted value is ignored
+			if msg, failed := ev.Errors[f.ID]; failed {
+				errs = append(errs, domain.FieldError{FieldID: f.ID, FieldName: f.Name, Message: "formula failed: " + msg})
+			}
+			continue
+		}
+		v, ok := valueByID[f.ID]
+		if !ok || isEmptyValue(f, v.Value) {
+			if state.Required {
+				errs = append(errs, domain.FieldError{FieldID: f.ID, FieldName: f.Name, Message: "is required"})
+			}
+			continue
+		}
+		if msg := validateFieldValue(f, v.Value); msg != "" {
+			errs = append(errs, domain.FieldError{FieldID: f.ID, FieldName: f.Name, Message: msg})
+		}
+	}
+
+	if len(errs) > 0 {
+		return &domain.FieldValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func isEmptyValue(f domain.CustomFormField, v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(x) == ""
+	case bool:
+		// A required checkbox must be ticked.
+		return f.Type == domain.FieldTypeCheckbox && !x
+	}
+	return false
+}
+
+// validateFieldValue returns an error message for a non-empty value, or "" if valid.
+func validateFieldValue(f domain.CustomFormField, v interface{}) string {
+	rules := f.Validation
+	if rules == nil {
+		rules = &domain.FieldValidation{}
+	}
+	switch f.Type {
+	case domain.FieldTypeNumber, domain.FieldTypeCurrency:
+		n, ok := parseNumber(v)
+		if !ok {
+			return "must be a number"
+		}
+		if rules.Min != nil && n < *rules.Min {
+			return fmt.Sprintf("must be at least %v", *rules.Min)
+		}
+		if rules.Max != nil && n > *rules.Max {
+			return fmt.Sprintf("must be at most %v", *rules.Max)
+		}
+	case domain.FieldTypeText, domain.FieldTypeTextarea:
+		s, ok := v.(string)
+		if !ok {
+			return "must be text"
+		}
+		length := utf8.RuneCountInString(s)
+		if rules.MinLength != nil && length < *rules.MinLength {
+			return fmt.Sprintf("must be at least %d characters", *rules.MinLength)
+		}
+		if rules.MaxLength != nil && length > *rules.MaxLength {
+			return fmt.Sprintf("must be at most %d characters", *rules.MaxLength)
+		}
+		if rules.Pattern != "" {
+			re, err := regexp.Compile(rules.Pattern)
+			if err != nil || !re.MatchString(s) {
+				return "does not match the required format"
+			}
+		}
+	case domain.FieldTypeDropdown:
+		s, ok := scalarString(v)
+		if !ok {
+			return "must be one of the defined options"
+		}
+		for _, o := range f.Options {
+			if o.Value == s {
+				return ""
+			}
+		}
+		return "must be one of the defined options"
+	case domain.FieldTypeDate:
+		s, ok := v.(string)
+		if !ok {
+			return "must be a date (YYYY-MM-DD)"
+		}
+		d, err := time.Parse(fieldDateLayout, s)
+		if err != nil {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return "must be a date (YYYY-MM-DD)"
+			}
+			d = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		}
+		if minDate, _ := parseOptionalDate(rules.MinDate); minDate != nil && d.Before(*minDate) {
+			return "must be on or after " + rules.MinDate
+		}
+		if maxDate, _ := parseOptionalDate(rules.MaxDate); maxDate != nil && d.After(*maxDate) {
+			return "must be on or before " + rules.MaxDate
+		}
+	case domain.FieldTypeCheckbox:
+		switch x := v.(type) {
+		case bool:
+		case string:
+			if x != "true" && x != "false" {
+				return "must be true or false"
+			}
+		default:
+			return "must be true or false"
+		}
+	}
+	return ""
+}
+
+// parseNumber is the strict counterpart of parseFloat: it reports whether v is a finite number.
+func parseNumber(v interface{}) (float64, bool) {
+	var n float64
+	switch x := v.(type) {
+	case float64:
+		n = x
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		if err != nil {
+			return 0, false
+		}
+		n = f
+	default:
+		return 0, false
+	}
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, false
+	}
+	return n, true
+}
+
+func scalarString(v interface{}) (string, bool) {
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(x), true
+	}
+	return "", false
+}
+
+func parseOptionalDate(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	d, err := time.Parse(fieldDateLayout, s)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
@@ -0,0 +1,53 @@
+package calculation
+
+import "github.com/iamarpitzala/aca-reca-backend/internal/domain"
+
+// TaxSettings are the GST rules in force for one entry.
+type TaxSettings struct {
+	// GSTRate is the clinic's rate on the entry date, in percent. It applies to the service fee,
+	// the outwork charge and GST fields that do not set a rate of their own.
+	GSTRate float64
+	// AccountTaxes are the tax codes of the accounts the form's fields are linked to, by account ID.
+	AccountTaxes map[string]domain.AccountTaxCode
+}
+
+// fieldTax is how GST applies to one field.
+type fieldTax struct {
+	charged   bool // Whether GST applies at all
+	rate      float64
+	gstType   string
+	code      string // Tax code name, when the field is linked to an account
+	treatment string
+}
+
+// forField works out a field's GST. A field linked to an account follows the account's tax code:
+// only a taxable code charges GST, at the code's rate when it has one. Otherwise the field's own
+// GST settings apply. A charged field that sets no rate uses the clinic's rate; an explicit rate of
+// 0 is kept, so fields saved with a zero rate go on charging no GST.
+func (t TaxSettings) forField(f domain.CustomFormField) fieldTax {
+	ft := fieldTax{gstType: "exclusive"}
+	var rate *float64
+	if f.GstConfig != nil {
+		ft.charged = f.GstConfig.Enabled
+		rate = f.GstConfig.Rate
+		if f.GstConfig.Type != "" {
+			ft.gstType = f.GstConfig.Type
+		}
+	}
+	if code, ok := t.AccountTaxes[f.AccountID]; ok && f.AccountID != "" {
+		ft.code, ft.treatment = code.Name, code.Treatment
+		ft.charged = code.Treatment == domain.TaxTreatmentTaxable
+		if code.Rate > 0 {
+			rate = &code.Rate
+		}
+	}
+	switch {
+	case !ft.charged:
+		ft.rate = 0
+	case rate == nil:
+		ft.rate = t.GSTRate
+	default:
+		ft.rate = *rate
+	}
+	return ft
+}
@@ -60,10 +60,20 @@ type AccountType struct {
 	DeletedAt   *time.Time `db:"deleted_at"`
 }
 
+// Tax treatments of an account tax code (tbl_account_tax.treatment). Only taxable codes charge GST;
+// BAS-excluded amounts are also left out of the BAS.
+const (
+	TaxTreatmentTaxable     = "taxable"
+	TaxTreatmentGSTFree     = "gst_free"
+	TaxTreatmentInputTaxed  = "input_taxed"
+	TaxTreatmentBASExcluded = "bas_excluded"
+)
+
 type AccountTax struct {
 	ID          int        `db:"id"`
 	Name        string     `db:"name"`
-	Rate        float64    `db:"rate"`
+	Rate        float64    `db:"rate"` // 0 on a taxable code means the clinic's GST rate
+	Treatment   string     `db:"treatment"`
 	Description string     `db:"description"`
 	CreatedAt   time.Time  `db:"created_at"`
 	UpdatedAt   time.Time  `db:"updated_at"`
@@ -123,3 +133,11 @@ type BulkUpdateTaxRequest struct {
 type BulkArchiveAOCRequest struct {
 	IDs []string `json:"ids" validate:"omitempty,required"`
 }
+
+// AccountTaxCode is the tax code of an account, as a form field linked to that account uses it.
+type AccountTaxCode struct {
+	AccountID uuid.UUID `db:"account_id"`
+	Name      string    `db:"name"`
+	Rate      float64   `db:"rate"`
+	Treatment string    `db:"treatment"`
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audited entity types (tbl_audit_log.entity_type).
+const (
+	AuditEntityCustomForm          = "custom_form"
+	AuditEntityCustomFormEntry     = "custom_form_entry"
+	AuditEntityExpenseType         = "expense_type"
+	AuditEntityExpenseCategory     = "expense_category"
+	AuditEntityExpenseCategoryType = "expense_category_type"
+	AuditEntityExpenseEntry        = "expense_entry"
+	AuditEntityAccount             = "account"
+	AuditEntityClinic              = "clinic"
+	AuditEntityClinicGSTRate       = "clinic_gst_rate"
+	AuditEntityLoginLockout        = "login_lockout"
+)
+
+// Audit actions (tbl_audit_log.action).
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionPublish = "publish"
+	AuditActionArchive = "archive"
+)
+
+// AuditLog is one append-only record of a change. Before is empty for creates and After for deletes.
+// ClinicID is nil for entities that are not clinic scoped (chart of accounts).
+type AuditLog struct {
+	ID         uuid.UUID       `db:"id" json:"id"`
+	ClinicID   *uuid.UUID      `db:"clinic_id" json:"clinicId,omitempty"`
+	ActorID    uuid.UUID       `db:"actor_id" json:"actorId"`
+	EntityType string          `db:"entity_type" json:"entityType"`
+	EntityID   uuid.UUID       `db:"entity_id" json:"entityId"`
+	Action     string          `db:"action" json:"action"`
+	Before     json.RawMessage `db:"before" json:"before,omitempty"`
+	After      json.RawMessage `db:"after" json:"after,omitempty"`
+	CreatedAt  time.Time       `db:"created_at" json:"createdAt"`
+}
+
+// AuditLogFilter narrows an audit query. Zero values are ignored.
+type AuditLogFilter struct {
+	ClinicID   *uuid.UUID
+	ActorID    *uuid.UUID
+	EntityType string
+	EntityID   *uuid.UUID
+	Action     string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/iamarpitzala/aca-reca-backend/internal/money"
+)
+
+// BAS label codes (ATO Business Activity Statement, GST section)
+const (
+	BASLabelG1  = "G1"  // Total sales (including GST)
+	BASLabelG2  = "G2"  // Export sales
+	BASLabelG3  = "G3"  // Other GST-free sales
+	BASLabelG10 = "G10" // Capital purchases (including GST)
+	BASLabelG11 = "G11" // Non-capital purchases (including GST)
+	BASLabel1A  = "1A"  // GST on sales
+	BASLabel1B  = "1B"  // GST on purchases
+)
+
+// BAS drill-down sources
+const (
+	BASSourceCustomFormEntry = "custom_form_entry"
+	BASSourceExpenseEntry    = "expense_entry"
+)
+
+// BASLine is a single entry contributing to a BAS label.
+type BASLine struct {
+	Source      string       `json:"source"`
+	EntryID     string       `json:"entryId"`
+	FormID      string       `json:"formId,omitempty"`
+	FormName    string       `json:"formName,omitempty"`
+	Date        time.Time    `json:"date"`
+	Description string       `json:"description,omitempty"`
+	Amount      money.Amount `json:"amount"`
+}
+
+type BASLabel struct {
+	Label  string       `json:"label"`
+	Amount money.Amount `json:"amount"`
+	Lines  []BASLine    `json:"lines"`
+}
+
+// BASWorksheet is the GST section of a BAS for one clinic and period.
+type BASWorksheet struct {
+	ClinicID    string       `json:"clinicId"`
+	QuarterID   string       `json:"quarterId"`
+	QuarterName string       `json:"quarterName"`
+	PeriodStart time.Time    `json:"periodStart"`
+	PeriodEnd   time.Time    `json:"periodEnd"`
+	G1          BASLabel     `json:"g1"`
+	G2          BASLabel     `json:"g2"`
+	G3          BASLabel     `json:"g3"`
+	G10         BASLabel     `json:"g10"`
+	G11         BASLabel     `json:"g11"`
+	GST1A       BASLabel     `json:"1a"`
+	GST1B       BASLabel     `json:"1b"`
+	NetAmount   money.Amount `json:"netAmount"` // 1A - 1B
+	Position    string       `json:"position"`  // payable, refundable or nil
+	GeneratedAt time.Time    `json:"generatedAt"`
+}
@@ -20,6 +20,12 @@ const (
 
 var ValidStates = []string{StateNSW, StateVIC, StateQLD, StateSA, StateWA, StateTAS, StateNT, StateACT}
 
+// BAS reporting cycles (tbl_clinic.bas_cycle)
+const (
+	BASCycleMonthly   = "monthly"
+	BASCycleQuarterly = "quarterly"
+)
+
 type Clinic struct {
 	ID          uuid.UUID `db:"id" json:"id"`
 	Name        string    `db:"name" json:"name"`
@@ -37,6 +43,8 @@ type Clinic struct {
 	ShareType   string `db:"share_type" json:"shareType"`
 	ClinicShare int    `db:"clinic_share" json:"clinicShare"`
 	OwnerShare  int    `db:"owner_share" json:"ownerShare"`
+	BASCycle    string `db:"bas_cycle" json:"basCycle"`
+	RequireMFA  bool   `db:"require_mfa" json:"requireMfa"`
 
 	CreatedAt time.Time  `db:"created_at" json:"createdAt"`
 	UpdatedAt time.Time  `db:"updated_at" json:"updatedAt"`
@@ -58,4 +66,32 @@ type UpdateClinicRequest struct {
 	Description *string `json:"description"`
 	ClinicShare *int    `json:"clinicShare"`
 	OwnerShare  *int    `json:"ownerShare"`
+	BASCycle    *string `json:"basCycle"`
+	RequireMFA  *bool   `json:"requireMfa"`
+}
+
+// DefaultGSTRate is the GST rate, in percent, of a clinic that has not set one.
+const DefaultGSTRate = 10.0
+
+// ClinicGSTRate is a GST rate a clinic charges from EffectiveFrom until the next rate takes over.
+type ClinicGSTRate struct {
+	ID            uuid.UUID `db:"id" json:"id"`
+	ClinicID      uuid.UUID `db:"clinic_id" json:"clinicId"`
+	Rate          float64   `db:"rate" json:"rate"`
+	EffectiveFrom time.Time `db:"effective_from" json:"effectiveFrom"`
+	CreatedBy     uuid.UUID `db:"created_by" json:"createdBy"`
+	CreatedAt     time.Time `db:"created_at" json:"createdAt"`
+}
+
+// SetGSTRateRequest sets the rate a clinic charges from EffectiveFrom (YYYY-MM-DD), replacing any
+// rate already set for that date.
+type SetGSTRateRequest struct {
+	Rate          *float64 `json:"rate" validate:"required,min=0,max=100"`
+	EffectiveFrom string   `json:"effectiveFrom" validate:"required"`
+}
+
+// ClinicGSTRates is a clinic's rate history, newest first, with the rate that applies today.
+type ClinicGSTRates struct {
+	CurrentRate float64         `json:"currentRate"`
+	Rates       []ClinicGSTRate `json:"rates"`
 }
@@ -19,6 +19,7 @@ const (
 	FieldTypeCheckbox CustomFieldType = "checkbox"
 	FieldTypeTextarea CustomFieldType = "textarea"
 	FieldTypeCurrency CustomFieldType = "currency"
+	FieldTypeFormula  CustomFieldType = "formula" // computed from Formula; see package formula
 )
 
 type FormStatus string
@@ -52,7 +53,147 @@ const (
 )
 
 // FieldGSTConfig, DropdownOption, ConditionalLogicRule, FieldValidation, CustomFormField
-// are stored inside fields JSONB; we use json.RawMessage for flexibility.
+// are stored inside fields JSONB; the form row keeps json.RawMessage and the typed
+// model below is decoded from it for validation and calculation.
+
+type FieldGSTConfig struct {
+	Enabled bool `json:"enabled"`
+	// Rate in percent; absent means the clinic's rate on the entry date, while 0 charges no GST
+	Rate *float64 `json:"rate,omitempty"`
+	Type string   `json:"type"` // inclusive, exclusive, manual
+}
+
+type DropdownOption struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// UnmarshalJSON accepts either {"label","value"} objects or plain strings.
+func (o *DropdownOption) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		o.Label, o.Value = s, s
+		return nil
+	}
+	type alias DropdownOption
+	var a alias
+	if err := json.Unmarshal(data, &a); err != nil {
+		return err
+	}
+	*o = DropdownOption(a)
+	if o.Value == "" {
+		o.Value = o.Label
+	}
+	return nil
+}
+
+// Conditional logic actions: a matching show rule shows the field (a field with show rules is hidden
+// until one matches), a matching hide rule hides it, and a matching require rule makes it required.
+// Hidden fields are left out of validation and calculations.
+const (
+	RuleActionShow    = "show"
+	RuleActionHide    = "hide"
+	RuleActionRequire = "require"
+)
+
+// Condition operators and group matching modes.
+const (
+	RuleOpEquals      = "equals"
+	RuleOpNotEquals   = "not_equals"
+	RuleOpGreaterThan = "greater_than"
+	RuleOpContains    = "contains"
+
+	RuleMatchAll = "all"
+	RuleMatchAny = "any"
+)
+
+// RuleCondition compares another field's value with Value, or, when Conditions is set, is a group
+// that matches when all (the default) or any of its conditions match.
+type RuleCondition struct {
+	FieldID    string          `json:"fieldId,omitempty"`
+	Operator   string          `json:"operator,omitempty"`
+	Value      interface{}     `json:"value,omitempty"`
+	Match      string          `json:"match,omitempty"`
+	Conditions []RuleCondition `json:"conditions,omitempty"`
+}
+
+// ConditionalLogicRule applies Action when its conditions match: all of them, or any when Match is "any".
+type ConditionalLogicRule struct {
+	Enabled    *bool           `json:"enabled,omitempty"` // Rules saved as disabled are ignored
+	Action     string          `json:"action"`
+	Match      string          `json:"match,omitempty"`
+	Conditions []RuleCondition `json:"conditions"`
+}
+
+func (r ConditionalLogicRule) IsEnabled() bool {
+	return r.Enabled == nil || *r.Enabled
+}
+
+// ConditionalLogic is the list of rules on a field.
+type ConditionalLogic []ConditionalLogicRule
+
+// UnmarshalJSON accepts either a list of rules or a single rule object.
+func (l *ConditionalLogic) UnmarshalJSON(data []byte) error {
+	var rules []ConditionalLogicRule
+	if err := json.Unmarshal(data, &rules); err == nil {
+		*l = rules
+		return nil
+	}
+	var rule *ConditionalLogicRule
+	if err := json.Unmarshal(data, &rule); err != nil {
+		return err
+	}
+	*l = nil
+	if rule != nil {
+		*l = ConditionalLogic{*rule}
+	}
+	return nil
+}
+
+type FieldValidation struct {
+	Min       *float64 `json:"min,omitempty"`
+	Max       *float64 `json:"max,omitempty"`
+	MinLength *int     `json:"minLength,omitempty"`
+	MaxLength *int     `json:"maxLength,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"`
+	MinDate   string   `json:"minDate,omitempty"` // YYYY-MM-DD
+	MaxDate   string   `json:"maxDate,omitempty"` // YYYY-MM-DD
+}
+
+type CustomFormField struct {
+	ID                    string           `json:"id"`
+	Name                  string           `json:"name"`
+	Type                  CustomFieldType  `json:"type"`
+	Section               string           `json:"section,omitempty"`
+	Required              bool             `json:"required"`
+	IncludeInTotal        bool             `json:"includeInTotal"`
+	GstConfig             *FieldGSTConfig  `json:"gstConfig,omitempty"`
+	Options               []DropdownOption `json:"options,omitempty"`
+	Validation            *FieldValidation `json:"validation,omitempty"`
+	PaymentResponsibility string           `json:"paymentResponsibility,omitempty"`
+	ConditionalLogic      ConditionalLogic `json:"conditionalLogic,omitempty"`
+	Formula               string           `json:"formula,omitempty"` // Expression of a formula field
+	AccountID             string           `json:"accountId,omitempty"` // Account whose tax code decides the field's GST
+}
+
+// FieldError describes a single invalid field in a form definition or entry.
+type FieldError struct {
+	FieldID   string `json:"fieldId"`
+	FieldName string `json:"fieldName,omitempty"`
+	Message   string `json:"message"`
+}
+
+// FieldValidationError is returned when one or more fields fail validation.
+type FieldValidationError struct {
+	Errors []FieldError
+}
+
+func (e *FieldValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return "validation failed: " + e.Errors[0].Message
+	}
+	return "validation failed for one or more fields"
+}
 
 // CustomForm DB model
 type CustomForm struct {
@@ -82,6 +223,7 @@ type CustomFormEntry struct {
 	FormID                uuid.UUID       `db:"form_id"`
 	FormName              string          `db:"form_name"`
 	FormType              string          `db:"form_type"`
+	FormVersion           int             `db:"form_version"`
 	ClinicID              uuid.UUID       `db:"clinic_id"`
 	QuarterID             *uuid.UUID      `db:"quarter_id"`
 	Values                json.RawMessage `db:"values"`
@@ -91,6 +233,7 @@ type CustomFormEntry struct {
 	Remarks               string          `db:"remarks"`
 	PaymentResponsibility *string         `db:"payment_responsibility"`
 	Deductions            json.RawMessage `db:"deductions"`
+	CorrectsEntryID       *uuid.UUID      `db:"corrects_entry_id"`
 	CreatedBy             uuid.UUID       `db:"created_by"`
 	CreatedAt             time.Time       `db:"created_at"`
 	UpdatedAt             time.Time       `db:"updated_at"`
@@ -145,6 +288,7 @@ type CustomFormResponse struct {
 type CreateEntryRequest struct {
 	FormID                string          `json:"formId"`
 	ClinicID              string          `json:"clinicId"`
+	// Deprecated: ignored. The entry's period is derived from EntryDate.
 	QuarterID             *string         `json:"quarterId,omitempty"`
 	Values                json.RawMessage `json:"values"`
 	EntryDate             string          `json:"entryDate"` // ISO date
@@ -152,6 +296,8 @@ type CreateEntryRequest struct {
 	Remarks               string          `json:"remarks,omitempty"`
 	PaymentResponsibility *string         `json:"paymentResponsibility,omitempty"`
 	Deductions            json.RawMessage `json:"deductions,omitempty"`
+	// CorrectsEntryID marks this entry as an adjustment to an entry in a closed period
+	CorrectsEntryID       *string         `json:"correctsEntryId,omitempty"`
 	// Pre-calculated totals (frontend sends after running calculateEntryTotals / applyDeductionsToCalculations)
 	Calculations          json.RawMessage `json:"calculations,omitempty"`
 }
@@ -163,9 +309,11 @@ type UpdateEntryRequest struct {
 
 // PreviewCalculationsRequest for live calculation (no save)
 type PreviewCalculationsRequest struct {
-	FormID     string          `json:"formId"`
-	Values     json.RawMessage `json:"values"`
-	Deductions json.RawMessage `json:"deductions,omitempty"`
+	FormID      string          `json:"formId"`
+	FormVersion *int            `json:"formVersion,omitempty"`
+	EntryDate   string          `json:"entryDate,omitempty"` // YYYY-MM-DD, for the GST rate; defaults to today
+	Values      json.RawMessage `json:"values"`
+	Deductions  json.RawMessage `json:"deductions,omitempty"`
 }
 
 type CustomFormEntryResponse struct {
@@ -173,6 +321,7 @@ type CustomFormEntryResponse struct {
 	FormID                string          `json:"formId"`
 	FormName              string          `json:"formName"`
 	FormType              string          `json:"formType"`
+	FormVersion           int             `json:"formVersion"`
 	ClinicID              string          `json:"clinicId"`
 	QuarterID             *string         `json:"quarterId,omitempty"`
 	Values                json.RawMessage `json:"values"`
@@ -182,7 +331,68 @@ type CustomFormEntryResponse struct {
 	Remarks               string          `json:"remarks,omitempty"`
 	PaymentResponsibility *string         `json:"paymentResponsibility,omitempty"`
 	Deductions            json.RawMessage `json:"deductions,omitempty"`
+	CorrectsEntryID       *string         `json:"correctsEntryId,omitempty"`
+	// Fields of the form version the entry was captured on (single-entry view only)
+	Fields                json.RawMessage `json:"fields,omitempty"`
 	CreatedBy             string          `json:"createdBy"`
 	CreatedAt             time.Time       `json:"createdAt"`
 	UpdatedAt             time.Time       `json:"updatedAt"`
 }
+
+// CustomFormVersion is an immutable snapshot of a form definition as it was published.
+type CustomFormVersion struct {
+	ID                           uuid.UUID       `db:"id"`
+	FormID                       uuid.UUID       `db:"form_id"`
+	Version                      int             `db:"version"`
+	Name                         string          `db:"name"`
+	CalculationMethod            string          `db:"calculation_method"`
+	FormType                     string          `db:"form_type"`
+	Fields                       json.RawMessage `db:"fields"`
+	DefaultPaymentResponsibility *string         `db:"default_payment_responsibility"`
+	ServiceFacilityFeePercent    *float64        `db:"service_facility_fee_percent"`
+	OutworkEnabled               bool            `db:"outwork_enabled"`
+	OutworkRatePercent           *float64        `db:"outwork_rate_percent"`
+	CreatedBy                    uuid.UUID       `db:"created_by"`
+	CreatedAt                    time.Time       `db:"created_at"`
+}
+
+type CustomFormVersionResponse struct {
+	FormID                       string          `json:"formId"`
+	Version                      int             `json:"version"`
+	Name                         string          `json:"name"`
+	CalculationMethod            string          `json:"calculationMethod"`
+	FormType                     string          `json:"formType"`
+	Fields                       json.RawMessage `json:"fields"`
+	DefaultPaymentResponsibility *string         `json:"defaultPaymentResponsibility,omitempty"`
+	ServiceFacilityFeePercent    *float64        `json:"serviceFacilityFeePercent,omitempty"`
+	OutworkEnabled               bool            `json:"outworkEnabled"`
+	OutworkRatePercent           *float64        `json:"outworkRatePercent,omitempty"`
+	CreatedBy                    string          `json:"createdBy"`
+	CreatedAt                    time.Time       `json:"createdAt"`
+}
+
+// FieldChange lists the attributes of a field that differ between two versions.
+type FieldChange struct {
+	FieldID    string          `json:"fieldId"`
+	FieldName  string          `json:"fieldName"`
+	Attributes []string        `json:"attributes"`
+	Before     CustomFormField `json:"before"`
+	After      CustomFormField `json:"after"`
+}
+
+// SettingChange is a form-level setting (e.g. service fee %) that differs between two versions.
+type SettingChange struct {
+	Setting string      `json:"setting"`
+	From    interface{} `json:"from"`
+	To      interface{} `json:"to"`
+}
+
+type CustomFormVersionDiff struct {
+	FormID        string            `json:"formId"`
+	FromVersion   int               `json:"fromVersion"`
+	ToVersion     int               `json:"toVersion"`
+	AddedFields   []CustomFormField `json:"addedFields"`
+	RemovedFields []CustomFormField `json:"removedFields"`
+	ChangedFields []FieldChange     `json:"changedFields"`
+	Settings      []SettingChange   `json:"settings"`
+}
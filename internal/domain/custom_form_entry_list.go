@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/money"
+)
+
+// Entry list sort keys and orders
+const (
+	EntrySortEntryDate   = "entryDate"
+	EntrySortTotalAmount = "totalAmount"
+
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// CustomFormEntryFilter narrows and orders an entry listing. Zero values are ignored.
+type CustomFormEntryFilter struct {
+	ClinicID              uuid.UUID
+	FormID                *uuid.UUID
+	QuarterID             *uuid.UUID // Entries dated within the clinic period
+	From                  *time.Time
+	To                    *time.Time
+	FormType              string // income, expense or both
+	PaymentResponsibility string // Entry-level responsibility: owner or clinic
+	CreatedBy             *uuid.UUID
+	MinAmount             *money.Amount // Bounds on the entry's calculated totalAmount
+	MaxAmount             *money.Amount
+	Search                string // Full-text search over description and remarks
+	Sort                  string // EntrySortEntryDate (default) or EntrySortTotalAmount
+	Order                 string // SortDesc (default) or SortAsc
+	Limit                 int
+	Cursor                string // NextCursor of the previous page
+
+	// After is the decoded Cursor: the sort value and ID of the last entry already returned
+	After *CustomFormEntryCursor
+}
+
+// CustomFormEntryCursor is the position of an entry in a sorted listing.
+type CustomFormEntryCursor struct {
+	Sort  string    `json:"s"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// CustomFormEntryTotals aggregates the calculated amounts of every entry matching a filter.
+type CustomFormEntryTotals struct {
+	TotalBaseAmount money.Amount `db:"total_base_amount" json:"totalBaseAmount"`
+	TotalGSTAmount  money.Amount `db:"total_gst_amount" json:"totalGSTAmount"`
+	TotalAmount     money.Amount `db:"total_amount" json:"totalAmount"`
+}
+
+// CustomFormEntryPage is one page of an entry listing. Total and Totals cover the whole filtered set,
+// not just the page; NextCursor is empty on the last page.
+type CustomFormEntryPage struct {
+	Entries    []CustomFormEntryResponse `json:"entries"`
+	Total      int                       `json:"total"`
+	Totals     CustomFormEntryTotals     `json:"totals"`
+	NextCursor string                    `json:"nextCursor,omitempty"`
+}
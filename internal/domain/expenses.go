@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/money"
 )
 
 type ExpenseType struct {
@@ -22,6 +23,7 @@ type ExpenseCategory struct {
 	ClinicID    uuid.UUID  `db:"clinic_id" json:"clinicId"`
 	Name        string     `db:"name" json:"name"`
 	Description string     `db:"description" json:"description"`
+	IsCapital   bool       `db:"is_capital" json:"isCapital"` // capital purchases go to BAS G10
 	CreatedAt   time.Time  `db:"created_at" json:"createdAt"`
 	CreatedBy   uuid.UUID  `db:"created_by" json:"createdBy"`
 	DeletedAt   *time.Time `db:"deleted_at" json:"deletedAt"`
@@ -40,18 +42,21 @@ type ExpenseCategoryType struct {
 }
 
 type ExpenseEntry struct {
-	ID             uuid.UUID  `db:"id" json:"id"`
-	ClinicID       uuid.UUID  `db:"clinic_id" json:"clinicId"`
-	CategoryID     uuid.UUID  `db:"category_id" json:"categoryId"`
-	TypeID         uuid.UUID  `db:"type_id" json:"typeId"`
-	Amount         float64    `db:"amount" json:"amount"`
-	GSTRate        *float64   `db:"gst_rate" json:"gstRate"`
-	IsGSTInclusive *bool      `db:"is_gst_inclusive" json:"isGSTInclusive"`
-	ExpenseDate    time.Time  `db:"expense_date" json:"expenseDate"`
-	SupplierName   string     `db:"supplier_name" json:"supplierName"`
-	Notes          string     `db:"notes" json:"notes"`
-	CreatedAt      time.Time  `db:"created_at" json:"createdAt"`
-	CreatedBy      uuid.UUID  `db:"created_by" json:"createdBy"`
-	DeletedAt      *time.Time `db:"deleted_at" json:"deletedAt"`
-	DeletedBy      uuid.UUID  `db:"deleted_by" json:"deletedBy"`
+	ID             uuid.UUID    `db:"id" json:"id"`
+	ClinicID       uuid.UUID    `db:"clinic_id" json:"clinicId"`
+	CategoryID     uuid.UUID    `db:"category_id" json:"categoryId"`
+	TypeID         uuid.UUID    `db:"type_id" json:"typeId"`
+	Amount         money.Amount `db:"amount" json:"amount"`
+	GSTRate        *float64     `db:"gst_rate" json:"gstRate"`
+	IsGSTInclusive *bool        `db:"is_gst_inclusive" json:"isGSTInclusive"`
+	ExpenseDate    time.Time    `db:"expense_date" json:"expenseDate"`
+	SupplierName   string       `db:"supplier_name" json:"supplierName"`
+	Notes          string       `db:"notes" json:"notes"`
+	CreatedAt      time.Time    `db:"created_at" json:"createdAt"`
+	CreatedBy      uuid.UUID    `db:"created_by" json:"createdBy"`
+	DeletedAt      *time.Time   `db:"deleted_at" json:"deletedAt"`
+	DeletedBy      uuid.UUID    `db:"deleted_by" json:"deletedBy"`
+
+	// CorrectsEntryID points at the entry in a closed period that this entry adjusts
+	CorrectsEntryID *uuid.UUID `db:"corrects_entry_id" json:"correctsEntryId,omitempty"`
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	constants "github.com/iamarpitzala/aca-reca-backend/constant"
+	"github.com/iamarpitzala/aca-reca-backend/internal/money"
 	"github.com/iamarpitzala/aca-reca-backend/util"
 )
 
@@ -204,59 +205,62 @@ func (g GrossMethodConfig) Validate() error {
 // CalculationInput represents input values for calculations
 type CalculationInput struct {
 	// Common fields
-	GrossPatientFee float64 `json:"grossPatientFee"` // A
+	GrossPatientFee money.Amount `json:"grossPatientFee"` // A
 
 	// Net Method fields
-	LabFee float64 `json:"labFee,omitempty"` // B (shown if LabFeeEnabled)
+	LabFee money.Amount `json:"labFee,omitempty"` // B (shown if LabFeeEnabled)
 
 	// Gross Method fields
-	GSTOnPatientFee     float64 `json:"gstOnPatientFee,omitempty"`     // B (for B4)
-	LabFeePaidByDentist float64 `json:"labFeePaidByDentist,omitempty"` // I (for B4)
-	MerchantFeeInclGST  float64 `json:"merchantFeeInclGST,omitempty"`  // G (for B3)
-	BankFee             float64 `json:"bankFee,omitempty"`             // K (for B3)
-	GSTOnLabFee         float64 `json:"gstOnLabFee,omitempty"`         // G (for B2)
+	GSTOnPatientFee     money.Amount `json:"gstOnPatientFee,omitempty"`     // B (for B4)
+	LabFeePaidByDentist money.Amount `json:"labFeePaidByDentist,omitempty"` // I (for B4)
+	MerchantFeeInclGST  money.Amount `json:"merchantFeeInclGST,omitempty"`  // G (for B3)
+	BankFee             money.Amount `json:"bankFee,omitempty"`             // K (for B3)
+	GSTOnLabFee         money.Amount `json:"gstOnLabFee,omitempty"`         // G (for B2)
 
 	// Outwork Charge Rate fields (B5)
-	OutworkLabFee           float64 `json:"outworkLabFee,omitempty"`           // w
-	OutworkMerchantFee      float64 `json:"outworkMerchantFee,omitempty"`      // x
-	OutworkGSTOnLabFee      float64 `json:"outworkGSTOnLabFee,omitempty"`      // y
-	OutworkGSTOnMerchantFee float64 `json:"outworkGSTOnMerchantFee,omitempty"` // z
+	OutworkLabFee           money.Amount `json:"outworkLabFee,omitempty"`           // w
+	OutworkMerchantFee      money.Amount `json:"outworkMerchantFee,omitempty"`      // x
+	OutworkGSTOnLabFee      money.Amount `json:"outworkGSTOnLabFee,omitempty"`      // y
+	OutworkGSTOnMerchantFee money.Amount `json:"outworkGSTOnMerchantFee,omitempty"` // z
 }
 
 // CalculationResult represents calculated results
 type CalculationResult struct {
+	Variant string  `json:"variant"` // A1, A2, B1-B5
+	GSTRate float64 `json:"gstRate"` // Clinic rate on the calculation date, in percent
+
 	// Net Method results
-	NetPatientFee          float64 `json:"netPatientFee,omitempty"`          // C
-	CommissionForDentist   float64 `json:"commissionForDentist,omitempty"`   // D
-	CommissionComponent    float64 `json:"commissionComponent,omitempty"`    // F (with super)
-	SuperComponent         float64 `json:"superComponent,omitempty"`         // E (with super)
-	TotalForReconciliation float64 `json:"totalForReconciliation,omitempty"` // G (with super)
-	GSTOnCommission        float64 `json:"gstOnCommission,omitempty"`        // E (without super) or H (with super)
-	TotalCommission        float64 `json:"totalCommission,omitempty"`        // F (without super) or I (with super)
+	NetPatientFee          money.Amount `json:"netPatientFee,omitempty"`          // C
+	CommissionForDentist   money.Amount `json:"commissionForDentist,omitempty"`   // D
+	CommissionComponent    money.Amount `json:"commissionComponent,omitempty"`    // F (with super)
+	SuperComponent         money.Amount `json:"superComponent,omitempty"`         // E (with super)
+	TotalForReconciliation money.Amount `json:"totalForReconciliation,omitempty"` // G (with super)
+	GSTOnCommission        money.Amount `json:"gstOnCommission,omitempty"`        // E (without super) or H (with super)
+	TotalCommission        money.Amount `json:"totalCommission,omitempty"`        // F (without super) or I (with super)
 
 	// Gross Method results
-	ServiceFacilityFee      float64 `json:"serviceFacilityFee,omitempty"`      // D
-	GSTOnServiceFee         float64 `json:"gstOnServiceFee,omitempty"`         // E
-	TotalServiceFee         float64 `json:"totalServiceFee,omitempty"`         // F
-	AmountRemittedToDentist float64 `json:"amountRemittedToDentist,omitempty"` // G, I, H, or J (varies by variant)
+	ServiceFacilityFee      money.Amount `json:"serviceFacilityFee,omitempty"`      // D
+	GSTOnServiceFee         money.Amount `json:"gstOnServiceFee,omitempty"`         // E
+	TotalServiceFee         money.Amount `json:"totalServiceFee,omitempty"`         // F
+	AmountRemittedToDentist money.Amount `json:"amountRemittedToDentist,omitempty"` // G, I, H, or J (varies by variant)
 
 	// B3 specific
-	MerchantFeeGSTComponent float64 `json:"merchantFeeGSTComponent,omitempty"` // I
-	NetMerchantFee          float64 `json:"netMerchantFee,omitempty"`          // J
+	MerchantFeeGSTComponent money.Amount `json:"merchantFeeGSTComponent,omitempty"` // I
+	NetMerchantFee          money.Amount `json:"netMerchantFee,omitempty"`          // J
 
 	// B5 specific
-	TotalOutworkCharge          float64 `json:"totalOutworkCharge,omitempty"`          // B
-	LabOtherCostCharge          float64 `json:"labOtherCostCharge,omitempty"`          // E
-	TotalServiceFeeOtherCharges float64 `json:"totalServiceFeeOtherCharges,omitempty"` // F
-	TotalChargesInclGST         float64 `json:"totalChargesInclGST,omitempty"`         // H
+	TotalOutworkCharge          money.Amount `json:"totalOutworkCharge,omitempty"`          // B
+	LabOtherCostCharge          money.Amount `json:"labOtherCostCharge,omitempty"`          // E
+	TotalServiceFeeOtherCharges money.Amount `json:"totalServiceFeeOtherCharges,omitempty"` // F
+	TotalChargesInclGST         money.Amount `json:"totalChargesInclGST,omitempty"`         // H
 }
 
 // BASMapping represents BAS field mappings
 type BASMapping struct {
-	Field1A  float64 `json:"field1A,omitempty"`  // GST on Sales
-	Field1B  float64 `json:"field1B,omitempty"`  // GST Credit
-	FieldG1  float64 `json:"fieldG1,omitempty"`  // Total Sales incl GST
-	FieldG11 float64 `json:"fieldG11,omitempty"` // Clinic Expenses
+	Field1A  money.Amount `json:"field1A,omitempty"`  // GST on Sales
+	Field1B  money.Amount `json:"field1B,omitempty"`  // GST Credit
+	FieldG1  money.Amount `json:"fieldG1,omitempty"`  // Total Sales incl GST
+	FieldG11 money.Amount `json:"fieldG11,omitempty"` // Clinic Expenses
 }
 
 // FinancialCalculation represents a calculation record
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Invitation statuses (tbl_clinic_invitation.status). Expiry is derived from ExpiresAt.
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+	InvitationRevoked  = "revoked"
+)
+
+type ClinicInvitation struct {
+	ID          uuid.UUID  `db:"id" json:"id"`
+	ClinicID    uuid.UUID  `db:"clinic_id" json:"clinicId"`
+	Email       string     `db:"email" json:"email"`
+	Role        string     `db:"role" json:"role"`
+	Status      string     `db:"status" json:"status"`
+	TokenHash   string     `db:"token_hash" json:"-"`
+	InvitedBy   uuid.UUID  `db:"invited_by" json:"invitedBy"`
+	AcceptedBy  *uuid.UUID `db:"accepted_by" json:"acceptedBy,omitempty"`
+	ExpiresAt   time.Time  `db:"expires_at" json:"expiresAt"`
+	RespondedAt *time.Time `db:"responded_at" json:"respondedAt,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updatedAt"`
+}
+
+func (i *ClinicInvitation) IsExpired() bool {
+	return time.Now().After(i.ExpiresAt)
+}
+
+// InvitationClaims are carried by the signed token emailed to the invitee.
+type InvitationClaims struct {
+	InvitationID uuid.UUID `json:"invitationId"`
+	jwt.RegisteredClaims
+}
+
+type CreateInvitationRequest struct {
+	ClinicID string `json:"clinicId" validate:"required,uuid"`
+	Email    string `json:"email" validate:"required,email"`
+	Role     string `json:"role"`
+}
+
+// AcceptInvitationRequest accepts an invitation. When the caller is not signed in and no account exists
+// for the invited email, Password (and optionally names) are used to register one first.
+type AcceptInvitationRequest struct {
+	Token     string `json:"token" validate:"required"`
+	Password  string `json:"password,omitempty"`
+	FirstName string `json:"firstName,omitempty"`
+	LastName  string `json:"lastName,omitempty"`
+	Phone     string `json:"phone,omitempty"`
+}
+
+type InvitationTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// InvitationPreview is what an invitee sees before accepting.
+type InvitationPreview struct {
+	ClinicName string    `json:"clinicName"`
+	Email      string    `json:"email"`
+	Role       string    `json:"role"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	HasAccount bool      `json:"hasAccount"`
+}
+
+type AcceptInvitationResponse struct {
+	UserClinic *UserClinic   `json:"userClinic"`
+	Auth       *AuthResponse `json:"auth,omitempty"` // set when the invitee was registered while accepting
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Login lockout scopes (tbl_login_lockout.scope).
+const (
+	LockoutScopeAccount = "account"
+	LockoutScopeIP      = "ip"
+)
+
+// LoginLockout records an account or IP address being locked after repeated failed sign-ins.
+// Subject is the normalised email for account lockouts and the address for IP lockouts; UserID is
+// set when the email belongs to a user. IPAddress is the address of the attempt that caused the lock.
+type LoginLockout struct {
+	ID          uuid.UUID  `db:"id" json:"id"`
+	Scope       string     `db:"scope" json:"scope"`
+	Subject     string     `db:"subject" json:"subject"`
+	UserID      *uuid.UUID `db:"user_id" json:"userId,omitempty"`
+	IPAddress   string     `db:"ip_address" json:"ipAddress"`
+	Failures    int        `db:"failures" json:"failures"`
+	LockedUntil time.Time  `db:"locked_until" json:"lockedUntil"`
+	ClearedAt   *time.Time `db:"cleared_at" json:"clearedAt,omitempty"`
+	ClearedBy   *uuid.UUID `db:"cleared_by" json:"clearedBy,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"createdAt"`
+}
+
+// Active reports whether the lockout still blocks sign-in.
+func (l *LoginLockout) Active(now time.Time) bool {
+	return l.ClearedAt == nil && now.Before(l.LockedUntil)
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// UserMFA is a user's TOTP enrolment. It is pending until EnabledAt is set.
+type UserMFA struct {
+	UserID       uuid.UUID  `db:"user_id"`
+	Secret       string     `db:"secret"` // Encrypted
+	EnabledAt    *time.Time `db:"enabled_at"`
+	LastUsedStep int64      `db:"last_used_step"`
+	CreatedAt    time.Time  `db:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at"`
+}
+
+func (m *UserMFA) Enabled() bool {
+	return m != nil && m.EnabledAt != nil
+}
+
+// MFAChallengeClaims identify a user who has passed the password step of login but still owes a
+// second factor.
+type MFAChallengeClaims struct {
+	UserID uuid.UUID `json:"userId"`
+	jwt.RegisteredClaims
+}
+
+type MFAStatus struct {
+	Enabled                bool `json:"enabled"`
+	RecoveryCodesRemaining int  `json:"recoveryCodesRemaining"`
+}
+
+// MFAEnrollment is shown once while enrolling; OTPAuthURL is rendered as a QR code for authenticator apps.
+type MFAEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauthUrl"`
+}
+
+// MFARecoveryCodes are shown once, when generated.
+type MFARecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// MFACodeRequest carries an authenticator code or, where allowed, a recovery code.
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfaToken" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
@@ -1,5 +1,12 @@
 package domain
 
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
 type OAuthUserInfo struct {
 	ID            string
 	Email         string
@@ -8,3 +15,35 @@ type OAuthUserInfo struct {
 	AvatarURL     string
 	EmailVerified bool
 }
+
+// OAuthState is an authorisation in flight between the redirect to a provider and its callback.
+// UserID is set when a signed-in user is linking an identity to their account.
+type OAuthState struct {
+	StateHash    string     `db:"state_hash"`
+	Provider     string     `db:"provider"`
+	CodeVerifier string     `db:"code_verifier"`
+	Nonce        string     `db:"nonce"`
+	UserID       *uuid.UUID `db:"user_id"`
+	ExpiresAt    time.Time  `db:"expires_at"`
+	CreatedAt    time.Time  `db:"created_at"`
+}
+
+// OAuthLinkClaims let a signed-in user's browser start linking an identity at /auth/oauth/:provider,
+// which it reaches by navigation and so without the Authorization header.
+type OAuthLinkClaims struct {
+	UserID   uuid.UUID `json:"userId"`
+	Provider string    `json:"provider"`
+	jwt.RegisteredClaims
+}
+
+// OAuthIdentity is a provider account linked to a user, without its tokens.
+type OAuthIdentity struct {
+	Provider      string    `db:"provider" json:"provider"`
+	ProviderEmail string    `db:"provider_email" json:"providerEmail"`
+	CreatedAt     time.Time `db:"created_at" json:"linkedAt"`
+}
+
+// OAuthLinkResponse is where the browser should navigate to link a provider.
+type OAuthLinkResponse struct {
+	URL string `json:"url"`
+}
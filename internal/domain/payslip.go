@@ -1,33 +1,35 @@
 package domain
 
+import "github.com/iamarpitzala/aca-reca-backend/internal/money"
+
 type ExportIncome struct {
-	Q                 string  `json:"q"`
-	IncomeType        string  `json:"income_type"`
-	LabRecord         string  `json:"lab_record"`
-	PaymentDate       string  `json:"payment_date"`
-	DentalPractice    string  `json:"dental_practice"`
-	Adjustments       float64 `json:"adjustments"`
-	GrossIncomeG1     float64 `json:"gross_income_g1"`
-	LabFees           float64 `json:"lab_fees"`
-	GrossNetLabFees   float64 `json:"gross_net_lab_fees"`
-	GSTPayable1A      float64 `json:"gst_payable_1a"`
-	GSTFree           float64 `json:"gst_free"`
-	ManagementFeesG11 float64 `json:"management_fees_g11"`
-	Percentage        float64 `json:"percentage"`
-	GSTRefundable1B   float64 `json:"gst_refundable_1b"`
-	NetPayment        float64 `json:"net_payment"`
+	Q                 string       `json:"q"`
+	IncomeType        string       `json:"income_type"`
+	LabRecord         string       `json:"lab_record"`
+	PaymentDate       string       `json:"payment_date"`
+	DentalPractice    string       `json:"dental_practice"`
+	Adjustments       money.Amount `json:"adjustments"`
+	GrossIncomeG1     money.Amount `json:"gross_income_g1"`
+	LabFees           money.Amount `json:"lab_fees"`
+	GrossNetLabFees   money.Amount `json:"gross_net_lab_fees"`
+	GSTPayable1A      money.Amount `json:"gst_payable_1a"`
+	GSTFree           money.Amount `json:"gst_free"`
+	ManagementFeesG11 money.Amount `json:"management_fees_g11"`
+	Percentage        float64      `json:"percentage"`
+	GSTRefundable1B   money.Amount `json:"gst_refundable_1b"`
+	NetPayment        money.Amount `json:"net_payment"`
 }
 
 type ExportExpenses struct {
-	Q        string  `json:"q"`
-	Date     string  `json:"date"`
-	Supplier string  `json:"supplier"`
-	Category string  `json:"category"`
-	Amount   float64 `json:"amount"`
-	Bas      float64 `json:"bas"`
-	Net      float64 `json:"net"`
-	GST      float64 `json:"gst"`
-	Remarks  string  `json:"remarks"`
+	Q        string       `json:"q"`
+	Date     string       `json:"date"`
+	Supplier string       `json:"supplier"`
+	Category string       `json:"category"`
+	Amount   money.Amount `json:"amount"`
+	Bas      float64      `json:"bas"`
+	Net      money.Amount `json:"net"`
+	GST      money.Amount `json:"gst"`
+	Remarks  string       `json:"remarks"`
 }
 
 type Row struct {
@@ -91,6 +93,44 @@ type Statement struct {
 	DentalDrawPercent      string `json:"dentalDrawPercent"`
 	Notes                  string `json:"notes"`
 	// Config                 *domain.PDFConfig `json:"config"`
+
+	// Logo is the template's uploaded logo, or else the clinic's imported logo URL (PNG or JPEG), drawn
+	// when PDFConfig.ShowLogo is set
+	Logo     []byte `json:"-"`
+	LogoType string `json:"-"`
+}
+
+// Statement sections that PDFConfig.ShowSections can list
+const (
+	StatementSectionHeader         = "header"
+	StatementSectionTable          = "table"
+	StatementSectionReconciliation = "reconciliation"
+	StatementSectionServiceFee     = "serviceFee"
+	StatementSectionNotes          = "notes"
+)
+
+var StatementSections = []string{
+	StatementSectionHeader,
+	StatementSectionTable,
+	StatementSectionReconciliation,
+	StatementSectionServiceFee,
+	StatementSectionNotes,
+}
+
+// PDFFontFamilies are the built-in PDF fonts a template can use.
+var PDFFontFamilies = []string{"Arial", "Helvetica", "Times", "Courier"}
+
+// GenerateStatementRequest asks for a practitioner's service-fee statement over a quarter or a date range.
+type GenerateStatementRequest struct {
+	PractitionerID string  `json:"practitionerId" validate:"required,uuid"`
+	QuarterID      *string `json:"quarterId" validate:"omitempty,uuid"`
+	From           *string `json:"from"` // YYYY-MM-DD
+	To             *string `json:"to"`   // YYYY-MM-DD
+	Notes          string  `json:"notes" validate:"max=2000"`
+	// TemplateID picks one of the clinic's PDF templates; the clinic default is used when omitted.
+	TemplateID *string `json:"templateId" validate:"omitempty,uuid"`
+	// Config overrides the template entirely when set.
+	Config *PDFConfig `json:"config"`
 }
 
 var HeadersExpenses = []string{
@@ -122,45 +162,3 @@ var HeadersIncome = []string{
 	"(1B) GST Refundable",
 	"Net Payment",
 }
-
-func getDefaultData() Statement {
-	return Statement{
-		CompanyName:  "Bupa Dental",
-		Date:         "08/2025",
-		Reference:    "202508CHGV",
-		ProviderName: "Dr Zhongjin Huang",
-		SupplierCode: "ADMSC-0000003055-1",
-		TotalPayable: "$16,129.78",
-		GLCode:       "011000320125",
-		Rows: []Row{
-			{
-				Provider:    "Dr Zhongjin Huang",
-				Code:        "CHGV",
-				GST:         "$0.00",
-				Collection:  "$48,171.70",
-				ExternalLab: "($665.00)",
-				Implant:     "$0.00",
-				InternalLab: "$0.00",
-				Net:         "$47,506.70",
-			},
-		},
-		ServiceFee:             "$28,504.02",
-		TotalChargeExclGST:     "$28,504.02",
-		GST:                    "$2,850.40",
-		TotalChargeInclGST:     "$31,354.42",
-		CollectedFees:          "$48,171.70",
-		DirectCostExternalLab:  "-$665.00",
-		NetCollectedFees:       "$47,506.70",
-		RemainingAmountPercent: "40.0%",
-		RAPercentNetCollected:  "$19,002.68",
-		GSTOnCollection:        "$0.00",
-		DirectCostItemsGST:     "-$22.50",
-		RemainingAmount:        "$18,980.18",
-		GSTOnServiceFee:        "-$2,850.40",
-		RemittedAmount:         "$16,129.78",
-		ServiceFeePercent:      "60.00%",
-		DentalDrawPercent:      "40.00%",
-		Notes:                  "",
-		// Config:                 utils.GetDefaultPDFConfig(),
-	}
-}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PDFTemplate is a named, saved PDFConfig for a clinic's documents. The clinic's default template is
+// used whenever a document is generated without naming one.
+type PDFTemplate struct {
+	ID              uuid.UUID       `db:"id" json:"id"`
+	ClinicID        uuid.UUID       `db:"clinic_id" json:"clinicId"`
+	Name            string          `db:"name" json:"name"`
+	Config          json.RawMessage `db:"config" json:"config"`
+	IsDefault       bool            `db:"is_default" json:"isDefault"`
+	LogoKey         *string         `db:"logo_key" json:"-"`
+	LogoContentType *string         `db:"logo_content_type" json:"logoContentType,omitempty"`
+	CreatedBy       uuid.UUID       `db:"created_by" json:"createdBy"`
+	CreatedAt       time.Time       `db:"created_at" json:"createdAt"`
+	UpdatedAt       time.Time       `db:"updated_at" json:"updatedAt"`
+	DeletedAt       *time.Time      `db:"deleted_at" json:"-"`
+}
+
+type CreatePDFTemplateRequest struct {
+	Name      string    `json:"name" validate:"required,max=100"`
+	Config    PDFConfig `json:"config"`
+	IsDefault bool      `json:"isDefault"`
+}
+
+type UpdatePDFTemplateRequest struct {
+	Name   *string    `json:"name" validate:"omitempty,min=1,max=100"`
+	Config *PDFConfig `json:"config"`
+}
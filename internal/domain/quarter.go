@@ -6,12 +6,56 @@ import (
 	"github.com/google/uuid"
 )
 
+// Reporting period types (tbl_quarter.period_type)
+const (
+	PeriodTypeMonth   = "month"
+	PeriodTypeQuarter = "quarter"
+)
+
+// Quarter is a BAS reporting period. ClinicID is nil for the shared periods used by clinics that
+// have not generated their own. FinancialYear is the year the Australian financial year ends.
 type Quarter struct {
-	ID        uuid.UUID
-	Name      string
-	StartDate time.Time
-	EndDate   time.Time
-	CreatedAt time.Time
-	UpdatedAt *time.Time
-	DeletedAt *time.Time
+	ID            uuid.UUID  `db:"id"`
+	ClinicID      *uuid.UUID `db:"clinic_id"`
+	Name          string     `db:"name"`
+	PeriodType    string     `db:"period_type"`
+	FinancialYear *int       `db:"financial_year"`
+	StartDate     time.Time  `db:"start_date"`
+	EndDate       time.Time  `db:"end_date"`
+	CreatedAt     time.Time  `db:"created_at"`
+	UpdatedAt     *time.Time `db:"updated_at"`
+	DeletedAt     *time.Time `db:"deleted_at"`
+}
+
+type GenerateFinancialYearRequest struct {
+	FinancialYear int `json:"financialYear" validate:"required,min=2000,max=2100"`
+}
+
+// QuarterClose records a quarter being closed for one clinic, and its reopening if any.
+// A quarter is closed for a clinic while it has a QuarterClose with no ReopenedAt.
+type QuarterClose struct {
+	ID           uuid.UUID  `db:"id" json:"id"`
+	ClinicID     uuid.UUID  `db:"clinic_id" json:"clinicId"`
+	QuarterID    uuid.UUID  `db:"quarter_id" json:"quarterId"`
+	ClosedBy     uuid.UUID  `db:"closed_by" json:"closedBy"`
+	ClosedAt     time.Time  `db:"closed_at" json:"closedAt"`
+	ReopenedBy   *uuid.UUID `db:"reopened_by" json:"reopenedBy,omitempty"`
+	ReopenedAt   *time.Time `db:"reopened_at" json:"reopenedAt,omitempty"`
+	ReopenReason *string    `db:"reopen_reason" json:"reopenReason,omitempty"`
+}
+
+type ReopenQuarterRequest struct {
+	Reason string `json:"reason" validate:"required,min=5,max=1000"`
+}
+
+// ClinicQuarterStatus is a reporting period together with whether it is closed for a clinic.
+type ClinicQuarterStatus struct {
+	QuarterID     uuid.UUID     `json:"quarterId"`
+	Name          string        `json:"name"`
+	PeriodType    string        `json:"periodType"`
+	FinancialYear *int          `json:"financialYear,omitempty"`
+	StartDate     time.Time     `json:"startDate"`
+	EndDate       time.Time     `json:"endDate"`
+	Closed        bool          `json:"closed"`
+	Close         *QuarterClose `json:"close,omitempty"`
 }
@@ -7,16 +7,27 @@ import (
 	"github.com/google/uuid"
 )
 
+// Session is one issued refresh token. Refreshing rotates it: the row is marked rotated and a new
+// row joins the same family, so every session descended from one login shares a FamilyID.
+// UserAgent and IPAddress are those of the client that signed in or last refreshed.
 type Session struct {
-	ID           uuid.UUID  `db:"id" json:"id"`
-	UserID       uuid.UUID  `db:"user_id" json:"userId"`
-	RefreshToken string     `db:"refresh_token" json:"-"`
-	UserAgent    string     `db:"user_agent" json:"userAgent"`
-	IPAddress    string     `db:"ip_address" json:"ipAddress"`
-	ExpiresAt    time.Time  `db:"expires_at" json:"expiresAt"`
-	CreatedAt    time.Time  `db:"created_at" json:"createdAt"`
-	UpdatedAt    time.Time  `db:"updated_at" json:"updatedAt"`
-	DeletedAt    *time.Time `db:"deleted_at" json:"deletedAt"`
+	ID               uuid.UUID  `db:"id" json:"id"`
+	UserID           uuid.UUID  `db:"user_id" json:"userId"`
+	FamilyID         uuid.UUID  `db:"family_id" json:"familyId"`
+	RefreshTokenHash string     `db:"refresh_token_hash" json:"-"`
+	UserAgent        string     `db:"user_agent" json:"userAgent"`
+	IPAddress        string     `db:"ip_address" json:"ipAddress"`
+	ExpiresAt        time.Time  `db:"expires_at" json:"expiresAt"`
+	RotatedAt        *time.Time `db:"rotated_at" json:"-"`
+	LastSeenAt       time.Time  `db:"last_seen_at" json:"lastSeenAt"`
+	CreatedAt        time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt        time.Time  `db:"updated_at" json:"updatedAt"`
+	DeletedAt        *time.Time `db:"deleted_at" json:"deletedAt"`
+
+	// Filled in for the signed-in devices view
+	SignedInAt *time.Time `db:"signed_in_at" json:"signedInAt,omitempty"` // When the family's login happened
+	Device     string     `db:"-" json:"device,omitempty"`                // e.g. "Chrome on Windows"
+	Current    bool       `db:"-" json:"current"`                         // The session making the request
 }
 
 func (s *Session) IsExpired() bool {
@@ -25,3 +25,67 @@ type UserClinicWithUser struct {
 	UserClinic
 	User User `json:"user"`
 }
+
+// Clinic member roles (tbl_user_clinic.role)
+const (
+	RoleOwner        = "owner"
+	RoleAccountant   = "accountant"
+	RolePractitioner = "practitioner"
+	RoleViewer       = "viewer"
+)
+
+// Permission is an action a clinic member may be allowed to perform.
+type Permission string
+
+const (
+	PermissionManageClinic   Permission = "clinic:manage"   // update or delete the clinic itself
+	PermissionManageForms    Permission = "forms:manage"    // create, edit, archive, delete and duplicate forms
+	PermissionPublishForms   Permission = "forms:publish"   // publish forms
+	PermissionEditEntries    Permission = "entries:edit"    // create, edit and delete form and expense entries
+	PermissionManageAccounts Permission = "accounts:manage" // clinic expense types and categories; the shared chart of accounts needs a system administrator
+	PermissionInviteUsers    Permission = "users:invite"    // add and remove clinic members
+	PermissionExportReports  Permission = "reports:export"  // BAS worksheets, Excel and PDF exports
+	PermissionClosePeriods   Permission = "periods:close"   // close a quarter once its BAS is lodged
+	PermissionReopenPeriods  Permission = "periods:reopen"  // reopen a closed quarter
+)
+
+// RolePermissions maps each role to the actions it grants. Every role can read the clinic's data.
+var RolePermissions = map[string][]Permission{
+	RoleOwner: {
+		PermissionManageClinic,
+		PermissionManageForms,
+		PermissionPublishForms,
+		PermissionEditEntries,
+		PermissionManageAccounts,
+		PermissionInviteUsers,
+		PermissionExportReports,
+		PermissionClosePeriods,
+		PermissionReopenPeriods,
+	},
+	RoleAccountant: {
+		PermissionManageForms,
+		PermissionPublishForms,
+		PermissionEditEntries,
+		PermissionManageAccounts,
+		PermissionExportReports,
+		PermissionClosePeriods,
+	},
+	RolePractitioner: {
+		PermissionEditEntries,
+	},
+	RoleViewer: {},
+}
+
+func IsValidRole(role string) bool {
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+func RoleHasPermission(role string, permission Permission) bool {
+	for _, p := range RolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
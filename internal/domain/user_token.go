@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserTokenPurpose says what an emailed single-use token may be redeemed for.
+type UserTokenPurpose string
+
+const (
+	UserTokenPasswordReset     UserTokenPurpose = "password_reset"
+	UserTokenEmailVerification UserTokenPurpose = "email_verification"
+)
+
+// UserToken is a single-use, expiring token sent to a user by email. Only its hash is stored.
+type UserToken struct {
+	ID        uuid.UUID        `db:"id" json:"id"`
+	UserID    uuid.UUID        `db:"user_id" json:"userId"`
+	Purpose   UserTokenPurpose `db:"purpose" json:"purpose"`
+	TokenHash string           `db:"token_hash" json:"-"`
+	ExpiresAt time.Time        `db:"expires_at" json:"expiresAt"`
+	UsedAt    *time.Time       `db:"used_at" json:"usedAt"`
+	CreatedAt time.Time        `db:"created_at" json:"createdAt"`
+}
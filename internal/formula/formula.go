@@ -0,0 +1,219 @@
+// Package formula parses and evaluates the expressions of computed form fields, such as
+// "implant_fee * 0.6 + lab_adjustment" or "max(0, collections - lab_costs)".
+//
+// Expressions support numbers, references to other fields by ID (written bare, or in braces as
+// {field-id} when the ID is not a plain identifier), the arithmetic operators + - * /, the
+// comparisons < <= > >= == != and the logical operators && || !, with true as 1 and false as 0.
+// The functions are if(cond, then, else), round(x) or round(x, digits), min(...), max(...) and
+// sum(...), whose arguments may also be quoted section names to add up the fields of a section.
+package formula
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Limits keep a hostile or accidental expression from costing much to parse or evaluate.
+const (
+	maxLength = 1000
+	maxDepth  = 50
+)
+
+// Env supplies the values an expression refers to.
+type Env interface {
+	// Field returns the value of the field with the ID.
+	Field(id string) (float64, error)
+	// Section returns the sum of the fields in the named section.
+	Section(name string) (float64, error)
+}
+
+// Expr is a parsed expression.
+type Expr struct {
+	root     node
+	refs     []string
+	sections []string
+}
+
+// Refs returns the IDs of the fields the expression refers to, each once, in order of appearance.
+func (e *Expr) Refs() []string { return e.refs }
+
+// Sections returns the section names summed by the expression, each once.
+func (e *Expr) Sections() []string { return e.sections }
+
+// Eval computes the expression. Division by zero, and results that are not finite, are errors.
+func (e *Expr) Eval(env Env) (float64, error) {
+	v, err := e.root.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, errors.New("result is not a finite number")
+	}
+	return v, nil
+}
+
+type node interface {
+	eval(env Env) (float64, error)
+}
+
+type numberNode float64
+
+func (n numberNode) eval(Env) (float64, error) { return float64(n), nil }
+
+type refNode string
+
+func (n refNode) eval(env Env) (float64, error) { return env.Field(string(n)) }
+
+// sectionNode only appears as an argument of sum.
+type sectionNode string
+
+func (n sectionNode) eval(env Env) (float64, error) { return env.Section(string(n)) }
+
+type unaryNode struct {
+	op string
+	x  node
+}
+
+func (n *unaryNode) eval(env Env) (float64, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	if n.op == "!" {
+		return boolValue(x == 0), nil
+	}
+	if n.op == "-" {
+		return -x, nil
+	}
+	return x, nil
+}
+
+type binaryNode struct {
+	op   string
+	l, r node
+}
+
+func (n *binaryNode) eval(env Env) (float64, error) {
+	l, err := n.l.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	// && and || only evaluate their right side when it decides the result
+	switch n.op {
+	case "&&":
+		if l == 0 {
+			return 0, nil
+		}
+	case "||":
+		if l != 0 {
+			return 1, nil
+		}
+	}
+	r, err := n.r.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return l / r, nil
+	case "<":
+		return boolValue(l < r), nil
+	case "<=":
+		return boolValue(l <= r), nil
+	case ">":
+		return boolValue(l > r), nil
+	case ">=":
+		return boolValue(l >= r), nil
+	case "==":
+		return boolValue(l == r), nil
+	case "!=":
+		return boolValue(l != r), nil
+	case "&&", "||":
+		return boolValue(r != 0), nil
+	}
+	return 0, fmt.Errorf("unknown operator %s", n.op)
+}
+
+type callNode struct {
+	name string
+	args []node
+}
+
+func (n *callNode) eval(env Env) (float64, error) {
+	if n.name == "if" {
+		// Only the chosen branch is evaluated, so if(b == 0, 0, a / b) is safe
+		cond, err := n.args[0].eval(env)
+		if err != nil {
+			return 0, err
+		}
+		if cond != 0 {
+			return n.args[1].eval(env)
+		}
+		return n.args[2].eval(env)
+	}
+	args := make([]float64, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(env)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = v
+	}
+	switch n.name {
+	case "round":
+		digits := 0.0
+		if len(args) == 2 {
+			digits = args[1]
+		}
+		if digits != math.Trunc(digits) || digits < 0 || digits > 10 {
+			return 0, errors.New("round digits must be a whole number from 0 to 10")
+		}
+		scale := math.Pow(10, digits)
+		return math.Round(args[0]*scale) / scale, nil
+	case "min":
+		m := args[0]
+		for _, v := range args[1:] {
+			m = math.Min(m, v)
+		}
+		return m, nil
+	case "max":
+		m := args[0]
+		for _, v := range args[1:] {
+			m = math.Max(m, v)
+		}
+		return m, nil
+	case "sum":
+		var total float64
+		for _, v := range args {
+			total += v
+		}
+		return total, nil
+	}
+	return 0, fmt.Errorf("unknown function %s", n.name)
+}
+
+// functions maps each function to its minimum and maximum argument count; -1 is unlimited.
+var functions = map[string][2]int{
+	"if":    {3, 3},
+	"round": {1, 2},
+	"min":   {1, -1},
+	"max":   {1, -1},
+	"sum":   {1, -1},
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,212 @@
+package formula
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testEnv serves fixed field and section values; the field "boom" fails, so evaluating it shows.
+type testEnv struct {
+	fields   map[string]float64
+	sections map[string]float64
+}
+
+func (e testEnv) Field(id string) (float64, error) {
+	if id == "boom" {
+		return 0, errors.New("boom was evaluated")
+	}
+	v, ok := e.fields[id]
+	if !ok {
+		return 0, errors.New("unknown field " + id)
+	}
+	return v, nil
+}
+
+func (e testEnv) Section(name string) (float64, error) {
+	v, ok := e.sections[name]
+	if !ok {
+		return 0, errors.New("unknown section " + name)
+	}
+	return v, nil
+}
+
+var env = testEnv{
+	fields:   map[string]float64{"a": 10, "b": 4, "zero": 0, "lab-fee": 2.5, "big": 1e308},
+	sections: map[string]float64{"income": 100, "expense": 30},
+}
+
+func eval(src string) (float64, error) {
+	expr, err := Parse(src)
+	if err != nil {
+		return 0, err
+	}
+	return expr.Eval(env)
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		// precedence
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"-2 * 3 + 1", -5},
+		{"2 * -3", -6},
+		{"1 + 2 < 4", 1},
+		{"1 + 2 == 3", 1},
+		{"1 || 0 && 0", 1},
+		{"0 && 1 || 1", 1},
+		{"1 < 2 && 3 < 2", 0},
+		{"!0 + 1", 2},
+		{"!(2 > 1)", 0},
+		// associativity
+		{"10 - 4 - 3", 3},
+		{"64 / 4 / 2", 8},
+		{"2 * 3 / 4", 1.5},
+		{"--2", 2},
+		{"-+-2", 2},
+		{"!!5", 1},
+		// references
+		{"a * 0.6 + b", 10},
+		{"{lab-fee} * 2", 5},
+		{"{ a } + a", 20},
+		{"max(0, b - a)", 0},
+		// functions
+		{"if(a > 5, 1, 2)", 1},
+		{"IF(a < 5, 1, 2)", 2},
+		{"round(2.5)", 3},
+		{"round(12.3456, 2)", 12.35},
+		{"min(3, 1, 2)", 1},
+		{"max(3, 1, 2)", 3},
+		{"sum(1, 2, 3)", 6},
+		{`sum("income")`, 100},
+		{`sum("income") - sum("expense", a)`, 60},
+	}
+	for _, tt := range tests {
+		got, err := eval(tt.src)
+		if err != nil || got != tt.want {
+			t.Errorf("%s = %v, %v; want %v", tt.src, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src, wantErr string
+	}{
+		{"", "formula is empty"},
+		{"   ", "formula is empty"},
+		{"1 < 2 < 3", "comparisons cannot be chained"},
+		{"a == b != 0", "comparisons cannot be chained"},
+		{"1 <= 2 >= 0", "comparisons cannot be chained"},
+		{"(1", "expected )"},
+		{"1 +", "formula ends unexpectedly"},
+		{"a b", `unexpected "b"`},
+		{"1 # 2", "unexpected character"},
+		{"1..2", "invalid number"},
+		{strings.Repeat("9", 400), "invalid number"},
+		{"{a", "unterminated {"},
+		{"{ }", "empty {}"},
+		{`"income"`, "section names can only be used inside sum"},
+		{`max("income")`, "section names can only be used inside sum"},
+		{"foo(1)", "unknown function foo"},
+		{"if(1, 2)", "if takes 3 arguments"},
+		{"round()", "round takes 1 or 2 arguments"},
+		{"min()", "min takes at least 1 argument"},
+		{"min(1,", "formula ends unexpectedly"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.src, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	tests := []struct {
+		name, src, wantErr string
+	}{
+		{"longest formula", "1" + strings.Repeat("+1", (maxLength-1)/2), ""},
+		{"too long", "1" + strings.Repeat("+1", maxLength/2), "longer than 1000 characters"},
+		{"deepest parentheses", strings.Repeat("(", maxDepth-1) + "1" + strings.Repeat(")", maxDepth-1), ""},
+		{"parentheses too deep", strings.Repeat("(", maxDepth) + "1" + strings.Repeat(")", maxDepth), "nested too deeply"},
+		{"deepest unary", strings.Repeat("-", maxDepth-1) + "1", ""},
+		{"unary too deep", strings.Repeat("-", maxDepth) + "1", "nested too deeply"},
+		{"calls too deep", strings.Repeat("max(", maxDepth) + "1" + strings.Repeat(")", maxDepth), "nested too deeply"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.src)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		src, wantErr string
+	}{
+		{"1 / 0", "division by zero"},
+		{"a / zero", "division by zero"},
+		{"a / (b - b)", "division by zero"},
+		{"big * 10", "not a finite number"},
+		{"-big - big", "not a finite number"},
+		{"round(a, 11)", "round digits"},
+		{"round(a, 1.5)", "round digits"},
+		{"round(a, -1)", "round digits"},
+		{"missing + 1", "unknown field missing"},
+		{`sum("nowhere")`, "unknown section nowhere"},
+	}
+	for _, tt := range tests {
+		_, err := eval(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: error = %v, want %q", tt.src, err, tt.wantErr)
+		}
+	}
+}
+
+// if, && and || leave the operand that cannot change the result unevaluated.
+func TestShortCircuit(t *testing.T) {
+	tests := []struct {
+		src  string
+		want float64
+		err  bool
+	}{
+		{"if(1, 2, boom)", 2, false},
+		{"if(0, boom, 3)", 3, false},
+		{"if(zero == 0, 0, a / zero)", 0, false},
+		{"0 && boom", 0, false},
+		{"1 || boom", 1, false},
+		{"zero != 0 && a / zero > 1", 0, false},
+		{"if(boom, 1, 2)", 0, true},
+		{"1 && boom", 0, true},
+		{"0 || boom", 0, true},
+		{"min(1, boom)", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := eval(tt.src)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("%s = %v, %v; want %v (error %v)", tt.src, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestRefsAndSections(t *testing.T) {
+	expr, err := Parse(`a + {lab fee} * a + sum("Income", b, "Income") - sum("expense")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "lab fee", "b"}; !reflect.DeepEqual(expr.Refs(), want) {
+		t.Errorf("Refs() = %q, want %q", expr.Refs(), want)
+	}
+	if want := []string{"Income", "expense"}; !reflect.DeepEqual(expr.Sections(), want) {
+		t.Errorf("Sections() = %q, want %q", expr.Sections(), want)
+	}
+}
@@ -0,0 +1,321 @@
+package formula
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokRef    // {field-id}
+	tokString // "section name"
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// Parse parses an expression, checking function names and argument counts.
+func Parse(src string) (*Expr, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, errors.New("formula is empty")
+	}
+	if len(src) > maxLength {
+		return nil, fmt.Errorf("formula is longer than %d characters", maxLength)
+	}
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, seenRefs: map[string]bool{}, seenSections: map[string]bool{}}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos+1)
+	}
+	return &Expr{root: root, refs: p.refs, sections: p.sections}, nil
+}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r >= '0' && r <= '9' || r == '.':
+			start := i
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, src[start:i], start})
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(src) {
+				r, size := utf8.DecodeRuneInString(src[i:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, token{tokIdent, src[start:i], start})
+		case r == '{' || r == '"':
+			closing := byte('}')
+			kind := tokRef
+			if r == '"' {
+				closing, kind = '"', tokString
+			}
+			end := strings.IndexByte(src[i+1:], closing)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated %c at position %d", r, i+1)
+			}
+			text := strings.TrimSpace(src[i+1 : i+1+end])
+			if text == "" {
+				return nil, fmt.Errorf("empty %c%c at position %d", r, closing, i+1)
+			}
+			tokens = append(tokens, token{kind, text, i})
+			i += end + 2
+		case r == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{tokComma, ",", i})
+			i++
+		default:
+			op := ""
+			for _, candidate := range []string{"<=", ">=", "==", "!=", "&&", "||", "+", "-", "*", "/", "<", ">", "!"} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, i+1)
+			}
+			tokens = append(tokens, token{tokOp, op, i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{tokEOF, "end of formula", len(src)}), nil
+}
+
+// parser is a recursive descent parser. From lowest to highest precedence: ||, &&, comparisons
+// (which do not chain), + and -, * and /, then unary - + !.
+type parser struct {
+	tokens       []token
+	pos          int
+	depth        int
+	refs         []string
+	sections     []string
+	seenRefs     map[string]bool
+	seenSections map[string]bool
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) acceptOp(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseExpr() (node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxDepth {
+		return nil, errors.New("formula is nested too deeply")
+	}
+	return p.parseBinary(0)
+}
+
+// levels lists the binary operators by increasing precedence.
+var levels = [][]string{
+	{"||"},
+	{"&&"},
+	{"<", "<=", ">", ">=", "==", "!="},
+	{"+", "-"},
+	{"*", "/"},
+}
+
+const comparisonLevel = 2
+
+func (p *parser) parseBinary(level int) (node, error) {
+	if level == len(levels) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp(levels[level]...)
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, l: left, r: right}
+		if level == comparisonLevel {
+			if t := p.peek(); t.kind == tokOp && isComparison(t.text) {
+				return nil, fmt.Errorf("comparisons cannot be chained at position %d", t.pos+1)
+			}
+			return left, nil
+		}
+	}
+}
+
+func isComparison(op string) bool {
+	for _, c := range levels[comparisonLevel] {
+		if c == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if op, ok := p.acceptOp("-", "+", "!"); ok {
+		p.depth++
+		defer func() { p.depth-- }()
+		if p.depth > maxDepth {
+			return nil, errors.New("formula is nested too deeply")
+		}
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.text, t.pos+1)
+		}
+		return numberNode(v), nil
+	case tokRef:
+		return p.ref(t.text), nil
+	case tokIdent:
+		if p.peek().kind == tokLParen {
+			return p.parseCall(t)
+		}
+		return p.ref(t.text), nil
+	case tokLParen:
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, fmt.Errorf("expected ) at position %d", closing.pos+1)
+		}
+		return x, nil
+	case tokString:
+		return nil, fmt.Errorf("section names can only be used inside sum, at position %d", t.pos+1)
+	case tokEOF:
+		return nil, errors.New("formula ends unexpectedly")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos+1)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn := strings.ToLower(name.text)
+	arity, ok := functions[fn]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s at position %d", name.text, name.pos+1)
+	}
+	p.next() // (
+	var args []node
+	if p.peek().kind != tokRParen {
+		for {
+			if t := p.peek(); t.kind == tokString && fn == "sum" {
+				p.next()
+				args = append(args, p.section(t.text))
+			} else {
+				arg, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, arg)
+			}
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if closing := p.next(); closing.kind != tokRParen {
+		return nil, fmt.Errorf("expected , or ) at position %d", closing.pos+1)
+	}
+	if len(args) < arity[0] || (arity[1] >= 0 && len(args) > arity[1]) {
+		return nil, fmt.Errorf("%s takes %s", fn, describeArity(arity))
+	}
+	return &callNode{name: fn, args: args}, nil
+}
+
+func describeArity(arity [2]int) string {
+	switch {
+	case arity[0] == arity[1]:
+		return fmt.Sprintf("%d arguments", arity[0])
+	case arity[1] < 0:
+		return fmt.Sprintf("at least %d argument", arity[0])
+	}
+	return fmt.Sprintf("%d or %d arguments", arity[0], arity[1])
+}
+
+func (p *parser) ref(id string) node {
+	if !p.seenRefs[id] {
+		p.seenRefs[id] = true
+		p.refs = append(p.refs, id)
+	}
+	return refNode(id)
+}
+
+func (p *parser) section(name string) node {
+	if !p.seenSections[name] {
+		p.seenSections[name] = true
+		p.sections = append(p.sections, name)
+	}
+	return sectionNode(name)
+}
@@ -35,12 +35,17 @@ func NewAOCHandler(aocService *service.AOSService) *AOCHandler {
 // @Failure 500 {object} domain.H
 // @Router /aoc [post]
 func (h *AOCHandler) CreateAOC(c *gin.Context) {
+	userID, ok := c.MustGet("user_id").(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
 	var aoc domain.AOCRequest
 	if err := util.BindAndValidate(c, &aoc); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	err := h.aocService.CreateAOC(c.Request.Context(), &aoc)
+	err := h.aocService.CreateAOC(c.Request.Context(), &aoc, userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -264,6 +269,11 @@ func (h *AOCHandler) GetAOCsByAccountType(c *gin.Context) {
 // @Failure 404 {object} domain.H
 // @Failure 500 {object} domain.H
 func (h *AOCHandler) UpdateAOC(c *gin.Context) {
+	userID, ok := c.MustGet("user_id").(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
 	id := c.Param("id")
 	idUUID, err := uuid.Parse(id)
 	if err != nil {
@@ -277,7 +287,7 @@ func (h *AOCHandler) UpdateAOC(c *gin.Context) {
 	}
 	aocRepo := aoc.ToRepo()
 	aocRepo.ID = idUUID
-	err = h.aocService.UpdateAOC(c.Request.Context(), aocRepo)
+	err = h.aocService.UpdateAOC(c.Request.Context(), aocRepo, userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -298,6 +308,11 @@ func (h *AOCHandler) UpdateAOC(c *gin.Context) {
 // @Failure 404 {object} domain.H
 // @Failure 500 {object} domain.H
 func (h *AOCHandler) DeleteAOC(c *gin.Context) {
+	userID, ok := c.MustGet("user_id").(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
 	var req domain.BulkDeleteAOCRequest
 	if err := util.BindAndValidate(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: ids required"})
@@ -316,7 +331,7 @@ func (h *AOCHandler) DeleteAOC(c *gin.Context) {
 		}
 		ids = append(ids, id)
 	}
-	err := h.aocService.DeleteAOC(c.Request.Context(), ids)
+	err := h.aocService.DeleteAOC(c.Request.Context(), ids, userID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
@@ -326,6 +341,11 @@ func (h *AOCHandler) DeleteAOC(c *gin.Context) {
 
 // BulkUpdateTax updates account_tax_id for multiple accounts. PATCH /aoc/bulk-tax with body { ids, accountTaxId }.
 func (h *AOCHandler) BulkUpdateTax(c *gin.Context) {
+	userID, ok := c.MustGet("user_id").(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
 	var req domain.BulkUpdateTaxRequest
 	if err := util.BindAndValidate(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -344,7 +364,7 @@ func (h *AOCHandler) BulkUpdateTax(c *gin.Context) {
 		}
 		ids = append(ids, id)
 	}
-	if err := h.aocService.BulkUpdateTax(c.Request.Context(), ids, req.AccountTaxID); err != nil {
+	if err := h.aocService.BulkUpdateTax(c.Request.Context(), ids, req.AccountTaxID, userID); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -353,6 +373,11 @@ func (h *AOCHandler) BulkUpdateTax(c *gin.Context) {
 
 // ArchiveAOC soft-deletes (archives) multiple accounts. PATCH /aoc/archive with body { ids }.
 func (h *AOCHandler) ArchiveAOC(c *gin.Context) {
+	userID, ok := c.MustGet("user_id").(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
 	var req domain.BulkArchiveAOCRequest
 	if err := util.BindAndValidate(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: ids required"})
@@ -371,7 +396,7 @@ func (h *AOCHandler) ArchiveAOC(c *gin.Context) {
 		}
 		ids = append(ids, id)
 	}
-	if err := h.aocService.DeleteAOC(c.Request.Context(), ids); err != nil {
+	if err := h.aocService.DeleteAOC(c.Request.Context(), ids, userID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
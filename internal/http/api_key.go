@@ -0,0 +1,92 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+	utils "github.com/iamarpitzala/aca-reca-backend/util"
+)
+
+type APIKeyHandler struct {
+	authService *service.AuthService
+}
+
+func NewAPIKeyHandler(authService *service.AuthService) *APIKeyHandler {
+	return &APIKeyHandler{authService: authService}
+}
+
+// List returns the signed-in user's API keys, without the keys themselves
+// GET /api/v1/users/me/api-keys
+// @Summary List API keys
+// @Tags APIKey
+// @Produce json
+// @Success 200 {array} domain.APIKey
+// @Failure 500 {object} domain.H
+// @Router /users/me/api-keys [get]
+func (h *APIKeyHandler) List(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	keys, err := h.authService.ListAPIKeys(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// Create issues an API key for the signed-in user
+// POST /api/v1/users/me/api-keys
+// @Summary Create an API key
+// @Description The key is returned only in this response. Send it as "Authorization: Bearer <key>".
+// @Tags APIKey
+// @Accept json
+// @Produce json
+// @Param request body domain.CreateAPIKeyRequest true "Name, scopes, optional clinic and expiry"
+// @Success 201 {object} domain.CreatedAPIKey
+// @Failure 400 {object} domain.H
+// @Router /users/me/api-keys [post]
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	var req domain.CreateAPIKeyRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	key, err := h.authService.CreateAPIKey(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, key)
+}
+
+// Revoke revokes one of the signed-in user's API keys
+// DELETE /api/v1/users/me/api-keys/:id
+// @Summary Revoke an API key
+// @Tags APIKey
+// @Produce json
+// @Param id path string true "API key ID"
+// @Success 200 {object} domain.H
+// @Failure 400 {object} domain.H
+// @Failure 404 {object} domain.H
+// @Router /users/me/api-keys/{id} [delete]
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	keyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid API key ID"})
+		return
+	}
+	if err := h.authService.RevokeAPIKey(c.Request.Context(), userID, keyID); err != nil {
+		if errors.Is(err, service.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
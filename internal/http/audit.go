@@ -0,0 +1,148 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+)
+
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// GetClinicAuditLogs lists the audit trail of a clinic
+// GET /api/v1/audit/clinic/:clinicId
+// @Summary List clinic audit logs
+// @Description Changes to a clinic's forms, entries, expenses and settings, newest first
+// @Tags Audit
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param entityType query string false "Entity type, e.g. custom_form_entry or expense_entry"
+// @Param entityId query string false "Entity ID"
+// @Param userId query string false "Actor user ID"
+// @Param action query string false "create, update, delete, publish or archive"
+// @Param from query string false "Earliest change (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "Latest change (YYYY-MM-DD or RFC3339)"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param offset query int false "Records to skip"
+// @Success 200 {object} domain.H
+// @Failure 400 {object} domain.H
+// @Router /audit/clinic/{clinicId} [get]
+func (h *AuditHandler) GetClinicAuditLogs(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	filter.ClinicID = &clinicID
+	h.listAuditLogs(c, filter)
+}
+
+// GetAccountAuditLogs lists the audit trail of the shared chart of accounts
+// GET /api/v1/audit/accounts
+// @Summary List chart of accounts audit logs
+// @Tags Audit
+// @Produce json
+// @Param entityId query string false "Account ID"
+// @Param userId query string false "Actor user ID"
+// @Param action query string false "create, update or delete"
+// @Param from query string false "Earliest change (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "Latest change (YYYY-MM-DD or RFC3339)"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param offset query int false "Records to skip"
+// @Success 200 {object} domain.H
+// @Failure 400 {object} domain.H
+// @Router /audit/accounts [get]
+func (h *AuditHandler) GetAccountAuditLogs(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.EntityType = domain.AuditEntityAccount
+	h.listAuditLogs(c, filter)
+}
+
+func (h *AuditHandler) listAuditLogs(c *gin.Context, filter domain.AuditLogFilter) {
+	logs, err := h.auditService.List(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "audit logs retrieved successfully", "auditLogs": logs})
+}
+
+func parseAuditFilter(c *gin.Context) (domain.AuditLogFilter, error) {
+	filter := domain.AuditLogFilter{
+		EntityType: c.Query("entityType"),
+		Action:     c.Query("action"),
+	}
+	var err error
+	if filter.EntityID, err = optionalUUIDQuery(c, "entityId"); err != nil {
+		return filter, err
+	}
+	if filter.ActorID, err = optionalUUIDQuery(c, "userId"); err != nil {
+		return filter, err
+	}
+	if filter.From, err = optionalTimeQuery(c, "from", false); err != nil {
+		return filter, err
+	}
+	if filter.To, err = optionalTimeQuery(c, "to", true); err != nil {
+		return filter, err
+	}
+	if v := c.Query("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			return filter, errors.New("limit must be a number")
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil {
+			return filter, errors.New("offset must be a number")
+		}
+	}
+	return filter, nil
+}
+
+func optionalUUIDQuery(c *gin.Context, name string) (*uuid.UUID, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return nil, errors.New("invalid " + name)
+	}
+	return &id, nil
+}
+
+// optionalTimeQuery accepts RFC3339 or a plain date; a plain date used as an upper bound covers the whole day.
+func optionalTimeQuery(c *gin.Context, name string, endOfDay bool) (*time.Time, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return nil, errors.New(name + " must be a date (YYYY-MM-DD) or RFC3339 timestamp")
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
@@ -3,8 +3,11 @@ package http
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -22,13 +25,17 @@ func contains(s, substr string) bool {
 type AuthHandler struct {
 	authService  *service.AuthService
 	oauthService *service.OAuthService
+	mfaService   *service.MFAService
+	loginLimit   *service.LoginLimitService
 	frontendURL  string
 }
 
-func NewAuthHandler(authService *service.AuthService, oauthService *service.OAuthService, frontendURL string) *AuthHandler {
+func NewAuthHandler(authService *service.AuthService, oauthService *service.OAuthService, mfaService *service.MFAService, loginLimit *service.LoginLimitService, frontendURL string) *AuthHandler {
 	return &AuthHandler{
 		authService:  authService,
 		oauthService: oauthService,
+		mfaService:   mfaService,
+		loginLimit:   loginLimit,
 		frontendURL:  frontendURL,
 	}
 }
@@ -43,6 +50,7 @@ func NewAuthHandler(authService *service.AuthService, oauthService *service.OAut
 // @Param register_request body domain.RegisterRequest true "Register request"
 // @Success 201 {object} domain.AuthResponse
 // @Failure 400 {object} domain.H
+// @Failure 429 {object} domain.H
 // @Failure 500 {object} domain.H
 // @Router /auth/register [post]
 func (h *AuthHandler) Register(c *gin.Context) {
@@ -52,8 +60,18 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// Failed registrations count against the address only; they are how accounts are enumerated
+	ip := c.ClientIP()
+	if err := h.loginLimit.Allow(c.Request.Context(), "", ip); err != nil {
+		loginLimitError(c, err)
+		return
+	}
 	response, err := h.authService.Register(c.Request.Context(), &req)
 	if err != nil {
+		if limitErr := h.loginLimit.RecordFailure(c.Request.Context(), "", ip); limitErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": limitErr.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -64,13 +82,15 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // Login handles user login
 // POST /api/v1/auth/login
 // @Summary Login a user
-// @Description Login a user with email and password
+// @Description Login a user with email and password. Users with two-factor authentication get mfaRequired and an mfaToken instead of tokens.
+// @Description Repeated failures for an account or from an address are answered with 429 and a Retry-After header until the delay or lockout ends
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param login_request body domain.LoginRequest true "Login request"
 // @Success 200 {object} domain.AuthResponse
 // @Failure 400 {object} domain.H
+// @Failure 429 {object} domain.H
 // @Failure 500 {object} domain.H
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -81,11 +101,28 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	ip := c.ClientIP()
+	if err := h.loginLimit.Allow(c.Request.Context(), req.Email, ip); err != nil {
+		loginLimitError(c, err)
+		return
+	}
 	response, err := h.authService.Login(c.Request.Context(), &req)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			if limitErr := h.loginLimit.RecordFailure(c.Request.Context(), req.Email, ip); limitErr != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": limitErr.Error()})
+				return
+			}
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
+	// The account's failures are forgotten only once the login is complete; with two-factor
+	// authentication that happens in VerifyMFA. The session already exists, so failures that could
+	// not be reset still expire with their window.
+	if !response.MFARequired {
+		_ = h.loginLimit.RecordSuccess(c.Request.Context(), req.Email)
+	}
 
 	c.JSON(http.StatusOK, response)
 }
@@ -149,39 +186,166 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "logged out successfully"})
 }
 
+// VerifyMFA completes a two-step login
+// POST /api/v1/auth/mfa/verify
+// @Summary Complete a two-factor login
+// @Description Exchange the mfaToken from login and an authenticator or recovery code for tokens
+// @Description Wrong codes count as failed logins; after a few on one mfaToken the user must sign in again
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body domain.MFALoginRequest true "Challenge token and code"
+// @Success 200 {object} domain.AuthResponse
+// @Failure 400 {object} domain.H
+// @Failure 401 {object} domain.H
+// @Failure 429 {object} domain.H
+// @Router /auth/mfa/verify [post]
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req domain.MFALoginRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.mfaService.CompleteLogin(c.Request.Context(), &req, c.ClientIP())
+	if err != nil {
+		var blocked *service.LoginBlockedError
+		switch {
+		case errors.As(err, &blocked):
+			loginLimitError(c, err)
+		case errors.Is(err, service.ErrTooManyAttempts):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ForgotPassword emails a password reset link
+// POST /api/v1/auth/password/forgot
+// @Summary Request a password reset
+// @Description Email a single-use reset link. The response is the same whether or not the email is registered
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body domain.ForgotPasswordRequest true "Account email"
+// @Success 200 {object} domain.MessageResponse
+// @Failure 400 {object} domain.H
+// @Failure 500 {object} domain.H
+// @Router /auth/password/forgot [post]
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req domain.ForgotPasswordRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ForgotPassword(c.Request.Context(), req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "if an account exists for this email, a reset link has been sent"})
+}
+
+// ResetPassword sets a new password using a reset link
+// POST /api/v1/auth/password/reset
+// @Summary Reset a password
+// @Description Redeem a reset token, set a new password and sign out every session
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body domain.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} domain.MessageResponse
+// @Failure 400 {object} domain.H
+// @Router /auth/password/reset [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req domain.ResetPasswordRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ResetPassword(c.Request.Context(), &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password reset successfully"})
+}
+
+// VerifyEmail confirms an email address using a verification link
+// POST /api/v1/auth/email/verify
+// @Summary Verify an email address
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body domain.VerifyEmailRequest true "Verification token"
+// @Success 200 {object} domain.MessageResponse
+// @Failure 400 {object} domain.H
+// @Router /auth/email/verify [post]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req domain.VerifyEmailRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email verified successfully"})
+}
+
+// ResendVerification emails a new verification link
+// POST /api/v1/auth/email/resend
+// @Summary Resend the verification email
+// @Description The response is the same whether or not the email is registered or already verified
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body domain.ResendVerificationRequest true "Account email"
+// @Success 200 {object} domain.MessageResponse
+// @Failure 400 {object} domain.H
+// @Failure 500 {object} domain.H
+// @Router /auth/email/resend [post]
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req domain.ResendVerificationRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ResendVerification(c.Request.Context(), req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "if this email needs verifying, a new link has been sent"})
+}
+
 // InitiateOAuth initiates OAuth flow
 // GET /api/v1/auth/oauth/:provider
 // @Summary Initiate OAuth flow
-// @Description Initiate OAuth flow with a provider
+// @Description Redirects to the provider using PKCE and an OpenID nonce. With link_token (from POST /users/me/oauth/{provider}/link) the identity is linked to the signed-in user instead
 // @Tags Auth
 // @Accept json
 // @Produce json
-// @Param provider query string true "Provider"
-// @Param state query string true "State"
+// @Param provider path string true "Provider"
+// @Param link_token query string false "Link token"
 // @Success 307 {string} string "Redirect to OAuth provider"
 // @Failure 400 {object} domain.H
 // @Failure 500 {object} domain.H
-// @Router /auth/oauth [get]
+// @Router /auth/oauth/{provider} [get]
 func (h *AuthHandler) InitiateOAuth(c *gin.Context) {
 	provider := c.Param("provider")
 
-	// Generate state token for CSRF protection
-	state := uuid.New().String()
-
-	// Store state in cookie; SameSite=Lax so cookie is sent when Google redirects back
-	secure := c.Request.URL.Scheme == "https" || c.Request.TLS != nil
-	cookie := &http.Cookie{
-		Name:     "oauth_state",
-		Value:    state,
-		Path:     "/",
-		MaxAge:   600,
-		HttpOnly: true,
-		Secure:   secure,
-		SameSite: http.SameSiteLaxMode,
-	}
-	http.SetCookie(c.Writer, cookie)
-
-	authURL, err := h.oauthService.GetAuthURL(provider, state)
+	authURL, state, err := h.oauthService.Begin(c.Request.Context(), provider, c.Query("link_token"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
@@ -190,19 +354,24 @@ func (h *AuthHandler) InitiateOAuth(c *gin.Context) {
 		return
 	}
 
+	// Bind the state to this browser for CSRF protection; SameSite=Lax so the cookie is sent when the
+	// provider redirects back
+	setOAuthStateCookie(c, state, 600)
+
 	c.Redirect(http.StatusTemporaryRedirect, authURL)
 }
 
 // OAuthCallback handles OAuth callback
 // GET /api/v1/auth/oauth/:provider/callback
 // @Summary OAuth callback
-// @Description OAuth callback with a provider
+// @Description OAuth callback with a provider. Signs in, or links the identity when the flow was started with a link token
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param provider path string true "Provider"
 // @Success 200 {object} domain.AuthResponse
 // @Failure 400 {object} domain.H
+// @Failure 409 {object} domain.H
 // @Failure 500 {object} domain.H
 // @Router /auth/oauth/{provider}/callback [get]
 func (h *AuthHandler) OAuthCallback(c *gin.Context) {
@@ -216,10 +385,11 @@ func (h *AuthHandler) OAuthCallback(c *gin.Context) {
 	}
 
 	state := c.Query("state")
-	if state != stateCookie {
+	if state == "" || state != stateCookie {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid state parameter"})
 		return
 	}
+	setOAuthStateCookie(c, "", -1)
 
 	code := c.Query("code")
 	if code == "" {
@@ -227,8 +397,7 @@ func (h *AuthHandler) OAuthCallback(c *gin.Context) {
 		return
 	}
 
-	// Exchange code for token
-	token, err := h.oauthService.ExchangeCode(c.Request.Context(), provider, code)
+	user, linked, err := h.oauthService.Complete(c.Request.Context(), provider, state, code)
 	if err != nil {
 		// Provide helpful error message for redirect_uri_mismatch
 		errorMsg := err.Error()
@@ -243,55 +412,21 @@ func (h *AuthHandler) OAuthCallback(c *gin.Context) {
 			})
 			return
 		}
-		c.JSON(http.StatusBadRequest, gin.H{"error": errorMsg})
-		return
-	}
-
-	// Get user info from provider
-	userInfo, err := h.oauthService.GetUserInfo(c.Request.Context(), provider, token)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user info from provider", "details": err.Error()})
-		return
-	}
-
-	// Find or create user
-	existingUser, err := h.oauthService.FindUserByProvider(c.Request.Context(), provider, userInfo.ID)
-	if err != nil {
-		// User doesn't exist, create new user
-		newUser, err := h.oauthService.CreateUserFromOAuth(c.Request.Context(), userInfo)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user", "details": err.Error()})
-			return
-		}
-
-		// Link OAuth provider
-		if err := h.oauthService.LinkProvider(c.Request.Context(), newUser.ID, provider, userInfo.ID, userInfo.Email, token); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to link provider", "details": err.Error()})
-			return
-		}
-
-		// Generate tokens and create session
-		response, err := h.authService.OAuthLogin(c.Request.Context(), newUser.ID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "details": err.Error()})
-			return
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrOAuthEmailNotVerified) || errors.Is(err, service.ErrOAuthIdentityInUse) || errors.Is(err, service.ErrOAuthProviderLinked) {
+			status = http.StatusConflict
 		}
-
-		h.redirectToFrontendWithTokens(c, response)
+		c.JSON(status, gin.H{"error": errorMsg})
 		return
 	}
 
-	// User exists, link provider if not already linked
-	if err := h.oauthService.LinkProvider(c.Request.Context(), existingUser.ID, provider, userInfo.ID, userInfo.Email, token); err != nil {
-		// Provider might already be linked, log but continue
-		// In production, you might want to log this error
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to link provider", "details": err.Error()})
+	if linked {
+		h.redirectToFrontend(c, url.Values{"linked": {provider}})
 		return
 	}
 
 	// Generate tokens and create session
-	response, err := h.authService.OAuthLogin(c.Request.Context(), existingUser.ID)
+	response, err := h.authService.OAuthLogin(c.Request.Context(), user.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "details": err.Error()})
 		return
@@ -300,26 +435,59 @@ func (h *AuthHandler) OAuthCallback(c *gin.Context) {
 	h.redirectToFrontendWithTokens(c, response)
 }
 
+func setOAuthStateCookie(c *gin.Context, state string, maxAge int) {
+	secure := c.Request.URL.Scheme == "https" || c.Request.TLS != nil
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     "oauth_state",
+		Value:    state,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
 // redirectToFrontendWithTokens redirects the browser to the frontend callback with tokens in the URL hash (not logged).
 func (h *AuthHandler) redirectToFrontendWithTokens(c *gin.Context, response *domain.AuthResponse) {
 	if h.frontendURL == "" {
 		c.JSON(http.StatusOK, response)
 		return
 	}
-	redirectURL, err := url.Parse(strings.TrimSuffix(h.frontendURL, "/") + "/auth/callback")
-	if err != nil {
-		c.JSON(http.StatusOK, response)
-		return
+	q := url.Values{}
+	if response.MFARequired {
+		// The frontend finishes the login at /auth/mfa/verify
+		q.Set("mfa_token", response.MFAToken)
+	} else {
+		q.Set("access_token", response.AccessToken)
+		q.Set("refresh_token", response.RefreshToken)
 	}
-	q := redirectURL.Query()
-	q.Set("access_token", response.AccessToken)
-	q.Set("refresh_token", response.RefreshToken)
 	if response.User != nil {
 		userJSON, _ := json.Marshal(response.User)
 		q.Set("user", base64.URLEncoding.EncodeToString(userJSON))
 	}
-	redirectURL.RawQuery = q.Encode()
-	redirectURL.Fragment = redirectURL.RawQuery
-	redirectURL.RawQuery = ""
+	h.redirectToFrontend(c, q)
+}
+
+// redirectToFrontend sends the browser to the frontend callback with values in the URL hash.
+func (h *AuthHandler) redirectToFrontend(c *gin.Context, values url.Values) {
+	redirectURL, err := url.Parse(strings.TrimSuffix(h.frontendURL, "/") + "/auth/callback")
+	if h.frontendURL == "" || err != nil {
+		c.JSON(http.StatusOK, values)
+		return
+	}
+	redirectURL.Fragment = values.Encode()
 	c.Redirect(http.StatusTemporaryRedirect, redirectURL.String())
 }
+
+// loginLimitError answers a refused attempt with 429 and a Retry-After header.
+func loginLimitError(c *gin.Context, err error) {
+	var blocked *service.LoginBlockedError
+	if !errors.As(err, &blocked) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	seconds := int(math.Ceil(blocked.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "retryAfter": seconds, "locked": blocked.Locked})
+}
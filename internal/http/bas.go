@@ -0,0 +1,50 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+	utils "github.com/iamarpitzala/aca-reca-backend/util"
+)
+
+type BASHandler struct {
+	basService *service.BASService
+}
+
+func NewBASHandler(basService *service.BASService) *BASHandler {
+	return &BASHandler{basService: basService}
+}
+
+// GetWorksheet builds the BAS worksheet for a clinic and quarter
+// GET /api/v1/bas/clinic/:clinicId/quarter/:quarterId
+// @Summary Get BAS worksheet
+// @Description Sum custom form and expense entries in a quarter into BAS labels G1, G2, G3, G10, G11, 1A and 1B with drill-down
+// @Tags BAS
+// @Accept json
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param quarterId path string true "Quarter ID"
+// @Success 200 {object} domain.H
+// @Failure 400 {object} domain.H
+// @Failure 404 {object} domain.H
+// @Router /bas/clinic/{clinicId}/quarter/{quarterId} [get]
+func (h *BASHandler) GetWorksheet(c *gin.Context) {
+	clinicID, err := uuid.Parse(c.Param("clinicId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid clinic ID"})
+		return
+	}
+	quarterID, err := uuid.Parse(c.Param("quarterId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid quarter ID"})
+		return
+	}
+	ws, err := h.basService.GenerateWorksheet(c.Request.Context(), clinicID, quarterID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	utils.JSONResponse(c, http.StatusOK, "BAS worksheet generated", ws, nil)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
 	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+	"github.com/iamarpitzala/aca-reca-backend/util"
 )
 
 type ClinicHandler struct {
@@ -44,13 +45,13 @@ func (h *ClinicHandler) CreateClinic(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	err := h.clinicService.CreateClinic(c.Request.Context(), &clinic)
+	err := h.clinicService.CreateClinic(c.Request.Context(), &clinic, userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 	// Auto-associate the creating user as owner so they can access the clinic
-	_, err = h.userClinicService.AssociateUserWithClinic(c.Request.Context(), userID, clinic.ID, "owner")
+	_, err = h.userClinicService.AssociateUserWithClinic(c.Request.Context(), userID, clinic.ID, domain.RoleOwner)
 	if err != nil {
 		// Log but don't fail - clinic was created
 		c.JSON(http.StatusCreated, gin.H{"message": "clinic created successfully", "clinic_id": clinic.ID, "clinic": clinic})
@@ -79,7 +80,7 @@ func (h *ClinicHandler) checkClinicAccess(c *gin.Context, clinicID uuid.UUID) bo
 	return err == nil && hasAccess
 }
 
-// GetClinic retrieves a clinic by ID (requires user to be associated with the clinic)
+// GetClinic retrieves a clinic by ID (clinic membership is enforced by RequireClinicAccess)
 // GET /api/v1/clinic/:id
 // @Summary Retrieve a clinic by ID
 // @Description Retrieve a clinic by ID. User must be associated with the clinic.
@@ -100,10 +101,6 @@ func (h *ClinicHandler) GetClinic(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid clinic ID"})
 		return
 	}
-	if !h.checkClinicAccess(c, idUUID) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied: you do not have access to this clinic"})
-		return
-	}
 	clinic, err := h.clinicService.GetClinicByID(c.Request.Context(), idUUID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -112,7 +109,7 @@ func (h *ClinicHandler) GetClinic(c *gin.Context) {
 	c.JSON(http.StatusOK, clinic)
 }
 
-// UpdateClinic updates a clinic by ID (requires user to be associated with the clinic)
+// UpdateClinic updates a clinic by ID (clinic membership is enforced by RequireClinicAccess)
 // PUT /api/v1/clinic/:id
 // @Summary Update a clinic by ID
 // @Description Update a clinic by ID. User must be associated with the clinic.
@@ -134,16 +131,17 @@ func (h *ClinicHandler) UpdateClinic(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid clinic ID"})
 		return
 	}
-	if !h.checkClinicAccess(c, idUUID) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied: you do not have access to this clinic"})
-		return
-	}
 	var req domain.UpdateClinicRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	clinic, err := h.clinicService.UpdateClinicPartial(c.Request.Context(), idUUID, &req)
+	userID, ok := h.getAuthUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	clinic, err := h.clinicService.UpdateClinicPartial(c.Request.Context(), idUUID, &req, userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -151,7 +149,7 @@ func (h *ClinicHandler) UpdateClinic(c *gin.Context) {
 	c.JSON(http.StatusOK, clinic)
 }
 
-// DeleteClinic deletes a clinic by ID (requires user to be associated with the clinic)
+// DeleteClinic deletes a clinic by ID (clinic membership is enforced by RequireClinicAccess)
 // DELETE /api/v1/clinic/:id
 // @Summary Delete a clinic by ID
 // @Description Delete a clinic by ID. User must be associated with the clinic.
@@ -172,11 +170,12 @@ func (h *ClinicHandler) DeleteClinic(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid clinic ID"})
 		return
 	}
-	if !h.checkClinicAccess(c, idUUID) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied: you do not have access to this clinic"})
+	userID, ok := h.getAuthUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
 		return
 	}
-	err = h.clinicService.DeleteClinic(c.Request.Context(), idUUID)
+	err = h.clinicService.DeleteClinic(c.Request.Context(), idUUID, userID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
@@ -240,3 +239,70 @@ func (h *ClinicHandler) GetClinicByABNNumber(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, clinic)
 }
+
+// GetGSTRates lists a clinic's GST rate history
+// GET /api/v1/clinic/:id/gst-rates
+// @Summary Get a clinic's GST rates
+// @Description Get the GST rates a clinic has set, newest first, and the rate in force today. A clinic without rates charges 10%.
+// @Tags Clinic
+// @Produce json
+// @Param id path string true "Clinic ID"
+// @Success 200 {object} domain.ClinicGSTRates
+// @Failure 400 {object} domain.H
+// @Failure 403 {object} domain.H
+// @Failure 500 {object} domain.H
+// @Router /clinic/{id}/gst-rates [get]
+func (h *ClinicHandler) GetGSTRates(c *gin.Context) {
+	clinicID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid clinic ID"})
+		return
+	}
+	rates, err := h.clinicService.GetGSTRates(c.Request.Context(), clinicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rates)
+}
+
+// SetGSTRate sets the GST rate a clinic charges from a date
+// POST /api/v1/clinic/:id/gst-rates
+// @Summary Set a clinic's GST rate
+// @Description Set the GST rate charged on entries dated from effectiveFrom, replacing any rate set for that date. Entries in closed periods keep their rate, so the date cannot fall in one.
+// @Tags Clinic
+// @Accept json
+// @Produce json
+// @Param id path string true "Clinic ID"
+// @Param body body domain.SetGSTRateRequest true "Rate and effective date"
+// @Success 200 {object} domain.ClinicGSTRate
+// @Failure 400 {object} domain.H
+// @Failure 403 {object} domain.H
+// @Failure 409 {object} domain.H
+// @Router /clinic/{id}/gst-rates [post]
+func (h *ClinicHandler) SetGSTRate(c *gin.Context) {
+	clinicID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid clinic ID"})
+		return
+	}
+	var req domain.SetGSTRateRequest
+	if err := util.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, ok := h.getAuthUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	rate, err := h.clinicService.SetGSTRate(c.Request.Context(), clinicID, &req, userID)
+	if err != nil {
+		if periodClosed(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rate)
+}
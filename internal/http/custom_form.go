@@ -2,11 +2,16 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/money"
 	"github.com/iamarpitzala/aca-reca-backend/internal/service"
 	utils "github.com/iamarpitzala/aca-reca-backend/util"
 )
@@ -33,6 +38,24 @@ func (h *CustomFormHandler) getUserID(c *gin.Context) (uuid.UUID, bool) {
 	return userID, true
 }
 
+// badRequest writes a 400, including per-field errors when err is a FieldValidationError.
+// Changes rejected because their period is closed are reported as a 409 instead.
+func badRequest(c *gin.Context, err error) {
+	if periodClosed(c, err) {
+		return
+	}
+	if errors.Is(err, service.ErrCustomFormModified) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	var verr *domain.FieldValidationError
+	if errors.As(err, &verr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": verr.Error(), "fieldErrors": verr.Errors})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
 func (h *CustomFormHandler) Create(c *gin.Context) {
 	userID, ok := h.getUserID(c)
 	if !ok {
@@ -94,6 +117,10 @@ func (h *CustomFormHandler) GetPublishedByClinicID(c *gin.Context) {
 }
 
 func (h *CustomFormHandler) Update(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form ID"})
@@ -104,35 +131,43 @@ func (h *CustomFormHandler) Update(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	resp, err := h.svc.Update(c.Request.Context(), id, &req)
+	resp, err := h.svc.Update(c.Request.Context(), id, &req, userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(c, err)
 		return
 	}
 	utils.JSONResponse(c, http.StatusOK, "custom form updated", resp, nil)
 }
 
 func (h *CustomFormHandler) Publish(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form ID"})
 		return
 	}
-	resp, err := h.svc.Publish(c.Request.Context(), id)
+	resp, err := h.svc.Publish(c.Request.Context(), id, userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(c, err)
 		return
 	}
 	utils.JSONResponse(c, http.StatusOK, "form published", resp, nil)
 }
 
 func (h *CustomFormHandler) Archive(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form ID"})
 		return
 	}
-	resp, err := h.svc.Archive(c.Request.Context(), id)
+	resp, err := h.svc.Archive(c.Request.Context(), id, userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -141,12 +176,16 @@ func (h *CustomFormHandler) Archive(c *gin.Context) {
 }
 
 func (h *CustomFormHandler) Delete(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form ID"})
 		return
 	}
-	if err := h.svc.Delete(c.Request.Context(), id); err != nil {
+	if err := h.svc.Delete(c.Request.Context(), id, userID); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
@@ -171,6 +210,64 @@ func (h *CustomFormHandler) Duplicate(c *gin.Context) {
 	utils.JSONResponse(c, http.StatusCreated, "form duplicated", resp, nil)
 }
 
+func (h *CustomFormHandler) ListVersions(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form ID"})
+		return
+	}
+	list, err := h.svc.ListVersions(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	utils.JSONResponse(c, http.StatusOK, "form versions retrieved", list, nil)
+}
+
+func (h *CustomFormHandler) GetVersion(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form ID"})
+		return
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version"})
+		return
+	}
+	resp, err := h.svc.GetVersion(c.Request.Context(), id, version)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	utils.JSONResponse(c, http.StatusOK, "form version retrieved", resp, nil)
+}
+
+// DiffVersions compares two versions of a form: GET /custom-form/:id/versions/diff?from=1&to=2
+func (h *CustomFormHandler) DiffVersions(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form ID"})
+		return
+	}
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a version number"})
+		return
+	}
+	to, err := strconv.Atoi(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a version number"})
+		return
+	}
+	diff, err := h.svc.DiffVersions(c.Request.Context(), id, from, to)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	utils.JSONResponse(c, http.StatusOK, "form version diff", diff, nil)
+}
+
 // Entry handlers
 
 func (h *CustomFormHandler) CreateEntry(c *gin.Context) {
@@ -185,7 +282,7 @@ func (h *CustomFormHandler) CreateEntry(c *gin.Context) {
 	}
 	resp, err := h.svc.CreateEntry(c.Request.Context(), &req, userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(c, err)
 		return
 	}
 	utils.JSONResponse(c, http.StatusCreated, "entry created", resp, nil)
@@ -205,35 +302,152 @@ func (h *CustomFormHandler) GetEntryByID(c *gin.Context) {
 	utils.JSONResponse(c, http.StatusOK, "entry retrieved", resp, nil)
 }
 
+// GetEntriesByFormID lists a form's entries a page at a time; see parseEntryFilter for the query parameters.
 func (h *CustomFormHandler) GetEntriesByFormID(c *gin.Context) {
 	formID, err := uuid.Parse(c.Param("formId"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form ID"})
 		return
 	}
-	list, err := h.svc.GetEntriesByFormID(c.Request.Context(), formID)
+	filter, err := parseEntryFilter(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	utils.JSONResponse(c, http.StatusOK, "entries retrieved", list, nil)
+	filter.FormID = &formID
+	h.listEntries(c, filter)
 }
 
+// GetEntriesByClinicID lists a clinic's entries a page at a time; see parseEntryFilter for the query parameters.
 func (h *CustomFormHandler) GetEntriesByClinicID(c *gin.Context) {
-	clinicID, err := uuid.Parse(c.Param("clinicId"))
-	if err != nil {
+	if _, err := uuid.Parse(c.Param("clinicId")); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid clinic ID"})
 		return
 	}
-	list, err := h.svc.GetEntriesByClinicID(c.Request.Context(), clinicID)
+	filter, err := parseEntryFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.FormID, err = optionalUUIDQuery(c, "formId"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.listEntries(c, filter)
+}
+
+func (h *CustomFormHandler) listEntries(c *gin.Context, filter domain.CustomFormEntryFilter) {
+	filter.ClinicID = c.MustGet("clinic_id").(uuid.UUID)
+	page, err := h.svc.ListEntries(c.Request.Context(), filter)
+	if errors.Is(err, service.ErrInvalidEntryCursor) || errors.Is(err, service.ErrEntryQuarterNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	utils.JSONResponse(c, http.StatusOK, "entries retrieved", list, nil)
+	utils.JSONResponse(c, http.StatusOK, "entries retrieved", page, nil)
+}
+
+// parseEntryFilter reads the entry listing query parameters:
+//   - quarterId, from, to (YYYY-MM-DD): entry date range
+//   - formType (income, expense or both), paymentResponsibility (owner or clinic), createdBy (user ID)
+//   - minAmount, maxAmount: bounds on the entry's total amount
+//   - q: full-text search over description and remarks
+//   - sort (entryDate or totalAmount), order (desc or asc)
+//   - limit (default 50, max 500) and cursor (nextCursor of the previous page)
+func parseEntryFilter(c *gin.Context) (domain.CustomFormEntryFilter, error) {
+	filter := domain.CustomFormEntryFilter{
+		FormType:              c.Query("formType"),
+		PaymentResponsibility: c.Query("paymentResponsibility"),
+		Search:                strings.TrimSpace(c.Query("q")),
+		Sort:                  c.Query("sort"),
+		Order:                 strings.ToLower(c.Query("order")),
+		Cursor:                c.Query("cursor"),
+	}
+	switch filter.FormType {
+	case "", string(domain.FormTypeIncome), string(domain.FormTypeExpense), string(domain.FormTypeBoth):
+	default:
+		return filter, errors.New("formType must be income, expense or both")
+	}
+	switch filter.PaymentResponsibility {
+	case "", "owner", "clinic":
+	default:
+		return filter, errors.New("paymentResponsibility must be owner or clinic")
+	}
+	switch filter.Sort {
+	case "", domain.EntrySortEntryDate, domain.EntrySortTotalAmount:
+	default:
+		return filter, errors.New("sort must be entryDate or totalAmount")
+	}
+	switch filter.Order {
+	case "", domain.SortAsc, domain.SortDesc:
+	default:
+		return filter, errors.New("order must be asc or desc")
+	}
+	var err error
+	if filter.QuarterID, err = optionalUUIDQuery(c, "quarterId"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedBy, err = optionalUUIDQuery(c, "createdBy"); err != nil {
+		return filter, err
+	}
+	if filter.From, err = optionalDateQuery(c, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = optionalDateQuery(c, "to"); err != nil {
+		return filter, err
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return filter, errors.New("to must not be before from")
+	}
+	if filter.MinAmount, err = optionalAmountQuery(c, "minAmount"); err != nil {
+		return filter, err
+	}
+	if filter.MaxAmount, err = optionalAmountQuery(c, "maxAmount"); err != nil {
+		return filter, err
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MaxAmount < *filter.MinAmount {
+		return filter, errors.New("maxAmount must not be less than minAmount")
+	}
+	if v := c.Query("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			return filter, errors.New("limit must be a number")
+		}
+	}
+	return filter, nil
+}
+
+func optionalDateQuery(c *gin.Context, name string) (*time.Time, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return nil, errors.New(name + " must be a date (YYYY-MM-DD)")
+	}
+	return &t, nil
+}
+
+func optionalAmountQuery(c *gin.Context, name string) (*money.Amount, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+	a, err := money.Parse(v)
+	if err != nil {
+		return nil, errors.New(name + " must be an amount")
+	}
+	return &a, nil
 }
 
 func (h *CustomFormHandler) UpdateEntry(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
 	id, err := uuid.Parse(c.Param("entryId"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry ID"})
@@ -244,21 +458,28 @@ func (h *CustomFormHandler) UpdateEntry(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	resp, err := h.svc.UpdateEntry(c.Request.Context(), id, &req)
+	resp, err := h.svc.UpdateEntry(c.Request.Context(), id, &req, userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(c, err)
 		return
 	}
 	utils.JSONResponse(c, http.StatusOK, "entry updated", resp, nil)
 }
 
 func (h *CustomFormHandler) DeleteEntry(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
 	id, err := uuid.Parse(c.Param("entryId"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry ID"})
 		return
 	}
-	if err := h.svc.DeleteEntry(c.Request.Context(), id); err != nil {
+	if err := h.svc.DeleteEntry(c.Request.Context(), id, userID); err != nil {
+		if periodClosed(c, err) {
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
@@ -276,13 +497,20 @@ func (h *CustomFormHandler) PreviewCalculations(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form ID"})
 		return
 	}
+	entryDate := time.Now()
+	if req.EntryDate != "" {
+		if entryDate, err = time.Parse("2006-01-02", req.EntryDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "entryDate must be YYYY-MM-DD"})
+			return
+		}
+	}
 	deductions := req.Deductions
 	if len(deductions) == 0 {
 		deductions = nil
 	}
-	calculations, err := h.svc.PreviewCalculations(c.Request.Context(), formID, req.Values, deductions)
+	calculations, err := h.svc.PreviewCalculations(c.Request.Context(), formID, req.FormVersion, entryDate, req.Values, deductions)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(c, err)
 		return
 	}
 	var out map[string]interface{}
@@ -191,6 +191,9 @@ func (h *ExpensesHandler) CreateExpenseEntry(c *gin.Context) {
 
 	err := h.expensesService.CreateExpenseEntry(c.Request.Context(), &expenseEntry)
 	if err != nil {
+		if periodClosed(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -375,7 +378,12 @@ func (h *ExpensesHandler) UpdateExpenseCategory(c *gin.Context) {
 		existing.Description = *req.Description
 	}
 
-	err = h.expensesService.UpdateExpenseCategory(c.Request.Context(), existing)
+	userID, ok := c.MustGet("user_id").(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user ID"})
+		return
+	}
+	err = h.expensesService.UpdateExpenseCategory(c.Request.Context(), existing, userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
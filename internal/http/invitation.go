@@ -0,0 +1,219 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+	utils "github.com/iamarpitzala/aca-reca-backend/util"
+)
+
+type InvitationHandler struct {
+	invitationService *service.InvitationService
+}
+
+func NewInvitationHandler(invitationService *service.InvitationService) *InvitationHandler {
+	return &InvitationHandler{
+		invitationService: invitationService,
+	}
+}
+
+// CreateInvitation invites an email address to join a clinic
+// POST /api/v1/invitation
+// @Summary Invite a user to a clinic
+// @Description Email a signed, expiring invitation that grants the given role when accepted
+// @Tags Invitation
+// @Accept json
+// @Produce json
+// @Param request body domain.CreateInvitationRequest true "Invitation"
+// @Success 201 {object} domain.H
+// @Failure 400 {object} domain.H
+// @Failure 403 {object} domain.H
+// @Failure 502 {object} domain.H "Invitation saved but not emailed; resend it"
+// @Router /invitation [post]
+func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
+	userID, ok := c.MustGet("user_id").(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var req domain.CreateInvitationRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	invitation, err := h.invitationService.Create(c.Request.Context(), &req, userID)
+	if errors.Is(err, service.ErrInvitationNotSent) {
+		invitationNotSent(c, invitation)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "invitation sent successfully", "invitation": invitation})
+}
+
+// GetClinicInvitations lists invitations for a clinic
+// GET /api/v1/invitation/clinic/:clinicId
+// @Summary List clinic invitations
+// @Tags Invitation
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Success 200 {object} domain.H
+// @Router /invitation/clinic/{clinicId} [get]
+func (h *InvitationHandler) GetClinicInvitations(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	invitations, err := h.invitationService.ListByClinicID(c.Request.Context(), clinicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "invitations retrieved successfully", "invitations": invitations})
+}
+
+// ResendInvitation emails a fresh token for a pending invitation
+// POST /api/v1/invitation/:id/resend
+// @Summary Resend an invitation
+// @Tags Invitation
+// @Produce json
+// @Param id path string true "Invitation ID"
+// @Success 200 {object} domain.H
+// @Failure 400 {object} domain.H
+// @Failure 502 {object} domain.H "Invitation renewed but not emailed; resend it"
+// @Router /invitation/{id}/resend [post]
+func (h *InvitationHandler) ResendInvitation(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invitation ID"})
+		return
+	}
+	invitation, err := h.invitationService.Resend(c.Request.Context(), id)
+	if errors.Is(err, service.ErrInvitationNotSent) {
+		invitationNotSent(c, invitation)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "invitation resent successfully", "invitation": invitation})
+}
+
+// RevokeInvitation cancels a pending invitation
+// POST /api/v1/invitation/:id/revoke
+// @Summary Revoke an invitation
+// @Tags Invitation
+// @Produce json
+// @Param id path string true "Invitation ID"
+// @Success 200 {object} domain.H
+// @Failure 400 {object} domain.H
+// @Router /invitation/{id}/revoke [post]
+func (h *InvitationHandler) RevokeInvitation(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invitation ID"})
+		return
+	}
+	invitation, err := h.invitationService.Revoke(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "invitation revoked successfully", "invitation": invitation})
+}
+
+// PreviewInvitation shows the clinic, email and role behind an invitation token
+// POST /api/v1/invitation/preview
+// @Summary Preview an invitation
+// @Tags Invitation
+// @Accept json
+// @Produce json
+// @Param request body domain.InvitationTokenRequest true "Invitation token"
+// @Success 200 {object} domain.InvitationPreview
+// @Failure 400 {object} domain.H
+// @Router /invitation/preview [post]
+func (h *InvitationHandler) PreviewInvitation(c *gin.Context) {
+	var req domain.InvitationTokenRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	preview, err := h.invitationService.Preview(c.Request.Context(), req.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+// AcceptInvitation joins the clinic, registering the invitee first when they have no account
+// POST /api/v1/invitation/accept
+// @Summary Accept an invitation
+// @Tags Invitation
+// @Accept json
+// @Produce json
+// @Param request body domain.AcceptInvitationRequest true "Accept invitation"
+// @Success 200 {object} domain.AcceptInvitationResponse
+// @Failure 400 {object} domain.H
+// @Failure 401 {object} domain.H
+// @Failure 403 {object} domain.H
+// @Router /invitation/accept [post]
+func (h *InvitationHandler) AcceptInvitation(c *gin.Context) {
+	var req domain.AcceptInvitationRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var userID *uuid.UUID
+	if v, exists := c.Get("user_id"); exists {
+		if uid, ok := v.(uuid.UUID); ok {
+			userID = &uid
+		}
+	}
+	resp, err := h.invitationService.Accept(c.Request.Context(), &req, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvitationLoginRequired):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvitationEmailMismatch):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeclineInvitation declines an invitation
+// POST /api/v1/invitation/decline
+// @Summary Decline an invitation
+// @Tags Invitation
+// @Accept json
+// @Produce json
+// @Param request body domain.InvitationTokenRequest true "Invitation token"
+// @Success 200 {object} domain.H
+// @Failure 400 {object} domain.H
+// @Router /invitation/decline [post]
+func (h *InvitationHandler) DeclineInvitation(c *gin.Context) {
+	var req domain.InvitationTokenRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.invitationService.Decline(c.Request.Context(), req.Token); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "invitation declined"})
+}
+
+// invitationNotSent reports an invitation that was saved but not emailed, with the invitation so the
+// caller can resend it.
+func invitationNotSent(c *gin.Context, invitation *domain.ClinicInvitation) {
+	c.JSON(http.StatusBadGateway, gin.H{"error": service.ErrInvitationNotSent.Error(), "invitation": invitation})
+}
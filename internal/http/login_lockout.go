@@ -0,0 +1,64 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+	utils "github.com/iamarpitzala/aca-reca-backend/util"
+)
+
+type LoginLockoutHandler struct {
+	loginLimit *service.LoginLimitService
+}
+
+func NewLoginLockoutHandler(loginLimit *service.LoginLimitService) *LoginLockoutHandler {
+	return &LoginLockoutHandler{loginLimit: loginLimit}
+}
+
+// List returns recent login lockouts of a clinic's members
+// GET /api/v1/login-lockout/clinic/:clinicId
+// @Summary List member login lockouts
+// @Description Accounts locked after repeated failed sign-ins, newest first, including expired and cleared lockouts
+// @Tags Login Lockout
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Success 200 {array} domain.LoginLockout
+// @Failure 500 {object} domain.H
+// @Router /login-lockout/clinic/{clinicId} [get]
+func (h *LoginLockoutHandler) List(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	lockouts, err := h.loginLimit.ListClinicLockouts(c.Request.Context(), clinicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	utils.JSONResponse(c, http.StatusOK, "login lockouts retrieved successfully", lockouts, nil)
+}
+
+// Clear lifts the sign-in delay or lockout on a member's account
+// DELETE /api/v1/login-lockout/clinic/:clinicId/user/:userId
+// @Summary Clear a member's login lockout
+// @Description The clearing is recorded in the clinic's audit log
+// @Tags Login Lockout
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param userId path string true "User ID"
+// @Success 200 {object} domain.H
+// @Failure 400 {object} domain.H
+// @Router /login-lockout/clinic/{clinicId}/user/{userId} [delete]
+func (h *LoginLockoutHandler) Clear(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	actorID := c.MustGet("user_id").(uuid.UUID)
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+	if err := h.loginLimit.ClearUserLockout(c.Request.Context(), clinicID, actorID, userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "login lockout cleared successfully"})
+}
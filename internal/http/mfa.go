@@ -0,0 +1,133 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+	utils "github.com/iamarpitzala/aca-reca-backend/util"
+)
+
+type MFAHandler struct {
+	mfaService *service.MFAService
+}
+
+func NewMFAHandler(mfaService *service.MFAService) *MFAHandler {
+	return &MFAHandler{mfaService: mfaService}
+}
+
+// GetStatus reports whether the signed-in user has two-factor authentication enabled
+// GET /api/v1/users/me/mfa
+// @Summary Get two-factor status
+// @Tags MFA
+// @Produce json
+// @Success 200 {object} domain.MFAStatus
+// @Failure 500 {object} domain.H
+// @Router /users/me/mfa [get]
+func (h *MFAHandler) GetStatus(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	status, err := h.mfaService.Status(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// Enroll starts TOTP enrolment
+// POST /api/v1/users/me/mfa/enroll
+// @Summary Start two-factor enrolment
+// @Description Returns a secret and an otpauth:// URL to show as a QR code. Confirm it with /users/me/mfa/activate
+// @Tags MFA
+// @Produce json
+// @Success 200 {object} domain.MFAEnrollment
+// @Failure 400 {object} domain.H
+// @Router /users/me/mfa/enroll [post]
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	enrollment, err := h.mfaService.Enroll(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// Activate confirms enrolment with a first authenticator code
+// POST /api/v1/users/me/mfa/activate
+// @Summary Enable two-factor authentication
+// @Description Returns single-use recovery codes, shown only once. Other sessions are signed out
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param request body domain.MFACodeRequest true "Authenticator code"
+// @Success 200 {object} domain.MFARecoveryCodes
+// @Failure 400 {object} domain.H
+// @Router /users/me/mfa/activate [post]
+func (h *MFAHandler) Activate(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	sessionID := c.MustGet("session_id").(uuid.UUID)
+	var req domain.MFACodeRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	codes, err := h.mfaService.Activate(c.Request.Context(), userID, sessionID, req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, codes)
+}
+
+// Disable turns two-factor authentication off
+// DELETE /api/v1/users/me/mfa
+// @Summary Disable two-factor authentication
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param request body domain.MFACodeRequest true "Authenticator or recovery code"
+// @Success 200 {object} domain.MessageResponse
+// @Failure 400 {object} domain.H
+// @Router /users/me/mfa [delete]
+func (h *MFAHandler) Disable(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	var req domain.MFACodeRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.mfaService.Disable(c.Request.Context(), userID, req.Code); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes
+// POST /api/v1/users/me/mfa/recovery-codes
+// @Summary Regenerate recovery codes
+// @Description Every previous recovery code stops working
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param request body domain.MFACodeRequest true "Authenticator or recovery code"
+// @Success 200 {object} domain.MFARecoveryCodes
+// @Failure 400 {object} domain.H
+// @Router /users/me/mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	var req domain.MFACodeRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	codes, err := h.mfaService.RegenerateRecoveryCodes(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, codes)
+}
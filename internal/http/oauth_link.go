@@ -0,0 +1,80 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+)
+
+type OAuthLinkHandler struct {
+	oauthService *service.OAuthService
+}
+
+func NewOAuthLinkHandler(oauthService *service.OAuthService) *OAuthLinkHandler {
+	return &OAuthLinkHandler{oauthService: oauthService}
+}
+
+// List returns the providers linked to the signed-in user
+// GET /api/v1/users/me/oauth
+// @Summary List linked sign-in providers
+// @Tags OAuth
+// @Produce json
+// @Success 200 {array} domain.OAuthIdentity
+// @Failure 500 {object} domain.H
+// @Router /users/me/oauth [get]
+func (h *OAuthLinkHandler) List(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	identities, err := h.oauthService.Identities(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, identities)
+}
+
+// Link starts linking a provider to the signed-in user
+// POST /api/v1/users/me/oauth/:provider/link
+// @Summary Link a sign-in provider
+// @Description Returns a URL for the browser to open; the provider redirects back to the OAuth callback, which links the identity
+// @Tags OAuth
+// @Produce json
+// @Param provider path string true "Provider"
+// @Success 200 {object} domain.OAuthLinkResponse
+// @Failure 400 {object} domain.H
+// @Router /users/me/oauth/{provider}/link [post]
+func (h *OAuthLinkHandler) Link(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	link, err := h.oauthService.LinkURL(userID, c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, link)
+}
+
+// Unlink removes a provider from the signed-in user
+// DELETE /api/v1/users/me/oauth/:provider
+// @Summary Unlink a sign-in provider
+// @Description Refused when it is the only way left to sign in
+// @Tags OAuth
+// @Produce json
+// @Param provider path string true "Provider"
+// @Success 200 {object} domain.H
+// @Failure 400 {object} domain.H
+// @Failure 404 {object} domain.H
+// @Router /users/me/oauth/{provider} [delete]
+func (h *OAuthLinkHandler) Unlink(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	if err := h.oauthService.Unlink(c.Request.Context(), userID, c.Param("provider")); err != nil {
+		if errors.Is(err, service.ErrOAuthNotLinked) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "provider unlinked successfully"})
+}
@@ -1,20 +1,30 @@
 package http
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
 	utils "github.com/iamarpitzala/aca-reca-backend/util"
 	"github.com/xuri/excelize/v2"
 )
 
+// xlsxContentType is the MIME type of an Excel workbook.
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
 type PayslipHandler struct {
+	exportService    *service.ExportService
+	statementService *service.StatementService
 }
 
-func NewPayslipHandler() *PayslipHandler {
-	return &PayslipHandler{}
+func NewPayslipHandler(exportService *service.ExportService, statementService *service.StatementService) *PayslipHandler {
+	return &PayslipHandler{exportService: exportService, statementService: statementService}
 }
 
 // Export Excel Income
@@ -24,8 +34,9 @@ func NewPayslipHandler() *PayslipHandler {
 // @Tags Payslip
 // @Accept json
 // @Produce json
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 // @Param data body []domain.ExportIncome true "Data"
-// @Success 200 {object} domain.H
+// @Success 200 {file} file
 // @Failure 400 {object} domain.H
 // @Failure 500 {object} domain.H
 // @Router /payslip/export/income [post]
@@ -37,18 +48,14 @@ func (h *PayslipHandler) ExportExcelIncome(c *gin.Context) {
 		})
 		return
 	}
-	fileName, err := IncomeFormate(data)
+	f, err := IncomeFormate(data)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Income Excel created successfully",
-		"file":    fileName,
-	})
+	streamWorkbook(c, f, "Income.xlsx")
 }
 
 // Export Excel Expenses
@@ -58,8 +65,9 @@ func (h *PayslipHandler) ExportExcelIncome(c *gin.Context) {
 // @Tags Payslip
 // @Accept json
 // @Produce json
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 // @Param data body []domain.ExportExpenses true "Data"
-// @Success 200 {object} domain.H
+// @Success 200 {file} file
 // @Failure 400 {object} domain.H
 // @Failure 500 {object} domain.H
 // @Router /payslip/export/expenses [post]
@@ -72,48 +80,200 @@ func (h *PayslipHandler) ExportExcelExpanses(c *gin.Context) {
 		return
 	}
 
-	fileName, err := ExpensesFormate(data)
+	f, err := ExpensesFormate(data)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
+	streamWorkbook(c, f, "Expenses.xlsx")
+}
+
+// Export a clinic's income from stored entries
+// GET /api/v1/payslip/export/clinic/:clinicId/income
+// @Summary Export clinic income to Excel
+// @Description Build the income workbook from the clinic's custom form entries in a quarter or date range
+// @Tags Payslip
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param clinicId path string true "Clinic ID"
+// @Param quarterId query string false "Quarter ID (alternative to from/to)"
+// @Param from query string false "Start date (YYYY-MM-DD)"
+// @Param to query string false "End date (YYYY-MM-DD)"
+// @Success 200 {file} file
+// @Failure 400 {object} domain.H
+// @Failure 500 {object} domain.H
+// @Router /payslip/export/clinic/{clinicId}/income [get]
+func (h *PayslipHandler) ExportClinicIncome(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	rng, ok := h.exportRange(c, clinicID)
+	if !ok {
+		return
+	}
+	rows, err := h.exportService.IncomeRows(c.Request.Context(), clinicID, rng)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	f, err := IncomeFormate(rows)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	streamWorkbook(c, f, exportFileName("Income", rng, ".xlsx"))
+}
+
+// Export a clinic's expenses from stored entries
+// GET /api/v1/payslip/export/clinic/:clinicId/expenses
+// @Summary Export clinic expenses to Excel
+// @Description Build the expenses workbook from the clinic's expense entries in a quarter or date range
+// @Tags Payslip
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param clinicId path string true "Clinic ID"
+// @Param quarterId query string false "Quarter ID (alternative to from/to)"
+// @Param from query string false "Start date (YYYY-MM-DD)"
+// @Param to query string false "End date (YYYY-MM-DD)"
+// @Success 200 {file} file
+// @Failure 400 {object} domain.H
+// @Failure 500 {object} domain.H
+// @Router /payslip/export/clinic/{clinicId}/expenses [get]
+func (h *PayslipHandler) ExportClinicExpenses(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	rng, ok := h.exportRange(c, clinicID)
+	if !ok {
+		return
+	}
+	rows, err := h.exportService.ExpenseRows(c.Request.Context(), clinicID, rng)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	f, err := ExpensesFormate(rows)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	streamWorkbook(c, f, exportFileName("Expenses", rng, ".xlsx"))
+}
+
+// exportRange reads quarterId, or from and to, from the query string. It writes a 400 and returns
+// false when they are missing or invalid.
+func (h *PayslipHandler) exportRange(c *gin.Context, clinicID uuid.UUID) (*service.ExportRange, bool) {
+	quarterID, err := optionalUUIDQuery(c, "quarterId")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	from, err := optionalTimeQuery(c, "from", false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	to, err := optionalTimeQuery(c, "to", false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	rng, err := h.exportService.ResolveRange(c.Request.Context(), clinicID, quarterID, from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return rng, true
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Expenses Excel created successfully",
-		"file":    fileName,
-	})
+func exportFileName(kind string, rng *service.ExportRange, ext string) string {
+	label := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '/' || r == '\\' || r == '"' {
+			return '_'
+		}
+		return r
+	}, rng.Label)
+	return kind + "_" + label + ext
+}
 
+// streamWorkbook writes the workbook to the response as a download; nothing is kept on disk.
+func streamWorkbook(c *gin.Context, f *excelize.File, fileName string) {
+	defer f.Close()
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	c.Data(http.StatusOK, xlsxContentType, buf.Bytes())
 }
 
-// Generate PDF
-// POST /api/v1/payslip/generate/pdf
-// @Summary Generate PDF
-// @Description Generate PDF
+// Generate a practitioner's service-fee statement
+// POST /api/v1/payslip/statement/clinic/:clinicId
+// @Summary Generate a service-fee statement PDF
+// @Description Build a practitioner's statement (collections, lab costs, service fee, GST and remitted amount) from the clinic's entries for a quarter or date range
 // @Tags Payslip
 // @Accept json
-// @Produce json
-// @Success 200 {object} domain.H
+// @Produce application/pdf
+// @Param clinicId path string true "Clinic ID"
+// @Param request body domain.GenerateStatementRequest true "Practitioner, period and PDF options"
+// @Success 200 {file} file
 // @Failure 400 {object} domain.H
 // @Failure 500 {object} domain.H
-// @Router /payslip/generate/pdf [post]
-func (h *PayslipHandler) GeneratePdf(c *gin.Context) {
-	fileName, err := GenerateInvoiceOne()
+// @Router /payslip/statement/clinic/{clinicId} [post]
+func (h *PayslipHandler) GenerateStatement(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	var req domain.GenerateStatementRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	practitionerID, _ := uuid.Parse(req.PractitionerID)
+
+	var quarterID *uuid.UUID
+	if req.QuarterID != nil {
+		id, _ := uuid.Parse(*req.QuarterID)
+		quarterID = &id
+	}
+	var templateID *uuid.UUID
+	if req.TemplateID != nil {
+		id, _ := uuid.Parse(*req.TemplateID)
+		templateID = &id
+	}
+	from, err := optionalDate(req.From, "from")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	to, err := optionalDate(req.To, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rng, err := h.exportService.ResolveRange(c.Request.Context(), clinicID, quarterID, from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pdf, err := h.statementService.GenerateStatement(c.Request.Context(), clinicID, practitionerID, rng, req.Notes, templateID, req.Config)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"message": "PDF created successfully",
-		"file":    fileName,
-	})
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, exportFileName("Statement", rng, ".pdf")))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+func optionalDate(raw *string, name string) (*time.Time, error) {
+	if raw == nil || *raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", *raw)
+	if err != nil {
+		return nil, errors.New(name + " must be a date (YYYY-MM-DD)")
+	}
+	return &t, nil
 }
 
 // income formate
-func IncomeFormate(data []domain.ExportIncome) (string, error) {
+func IncomeFormate(data []domain.ExportIncome) (*excelize.File, error) {
 	f := excelize.NewFile()
 	sheet := "Users_Income"
 
@@ -121,7 +281,8 @@ func IncomeFormate(data []domain.ExportIncome) (string, error) {
 
 	styles, err := utils.CreateStyles(f, "$#,##0.00")
 	if err != nil {
-		return "", err
+		f.Close()
+		return nil, err
 	}
 
 	utils.WriteHeaders(f, sheet, domain.HeadersIncome, styles.Header)
@@ -146,11 +307,11 @@ func IncomeFormate(data []domain.ExportIncome) (string, error) {
 			col string
 			val float64
 		}{
-			{"F", d.Adjustments}, {"G", d.GrossIncomeG1},
-			{"H", d.LabFees}, {"I", d.GrossNetLabFees},
-			{"J", d.GSTPayable1A}, {"K", d.GSTFree},
-			{"L", d.ManagementFeesG11}, {"M", d.Percentage},
-			{"N", d.GSTRefundable1B}, {"O", d.NetPayment},
+			{"F", d.Adjustments.Float64()}, {"G", d.GrossIncomeG1.Float64()},
+			{"H", d.LabFees.Float64()}, {"I", d.GrossNetLabFees.Float64()},
+			{"J", d.GSTPayable1A.Float64()}, {"K", d.GSTFree.Float64()},
+			{"L", d.ManagementFeesG11.Float64()}, {"M", d.Percentage},
+			{"N", d.GSTRefundable1B.Float64()}, {"O", d.NetPayment.Float64()},
 		}
 
 		for _, v := range values {
@@ -168,12 +329,11 @@ func IncomeFormate(data []domain.ExportIncome) (string, error) {
 
 	utils.SetColWidths(f, sheet, incomeColWidths)
 	f.AutoFilter(sheet, "A1:O1", nil)
-	file := "Income.xlsx"
-	return file, f.SaveAs(file)
+	return f, nil
 }
 
 // expenses formate
-func ExpensesFormate(data []domain.ExportExpenses) (string, error) {
+func ExpensesFormate(data []domain.ExportExpenses) (*excelize.File, error) {
 	f := excelize.NewFile()
 	sheet := "Users_Expenses"
 
@@ -181,7 +341,8 @@ func ExpensesFormate(data []domain.ExportExpenses) (string, error) {
 
 	styles, err := utils.CreateStyles(f, "$#,##0.00")
 	if err != nil {
-		return "", err
+		f.Close()
+		return nil, err
 	}
 
 	utils.WriteHeaders(f, sheet, domain.HeadersExpenses, styles.Header)
@@ -205,10 +366,10 @@ func ExpensesFormate(data []domain.ExportExpenses) (string, error) {
 
 		// Numbers
 		nums := map[string]float64{
-			"E": d.Amount,
+			"E": d.Amount.Float64(),
 			"F": d.Bas,
-			"G": d.Net,
-			"H": d.GST,
+			"G": d.Net.Float64(),
+			"H": d.GST.Float64(),
 		}
 
 		for col, val := range nums {
@@ -224,15 +385,5 @@ func ExpensesFormate(data []domain.ExportExpenses) (string, error) {
 	}
 	utils.SetColWidths(f, sheet, expensesColWidths)
 	f.AutoFilter(sheet, "A1:I1", nil)
-
-	file := "Expenses.xlsx"
-	return file, f.SaveAs(file)
-}
-
-// pdf formatting
-func GenerateInvoiceOne() (string, error) {
-	// pdf := utils.NewPDF()
-	// config := utils.GetDefaultPDFConfig()
-
-	return "", nil
+	return f, nil
 }
@@ -0,0 +1,285 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+	"github.com/iamarpitzala/aca-reca-backend/internal/storage"
+	utils "github.com/iamarpitzala/aca-reca-backend/util"
+)
+
+type PDFTemplateHandler struct {
+	templateService *service.PDFTemplateService
+}
+
+func NewPDFTemplateHandler(templateService *service.PDFTemplateService) *PDFTemplateHandler {
+	return &PDFTemplateHandler{templateService: templateService}
+}
+
+// List returns a clinic's PDF templates, default first
+// GET /api/v1/pdf-template/clinic/:clinicId
+// @Summary List PDF templates
+// @Tags PDF Template
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Success 200 {array} domain.PDFTemplate
+// @Failure 500 {object} domain.H
+// @Router /pdf-template/clinic/{clinicId} [get]
+func (h *PDFTemplateHandler) List(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	templates, err := h.templateService.List(c.Request.Context(), clinicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	utils.JSONResponse(c, http.StatusOK, "pdf templates retrieved successfully", templates, nil)
+}
+
+// Create saves a new PDF template for a clinic
+// POST /api/v1/pdf-template/clinic/:clinicId
+// @Summary Create a PDF template
+// @Description The clinic's first template becomes its default
+// @Tags PDF Template
+// @Accept json
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param request body domain.CreatePDFTemplateRequest true "Template name and layout"
+// @Success 201 {object} domain.PDFTemplate
+// @Failure 400 {object} domain.H
+// @Router /pdf-template/clinic/{clinicId} [post]
+func (h *PDFTemplateHandler) Create(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	userID, ok := c.MustGet("user_id").(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	var req domain.CreatePDFTemplateRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t, err := h.templateService.Create(c.Request.Context(), clinicID, userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	utils.JSONResponse(c, http.StatusCreated, "pdf template created successfully", t, nil)
+}
+
+// Get returns one PDF template
+// GET /api/v1/pdf-template/clinic/:clinicId/:id
+// @Summary Get a PDF template
+// @Tags PDF Template
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param id path string true "Template ID"
+// @Success 200 {object} domain.PDFTemplate
+// @Failure 404 {object} domain.H
+// @Router /pdf-template/clinic/{clinicId}/{id} [get]
+func (h *PDFTemplateHandler) Get(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+	t, err := h.templateService.Get(c.Request.Context(), clinicID, id)
+	if err != nil {
+		templateError(c, err)
+		return
+	}
+	utils.JSONResponse(c, http.StatusOK, "pdf template retrieved successfully", t, nil)
+}
+
+// Update renames a PDF template or replaces its layout
+// PUT /api/v1/pdf-template/clinic/:clinicId/:id
+// @Summary Update a PDF template
+// @Tags PDF Template
+// @Accept json
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param id path string true "Template ID"
+// @Param request body domain.UpdatePDFTemplateRequest true "Fields to change"
+// @Success 200 {object} domain.PDFTemplate
+// @Failure 400 {object} domain.H
+// @Failure 404 {object} domain.H
+// @Router /pdf-template/clinic/{clinicId}/{id} [put]
+func (h *PDFTemplateHandler) Update(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+	var req domain.UpdatePDFTemplateRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t, err := h.templateService.Update(c.Request.Context(), clinicID, id, req)
+	if err != nil {
+		templateError(c, err)
+		return
+	}
+	utils.JSONResponse(c, http.StatusOK, "pdf template updated successfully", t, nil)
+}
+
+// Delete removes a PDF template and its logo
+// DELETE /api/v1/pdf-template/clinic/:clinicId/:id
+// @Summary Delete a PDF template
+// @Tags PDF Template
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param id path string true "Template ID"
+// @Success 200 {object} domain.H
+// @Failure 404 {object} domain.H
+// @Router /pdf-template/clinic/{clinicId}/{id} [delete]
+func (h *PDFTemplateHandler) Delete(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+	if err := h.templateService.Delete(c.Request.Context(), clinicID, id); err != nil {
+		templateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "pdf template deleted successfully"})
+}
+
+// SetDefault marks a template as the clinic's default
+// POST /api/v1/pdf-template/clinic/:clinicId/:id/default
+// @Summary Set the default PDF template
+// @Tags PDF Template
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param id path string true "Template ID"
+// @Success 200 {object} domain.PDFTemplate
+// @Failure 404 {object} domain.H
+// @Router /pdf-template/clinic/{clinicId}/{id}/default [post]
+func (h *PDFTemplateHandler) SetDefault(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+	t, err := h.templateService.SetDefault(c.Request.Context(), clinicID, id)
+	if err != nil {
+		templateError(c, err)
+		return
+	}
+	utils.JSONResponse(c, http.StatusOK, "default pdf template updated", t, nil)
+}
+
+// UploadLogo stores the logo drawn on documents using the template
+// PUT /api/v1/pdf-template/clinic/:clinicId/:id/logo
+// @Summary Upload a template logo
+// @Description Multipart upload in the "logo" field; PNG or JPEG, at most 2 MB
+// @Tags PDF Template
+// @Accept multipart/form-data
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param id path string true "Template ID"
+// @Param logo formData file true "Logo image"
+// @Success 200 {object} domain.PDFTemplate
+// @Failure 400 {object} domain.H
+// @Router /pdf-template/clinic/{clinicId}/{id}/logo [put]
+func (h *PDFTemplateHandler) UploadLogo(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+	header, err := c.FormFile("logo")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "logo file is required"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+	// Read one byte past the limit so the service can reject oversized files
+	data, err := io.ReadAll(io.LimitReader(file, service.MaxLogoBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t, err := h.templateService.UploadLogo(c.Request.Context(), clinicID, id, data)
+	if err != nil {
+		templateError(c, err)
+		return
+	}
+	utils.JSONResponse(c, http.StatusOK, "logo uploaded successfully", t, nil)
+}
+
+// GetLogo serves the template's logo image
+// GET /api/v1/pdf-template/clinic/:clinicId/:id/logo
+// @Summary Get a template logo
+// @Tags PDF Template
+// @Produce image/png
+// @Produce image/jpeg
+// @Param clinicId path string true "Clinic ID"
+// @Param id path string true "Template ID"
+// @Success 200 {file} file
+// @Failure 404 {object} domain.H
+// @Router /pdf-template/clinic/{clinicId}/{id}/logo [get]
+func (h *PDFTemplateHandler) GetLogo(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+	data, contentType, err := h.templateService.GetLogo(c.Request.Context(), clinicID, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// DeleteLogo removes the template's logo
+// DELETE /api/v1/pdf-template/clinic/:clinicId/:id/logo
+// @Summary Delete a template logo
+// @Tags PDF Template
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param id path string true "Template ID"
+// @Success 200 {object} domain.H
+// @Failure 404 {object} domain.H
+// @Router /pdf-template/clinic/{clinicId}/{id}/logo [delete]
+func (h *PDFTemplateHandler) DeleteLogo(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+	if err := h.templateService.DeleteLogo(c.Request.Context(), clinicID, id); err != nil {
+		templateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "logo deleted successfully"})
+}
+
+func templateID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template ID"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func templateError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrPDFTemplateNotFound) || errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
@@ -1,12 +1,14 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
 	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+	utils "github.com/iamarpitzala/aca-reca-backend/util"
 )
 
 type QuarterHandler struct {
@@ -156,3 +158,176 @@ func (h *QuarterHandler) List(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"data": list})
 }
+
+// ListClinicQuarters lists quarters with their close status for a clinic
+// GET /api/v1/quarter/clinic/:clinicId
+// @Summary List quarters with close status
+// @Tags Quarter
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Success 200 {object} []domain.ClinicQuarterStatus
+// @Router /quarter/clinic/{clinicId} [get]
+func (h *QuarterHandler) ListClinicQuarters(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	list, err := h.QuarterService.ListClinicQuarters(c.Request.Context(), clinicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// GetCloseHistory lists when a clinic's quarters were closed and reopened
+// GET /api/v1/quarter/clinic/:clinicId/history
+// @Summary Quarter close history
+// @Tags Quarter
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param quarterId query string false "Only this quarter"
+// @Success 200 {object} []domain.QuarterClose
+// @Failure 400 {object} map[string]string
+// @Router /quarter/clinic/{clinicId}/history [get]
+func (h *QuarterHandler) GetCloseHistory(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	var quarterID *uuid.UUID
+	if v := c.Query("quarterId"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid financial quarter ID"})
+			return
+		}
+		quarterID = &id
+	}
+	history, err := h.QuarterService.GetCloseHistory(c.Request.Context(), clinicID, quarterID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": history})
+}
+
+// Close closes a quarter for a clinic once its BAS is lodged
+// POST /api/v1/quarter/clinic/:clinicId/:id/close
+// @Summary Close a quarter
+// @Description Freeze entries dated inside the quarter for the clinic
+// @Tags Quarter
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param id path string true "Quarter ID"
+// @Success 200 {object} domain.QuarterClose
+// @Failure 400 {object} map[string]string
+// @Router /quarter/clinic/{clinicId}/{id}/close [post]
+func (h *QuarterHandler) Close(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	userID := c.MustGet("user_id").(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid financial quarter ID"})
+		return
+	}
+	qc, err := h.QuarterService.CloseQuarter(c.Request.Context(), clinicID, id, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "financial quarter closed successfully", "close": qc})
+}
+
+// Reopen reopens a closed quarter for a clinic (owners only)
+// POST /api/v1/quarter/clinic/:clinicId/:id/reopen
+// @Summary Reopen a quarter
+// @Tags Quarter
+// @Accept json
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param id path string true "Quarter ID"
+// @Param request body domain.ReopenQuarterRequest true "Reason for reopening"
+// @Success 200 {object} domain.QuarterClose
+// @Failure 400 {object} map[string]string
+// @Router /quarter/clinic/{clinicId}/{id}/reopen [post]
+func (h *QuarterHandler) Reopen(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	userID := c.MustGet("user_id").(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid financial quarter ID"})
+		return
+	}
+	var req domain.ReopenQuarterRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	qc, err := h.QuarterService.ReopenQuarter(c.Request.Context(), clinicID, id, userID, req.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "financial quarter reopened successfully", "close": qc})
+}
+
+// GenerateFinancialYear creates a clinic's BAS periods for a financial year
+// POST /api/v1/quarter/clinic/:clinicId/generate
+// @Summary Generate financial year periods
+// @Description Create the ATO quarters (Jul–Sep, Oct–Dec, Jan–Mar, Apr–Jun), or the twelve months for a monthly BAS cycle, of a financial year
+// @Tags Quarter
+// @Accept json
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param request body domain.GenerateFinancialYearRequest true "Financial year (the year it ends, e.g. 2025 for FY2024-25)"
+// @Success 201 {object} []domain.Quarter
+// @Failure 400 {object} map[string]string
+// @Router /quarter/clinic/{clinicId}/generate [post]
+func (h *QuarterHandler) GenerateFinancialYear(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	var req domain.GenerateFinancialYearRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	periods, err := h.QuarterService.GenerateFinancialYear(c.Request.Context(), clinicID, req.FinancialYear)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "financial year periods generated successfully", "data": periods})
+}
+
+// DeleteClinicPeriod deletes one of a clinic's own periods
+// DELETE /api/v1/quarter/clinic/:clinicId/:id
+// @Summary Delete a clinic period
+// @Description Only the first or last period can be deleted so the remaining periods stay contiguous
+// @Tags Quarter
+// @Produce json
+// @Param clinicId path string true "Clinic ID"
+// @Param id path string true "Quarter ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /quarter/clinic/{clinicId}/{id} [delete]
+func (h *QuarterHandler) DeleteClinicPeriod(c *gin.Context) {
+	clinicID := c.MustGet("clinic_id").(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid financial quarter ID"})
+		return
+	}
+	if err := h.QuarterService.DeleteClinicPeriod(c.Request.Context(), clinicID, id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "financial quarter deleted successfully"})
+}
+
+// periodClosed writes a 409 with a machine-readable code when err rejects a change to a closed period.
+func periodClosed(c *gin.Context, err error) bool {
+	var pcErr *service.PeriodClosedError
+	if !errors.As(err, &pcErr) {
+		return false
+	}
+	body := gin.H{"error": pcErr.Error(), "code": service.PeriodClosedErrorCode, "closedQuarterId": pcErr.Quarter.ID}
+	if pcErr.NextOpen != nil {
+		body["nextOpenQuarterId"] = pcErr.NextOpen.ID
+	}
+	c.JSON(http.StatusConflict, body)
+	return true
+}
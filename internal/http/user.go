@@ -52,6 +52,43 @@ func (h *UserHandler) GetMe(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
+// ChangePassword changes the signed-in user's password
+// PUT /api/v1/users/me/password
+// @Summary Change password
+// @Description Change the password after confirming the current one. Every other session is signed out
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param request body domain.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} domain.MessageResponse
+// @Failure 400 {object} domain.H
+// @Failure 401 {object} domain.H
+// @Router /users/me/password [put]
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	userID, ok := c.MustGet("user_id").(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user context"})
+		return
+	}
+	sessionID, ok := c.MustGet("session_id").(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid session context"})
+		return
+	}
+	var req domain.ChangePasswordRequest
+	if err := utils.BindAndValidate(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ChangePassword(c.Request.Context(), userID, sessionID, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password changed successfully"})
+}
+
 // GetCurrentUser returns the current authenticated user
 // GET /api/v1/users/:user_id
 // @Summary Get current user
@@ -128,13 +165,12 @@ func (h *UserHandler) UpdateCurrentUser(c *gin.Context) {
 // @Param userId path string true "User ID"
 // @Success 200 {array} domain.Session
 // @Failure 400 {object} domain.H
+// @Failure 403 {object} domain.H
 // @Failure 500 {object} domain.H
 // @Router /users/{userId}/sessions [get]
 func (h *UserHandler) GetActiveSessions(c *gin.Context) {
-	userID := c.Param("userId")
-	userUUID, err := uuid.Parse(userID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+	userUUID, ok := h.ownUserParam(c)
+	if !ok {
 		return
 	}
 
@@ -144,6 +180,12 @@ func (h *UserHandler) GetActiveSessions(c *gin.Context) {
 		return
 	}
 
+	if sessionID, ok := c.Get("session_id"); ok {
+		for i := range sessions {
+			sessions[i].Current = sessions[i].ID == sessionID
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "sessions retrieved successfully", "sessions": sessions})
 }
 
@@ -161,10 +203,8 @@ func (h *UserHandler) GetActiveSessions(c *gin.Context) {
 // @Failure 500 {object} domain.H
 // @Router /users/{userId}/sessions/{sessionId} [delete]
 func (h *UserHandler) RevokeSession(c *gin.Context) {
-	userID := c.Param("userId")
-	userUUID, err := uuid.Parse(userID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+	userUUID, ok := h.ownUserParam(c)
+	if !ok {
 		return
 	}
 
@@ -202,3 +242,48 @@ func (h *UserHandler) RevokeSession(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "session revoked successfully", "sessionId": sessionID})
 }
+
+// RevokeOtherSessions signs the current user out of every device except this one
+// DELETE /api/v1/users/me/sessions
+// @Summary Sign out all other devices
+// @Description Revoke every session of the current user except the one making the request
+// @Tags User
+// @Produce json
+// @Success 200 {object} domain.H
+// @Failure 401 {object} domain.H
+// @Failure 500 {object} domain.H
+// @Router /users/me/sessions [delete]
+func (h *UserHandler) RevokeOtherSessions(c *gin.Context) {
+	userID, ok := c.MustGet("user_id").(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user context"})
+		return
+	}
+	sessionID, ok := c.MustGet("session_id").(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid session context"})
+		return
+	}
+
+	if err := h.authService.RevokeOtherSessions(c.Request.Context(), userID, sessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "signed out of all other devices"})
+}
+
+// ownUserParam parses the :userId path parameter and checks that it is the authenticated user, since
+// sessions expose the addresses and browsers a user signs in from. It writes the error response itself.
+func (h *UserHandler) ownUserParam(c *gin.Context) (uuid.UUID, bool) {
+	userUUID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return uuid.Nil, false
+	}
+	if current, ok := c.Get("user_id"); !ok || current != userUUID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "cannot access another user's sessions"})
+		return uuid.Nil, false
+	}
+	return userUUID, true
+}
@@ -2,13 +2,19 @@ package middleware
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
 	"github.com/iamarpitzala/aca-reca-backend/internal/service"
 )
 
-func AuthMiddleware(tokenService *service.TokenService) gin.HandlerFunc {
+// AuthMiddleware accepts a bearer access token whose session is still live, so logging out or
+// revoking a session cuts off its access tokens before they expire. It also accepts an API key in
+// place of the token on the routes and within the scopes the key allows; such requests have no
+// "session_id" but carry the key as "api_key".
+func AuthMiddleware(tokenService *service.TokenService, authService *service.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -23,6 +29,12 @@ func AuthMiddleware(tokenService *service.TokenService) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		if strings.HasPrefix(tokenString, domain.APIKeyPrefix) {
+			if authenticateAPIKey(c, authService, tokenString) {
+				c.Next()
+			}
+			return
+		}
 
 		claims, err := tokenService.ValidateToken(tokenString)
 		if err != nil {
@@ -30,6 +42,11 @@ func AuthMiddleware(tokenService *service.TokenService) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		if err := authService.ValidateSession(c.Request.Context(), claims.SessionID); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "session has expired or been revoked"})
+			c.Abort()
+			return
+		}
 
 		// Set user context
 		c.Set("user_id", claims.UserID)
@@ -40,7 +57,7 @@ func AuthMiddleware(tokenService *service.TokenService) gin.HandlerFunc {
 	}
 }
 
-func OptionalAuthMiddleware(tokenService *service.TokenService) gin.HandlerFunc {
+func OptionalAuthMiddleware(tokenService *service.TokenService, authService *service.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -55,7 +72,7 @@ func OptionalAuthMiddleware(tokenService *service.TokenService) gin.HandlerFunc
 		}
 
 		claims, err := tokenService.ValidateToken(tokenString)
-		if err != nil {
+		if err != nil || authService.ValidateSession(c.Request.Context(), claims.SessionID) != nil {
 			c.Next()
 			return
 		}
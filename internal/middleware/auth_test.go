@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestAuthMiddlewareTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	tokens := service.NewTokenService(config.JWTConfig{SecretKey: "test-secret", AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour, Issuer: "test"})
+	auth := service.NewAuthService(sqlx.NewDb(db, "postgres"), tokens, nil, nil, config.AccountConfig{})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/me", AuthMiddleware(tokens, auth), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	userID, sessionID := uuid.New(), uuid.New()
+	pair, err := tokens.GenerateTokenPair(userID, "dentist@example.com", sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectSession := func(rotatedAt *time.Time) {
+		now := time.Now()
+		mock.ExpectQuery(regexp.QuoteMeta("FROM tbl_session WHERE id = $1")).WithArgs(sessionID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "family_id", "refresh_token_hash", "user_agent", "ip_address",
+				"expires_at", "rotated_at", "last_seen_at", "created_at", "updated_at", "deleted_at"}).
+				AddRow(sessionID, userID, uuid.New(), "hash", "", "", now.Add(time.Hour), rotatedAt, now, now, now, nil))
+	}
+	get := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	expectSession(nil)
+	if code := get(pair.AccessToken); code != http.StatusOK {
+		t.Errorf("access token: status %d, want 200", code)
+	}
+
+	// Refresh tokens are rejected before the session is looked up
+	if code := get(pair.RefreshToken); code != http.StatusUnauthorized {
+		t.Errorf("refresh token: status %d, want 401", code)
+	}
+
+	recently := time.Now().Add(-10 * time.Second)
+	expectSession(&recently)
+	if code := get(pair.AccessToken); code != http.StatusOK {
+		t.Errorf("access token of a session rotated within the access token TTL: status %d, want 200", code)
+	}
+
+	longAgo := time.Now().Add(-2 * time.Minute)
+	expectSession(&longAgo)
+	if code := get(pair.AccessToken); code != http.StatusUnauthorized {
+		t.Errorf("access token of a session rotated before the access token TTL: status %d, want 401", code)
+	}
+
+	if _, err := tokens.ValidateRefreshToken(pair.AccessToken); err == nil {
+		t.Error("access token was accepted as a refresh token")
+	}
+	if claims, err := tokens.ValidateRefreshToken(pair.RefreshToken); err != nil || claims.SessionID != sessionID {
+		t.Errorf("refresh token: %v, %v", claims, err)
+	}
+}
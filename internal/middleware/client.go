@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+)
+
+// maxUserAgentLength caps what a client can make us store with each session.
+const maxUserAgentLength = 512
+
+// ClientInfoMiddleware records the caller's user agent and address on the request context, where
+// the auth service picks them up for the sessions it starts.
+func ClientInfoMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userAgent := c.GetHeader("User-Agent")
+		if len(userAgent) > maxUserAgentLength {
+			userAgent = userAgent[:maxUserAgentLength]
+		}
+		// Postgres rejects invalid UTF-8, which a cut or a hostile header can leave behind
+		userAgent = strings.ToValidUTF8(userAgent, "")
+		ctx := service.WithClientInfo(c.Request.Context(), service.ClientInfo{
+			UserAgent: userAgent,
+			IPAddress: c.ClientIP(),
+		})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+)
+
+// ClinicResolver returns the clinic that owns the resource addressed by a request.
+type ClinicResolver func(c *gin.Context) (uuid.UUID, error)
+
+// ClinicLookup resolves a clinic-owned resource ID to its clinic.
+type ClinicLookup func(ctx context.Context, id uuid.UUID) (uuid.UUID, error)
+
+type badRequestError struct{ msg string }
+
+func (e *badRequestError) Error() string { return e.msg }
+
+// ClinicParam reads the clinic ID straight from a path parameter.
+func ClinicParam(name string) ClinicResolver {
+	return func(c *gin.Context) (uuid.UUID, error) {
+		return parseID(c.Param(name), name)
+	}
+}
+
+// ResourceParam reads a resource ID from a path parameter and resolves its clinic.
+func ResourceParam(name string, lookup ClinicLookup) ClinicResolver {
+	return func(c *gin.Context) (uuid.UUID, error) {
+		id, err := parseID(c.Param(name), name)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		return lookup(c.Request.Context(), id)
+	}
+}
+
+// ClinicField reads the clinic ID from a top-level field of the JSON body.
+func ClinicField(field string) ClinicResolver {
+	return func(c *gin.Context) (uuid.UUID, error) {
+		return bodyID(c, field)
+	}
+}
+
+// ResourceField reads a resource ID from a top-level field of the JSON body and resolves its clinic.
+func ResourceField(field string, lookup ClinicLookup) ClinicResolver {
+	return func(c *gin.Context) (uuid.UUID, error) {
+		id, err := bodyID(c, field)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		return lookup(c.Request.Context(), id)
+	}
+}
+
+// RequireClinicAccess must run after AuthMiddleware. It resolves the owning clinic and aborts with
+// 404 when the clinic or resource does not exist, or 403 when the user is not a member of the clinic
+// or the request's API key is bound to another clinic.
+// On success the clinic ID and the user's role are available as "clinic_id" and "clinic_role".
+func RequireClinicAccess(access *service.ClinicAccessService, resolve ClinicResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get("user_id")
+		userUUID, isUUID := userID.(uuid.UUID)
+		if !ok || !isUUID {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			c.Abort()
+			return
+		}
+
+		var role string
+		clinicID, err := resolve(c)
+		if key := clinicBoundKey(c); err == nil && key != nil && *key.ClinicID != clinicID {
+			err = service.ErrClinicAccessDenied
+		}
+		if err == nil {
+			role, err = access.Authorize(c.Request.Context(), userUUID, clinicID)
+		}
+		if err != nil {
+			var badReq *badRequestError
+			switch {
+			case errors.As(err, &badReq):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			case errors.Is(err, service.ErrNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			case errors.Is(err, service.ErrClinicAccessDenied):
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			case errors.Is(err, service.ErrMFARequired):
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "mfa_required"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set("clinic_id", clinicID)
+		c.Set("clinic_role", role)
+		c.Next()
+	}
+}
+
+func parseID(raw, name string) (uuid.UUID, error) {
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil, &badRequestError{msg: fmt.Sprintf("invalid %s", name)}
+	}
+	return id, nil
+}
+
+// bodyID peeks at a JSON body field, restoring the body so the handler can still bind it.
+func bodyID(c *gin.Context, field string) (uuid.UUID, error) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return uuid.Nil, &badRequestError{msg: "failed to read request body"}
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return uuid.Nil, &badRequestError{msg: "invalid request body"}
+	}
+	var raw string
+	if v, ok := fields[field]; !ok || json.Unmarshal(v, &raw) != nil || raw == "" {
+		return uuid.Nil, &badRequestError{msg: fmt.Sprintf("%s is required", field)}
+	}
+	return parseID(raw, field)
+}
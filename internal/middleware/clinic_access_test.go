@@ -0,0 +1,252 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+	"github.com/jmoiron/sqlx"
+)
+
+// tenants is two clinics, a user who belongs only to the first, and a form, an entry and an expense
+// entry in each clinic.
+type tenants struct {
+	user, own, other                     uuid.UUID
+	ownForm, otherForm, ownEntry         uuid.UUID
+	otherEntry, ownExpense, otherExpense uuid.UUID
+	owner                                map[uuid.UUID]uuid.UUID // resource to clinic
+}
+
+func newTenants() *tenants {
+	t := &tenants{user: uuid.New(), own: uuid.New(), other: uuid.New()}
+	t.ownForm, t.otherForm = uuid.New(), uuid.New()
+	t.ownEntry, t.otherEntry = uuid.New(), uuid.New()
+	t.ownExpense, t.otherExpense = uuid.New(), uuid.New()
+	t.owner = map[uuid.UUID]uuid.UUID{
+		t.ownForm: t.own, t.otherForm: t.other,
+		t.ownEntry: t.own, t.otherEntry: t.other,
+		t.ownExpense: t.own, t.otherExpense: t.other,
+	}
+	return t
+}
+
+// expectOwner expects the resource to be resolved to its clinic, or to be missing.
+func (tn *tenants) expectOwner(mock sqlmock.Sqlmock, table string, id uuid.UUID) {
+	rows := sqlmock.NewRows([]string{"clinic_id"})
+	if clinic, ok := tn.owner[id]; ok {
+		rows.AddRow(clinic)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT clinic_id FROM " + table + " WHERE id = $1")).WithArgs(id).WillReturnRows(rows)
+}
+
+// expectAuthorize expects the membership checks for the clinic, which stop at the first that fails.
+func (tn *tenants) expectAuthorize(mock sqlmock.Sqlmock, clinic uuid.UUID, member, mfaBlocked bool) {
+	exists := clinic == tn.own || clinic == tn.other
+	mock.ExpectQuery(regexp.QuoteMeta("FROM tbl_clinic WHERE id = $1")).WithArgs(clinic).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(exists))
+	if !exists {
+		return
+	}
+	rows := sqlmock.NewRows([]string{"id", "user_id", "clinic_id", "role", "created_at", "updated_at"})
+	if member {
+		rows.AddRow(uuid.New(), tn.user, clinic, domain.RoleAccountant, time.Now(), time.Now())
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("FROM tbl_user_clinic")).WithArgs(tn.user, clinic).WillReturnRows(rows)
+	if !member {
+		return
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT c.require_mfa")).WithArgs(clinic, tn.user).
+		WillReturnRows(sqlmock.NewRows([]string{"blocked"}).AddRow(mfaBlocked))
+}
+
+// newRouter mounts the middleware the way the routes do, behind a stand-in for AuthMiddleware
+// that signs in the user, with key as the request's API key when set.
+func newRouter(t *testing.T, tn *tenants, key *domain.APIKey) (*gin.Engine, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	access := service.NewClinicAccessService(sqlx.NewDb(db, "postgres"))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			return
+		}
+		c.Set("user_id", tn.user)
+		if key != nil {
+			c.Set("api_key", key)
+		}
+	})
+	ok := func(c *gin.Context) {
+		var body map[string]interface{}
+		if c.Request.Method == http.MethodPost && c.ShouldBindJSON(&body) != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "body was consumed"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"clinicId": c.MustGet("clinic_id"), "role": c.MustGet("clinic_role")})
+	}
+	r.GET("/clinic/:id", RequireClinicAccess(access, ClinicParam("id")), ok)
+	r.GET("/custom-form/:id", RequireClinicAccess(access, ResourceParam("id", access.CustomFormClinic)), ok)
+	r.GET("/custom-form/entries/:entryId", RequireClinicAccess(access, ResourceParam("entryId", access.CustomFormEntryClinic)), ok)
+	r.GET("/expense/entry/:id", RequireClinicAccess(access, ResourceParam("id", access.ExpenseEntryClinic)), ok)
+	r.POST("/custom-form", RequireClinicAccess(access, ClinicField("clinicId")), ok)
+	r.POST("/custom-form/entries", RequireClinicAccess(access, ResourceField("formId", access.CustomFormClinic)), ok)
+	return r, mock
+}
+
+func serve(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+var resourceRoutes = []struct {
+	path, table string
+	own, other  func(*tenants) uuid.UUID
+}{
+	{"/custom-form/", "tbl_custom_form", func(t *tenants) uuid.UUID { return t.ownForm }, func(t *tenants) uuid.UUID { return t.otherForm }},
+	{"/custom-form/entries/", "tbl_custom_form_entry", func(t *tenants) uuid.UUID { return t.ownEntry }, func(t *tenants) uuid.UUID { return t.otherEntry }},
+	{"/expense/entry/", "tbl_expense_entry", func(t *tenants) uuid.UUID { return t.ownExpense }, func(t *tenants) uuid.UUID { return t.otherExpense }},
+}
+
+func TestRequireClinicAccessByClinic(t *testing.T) {
+	tn := newTenants()
+	r, mock := newRouter(t, tn, nil)
+
+	tn.expectAuthorize(mock, tn.own, true, false)
+	w := serve(r, http.MethodGet, "/clinic/"+tn.own.String(), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("own clinic: status %d, body %s", w.Code, w.Body)
+	}
+	var got struct{ ClinicID, Role string }
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got.ClinicID != tn.own.String() || got.Role != domain.RoleAccountant {
+		t.Fatalf("own clinic: handler saw %+v (%v)", got, err)
+	}
+
+	tn.expectAuthorize(mock, tn.other, false, false)
+	if w := serve(r, http.MethodGet, "/clinic/"+tn.other.String(), ""); w.Code != http.StatusForbidden {
+		t.Errorf("other clinic: status %d, want 403", w.Code)
+	}
+
+	missing := uuid.New()
+	tn.expectAuthorize(mock, missing, false, false)
+	if w := serve(r, http.MethodGet, "/clinic/"+missing.String(), ""); w.Code != http.StatusNotFound {
+		t.Errorf("missing clinic: status %d, want 404", w.Code)
+	}
+
+	if w := serve(r, http.MethodGet, "/clinic/not-a-uuid", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("malformed clinic ID: status %d, want 400", w.Code)
+	}
+
+	tn.expectAuthorize(mock, tn.own, true, true)
+	w = serve(r, http.MethodGet, "/clinic/"+tn.own.String(), "")
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "mfa_required") {
+		t.Errorf("clinic requiring two-factor: status %d, body %s", w.Code, w.Body)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/clinic/"+tn.own.String(), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("signed out: status %d, want 401", w.Code)
+	}
+}
+
+func TestRequireClinicAccessByResource(t *testing.T) {
+	for _, rt := range resourceRoutes {
+		t.Run(rt.table, func(t *testing.T) {
+			tn := newTenants()
+			r, mock := newRouter(t, tn, nil)
+
+			tn.expectOwner(mock, rt.table, rt.own(tn))
+			tn.expectAuthorize(mock, tn.own, true, false)
+			if w := serve(r, http.MethodGet, rt.path+rt.own(tn).String(), ""); w.Code != http.StatusOK {
+				t.Errorf("own clinic's resource: status %d, body %s", w.Code, w.Body)
+			}
+
+			tn.expectOwner(mock, rt.table, rt.other(tn))
+			tn.expectAuthorize(mock, tn.other, false, false)
+			if w := serve(r, http.MethodGet, rt.path+rt.other(tn).String(), ""); w.Code != http.StatusForbidden {
+				t.Errorf("other clinic's resource: status %d, want 403", w.Code)
+			}
+
+			missing := uuid.New()
+			tn.expectOwner(mock, rt.table, missing)
+			if w := serve(r, http.MethodGet, rt.path+missing.String(), ""); w.Code != http.StatusNotFound {
+				t.Errorf("missing resource: status %d, want 404", w.Code)
+			}
+		})
+	}
+}
+
+func TestRequireClinicAccessByBody(t *testing.T) {
+	tn := newTenants()
+	r, mock := newRouter(t, tn, nil)
+
+	tn.expectAuthorize(mock, tn.own, true, false)
+	if w := serve(r, http.MethodPost, "/custom-form", `{"clinicId":"`+tn.own.String()+`","name":"Fees"}`); w.Code != http.StatusOK {
+		t.Errorf("own clinic: status %d, body %s", w.Code, w.Body)
+	}
+
+	tn.expectAuthorize(mock, tn.other, false, false)
+	if w := serve(r, http.MethodPost, "/custom-form", `{"clinicId":"`+tn.other.String()+`"}`); w.Code != http.StatusForbidden {
+		t.Errorf("other clinic: status %d, want 403", w.Code)
+	}
+
+	tn.expectOwner(mock, "tbl_custom_form", tn.otherForm)
+	tn.expectAuthorize(mock, tn.other, false, false)
+	if w := serve(r, http.MethodPost, "/custom-form/entries", `{"formId":"`+tn.otherForm.String()+`"}`); w.Code != http.StatusForbidden {
+		t.Errorf("other clinic's form: status %d, want 403", w.Code)
+	}
+
+	if w := serve(r, http.MethodPost, "/custom-form", `{"name":"Fees"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("no clinicId: status %d, want 400", w.Code)
+	}
+}
+
+// A key bound to one clinic reaches nothing in another, even when its user belongs to both.
+func TestRequireClinicAccessClinicBoundKey(t *testing.T) {
+	tn := newTenants()
+	key := &domain.APIKey{ID: uuid.New(), UserID: tn.user, ClinicID: &tn.own}
+	r, mock := newRouter(t, tn, key)
+
+	tn.expectAuthorize(mock, tn.own, true, false)
+	if w := serve(r, http.MethodGet, "/clinic/"+tn.own.String(), ""); w.Code != http.StatusOK {
+		t.Errorf("bound clinic: status %d, body %s", w.Code, w.Body)
+	}
+
+	// The key's clinic is checked before membership, so no membership queries are expected
+	if w := serve(r, http.MethodGet, "/clinic/"+tn.other.String(), ""); w.Code != http.StatusForbidden {
+		t.Errorf("other clinic: status %d, want 403", w.Code)
+	}
+	for _, rt := range resourceRoutes {
+		tn.expectOwner(mock, rt.table, rt.other(tn))
+		if w := serve(r, http.MethodGet, rt.path+rt.other(tn).String(), ""); w.Code != http.StatusForbidden {
+			t.Errorf("%s in other clinic: status %d, want 403", rt.table, w.Code)
+		}
+	}
+	if w := serve(r, http.MethodPost, "/custom-form", `{"clinicId":"`+tn.other.String()+`"}`); w.Code != http.StatusForbidden {
+		t.Errorf("other clinic in body: status %d, want 403", w.Code)
+	}
+}
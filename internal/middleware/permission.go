@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+)
+
+// RequirePermission rejects the request with 403 unless the user's clinic role grants permission.
+// Behind RequireClinicAccess the role for the addressed clinic is used; on routes that are not
+// scoped to a clinic, a role in any of the user's clinics is sufficient, or in the key's clinic for a
+// clinic-bound API key.
+func RequirePermission(access *service.ClinicAccessService, permission domain.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if role, ok := c.Get("clinic_role"); ok {
+			roleStr, _ := role.(string)
+			if !domain.RoleHasPermission(roleStr, permission) {
+				forbidden(c, permission)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		userID, ok := c.Get("user_id")
+		userUUID, isUUID := userID.(uuid.UUID)
+		if !ok || !isUUID {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			c.Abort()
+			return
+		}
+		if key := clinicBoundKey(c); key != nil {
+			role, err := access.Authorize(c.Request.Context(), userUUID, *key.ClinicID)
+			if err != nil || !domain.RoleHasPermission(role, permission) {
+				forbidden(c, permission)
+				return
+			}
+			c.Next()
+			return
+		}
+		allowed, err := access.HasPermissionInAnyClinic(c.Request.Context(), userUUID, permission)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		if !allowed {
+			forbidden(c, permission)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSystemAdmin rejects the request with 403 unless the user is a system administrator. It
+// guards writes to data shared by every clinic, which no clinic role may change. Clinic-bound API
+// keys are refused, since the data is not the key's clinic's.
+func RequireSystemAdmin(access *service.ClinicAccessService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get("user_id")
+		userUUID, isUUID := userID.(uuid.UUID)
+		if !ok || !isUUID {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			c.Abort()
+			return
+		}
+		if clinicBoundKey(c) != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "access denied: clinic-bound API keys cannot manage shared data"})
+			c.Abort()
+			return
+		}
+		admin, err := access.IsSystemAdmin(c.Request.Context(), userUUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		if !admin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "access denied: only a system administrator can manage shared data"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func forbidden(c *gin.Context, permission domain.Permission) {
+	c.JSON(http.StatusForbidden, gin.H{"error": "access denied: your role does not allow " + string(permission)})
+	c.Abort()
+}
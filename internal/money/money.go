@@ -0,0 +1,215 @@
+// Package money holds amounts as whole cents, so that adding up entries gives exactly the totals an
+// accountant's ledger does.
+//
+// Amounts are only rounded when they are scaled by a rate: GST follows the ATO's rule of rounding to
+// the nearest cent with half a cent rounded up, and other percentages (service fees, outwork charges,
+// commissions) use the configured mode, half up by default or banker's rounding. Amounts read from
+// JSON, the database or a float are converted through their decimal form, so 0.1 is exactly 10 cents.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Amount is a sum of money in cents. Amounts add and subtract exactly with + and -.
+type Amount int64
+
+// Rounding is how a result that falls between two cents is rounded.
+type Rounding int32
+
+const (
+	// HalfUp rounds half a cent away from zero. It is the ATO's rule for GST.
+	HalfUp Rounding = iota
+	// HalfEven rounds half a cent to the even cent (banker's rounding).
+	HalfEven
+)
+
+var defaultRounding atomic.Int32
+
+// ParseRounding reads a rounding mode: "half_up", or "half_even" (also "bankers").
+func ParseRounding(s string) (Rounding, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "half_up":
+		return HalfUp, nil
+	case "half_even", "bankers":
+		return HalfEven, nil
+	}
+	return HalfUp, fmt.Errorf("unknown rounding mode %q (use half_up or half_even)", s)
+}
+
+// SetRounding sets the mode DefaultRounding returns. It is meant to be called once at start-up.
+func SetRounding(r Rounding) { defaultRounding.Store(int32(r)) }
+
+// DefaultRounding is the mode for percentages other than GST, and for amounts given with more than
+// two decimals.
+func DefaultRounding() Rounding { return Rounding(defaultRounding.Load()) }
+
+// Cents returns an amount of c cents.
+func Cents(c int64) Amount { return Amount(c) }
+
+// Parse reads a decimal amount such as "1234.5" or "-0.05". Digits past the cent are rounded with
+// DefaultRounding.
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.ContainsAny(s, "/") {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return fromRat(r.Mul(r, big.NewRat(100, 1)), DefaultRounding())
+}
+
+// FromFloat converts a float through its shortest decimal form, so FromFloat(0.1) is 10 cents.
+// NaN and infinities are 0.
+func FromFloat(f float64) Amount {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0
+	}
+	a, err := Parse(strconv.FormatFloat(f, 'f', -1, 64))
+	if err != nil {
+		return 0
+	}
+	return a
+}
+
+// Cents returns the amount in cents.
+func (a Amount) Cents() int64 { return int64(a) }
+
+// Float64 returns the amount in dollars, for display and spreadsheets only.
+func (a Amount) Float64() float64 { return float64(a) / 100 }
+
+// String formats the amount with two decimals, e.g. "-1234.50".
+func (a Amount) String() string {
+	sign := ""
+	c := int64(a)
+	if c < 0 {
+		sign = "-"
+	}
+	u := uint64(c)
+	if c < 0 {
+		u = uint64(-c)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, u/100, u%100)
+}
+
+// Sum adds up amounts.
+func Sum(amounts ...Amount) Amount {
+	var total Amount
+	for _, a := range amounts {
+		total += a
+	}
+	return total
+}
+
+// Percent returns pct percent of the amount, rounded with r.
+func (a Amount) Percent(pct float64, r Rounding) Amount { return a.Ratio(pct, 100, r) }
+
+// Ratio returns the amount times num/den, rounded with r. num and den are read as the decimals they
+// print as, so a rate of 12.5 is exactly 12.5. A zero or non-finite den gives 0.
+func (a Amount) Ratio(num, den float64, r Rounding) Amount {
+	n, okN := decimalRat(num)
+	d, okD := decimalRat(den)
+	if !okN || !okD || d.Sign() == 0 {
+		return 0
+	}
+	x := new(big.Rat).SetInt64(int64(a))
+	x.Mul(x, n).Quo(x, d)
+	out, err := fromRat(x, r)
+	if err != nil {
+		return 0
+	}
+	return out
+}
+
+// GST returns the GST on a GST-exclusive amount at rate percent, rounded the ATO way.
+func (a Amount) GST(rate float64) Amount { return a.Ratio(rate, 100, HalfUp) }
+
+// GSTIncluded returns the GST contained in a GST-inclusive amount at rate percent (an eleventh at
+// 10%), rounded the ATO way.
+func (a Amount) GSTIncluded(rate float64) Amount { return a.Ratio(rate, 100+rate, HalfUp) }
+
+func decimalRat(f float64) (*big.Rat, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, false
+	}
+	return new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// fromRat rounds a number of cents to a whole cent.
+func fromRat(x *big.Rat, r Rounding) (Amount, error) {
+	num, den := x.Num(), x.Denom()
+	q, m := new(big.Int).QuoRem(num, den, new(big.Int)) // q truncated towards zero
+	if m.Sign() != 0 {
+		// Compare twice the remainder with the denominator to find which cent is nearer
+		cmp := new(big.Int).Abs(new(big.Int).Lsh(m, 1)).Cmp(den)
+		away := cmp > 0 || (cmp == 0 && (r == HalfUp || q.Bit(0) == 1))
+		if away {
+			q.Add(q, big.NewInt(int64(num.Sign())))
+		}
+	}
+	if !q.IsInt64() {
+		return 0, errors.New("amount out of range")
+	}
+	return Amount(q.Int64()), nil
+}
+
+// MarshalJSON writes the amount as a JSON number with two decimals.
+func (a Amount) MarshalJSON() ([]byte, error) { return []byte(a.String()), nil }
+
+// UnmarshalJSON accepts a number or a numeric string; null leaves the amount unchanged.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Value stores the amount as a decimal string, which NUMERIC columns take exactly.
+func (a Amount) Value() (driver.Value, error) { return a.String(), nil }
+
+// Scan reads a NUMERIC (returned as text), integer or float column.
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	case int64:
+		*a = Amount(v * 100)
+		return nil
+	case float64:
+		*a = FromFloat(v)
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into money.Amount", src)
+}
+
+func (a *Amount) scanString(s string) error {
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
@@ -0,0 +1,220 @@
+package money
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"testing/quick"
+)
+
+func check(t *testing.T, f interface{}) {
+	t.Helper()
+	if err := quick.Check(f, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+}
+
+// cents keeps generated amounts well inside the range where any arithmetic below stays exact.
+func cents(c int64) int64 { return c % 1_000_000_000_000 }
+
+// rateOf turns a generated number into a GST rate between 0 and 100 with up to two decimals.
+func rateOf(r uint16) float64 { return float64(r%10001) / 100 }
+
+func TestFromRatRoundsHalfCents(t *testing.T) {
+	check(t, func(raw int64) bool {
+		c := cents(raw)
+		if c < 0 {
+			c = -c
+		}
+		for _, sign := range []int64{1, -1} {
+			half := big.NewRat(sign*(2*c+1), 2) // c.5 cents
+			up, err := fromRat(half, HalfUp)
+			if err != nil || up != Amount(sign*(c+1)) {
+				return false
+			}
+			even := c
+			if c%2 == 1 {
+				even = c + 1
+			}
+			got, err := fromRat(half, HalfEven)
+			if err != nil || got != Amount(sign*even) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func TestFromRatRoundsToNearestOffTheHalf(t *testing.T) {
+	check(t, func(raw int64, offset uint16) bool {
+		c := cents(raw)
+		d := int64(offset%999) + 1 // 0.001 to 0.999 of a cent away from the half, in thousandths
+		below := new(big.Rat).Add(big.NewRat(2*c+1, 2), big.NewRat(-d, 2000))
+		above := new(big.Rat).Add(big.NewRat(2*c+1, 2), big.NewRat(d, 2000))
+		for _, r := range []Rounding{HalfUp, HalfEven} {
+			lo, err1 := fromRat(below, r)
+			hi, err2 := fromRat(above, r)
+			if err1 != nil || err2 != nil || lo != Amount(c) || hi != Amount(c+1) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func TestParseRoundsHalfCents(t *testing.T) {
+	defer SetRounding(DefaultRounding())
+	tests := []struct {
+		in             string
+		halfUp, halfEv int64
+	}{
+		{"0.005", 1, 0},
+		{"0.015", 2, 2},
+		{"0.025", 3, 2},
+		{"-0.005", -1, 0},
+		{"-0.015", -2, -2},
+		{"-1.125", -113, -112},
+		{"1.0049", 100, 100},
+		{"-1.0051", -101, -101},
+	}
+	for _, tt := range tests {
+		SetRounding(HalfUp)
+		if got, err := Parse(tt.in); err != nil || got != Amount(tt.halfUp) {
+			t.Errorf("Parse(%q) half up = %v, %v; want %d cents", tt.in, got.Cents(), err, tt.halfUp)
+		}
+		SetRounding(HalfEven)
+		if got, err := Parse(tt.in); err != nil || got != Amount(tt.halfEv) {
+			t.Errorf("Parse(%q) half even = %v, %v; want %d cents", tt.in, got.Cents(), err, tt.halfEv)
+		}
+	}
+}
+
+func TestParseStringRoundTrip(t *testing.T) {
+	check(t, func(c int64) bool {
+		a := Amount(c)
+		got, err := Parse(a.String())
+		return err == nil && got == a
+	})
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	check(t, func(c int64) bool {
+		a := Amount(cents(c))
+		data, err := json.Marshal(a)
+		if err != nil {
+			return false
+		}
+		var got Amount
+		return json.Unmarshal(data, &got) == nil && got == a
+	})
+}
+
+func TestFromFloat(t *testing.T) {
+	if got := FromFloat(0.1); got != 10 {
+		t.Fatalf("FromFloat(0.1) = %d cents, want 10", got.Cents())
+	}
+	if got := FromFloat(0.1 + 0.2); got != 30 {
+		t.Fatalf("FromFloat(0.1 + 0.2) = %d cents, want 30", got.Cents())
+	}
+	check(t, func(c int64) bool {
+		c = cents(c)
+		return FromFloat(float64(c)/100) == Amount(c)
+	})
+}
+
+// GST on an exclusive amount is the exact GST rounded to the nearest cent.
+func TestGSTIsNearestCent(t *testing.T) {
+	check(t, func(c int64, r uint16) bool {
+		base, rate := Amount(cents(c)), rateOf(r)
+		gst := base.GST(rate)
+		exact := new(big.Rat).Mul(big.NewRat(int64(base), 100), ratOf(rate)) // cents
+		return nearestCent(gst, exact)
+	})
+}
+
+// GST contained in an inclusive total is rate/(100+rate) of it rounded to the nearest cent, and the
+// base is what remains, so base + GST is the total.
+func TestGSTIncludedIsNearestCent(t *testing.T) {
+	check(t, func(c int64, r uint16) bool {
+		total, rate := Amount(cents(c)), rateOf(r)
+		gst := total.GSTIncluded(rate)
+		base := total - gst
+		den := new(big.Rat).Add(big.NewRat(100, 1), ratOf(rate))
+		exact := new(big.Rat).Quo(new(big.Rat).Mul(big.NewRat(int64(total), 1), ratOf(rate)), den)
+		return nearestCent(gst, exact) && base+gst == total
+	})
+}
+
+// At 10% the GST in an inclusive total is an eleventh, half a cent rounded up.
+func TestGSTIncludedElevenths(t *testing.T) {
+	tests := []struct{ total, gst int64 }{
+		{1100, 100},
+		{5, 0},
+		{6, 1},
+		{-6, -1},
+		{16500, 1500},
+		{100, 9},
+	}
+	for _, tt := range tests {
+		if got := Amount(tt.total).GSTIncluded(10); got != Amount(tt.gst) {
+			t.Errorf("GSTIncluded(%d cents) = %d, want %d", tt.total, got.Cents(), tt.gst)
+		}
+	}
+}
+
+// Entry amounts stored as decimals add up, through any of the routes totals are built by, to exactly
+// the sum of the cents: in Go, after a JSON round trip (BAS worksheets read stored calculations), and
+// as decimals (listing totals are a NUMERIC SUM in the database).
+func TestTotalsReconcile(t *testing.T) {
+	check(t, func(raw []int64) bool {
+		var want int64
+		amounts := make([]Amount, len(raw))
+		for i, c := range raw {
+			amounts[i] = Amount(cents(c))
+			want += cents(c)
+		}
+
+		var viaJSON Amount
+		decimal := new(big.Rat)
+		for _, a := range amounts {
+			data, err := json.Marshal(a)
+			if err != nil {
+				return false
+			}
+			var stored Amount
+			if err := json.Unmarshal(data, &stored); err != nil {
+				return false
+			}
+			viaJSON += stored
+			d, ok := new(big.Rat).SetString(a.String())
+			if !ok {
+				return false
+			}
+			decimal.Add(decimal, d)
+		}
+		var scanned Amount
+		if err := scanned.Scan([]byte(decimal.FloatString(2))); err != nil {
+			return false
+		}
+		return Sum(amounts...) == Amount(want) && viaJSON == Amount(want) && scanned == Amount(want)
+	})
+}
+
+func ratOf(f float64) *big.Rat {
+	r, ok := decimalRat(f)
+	if !ok {
+		panic("invalid rate")
+	}
+	return r
+}
+
+// nearestCent reports whether got is exact cents rounded to the nearest cent, half a cent away from
+// zero as the ATO requires for GST.
+func nearestCent(got Amount, exact *big.Rat) bool {
+	half := new(big.Rat).Add(new(big.Rat).Abs(exact), big.NewRat(1, 2))
+	want := new(big.Int).Quo(half.Num(), half.Denom())
+	if exact.Sign() < 0 {
+		want.Neg(want)
+	}
+	return want.IsInt64() && got == Amount(want.Int64())
+}
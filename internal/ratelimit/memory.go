@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryLimiter keeps counters in process memory. Counters are not shared between instances, so it
+// suits development and single-instance deployments.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+type memoryEntry struct {
+	status    Status
+	expiresAt time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{entries: make(map[string]memoryEntry)}
+}
+
+func (l *MemoryLimiter) Status(_ context.Context, key string) (Status, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.current(key, time.Now()).status, nil
+}
+
+func (l *MemoryLimiter) Fail(_ context.Context, key string, policy Policy) (Status, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.sweep(now)
+	status, ttl := next(l.current(key, now).status.Failures+1, policy, now)
+	l.entries[key] = memoryEntry{status: status, expiresAt: now.Add(ttl)}
+	return status, nil
+}
+
+func (l *MemoryLimiter) Reset(_ context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+	return nil
+}
+
+// current returns the key's entry, treating an expired one as absent. Callers hold mu.
+func (l *MemoryLimiter) current(key string, now time.Time) memoryEntry {
+	entry, ok := l.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		return memoryEntry{}
+	}
+	return entry
+}
+
+// sweep drops expired entries, at most once a minute, so keys from one-off addresses do not
+// accumulate. Callers hold mu.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, entry := range l.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(l.entries, key)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/iamarpitzala/aca-reca-backend/config"
+)
+
+// Policy turns a run of failures into a wait before the next attempt. The first FreeAttempts
+// failures cost nothing; after that the wait starts at BaseDelay and doubles with each failure up to
+// MaxDelay. Reaching LockoutAfter failures blocks the key for LockoutDuration. Failures are
+// forgotten once Window passes without another one.
+type Policy struct {
+	FreeAttempts    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutAfter    int
+	LockoutDuration time.Duration
+	Window          time.Duration
+}
+
+// Delay returns how long a key must wait after its nth failure, and whether that wait is a lockout.
+func (p Policy) Delay(failures int) (time.Duration, bool) {
+	if p.LockoutAfter > 0 && failures >= p.LockoutAfter {
+		return p.LockoutDuration, true
+	}
+	over := failures - p.FreeAttempts
+	if over <= 0 || p.BaseDelay <= 0 {
+		return 0, false
+	}
+	delay := p.BaseDelay
+	for i := 1; i < over && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay, false
+}
+
+// Status is what a limiter knows about a key. The zero value means no recent failures.
+type Status struct {
+	Failures int
+	RetryAt  time.Time // Attempts before this time are refused
+	Locked   bool      // The wait is a lockout rather than a progressive delay
+}
+
+// RetryAfter returns how long until the key may be tried again, or zero when it may be tried now.
+func (s Status) RetryAfter(now time.Time) time.Duration {
+	if wait := s.RetryAt.Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// Limiter counts failed attempts per key, e.g. "login:account:<email>" or "login:ip:<address>".
+// Implementations must be safe for concurrent use.
+type Limiter interface {
+	// Status reports the failures recorded against key.
+	Status(ctx context.Context, key string) (Status, error)
+	// Fail records one more failure against key and returns the resulting status.
+	Fail(ctx context.Context, key string, policy Policy) (Status, error)
+	// Reset forgets every failure recorded against key, lifting any delay or lockout.
+	Reset(ctx context.Context, key string) error
+}
+
+// New returns the limiter selected by cfg.Driver. The redis driver connects using redisCfg.
+func New(cfg config.LoginLimitConfig, redisCfg config.RedisConfig) (Limiter, error) {
+	switch cfg.Driver {
+	case "", "memory":
+		return NewMemoryLimiter(), nil
+	case "redis":
+		return NewRedisLimiter(redisCfg)
+	default:
+		return nil, fmt.Errorf("unknown rate limit driver: %s", cfg.Driver)
+	}
+}
+
+// next applies one more failure to a key that had failures, returning its new status and how long
+// the key must be remembered.
+func next(failures int, policy Policy, now time.Time) (Status, time.Duration) {
+	delay, locked := policy.Delay(failures)
+	ttl := policy.Window
+	if delay > ttl {
+		ttl = delay
+	}
+	return Status{Failures: failures, RetryAt: now.Add(delay), Locked: locked}, ttl
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces limiter keys in a Redis database that may be shared with other data.
+const keyPrefix = "ratelimit:"
+
+// RedisLimiter keeps counters in Redis so every instance of the API sees the same failures. Each key
+// is a hash of failures, retry_at (Unix milliseconds) and locked, expiring with its policy window.
+type RedisLimiter struct {
+	client *redis.Client
+}
+
+// NewRedisLimiter connects to the Redis server in cfg and checks that it answers.
+func NewRedisLimiter(cfg config.RedisConfig) (*RedisLimiter, error) {
+	opts := &redis.Options{
+		Addr:     net.JoinHostPort(cfg.Host, cfg.Port),
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	}
+	if cfg.TLSEnabled {
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12, ServerName: cfg.Host}
+	}
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	return &RedisLimiter{client: client}, nil
+}
+
+func (l *RedisLimiter) Status(ctx context.Context, key string) (Status, error) {
+	fields, err := l.client.HGetAll(ctx, keyPrefix+key).Result()
+	if err != nil {
+		return Status{}, err
+	}
+	failures, _ := strconv.Atoi(fields["failures"])
+	status := Status{Failures: failures, Locked: fields["locked"] == "1"}
+	if ms, err := strconv.ParseInt(fields["retry_at"], 10, 64); err == nil {
+		status.RetryAt = time.UnixMilli(ms)
+	}
+	return status, nil
+}
+
+// storeWait keeps the longer of the stored and the new wait, so when failures on one key race the
+// later count cannot shorten a wait already earned, and never shortens the key's expiry.
+var storeWait = redis.NewScript(`
+local retry = tonumber(redis.call('HGET', KEYS[1], 'retry_at') or '0')
+if tonumber(ARGV[1]) > retry then
+	redis.call('HSET', KEYS[1], 'retry_at', ARGV[1], 'locked', ARGV[2])
+end
+if redis.call('PTTL', KEYS[1]) < tonumber(ARGV[3]) then
+	redis.call('PEXPIRE', KEYS[1], ARGV[3])
+end
+return 1
+`)
+
+// Fail increments the counter atomically, then stores the wait the new count earns.
+func (l *RedisLimiter) Fail(ctx context.Context, key string, policy Policy) (Status, error) {
+	key = keyPrefix + key
+	failures, err := l.client.HIncrBy(ctx, key, "failures", 1).Result()
+	if err != nil {
+		return Status{}, err
+	}
+	now := time.Now()
+	status, ttl := next(int(failures), policy, now)
+	locked := "0"
+	if status.Locked {
+		locked = "1"
+	}
+	args := []interface{}{status.RetryAt.UnixMilli(), locked, ttl.Milliseconds()}
+	if err := storeWait.Run(ctx, l.client, []string{key}, args...).Err(); err != nil {
+		return Status{}, err
+	}
+	return status, nil
+}
+
+func (l *RedisLimiter) Reset(ctx context.Context, key string) error {
+	return l.client.Del(ctx, keyPrefix+key).Err()
+}
@@ -11,10 +11,10 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
-func CreateAOC(ctx context.Context, db *sqlx.DB, aoc *domain.AOC) error {
+func CreateAOC(ctx context.Context, db sqlx.ExtContext, aoc *domain.AOC) error {
 	query := `INSERT INTO tbl_account (id, account_type_id, account_tax_id, code, name, description, created_at, updated_at, deleted_at)
 		VALUES (:id, :account_type_id, :account_tax_id, :code, :name, :description, :created_at, :updated_at, :deleted_at)`
-	_, err := db.NamedExecContext(ctx, query, aoc)
+	_, err := sqlx.NamedExecContext(ctx, db, query, aoc)
 	if err != nil {
 		return err
 	}
@@ -31,16 +31,16 @@ func GetAOCByID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (*domain.AOC, er
 	return &aoc, nil
 }
 
-func UpdateAOC(ctx context.Context, db *sqlx.DB, aoc *domain.AOC) error {
+func UpdateAOC(ctx context.Context, db sqlx.ExtContext, aoc *domain.AOC) error {
 	query := `UPDATE tbl_account SET account_type_id = :account_type_id, account_tax_id = :account_tax_id, code = :code, name = :name, description = :description, updated_at = :updated_at WHERE id = :id`
-	_, err := db.NamedExecContext(ctx, query, aoc)
+	_, err := sqlx.NamedExecContext(ctx, db, query, aoc)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func DeleteAOC(ctx context.Context, db *sqlx.DB, ids []uuid.UUID) error {
+func DeleteAOC(ctx context.Context, db sqlx.ExtContext, ids []uuid.UUID) error {
 	now := time.Now()
 	for _, id := range ids {
 		query := `UPDATE tbl_account SET deleted_at = $1 WHERE id = $2`
@@ -53,7 +53,7 @@ func DeleteAOC(ctx context.Context, db *sqlx.DB, ids []uuid.UUID) error {
 }
 
 // BulkUpdateAccountTax sets account_tax_id for the given account IDs.
-func BulkUpdateAccountTax(ctx context.Context, db *sqlx.DB, ids []uuid.UUID, accountTaxID int) error {
+func BulkUpdateAccountTax(ctx context.Context, db sqlx.ExtContext, ids []uuid.UUID, accountTaxID int) error {
 	now := time.Now()
 	for _, id := range ids {
 		query := `UPDATE tbl_account SET account_tax_id = $1, updated_at = $2 WHERE id = $3 AND deleted_at IS NULL`
@@ -177,7 +177,7 @@ func GetAccountTypeByID(ctx context.Context, db *sqlx.DB, id int) (*domain.Accou
 }
 
 func GetAllAccountTax(ctx context.Context, db *sqlx.DB) ([]domain.AccountTax, error) {
-	query := `SELECT id, name, rate, description, created_at, updated_at FROM tbl_account_tax WHERE deleted_at IS NULL`
+	query := `SELECT id, name, rate, treatment, description, created_at, updated_at FROM tbl_account_tax WHERE deleted_at IS NULL`
 	var taxes []domain.AccountTax
 	err := db.SelectContext(ctx, &taxes, query)
 	if err != nil {
@@ -187,7 +187,7 @@ func GetAllAccountTax(ctx context.Context, db *sqlx.DB) ([]domain.AccountTax, er
 }
 
 func GetAccountTaxByID(ctx context.Context, db *sqlx.DB, id int) (*domain.AccountTax, error) {
-	query := `SELECT id, name, rate, description, created_at, updated_at FROM tbl_account_tax WHERE id = $1 AND deleted_at IS NULL`
+	query := `SELECT id, name, rate, treatment, description, created_at, updated_at FROM tbl_account_tax WHERE id = $1 AND deleted_at IS NULL`
 	var at domain.AccountTax
 	err := db.GetContext(ctx, &at, query, id)
 	if err != nil && err != sql.ErrNoRows {
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+const apiKeyColumns = `id, user_id, clinic_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at, revoked_at`
+
+func CreateAPIKey(ctx context.Context, db sqlx.ExtContext, key *domain.APIKey) error {
+	query := `INSERT INTO tbl_api_key (id, user_id, clinic_id, name, prefix, key_hash, scopes, expires_at, created_at)
+		VALUES (:id, :user_id, :clinic_id, :name, :prefix, :key_hash, :scopes, :expires_at, :created_at)`
+	_, err := sqlx.NamedExecContext(ctx, db, query, key)
+	return err
+}
+
+// GetAPIKeyByHash returns the unrevoked key with the hash, or nil when there is none.
+func GetAPIKeyByHash(ctx context.Context, db *sqlx.DB, hash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := db.GetContext(ctx, &key, `SELECT `+apiKeyColumns+` FROM tbl_api_key WHERE key_hash = $1 AND revoked_at IS NULL`, hash)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetUserAPIKeys lists a user's unrevoked keys, newest first.
+func GetUserAPIKeys(ctx context.Context, db *sqlx.DB, userID uuid.UUID) ([]domain.APIKey, error) {
+	keys := []domain.APIKey{}
+	err := db.SelectContext(ctx, &keys, `SELECT `+apiKeyColumns+` FROM tbl_api_key
+		WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes one of the user's keys. It reports false when the user has no such key.
+func RevokeAPIKey(ctx context.Context, db *sqlx.DB, userID, keyID uuid.UUID) (bool, error) {
+	result, err := db.ExecContext(ctx, `UPDATE tbl_api_key SET revoked_at = $1
+		WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL`, time.Now(), keyID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// TouchAPIKey records that the key was just used.
+func TouchAPIKey(ctx context.Context, db *sqlx.DB, keyID uuid.UUID, usedAt time.Time) error {
+	_, err := db.ExecContext(ctx, `UPDATE tbl_api_key SET last_used_at = $1 WHERE id = $2`, usedAt, keyID)
+	return err
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+const auditLogColumns = `id, clinic_id, actor_id, entity_type, entity_id, action, before, after, created_at`
+
+// CreateAuditLog appends an audit record. Pass the transaction of the change being audited so both
+// commit or roll back together.
+func CreateAuditLog(ctx context.Context, db sqlx.ExtContext, log *domain.AuditLog) error {
+	query := `INSERT INTO tbl_audit_log (` + auditLogColumns + `)
+		VALUES (:id, :clinic_id, :actor_id, :entity_type, :entity_id, :action, :before, :after, :created_at)`
+	_, err := sqlx.NamedExecContext(ctx, db, query, log)
+	return err
+}
+
+// GetAuditLogs returns audit records matching filter, newest first.
+func GetAuditLogs(ctx context.Context, db *sqlx.DB, filter domain.AuditLogFilter) ([]domain.AuditLog, error) {
+	var where []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, strings.Replace(cond, "?", "$"+strconv.Itoa(len(args)), 1))
+	}
+	if filter.ClinicID != nil {
+		add("clinic_id = ?", *filter.ClinicID)
+	}
+	if filter.ActorID != nil {
+		add("actor_id = ?", *filter.ActorID)
+	}
+	if filter.EntityType != "" {
+		add("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != nil {
+		add("entity_id = ?", *filter.EntityID)
+	}
+	if filter.Action != "" {
+		add("action = ?", filter.Action)
+	}
+	if filter.From != nil {
+		add("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at <= ?", *filter.To)
+	}
+
+	query := `SELECT ` + auditLogColumns + ` FROM tbl_audit_log`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY created_at DESC, id`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += ` LIMIT $` + strconv.Itoa(len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += ` OFFSET $` + strconv.Itoa(len(args))
+	}
+
+	logs := []domain.AuditLog{}
+	if err := db.SelectContext(ctx, &logs, query, args...); err != nil {
+		return nil, errors.New("failed to get audit logs")
+	}
+	return logs, nil
+}
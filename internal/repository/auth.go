@@ -11,10 +11,10 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
-func CreateUser(ctx context.Context, db *sqlx.DB, user *domain.User) error {
-	query := `INSERT INTO tbl_user (id, email, password, first_name, last_name, phone, created_at, updated_at)
-		VALUES (:id, :email, :password, :first_name, :last_name, :phone, :created_at, :updated_at)`
-	_, err := db.NamedExecContext(ctx, query, user)
+func CreateUser(ctx context.Context, db sqlx.ExtContext, user *domain.User) error {
+	query := `INSERT INTO tbl_user (id, email, password, first_name, last_name, phone, avatar_url, is_email_verified, created_at, updated_at)
+		VALUES (:id, :email, :password, :first_name, :last_name, :phone, :avatar_url, :is_email_verified, :created_at, :updated_at)`
+	_, err := sqlx.NamedExecContext(ctx, db, query, user)
 	if err != nil {
 		return err
 	}
@@ -23,7 +23,7 @@ func CreateUser(ctx context.Context, db *sqlx.DB, user *domain.User) error {
 
 func GetUserByEmail(ctx context.Context, db *sqlx.DB, email string) (*domain.User, error) {
 	// Use case-insensitive lookup to match the LOWER(email) unique index
-	query := `SELECT id, email, password, first_name, last_name, phone, created_at, updated_at
+	query := `SELECT id, email, password, first_name, last_name, phone, is_email_verified, created_at, updated_at
 		FROM tbl_user
 		WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL`
 	var user domain.User
@@ -50,50 +50,56 @@ func EmailExists(ctx context.Context, db *sqlx.DB, email string) (bool, error) {
 	return count > 0, nil
 }
 
-func CreateSession(ctx context.Context, db *sqlx.DB, session *domain.Session) error {
-	query := `INSERT INTO tbl_session (id, user_id, refresh_token, expires_at, created_at, updated_at)
-		VALUES (:id, :user_id, :refresh_token, :expires_at, :created_at, :updated_at)`
+const sessionColumns = `id, user_id, family_id, refresh_token_hash, COALESCE(user_agent, '') AS user_agent,
+	COALESCE(ip_address, '') AS ip_address, expires_at, rotated_at, last_seen_at, created_at, updated_at, deleted_at`
 
-	_, err := db.NamedExecContext(ctx, query, session)
-	if err != nil {
-		return err
-	}
-	return nil
+func CreateSession(ctx context.Context, db sqlx.ExtContext, session *domain.Session) error {
+	query := `INSERT INTO tbl_session (id, user_id, family_id, refresh_token_hash, user_agent, ip_address, expires_at, last_seen_at, created_at, updated_at)
+		VALUES (:id, :user_id, :family_id, :refresh_token_hash, :user_agent, :ip_address, :expires_at, :last_seen_at, :created_at, :updated_at)`
+	_, err := sqlx.NamedExecContext(ctx, db, query, session)
+	return err
 }
 
-func GetSessionByRefreshToken(ctx context.Context, db *sqlx.DB, refreshToken string) (*domain.Session, error) {
-	query := `SELECT id, user_id, refresh_token,expires_at, created_at, updated_at FROM tbl_session WHERE refresh_token = $1 AND deleted_at IS NULL`
+// GetSessionByRefreshTokenHash finds the session a refresh token was issued for, including rotated
+// and revoked ones so that replays can be recognised. It returns nil when no session matches.
+func GetSessionByRefreshTokenHash(ctx context.Context, db *sqlx.DB, hash string) (*domain.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM tbl_session WHERE refresh_token_hash = $1`
 	var session domain.Session
-	err := db.GetContext(ctx, &session, query, refreshToken)
+	err := db.GetContext(ctx, &session, query, hash)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("session not found")
+			return nil, nil
 		}
 		return nil, errors.New("failed to find session")
 	}
 	return &session, nil
 }
 
-func UpdateSession(ctx context.Context, db *sqlx.DB, session *domain.Session) error {
-	query := `UPDATE tbl_session SET refresh_token = :refresh_token, expires_at = :expires_at, updated_at = :updated_at WHERE id = :id`
-	_, err := db.NamedExecContext(ctx, query, session)
+// MarkSessionRotated flags a session's refresh token as used. It reports false when the token had
+// already been rotated, which means it is being replayed.
+func MarkSessionRotated(ctx context.Context, db sqlx.ExtContext, sessionID uuid.UUID, at time.Time) (bool, error) {
+	res, err := db.ExecContext(ctx, `UPDATE tbl_session SET rotated_at = $1, updated_at = $1
+		WHERE id = $2 AND rotated_at IS NULL AND deleted_at IS NULL`, at, sessionID)
 	if err != nil {
-		return err
+		return false, err
 	}
-	return nil
+	n, err := res.RowsAffected()
+	return n == 1, err
 }
 
-func DeleteSession(ctx context.Context, db *sqlx.DB, sessionID uuid.UUID) error {
-	query := `UPDATE tbl_session SET deleted_at = $1 WHERE id = $2`
-	_, err := db.ExecContext(ctx, query, time.Now(), sessionID)
+// RevokeSessionFamily revokes every session descended from the same login.
+func RevokeSessionFamily(ctx context.Context, db sqlx.ExtContext, familyID uuid.UUID) error {
+	_, err := db.ExecContext(ctx, `UPDATE tbl_session SET deleted_at = $1, updated_at = $1
+		WHERE family_id = $2 AND deleted_at IS NULL`, time.Now(), familyID)
 	if err != nil {
-		return err
+		return errors.New("failed to revoke session")
 	}
 	return nil
 }
 
+// GetSessionByID returns a session that has not been revoked, or an error when there is none.
 func GetSessionByID(ctx context.Context, db *sqlx.DB, sessionID uuid.UUID) (*domain.Session, error) {
-	query := `SELECT id, user_id, refresh_token, expires_at, created_at, updated_at FROM tbl_session WHERE id = $1 AND deleted_at IS NULL`
+	query := `SELECT ` + sessionColumns + ` FROM tbl_session WHERE id = $1 AND deleted_at IS NULL`
 	var session domain.Session
 	err := db.GetContext(ctx, &session, query, sessionID)
 	if err != nil {
@@ -106,7 +112,7 @@ func GetSessionByID(ctx context.Context, db *sqlx.DB, sessionID uuid.UUID) (*dom
 }
 
 func GetUserByID(ctx context.Context, db *sqlx.DB, userID uuid.UUID) (*domain.User, error) {
-	query := `SELECT id, email, password, first_name, last_name, phone, created_at, updated_at FROM tbl_user WHERE id = $1 AND deleted_at IS NULL`
+	query := `SELECT id, email, password, first_name, last_name, phone, is_email_verified, created_at, updated_at FROM tbl_user WHERE id = $1 AND deleted_at IS NULL`
 	var user domain.User
 	err := db.GetContext(ctx, &user, query, userID)
 	if err != nil {
@@ -127,22 +133,57 @@ func UpdateUser(ctx context.Context, db *sqlx.DB, user *domain.User) error {
 	return nil
 }
 
+func UpdateUserPassword(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, passwordHash string) error {
+	_, err := db.ExecContext(ctx, `UPDATE tbl_user SET password = $1, updated_at = $2 WHERE id = $3 AND deleted_at IS NULL`,
+		passwordHash, time.Now(), userID)
+	return err
+}
+
+func SetUserEmailVerified(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID) error {
+	_, err := db.ExecContext(ctx, `UPDATE tbl_user SET is_email_verified = TRUE, updated_at = $1 WHERE id = $2 AND deleted_at IS NULL`,
+		time.Now(), userID)
+	return err
+}
+
+// RevokeUserSessions revokes all of a user's sessions, except the family given in keepFamily.
+func RevokeUserSessions(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, keepFamily *uuid.UUID) error {
+	_, err := db.ExecContext(ctx, `UPDATE tbl_session SET deleted_at = $1, updated_at = $1
+		WHERE user_id = $2 AND deleted_at IS NULL AND ($3::uuid IS NULL OR family_id <> $3)`, time.Now(), userID, keepFamily)
+	if err != nil {
+		return errors.New("failed to revoke sessions")
+	}
+	return nil
+}
+
+// GetUserSessions lists a user's signed-in sessions: the current, unexpired member of each family,
+// with the time the family's login happened, most recently used first.
 func GetUserSessions(ctx context.Context, db *sqlx.DB, userId uuid.UUID) ([]domain.Session, error) {
-	var sessions []domain.Session
-	err := db.Select(&sessions,
-		"SELECT id, user_id, refresh_token, expires_at, created_at, updated_at FROM tbl_session WHERE user_id = $1 AND deleted_at IS NULL",
-		userId, true)
+	sessions := []domain.Session{}
+	err := db.SelectContext(ctx, &sessions,
+		`SELECT `+sessionColumns+`,
+			(SELECT MIN(f.created_at) FROM tbl_session f WHERE f.family_id = tbl_session.family_id) AS signed_in_at
+		FROM tbl_session
+		WHERE user_id = $1 AND deleted_at IS NULL AND rotated_at IS NULL AND expires_at > $2
+		ORDER BY last_seen_at DESC`,
+		userId, time.Now())
 	if err != nil {
 		return nil, err
 	}
 	return sessions, nil
 }
 
-func RevokeSession(ctx context.Context, db *sqlx.DB, sessionID uuid.UUID) error {
-	_, err := db.Exec("UPDATE tbl_session SET updated_at = $2 WHERE id = $3", false, time.Now(), sessionID)
-	if err != nil {
-		return errors.New("failed to revoke session")
-	}
+// TouchSession records that the session was just used.
+func TouchSession(ctx context.Context, db *sqlx.DB, sessionID uuid.UUID, seenAt time.Time) error {
+	_, err := db.ExecContext(ctx, `UPDATE tbl_session SET last_seen_at = $1 WHERE id = $2`, seenAt, sessionID)
+	return err
+}
 
-	return nil
+// HasOtherSessions reports whether the user has any session besides sessionID, and whether any of
+// them, current or not, came from the same user agent.
+func HasOtherSessions(ctx context.Context, db *sqlx.DB, userID, sessionID uuid.UUID, userAgent string) (others, sameAgent bool, err error) {
+	err = db.QueryRowxContext(ctx, `SELECT
+			EXISTS (SELECT 1 FROM tbl_session WHERE user_id = $1 AND id <> $2),
+			EXISTS (SELECT 1 FROM tbl_session WHERE user_id = $1 AND id <> $2 AND user_agent = $3)`,
+		userID, sessionID, userAgent).Scan(&others, &sameAgent)
+	return others, sameAgent, err
 }
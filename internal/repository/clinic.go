@@ -11,10 +11,10 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
-func CreateClinic(ctx context.Context, db *sqlx.DB, clinic *domain.Clinic) error {
-	query := `INSERT INTO tbl_clinic (id, name, abn_number, address, city, state, postcode, phone, email, website, logo_url, description, share_type, clinic_share, owner_share,created_at, updated_at)
-		VALUES (:id, :name, :abn_number, :address, :city, :state, :postcode, :phone, :email, :website, :logo_url, :description, :share_type, :clinic_share, :owner_share, :created_at, :updated_at)`
-	_, err := db.NamedExecContext(ctx, query, clinic)
+func CreateClinic(ctx context.Context, db sqlx.ExtContext, clinic *domain.Clinic) error {
+	query := `INSERT INTO tbl_clinic (id, name, abn_number, address, city, state, postcode, phone, email, website, logo_url, description, share_type, clinic_share, owner_share, bas_cycle, require_mfa, created_at, updated_at)
+		VALUES (:id, :name, :abn_number, :address, :city, :state, :postcode, :phone, :email, :website, :logo_url, :description, :share_type, :clinic_share, :owner_share, :bas_cycle, :require_mfa, :created_at, :updated_at)`
+	_, err := sqlx.NamedExecContext(ctx, db, query, clinic)
 	if err != nil {
 		return err
 	}
@@ -22,7 +22,7 @@ func CreateClinic(ctx context.Context, db *sqlx.DB, clinic *domain.Clinic) error
 }
 
 func GetClinicByID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (*domain.Clinic, error) {
-	query := `SELECT id, name, abn_number, address, city, state, postcode, phone, email, website, logo_url, description, share_type, clinic_share, owner_share,created_at, updated_at FROM tbl_clinic WHERE id = $1 AND deleted_at IS NULL`
+	query := `SELECT id, name, abn_number, address, city, state, postcode, phone, email, website, logo_url, description, share_type, clinic_share, owner_share, bas_cycle, require_mfa, created_at, updated_at FROM tbl_clinic WHERE id = $1 AND deleted_at IS NULL`
 	var clinic domain.Clinic
 	err := db.GetContext(ctx, &clinic, query, id)
 	if err != nil && err != sql.ErrNoRows {
@@ -31,16 +31,16 @@ func GetClinicByID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (*domain.Clin
 	return &clinic, nil
 }
 
-func UpdateClinic(ctx context.Context, db *sqlx.DB, clinic *domain.Clinic) error {
-	query := `UPDATE tbl_clinic SET name = :name, abn_number = :abn_number, address = :address, city = :city, state = :state, postcode = :postcode, phone = :phone, email = :email, website = :website, logo_url = :logo_url, description = :description, share_type = :share_type, clinic_share = :clinic_share, owner_share = :owner_share, updated_at = :updated_at WHERE id = :id`
-	_, err := db.NamedExecContext(ctx, query, clinic)
+func UpdateClinic(ctx context.Context, db sqlx.ExtContext, clinic *domain.Clinic) error {
+	query := `UPDATE tbl_clinic SET name = :name, abn_number = :abn_number, address = :address, city = :city, state = :state, postcode = :postcode, phone = :phone, email = :email, website = :website, logo_url = :logo_url, description = :description, share_type = :share_type, clinic_share = :clinic_share, owner_share = :owner_share, bas_cycle = :bas_cycle, require_mfa = :require_mfa, updated_at = :updated_at WHERE id = :id`
+	_, err := sqlx.NamedExecContext(ctx, db, query, clinic)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func DeleteClinic(ctx context.Context, db *sqlx.DB, id uuid.UUID) error {
+func DeleteClinic(ctx context.Context, db sqlx.ExtContext, id uuid.UUID) error {
 	query := `UPDATE tbl_clinic SET deleted_at = $1 WHERE id = $2`
 	_, err := db.ExecContext(ctx, query, time.Now(), id)
 	if err != nil {
@@ -50,7 +50,7 @@ func DeleteClinic(ctx context.Context, db *sqlx.DB, id uuid.UUID) error {
 }
 
 func GetAllClinics(ctx context.Context, db *sqlx.DB) ([]domain.Clinic, error) {
-	query := `SELECT id, name, abn_number, address, city, state, postcode, phone, email, website, logo_url, description, share_type, clinic_share, owner_share,created_at, updated_at FROM tbl_clinic WHERE deleted_at IS NULL`
+	query := `SELECT id, name, abn_number, address, city, state, postcode, phone, email, website, logo_url, description, share_type, clinic_share, owner_share, bas_cycle, require_mfa, created_at, updated_at FROM tbl_clinic WHERE deleted_at IS NULL`
 	var clinics []domain.Clinic
 	err := db.SelectContext(ctx, &clinics, query)
 	if err != nil {
@@ -60,7 +60,7 @@ func GetAllClinics(ctx context.Context, db *sqlx.DB) ([]domain.Clinic, error) {
 }
 
 func GetClinicByABNNumber(ctx context.Context, db *sqlx.DB, abnNumber string) (*domain.Clinic, error) {
-	query := `SELECT id, name, abn_number, address, city, state, postcode, phone, email, website, logo_url, description, share_type, clinic_share, owner_share,created_at, updated_at FROM tbl_clinic WHERE abn_number = $1 AND deleted_at IS NULL`
+	query := `SELECT id, name, abn_number, address, city, state, postcode, phone, email, website, logo_url, description, share_type, clinic_share, owner_share, bas_cycle, require_mfa, created_at, updated_at FROM tbl_clinic WHERE abn_number = $1 AND deleted_at IS NULL`
 	var clinic domain.Clinic
 	err := db.GetContext(ctx, &clinic, query, abnNumber)
 	if err != nil && err != sql.ErrNoRows {
@@ -68,3 +68,21 @@ func GetClinicByABNNumber(ctx context.Context, db *sqlx.DB, abnNumber string) (*
 	}
 	return &clinic, nil
 }
+
+// GetClinicLogoSource returns the logo URL the clinic's stored logo was downloaded from, or nil when
+// no logo has been imported.
+func GetClinicLogoSource(ctx context.Context, db sqlx.QueryerContext, clinicID uuid.UUID) (*string, error) {
+	query := `SELECT logo_source_url FROM tbl_clinic WHERE id = $1`
+	var source *string
+	err := sqlx.GetContext(ctx, db, &source, query, clinicID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, errors.New("failed to get clinic logo")
+	}
+	return source, nil
+}
+
+func UpdateClinicLogoSource(ctx context.Context, db sqlx.ExtContext, clinicID uuid.UUID, source *string) error {
+	query := `UPDATE tbl_clinic SET logo_source_url = $1 WHERE id = $2`
+	_, err := db.ExecContext(ctx, query, source, clinicID)
+	return err
+}
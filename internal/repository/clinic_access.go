@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// getOwningClinicID returns the clinic_id of a live row in table. ok is false when no such row exists.
+// table is never user supplied; callers pass one of the fixed table names below.
+func getOwningClinicID(ctx context.Context, db *sqlx.DB, table string, id uuid.UUID) (clinicID uuid.UUID, ok bool, err error) {
+	query := `SELECT clinic_id FROM ` + table + ` WHERE id = $1 AND deleted_at IS NULL`
+	err = db.GetContext(ctx, &clinicID, query, id)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, false, nil
+	}
+	if err != nil {
+		return uuid.Nil, false, errors.New("failed to resolve clinic")
+	}
+	return clinicID, true, nil
+}
+
+func ClinicExists(ctx context.Context, db *sqlx.DB, id uuid.UUID) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM tbl_clinic WHERE id = $1 AND deleted_at IS NULL)`
+	if err := db.GetContext(ctx, &exists, query, id); err != nil {
+		return false, errors.New("failed to get clinic")
+	}
+	return exists, nil
+}
+
+// IsSystemAdmin reports whether a live user holds the system administrator flag.
+func IsSystemAdmin(ctx context.Context, db *sqlx.DB, userID uuid.UUID) (bool, error) {
+	var admin bool
+	query := `SELECT EXISTS (SELECT 1 FROM tbl_user WHERE id = $1 AND is_system_admin AND deleted_at IS NULL)`
+	if err := db.GetContext(ctx, &admin, query, userID); err != nil {
+		return false, errors.New("failed to get user")
+	}
+	return admin, nil
+}
+
+func GetCustomFormClinicID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (uuid.UUID, bool, error) {
+	return getOwningClinicID(ctx, db, "tbl_custom_form", id)
+}
+
+func GetCustomFormEntryClinicID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (uuid.UUID, bool, error) {
+	return getOwningClinicID(ctx, db, "tbl_custom_form_entry", id)
+}
+
+func GetFinancialFormClinicID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (uuid.UUID, bool, error) {
+	return getOwningClinicID(ctx, db, "tbl_financial_form", id)
+}
+
+func GetExpenseTypeClinicID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (uuid.UUID, bool, error) {
+	return getOwningClinicID(ctx, db, "tbl_expense_type", id)
+}
+
+func GetExpenseCategoryClinicID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (uuid.UUID, bool, error) {
+	return getOwningClinicID(ctx, db, "tbl_expense_category", id)
+}
+
+func GetExpenseCategoryTypeClinicID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (uuid.UUID, bool, error) {
+	return getOwningClinicID(ctx, db, "tbl_expense_category_type", id)
+}
+
+func GetExpenseEntryClinicID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (uuid.UUID, bool, error) {
+	return getOwningClinicID(ctx, db, "tbl_expense_entry", id)
+}
+
+func GetUserClinicClinicID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (uuid.UUID, bool, error) {
+	return getOwningClinicID(ctx, db, "tbl_user_clinic", id)
+}
+
+// GetInvitationClinicID resolves any invitation, whatever its status, to its clinic.
+func GetInvitationClinicID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (uuid.UUID, bool, error) {
+	var clinicID uuid.UUID
+	err := db.GetContext(ctx, &clinicID, `SELECT clinic_id FROM tbl_clinic_invitation WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, false, nil
+	}
+	if err != nil {
+		return uuid.Nil, false, errors.New("failed to resolve clinic")
+	}
+	return clinicID, true, nil
+}
@@ -12,7 +12,7 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
-func CreateCustomForm(ctx context.Context, db *sqlx.DB, form *domain.CustomForm) error {
+func CreateCustomForm(ctx context.Context, db sqlx.ExtContext, form *domain.CustomForm) error {
 	query := `INSERT INTO tbl_custom_form (
 		id, clinic_id, name, description, calculation_method, form_type, status, fields,
 		default_payment_responsibility, service_facility_fee_percent, outwork_enabled, outwork_rate_percent, version, created_by, created_at, updated_at
@@ -20,7 +20,7 @@ func CreateCustomForm(ctx context.Context, db *sqlx.DB, form *domain.CustomForm)
 		:id, :clinic_id, :name, :description, :calculation_method, :form_type, :status, :fields,
 		:default_payment_responsibility, :service_facility_fee_percent, :outwork_enabled, :outwork_rate_percent, :version, :created_by, :created_at, :updated_at
 	)`
-	_, err := db.NamedExecContext(ctx, query, form)
+	_, err := sqlx.NamedExecContext(ctx, db, query, form)
 	return err
 }
 
@@ -61,16 +61,26 @@ func GetPublishedCustomFormsByClinicID(ctx context.Context, db *sqlx.DB, clinicI
 	return rows, nil
 }
 
-func UpdateCustomForm(ctx context.Context, db *sqlx.DB, form *domain.CustomForm) error {
+// UpdateCustomForm saves the form only while it is still at expectedVersion, reporting whether it
+// did, so two edits of the same version cannot both write the next one.
+func UpdateCustomForm(ctx context.Context, db sqlx.ExtContext, form *domain.CustomForm, expectedVersion int) (bool, error) {
 	query := `UPDATE tbl_custom_form SET name = :name, description = :description, fields = :fields,
 		default_payment_responsibility = :default_payment_responsibility, service_facility_fee_percent = :service_facility_fee_percent,
-		outwork_enabled = :outwork_enabled, outwork_rate_percent = :outwork_rate_percent,
-		updated_at = :updated_at WHERE id = :id AND deleted_at IS NULL`
-	_, err := db.NamedExecContext(ctx, query, form)
-	return err
+		outwork_enabled = :outwork_enabled, outwork_rate_percent = :outwork_rate_percent, version = :version,
+		updated_at = :updated_at WHERE id = :id AND version = :expected_version AND deleted_at IS NULL`
+	args := struct {
+		*domain.CustomForm
+		ExpectedVersion int `db:"expected_version"`
+	}{form, expectedVersion}
+	res, err := sqlx.NamedExecContext(ctx, db, query, args)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
 }
 
-func PublishCustomForm(ctx context.Context, db *sqlx.DB, id uuid.UUID) error {
+func PublishCustomForm(ctx context.Context, db sqlx.ExtContext, id uuid.UUID) error {
 	now := time.Now()
 	query := `UPDATE tbl_custom_form SET status = 'published', published_at = $1, updated_at = $1 WHERE id = $2 AND deleted_at IS NULL`
 	res, err := db.ExecContext(ctx, query, now, id)
@@ -84,7 +94,7 @@ func PublishCustomForm(ctx context.Context, db *sqlx.DB, id uuid.UUID) error {
 	return nil
 }
 
-func ArchiveCustomForm(ctx context.Context, db *sqlx.DB, id uuid.UUID) error {
+func ArchiveCustomForm(ctx context.Context, db sqlx.ExtContext, id uuid.UUID) error {
 	query := `UPDATE tbl_custom_form SET status = 'archived', updated_at = $1 WHERE id = $2 AND deleted_at IS NULL`
 	res, err := db.ExecContext(ctx, query, time.Now(), id)
 	if err != nil {
@@ -97,7 +107,7 @@ func ArchiveCustomForm(ctx context.Context, db *sqlx.DB, id uuid.UUID) error {
 	return nil
 }
 
-func DeleteCustomForm(ctx context.Context, db *sqlx.DB, id uuid.UUID) error {
+func DeleteCustomForm(ctx context.Context, db sqlx.ExtContext, id uuid.UUID) error {
 	query := `UPDATE tbl_custom_form SET deleted_at = $1 WHERE id = $2`
 	res, err := db.ExecContext(ctx, query, time.Now(), id)
 	if err != nil {
@@ -110,22 +120,66 @@ func DeleteCustomForm(ctx context.Context, db *sqlx.DB, id uuid.UUID) error {
 	return nil
 }
 
+// CreateCustomFormVersion stores a snapshot of a form definition. A snapshot already stored for the
+// version is an error rather than overwritten or skipped.
+func CreateCustomFormVersion(ctx context.Context, db sqlx.ExtContext, v *domain.CustomFormVersion) error {
+	query := `INSERT INTO tbl_custom_form_version (
+		id, form_id, version, name, calculation_method, form_type, fields,
+		default_payment_responsibility, service_facility_fee_percent, outwork_enabled, outwork_rate_percent, created_by, created_at
+	) VALUES (
+		:id, :form_id, :version, :name, :calculation_method, :form_type, :fields,
+		:default_payment_responsibility, :service_facility_fee_percent, :outwork_enabled, :outwork_rate_percent, :created_by, :created_at
+	)`
+	_, err := sqlx.NamedExecContext(ctx, db, query, v)
+	return err
+}
+
+// ErrFormVersionNotFound is returned for a form version that has no snapshot, as with entries captured
+// before forms were versioned.
+var ErrFormVersionNotFound = errors.New("form version not found")
+
+func GetCustomFormVersion(ctx context.Context, db *sqlx.DB, formID uuid.UUID, version int) (*domain.CustomFormVersion, error) {
+	query := `SELECT id, form_id, version, name, calculation_method, form_type, fields,
+		default_payment_responsibility, service_facility_fee_percent, outwork_enabled, outwork_rate_percent, created_by, created_at
+		FROM tbl_custom_form_version WHERE form_id = $1 AND version = $2`
+	var v domain.CustomFormVersion
+	err := db.GetContext(ctx, &v, query, formID, version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrFormVersionNotFound
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+func GetCustomFormVersions(ctx context.Context, db *sqlx.DB, formID uuid.UUID) ([]domain.CustomFormVersion, error) {
+	query := `SELECT id, form_id, version, name, calculation_method, form_type, fields,
+		default_payment_responsibility, service_facility_fee_percent, outwork_enabled, outwork_rate_percent, created_by, created_at
+		FROM tbl_custom_form_version WHERE form_id = $1 ORDER BY version DESC`
+	var rows []domain.CustomFormVersion
+	if err := db.SelectContext(ctx, &rows, query, formID); err != nil {
+		return nil, fmt.Errorf("failed to get form versions: %w", err)
+	}
+	return rows, nil
+}
+
 // CreateCustomFormEntry
-func CreateCustomFormEntry(ctx context.Context, db *sqlx.DB, entry *domain.CustomFormEntry) error {
+func CreateCustomFormEntry(ctx context.Context, db sqlx.ExtContext, entry *domain.CustomFormEntry) error {
 	query := `INSERT INTO tbl_custom_form_entry (
-		id, form_id, form_name, form_type, clinic_id, quarter_id, values, calculations, entry_date,
-		description, remarks, payment_responsibility, deductions, created_by, created_at, updated_at
+		id, form_id, form_name, form_type, form_version, clinic_id, quarter_id, values, calculations, entry_date,
+		description, remarks, payment_responsibility, deductions, corrects_entry_id, created_by, created_at, updated_at
 	) VALUES (
-		:id, :form_id, :form_name, :form_type, :clinic_id, :quarter_id, :values, :calculations, :entry_date,
-		:description, :remarks, :payment_responsibility, :deductions, :created_by, :created_at, :updated_at
+		:id, :form_id, :form_name, :form_type, :form_version, :clinic_id, :quarter_id, :values, :calculations, :entry_date,
+		:description, :remarks, :payment_responsibility, :deductions, :corrects_entry_id, :created_by, :created_at, :updated_at
 	)`
-	_, err := db.NamedExecContext(ctx, query, entry)
+	_, err := sqlx.NamedExecContext(ctx, db, query, entry)
 	return err
 }
 
 func GetCustomFormEntryByID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (*domain.CustomFormEntry, error) {
-	query := `SELECT id, form_id, form_name, form_type, clinic_id, quarter_id, values, calculations, entry_date,
-		description, remarks, payment_responsibility, deductions, created_by, created_at, updated_at, deleted_at
+	query := `SELECT id, form_id, form_name, form_type, form_version, clinic_id, quarter_id, values, calculations, entry_date,
+		description, remarks, payment_responsibility, deductions, corrects_entry_id, created_by, created_at, updated_at, deleted_at
 		FROM tbl_custom_form_entry WHERE id = $1 AND deleted_at IS NULL`
 	var entry domain.CustomFormEntry
 	err := db.GetContext(ctx, &entry, query, id)
@@ -138,47 +192,37 @@ func GetCustomFormEntryByID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (*do
 	return &entry, nil
 }
 
-func GetCustomFormEntriesByFormID(ctx context.Context, db *sqlx.DB, formID uuid.UUID) ([]domain.CustomFormEntry, error) {
-	query := `SELECT id, form_id, form_name, form_type, clinic_id, quarter_id, values, calculations, entry_date,
-		description, remarks, payment_responsibility, deductions, created_by, created_at, updated_at, deleted_at
-		FROM tbl_custom_form_entry WHERE form_id = $1 AND deleted_at IS NULL ORDER BY entry_date DESC, created_at DESC`
-	var rows []domain.CustomFormEntry
-	if err := db.SelectContext(ctx, &rows, query, formID); err != nil {
-		return nil, fmt.Errorf("failed to get entries: %w", err)
-	}
-	return rows, nil
-}
-
-func GetCustomFormEntriesByClinicID(ctx context.Context, db *sqlx.DB, clinicID uuid.UUID) ([]domain.CustomFormEntry, error) {
-	query := `SELECT id, form_id, form_name, form_type, clinic_id, quarter_id, values, calculations, entry_date,
-		description, remarks, payment_responsibility, deductions, created_by, created_at, updated_at, deleted_at
-		FROM tbl_custom_form_entry WHERE clinic_id = $1 AND deleted_at IS NULL ORDER BY entry_date DESC, created_at DESC`
+func GetCustomFormEntriesByQuarter(ctx context.Context, db *sqlx.DB, clinicID, quarterID uuid.UUID) ([]domain.CustomFormEntry, error) {
+	query := `SELECT id, form_id, form_name, form_type, form_version, clinic_id, quarter_id, values, calculations, entry_date,
+		description, remarks, payment_responsibility, deductions, corrects_entry_id, created_by, created_at, updated_at, deleted_at
+		FROM tbl_custom_form_entry WHERE clinic_id = $1 AND quarter_id = $2 AND deleted_at IS NULL ORDER BY entry_date DESC`
 	var rows []domain.CustomFormEntry
-	if err := db.SelectContext(ctx, &rows, query, clinicID); err != nil {
+	if err := db.SelectContext(ctx, &rows, query, clinicID, quarterID); err != nil {
 		return nil, fmt.Errorf("failed to get entries: %w", err)
 	}
 	return rows, nil
 }
 
-func GetCustomFormEntriesByQuarter(ctx context.Context, db *sqlx.DB, clinicID, quarterID uuid.UUID) ([]domain.CustomFormEntry, error) {
-	query := `SELECT id, form_id, form_name, form_type, clinic_id, quarter_id, values, calculations, entry_date,
-		description, remarks, payment_responsibility, deductions, created_by, created_at, updated_at, deleted_at
-		FROM tbl_custom_form_entry WHERE clinic_id = $1 AND quarter_id = $2 AND deleted_at IS NULL ORDER BY entry_date DESC`
+// GetCustomFormEntriesByDateRange returns a clinic's entries dated within [from, to] (inclusive).
+func GetCustomFormEntriesByDateRange(ctx context.Context, db *sqlx.DB, clinicID uuid.UUID, from, to time.Time) ([]domain.CustomFormEntry, error) {
+	query := `SELECT id, form_id, form_name, form_type, form_version, clinic_id, quarter_id, values, calculations, entry_date,
+		description, remarks, payment_responsibility, deductions, corrects_entry_id, created_by, created_at, updated_at, deleted_at
+		FROM tbl_custom_form_entry WHERE clinic_id = $1 AND entry_date BETWEEN $2 AND $3 AND deleted_at IS NULL ORDER BY entry_date, created_at`
 	var rows []domain.CustomFormEntry
-	if err := db.SelectContext(ctx, &rows, query, clinicID, quarterID); err != nil {
+	if err := db.SelectContext(ctx, &rows, query, clinicID, from, to); err != nil {
 		return nil, fmt.Errorf("failed to get entries: %w", err)
 	}
 	return rows, nil
 }
 
-func UpdateCustomFormEntry(ctx context.Context, db *sqlx.DB, entry *domain.CustomFormEntry) error {
+func UpdateCustomFormEntry(ctx context.Context, db sqlx.ExtContext, entry *domain.CustomFormEntry) error {
 	query := `UPDATE tbl_custom_form_entry SET values = :values, calculations = :calculations, updated_at = :updated_at
 		WHERE id = :id AND deleted_at IS NULL`
-	_, err := db.NamedExecContext(ctx, query, entry)
+	_, err := sqlx.NamedExecContext(ctx, db, query, entry)
 	return err
 }
 
-func DeleteCustomFormEntry(ctx context.Context, db *sqlx.DB, id uuid.UUID) error {
+func DeleteCustomFormEntry(ctx context.Context, db sqlx.ExtContext, id uuid.UUID) error {
 	query := `UPDATE tbl_custom_form_entry SET deleted_at = $1 WHERE id = $2`
 	res, err := db.ExecContext(ctx, query, time.Now(), id)
 	if err != nil {
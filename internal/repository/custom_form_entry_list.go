@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+const customFormEntryColumns = `id, form_id, form_name, form_type, form_version, clinic_id, quarter_id, values, calculations, entry_date,
+	description, remarks, payment_responsibility, deductions, corrects_entry_id, created_by, created_at, updated_at, deleted_at`
+
+// Calculated amounts of an entry, as stored by the calculation engine
+const (
+	entryBaseAmountExpr  = `COALESCE((calculations->>'totalBaseAmount')::numeric, 0)`
+	entryGSTAmountExpr   = `COALESCE((calculations->>'totalGSTAmount')::numeric, 0)`
+	entryTotalAmountExpr = `COALESCE((calculations->>'totalAmount')::numeric, 0)`
+	entrySearchExpr      = `to_tsvector('english', COALESCE(description, '') || ' ' || COALESCE(remarks, ''))`
+)
+
+// ListCustomFormEntries returns one page of the entries matching filter: at most filter.Limit entries
+// after filter.After, in the filter's order. Ties are broken by entry ID so pages never overlap.
+func ListCustomFormEntries(ctx context.Context, db *sqlx.DB, filter domain.CustomFormEntryFilter) ([]domain.CustomFormEntry, error) {
+	where, args := customFormEntryConditions(filter)
+
+	sortExpr, cast := `entry_date`, `date`
+	if filter.Sort == domain.EntrySortTotalAmount {
+		sortExpr, cast = entryTotalAmountExpr, `numeric`
+	}
+	dir, cmp := `DESC`, `<`
+	if filter.Order == domain.SortAsc {
+		dir, cmp = `ASC`, `>`
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.Value, filter.After.ID)
+		n := len(args)
+		where = append(where, `(`+sortExpr+`, id) `+cmp+` ($`+strconv.Itoa(n-1)+`::`+cast+`, $`+strconv.Itoa(n)+`)`)
+	}
+
+	query := `SELECT ` + customFormEntryColumns + ` FROM tbl_custom_form_entry WHERE ` + strings.Join(where, " AND ") +
+		` ORDER BY ` + sortExpr + ` ` + dir + `, id ` + dir
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += ` LIMIT $` + strconv.Itoa(len(args))
+	}
+
+	rows := []domain.CustomFormEntry{}
+	if err := db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, errors.New("failed to get entries")
+	}
+	return rows, nil
+}
+
+// GetCustomFormEntryTotals counts the entries matching filter and adds up their calculated amounts,
+// ignoring the page (Limit and After).
+func GetCustomFormEntryTotals(ctx context.Context, db *sqlx.DB, filter domain.CustomFormEntryFilter) (int, *domain.CustomFormEntryTotals, error) {
+	where, args := customFormEntryConditions(filter)
+	query := `SELECT COUNT(*) AS count,
+			COALESCE(SUM(` + entryBaseAmountExpr + `), 0) AS total_base_amount,
+			COALESCE(SUM(` + entryGSTAmountExpr + `), 0) AS total_gst_amount,
+			COALESCE(SUM(` + entryTotalAmountExpr + `), 0) AS total_amount
+		FROM tbl_custom_form_entry WHERE ` + strings.Join(where, " AND ")
+	var row struct {
+		Count int `db:"count"`
+		domain.CustomFormEntryTotals
+	}
+	if err := db.GetContext(ctx, &row, query, args...); err != nil {
+		return 0, nil, errors.New("failed to get entry totals")
+	}
+	return row.Count, &row.CustomFormEntryTotals, nil
+}
+
+// customFormEntryConditions turns the filter (other than its page) into WHERE conditions.
+func customFormEntryConditions(filter domain.CustomFormEntryFilter) ([]string, []interface{}) {
+	where := []string{`deleted_at IS NULL`}
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, strings.Replace(cond, "?", "$"+strconv.Itoa(len(args)), 1))
+	}
+	add("clinic_id = ?", filter.ClinicID)
+	if filter.FormID != nil {
+		add("form_id = ?", *filter.FormID)
+	}
+	if filter.From != nil {
+		add("entry_date >= ?::date", filter.From.Format("2006-01-02"))
+	}
+	if filter.To != nil {
+		add("entry_date <= ?::date", filter.To.Format("2006-01-02"))
+	}
+	if filter.FormType != "" {
+		add("form_type = ?", filter.FormType)
+	}
+	if filter.PaymentResponsibility != "" {
+		add("payment_responsibility = ?", filter.PaymentResponsibility)
+	}
+	if filter.CreatedBy != nil {
+		add("created_by = ?", *filter.CreatedBy)
+	}
+	if filter.MinAmount != nil {
+		add(entryTotalAmountExpr+" >= ?::numeric", filter.MinAmount.String())
+	}
+	if filter.MaxAmount != nil {
+		add(entryTotalAmountExpr+" <= ?::numeric", filter.MaxAmount.String())
+	}
+	if filter.Search != "" {
+		add(entrySearchExpr+" @@ websearch_to_tsquery('english', ?)", filter.Search)
+	}
+	return where, args
+}
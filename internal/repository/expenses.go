@@ -4,50 +4,51 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
 	"github.com/jmoiron/sqlx"
 )
 
-func CreateExpenseType(ctx context.Context, db *sqlx.DB, expenseType *domain.ExpenseType) error {
+func CreateExpenseType(ctx context.Context, db sqlx.ExtContext, expenseType *domain.ExpenseType) error {
 	// Use NULL for deleted_at/deleted_by - uuid.Nil violates deleted_by foreign key
 	query := `INSERT INTO tbl_expense_type (id, clinic_id, name, description, created_at, created_by, deleted_at, deleted_by)
 		VALUES (:id, :clinic_id, :name, :description, :created_at, :created_by, NULL, NULL)`
-	_, err := db.NamedExecContext(ctx, query, expenseType)
+	_, err := sqlx.NamedExecContext(ctx, db, query, expenseType)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func CreateExpenseCategory(ctx context.Context, db *sqlx.DB, expenseCategory *domain.ExpenseCategory) error {
+func CreateExpenseCategory(ctx context.Context, db sqlx.ExtContext, expenseCategory *domain.ExpenseCategory) error {
 	// Use NULL for deleted_at/deleted_by - uuid.Nil violates deleted_by foreign key
-	query := `INSERT INTO tbl_expense_category (id, clinic_id, name, description, created_at, created_by, deleted_at, deleted_by)
-		VALUES (:id, :clinic_id, :name, :description, :created_at, :created_by, NULL, NULL)`
-	_, err := db.NamedExecContext(ctx, query, expenseCategory)
+	query := `INSERT INTO tbl_expense_category (id, clinic_id, name, description, is_capital, created_at, created_by, deleted_at, deleted_by)
+		VALUES (:id, :clinic_id, :name, :description, :is_capital, :created_at, :created_by, NULL, NULL)`
+	_, err := sqlx.NamedExecContext(ctx, db, query, expenseCategory)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func CreateExpenseCategoryType(ctx context.Context, db *sqlx.DB, expenseCategoryType *domain.ExpenseCategoryType) error {
+func CreateExpenseCategoryType(ctx context.Context, db sqlx.ExtContext, expenseCategoryType *domain.ExpenseCategoryType) error {
 	// Use NULL for deleted_at/deleted_by - uuid.Nil violates deleted_by foreign key
 	query := `INSERT INTO tbl_expense_category_type (id, clinic_id, type_id, category_id, created_at, created_by, deleted_at, deleted_by)
 		VALUES (:id, :clinic_id, :type_id, :category_id, :created_at, :created_by, NULL, NULL)`
-	_, err := db.NamedExecContext(ctx, query, expenseCategoryType)
+	_, err := sqlx.NamedExecContext(ctx, db, query, expenseCategoryType)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func CreateExpenseEntry(ctx context.Context, db *sqlx.DB, expenseEntry *domain.ExpenseEntry) error {
+func CreateExpenseEntry(ctx context.Context, db sqlx.ExtContext, expenseEntry *domain.ExpenseEntry) error {
 	// Use NULL for deleted_at/deleted_by - uuid.Nil violates deleted_by foreign key
-	query := `INSERT INTO tbl_expense_entry (id, clinic_id, category_id, type_id, amount, gst_rate, is_gst_inclusive, expense_date, supplier_name, notes, created_at, created_by, deleted_at, deleted_by)
-		VALUES (:id, :clinic_id, :category_id, :type_id, :amount, :gst_rate, :is_gst_inclusive, :expense_date, :supplier_name, :notes, :created_at, :created_by, NULL, NULL)`
-	_, err := db.NamedExecContext(ctx, query, expenseEntry)
+	query := `INSERT INTO tbl_expense_entry (id, clinic_id, category_id, type_id, amount, gst_rate, is_gst_inclusive, expense_date, supplier_name, notes, corrects_entry_id, created_at, created_by, deleted_at, deleted_by)
+		VALUES (:id, :clinic_id, :category_id, :type_id, :amount, :gst_rate, :is_gst_inclusive, :expense_date, :supplier_name, :notes, :corrects_entry_id, :created_at, :created_by, NULL, NULL)`
+	_, err := sqlx.NamedExecContext(ctx, db, query, expenseEntry)
 	if err != nil {
 		return err
 	}
@@ -75,7 +76,7 @@ func GetExpenseTypeByID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (*domain
 }
 
 func GetExpenseCategoryByID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (*domain.ExpenseCategory, error) {
-	query := `SELECT id, clinic_id, name, description, created_at, created_by, deleted_at, deleted_by FROM tbl_expense_category WHERE id = $1 AND deleted_at IS NULL`
+	query := `SELECT id, clinic_id, name, description, is_capital, created_at, created_by, deleted_at, deleted_by FROM tbl_expense_category WHERE id = $1 AND deleted_at IS NULL`
 	var expenseCategory domain.ExpenseCategory
 	err := db.GetContext(ctx, &expenseCategory, query, id)
 	if err != nil && err != sql.ErrNoRows {
@@ -95,7 +96,7 @@ func GetExpenseCategoryTypeByID(ctx context.Context, db *sqlx.DB, id uuid.UUID)
 }
 
 func GetExpenseEntryByID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (*domain.ExpenseEntry, error) {
-	query := `SELECT id, clinic_id, category_id, type_id, amount, gst_rate, is_gst_inclusive, expense_date, supplier_name, notes, created_at, created_by, deleted_at, deleted_by FROM tbl_expense_entry WHERE id = $1 AND deleted_at IS NULL`
+	query := `SELECT id, clinic_id, category_id, type_id, amount, gst_rate, is_gst_inclusive, expense_date, supplier_name, notes, corrects_entry_id, created_at, created_by, deleted_at, deleted_by FROM tbl_expense_entry WHERE id = $1 AND deleted_at IS NULL`
 	var expenseEntry domain.ExpenseEntry
 	err := db.GetContext(ctx, &expenseEntry, query, id)
 	if err != nil && err != sql.ErrNoRows {
@@ -105,7 +106,7 @@ func GetExpenseEntryByID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (*domai
 }
 
 func GetExpenseEntriesByClinicID(ctx context.Context, db *sqlx.DB, clinicID uuid.UUID) ([]domain.ExpenseEntry, error) {
-	query := `SELECT id, clinic_id, category_id, type_id, amount, gst_rate, is_gst_inclusive, expense_date, supplier_name, notes, created_at, created_by, deleted_at, deleted_by FROM tbl_expense_entry WHERE clinic_id = $1 AND deleted_at IS NULL ORDER BY expense_date DESC`
+	query := `SELECT id, clinic_id, category_id, type_id, amount, gst_rate, is_gst_inclusive, expense_date, supplier_name, notes, corrects_entry_id, created_at, created_by, deleted_at, deleted_by FROM tbl_expense_entry WHERE clinic_id = $1 AND deleted_at IS NULL ORDER BY expense_date DESC`
 	var entries []domain.ExpenseEntry
 	err := db.SelectContext(ctx, &entries, query, clinicID)
 	if err != nil {
@@ -114,8 +115,19 @@ func GetExpenseEntriesByClinicID(ctx context.Context, db *sqlx.DB, clinicID uuid
 	return entries, nil
 }
 
+// GetExpenseEntriesByDateRange returns a clinic's expense entries dated within [from, to] (inclusive).
+func GetExpenseEntriesByDateRange(ctx context.Context, db *sqlx.DB, clinicID uuid.UUID, from, to time.Time) ([]domain.ExpenseEntry, error) {
+	query := `SELECT id, clinic_id, category_id, type_id, amount, gst_rate, is_gst_inclusive, expense_date, supplier_name, notes, corrects_entry_id, created_at, created_by, deleted_at, deleted_by FROM tbl_expense_entry WHERE clinic_id = $1 AND expense_date BETWEEN $2 AND $3 AND deleted_at IS NULL ORDER BY expense_date`
+	var entries []domain.ExpenseEntry
+	err := db.SelectContext(ctx, &entries, query, clinicID, from, to)
+	if err != nil {
+		return nil, errors.New("failed to list expense entries for period")
+	}
+	return entries, nil
+}
+
 func GetExpenseCategoriesByClinicID(ctx context.Context, db *sqlx.DB, clinicID uuid.UUID) ([]domain.ExpenseCategory, error) {
-	query := `SELECT id, clinic_id, name, description, created_at, created_by, deleted_at, deleted_by FROM tbl_expense_category WHERE clinic_id = $1 AND deleted_at IS NULL ORDER BY name`
+	query := `SELECT id, clinic_id, name, description, is_capital, created_at, created_by, deleted_at, deleted_by FROM tbl_expense_category WHERE clinic_id = $1 AND deleted_at IS NULL ORDER BY name`
 	var categories []domain.ExpenseCategory
 	err := db.SelectContext(ctx, &categories, query, clinicID)
 	if err != nil {
@@ -124,9 +136,9 @@ func GetExpenseCategoriesByClinicID(ctx context.Context, db *sqlx.DB, clinicID u
 	return categories, nil
 }
 
-func UpdateExpenseCategory(ctx context.Context, db *sqlx.DB, category *domain.ExpenseCategory) error {
-	query := `UPDATE tbl_expense_category SET name = :name, description = :description WHERE id = :id AND deleted_at IS NULL`
-	result, err := db.NamedExecContext(ctx, query, category)
+func UpdateExpenseCategory(ctx context.Context, db sqlx.ExtContext, category *domain.ExpenseCategory) error {
+	query := `UPDATE tbl_expense_category SET name = :name, description = :description, is_capital = :is_capital WHERE id = :id AND deleted_at IS NULL`
+	result, err := sqlx.NamedExecContext(ctx, db, query, category)
 	if err != nil {
 		return err
 	}
@@ -137,7 +149,7 @@ func UpdateExpenseCategory(ctx context.Context, db *sqlx.DB, category *domain.Ex
 	return nil
 }
 
-func DeleteExpenseCategory(ctx context.Context, db *sqlx.DB, id uuid.UUID, deletedBy uuid.UUID) error {
+func DeleteExpenseCategory(ctx context.Context, db sqlx.ExtContext, id uuid.UUID, deletedBy uuid.UUID) error {
 	query := `UPDATE tbl_expense_category SET deleted_at = CURRENT_TIMESTAMP, deleted_by = $1 WHERE id = $2 AND deleted_at IS NULL`
 	result, err := db.ExecContext(ctx, query, deletedBy, id)
 	if err != nil {
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const clinicGSTRateColumns = `id, clinic_id, rate, effective_from, created_by, created_at`
+
+// SetClinicGSTRate saves a rate, replacing the rate already set for the same clinic and date.
+func SetClinicGSTRate(ctx context.Context, db sqlx.ExtContext, r *domain.ClinicGSTRate) error {
+	query := `INSERT INTO tbl_clinic_gst_rate (` + clinicGSTRateColumns + `)
+		VALUES (:id, :clinic_id, :rate, :effective_from, :created_by, :created_at)
+		ON CONFLICT (clinic_id, effective_from) DO UPDATE
+		SET rate = EXCLUDED.rate, created_by = EXCLUDED.created_by, created_at = EXCLUDED.created_at
+		RETURNING id`
+	rows, err := sqlx.NamedQueryContext(ctx, db, query, r)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		return rows.Scan(&r.ID)
+	}
+	return rows.Err()
+}
+
+// GetClinicGSTRates returns a clinic's rate history, newest first.
+func GetClinicGSTRates(ctx context.Context, db *sqlx.DB, clinicID uuid.UUID) ([]domain.ClinicGSTRate, error) {
+	query := `SELECT ` + clinicGSTRateColumns + ` FROM tbl_clinic_gst_rate WHERE clinic_id = $1 ORDER BY effective_from DESC`
+	rates := []domain.ClinicGSTRate{}
+	if err := db.SelectContext(ctx, &rates, query, clinicID); err != nil {
+		return nil, errors.New("failed to get GST rates")
+	}
+	return rates, nil
+}
+
+// GetClinicGSTRateForDate returns the rate in force on date, or nil when the clinic has none.
+func GetClinicGSTRateForDate(ctx context.Context, db sqlx.QueryerContext, clinicID uuid.UUID, date time.Time) (*domain.ClinicGSTRate, error) {
+	query := `SELECT ` + clinicGSTRateColumns + ` FROM tbl_clinic_gst_rate
+		WHERE clinic_id = $1 AND effective_from <= $2::date
+		ORDER BY effective_from DESC LIMIT 1`
+	var r domain.ClinicGSTRate
+	err := sqlx.GetContext(ctx, db, &r, query, clinicID, date)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to get GST rate")
+	}
+	return &r, nil
+}
+
+// GetAccountTaxCodes returns the tax codes of the given live accounts; unknown IDs are skipped.
+func GetAccountTaxCodes(ctx context.Context, db sqlx.QueryerContext, accountIDs []uuid.UUID) ([]domain.AccountTaxCode, error) {
+	codes := []domain.AccountTaxCode{}
+	if len(accountIDs) == 0 {
+		return codes, nil
+	}
+	ids := make([]string, len(accountIDs))
+	for i, id := range accountIDs {
+		ids[i] = id.String()
+	}
+	query := `SELECT a.id AS account_id, t.name, t.rate, t.treatment
+		FROM tbl_account a
+		JOIN tbl_account_tax t ON t.id = a.account_tax_id
+		WHERE a.id = ANY($1::uuid[]) AND a.deleted_at IS NULL`
+	if err := sqlx.SelectContext(ctx, db, &codes, query, pq.Array(ids)); err != nil {
+		return nil, errors.New("failed to get account tax codes")
+	}
+	return codes, nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+const invitationColumns = `id, clinic_id, email, role, status, token_hash, invited_by, accepted_by, expires_at, responded_at, created_at, updated_at`
+
+func CreateInvitation(ctx context.Context, db sqlx.ExtContext, inv *domain.ClinicInvitation) error {
+	query := `INSERT INTO tbl_clinic_invitation (` + invitationColumns + `)
+		VALUES (:id, :clinic_id, :email, :role, :status, :token_hash, :invited_by, :accepted_by, :expires_at, :responded_at, :created_at, :updated_at)`
+	_, err := sqlx.NamedExecContext(ctx, db, query, inv)
+	return err
+}
+
+func GetInvitationByID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (*domain.ClinicInvitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM tbl_clinic_invitation WHERE id = $1`
+	var inv domain.ClinicInvitation
+	err := db.GetContext(ctx, &inv, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("invitation not found")
+		}
+		return nil, errors.New("failed to get invitation")
+	}
+	return &inv, nil
+}
+
+// GetPendingInvitation returns the pending invitation for an email at a clinic, or nil when there is none.
+func GetPendingInvitation(ctx context.Context, db *sqlx.DB, clinicID uuid.UUID, email string) (*domain.ClinicInvitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM tbl_clinic_invitation
+		WHERE clinic_id = $1 AND LOWER(email) = LOWER($2) AND status = 'pending'`
+	var inv domain.ClinicInvitation
+	err := db.GetContext(ctx, &inv, query, clinicID, email)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to get invitation")
+	}
+	return &inv, nil
+}
+
+func GetInvitationsByClinicID(ctx context.Context, db *sqlx.DB, clinicID uuid.UUID) ([]domain.ClinicInvitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM tbl_clinic_invitation WHERE clinic_id = $1 ORDER BY created_at DESC`
+	invitations := []domain.ClinicInvitation{}
+	if err := db.SelectContext(ctx, &invitations, query, clinicID); err != nil {
+		return nil, errors.New("failed to get invitations")
+	}
+	return invitations, nil
+}
+
+// UpdateInvitation persists status, token and response changes. Only pending invitations can change,
+// so two concurrent responses cannot both succeed.
+func UpdateInvitation(ctx context.Context, db sqlx.ExtContext, inv *domain.ClinicInvitation) error {
+	query := `UPDATE tbl_clinic_invitation SET status = :status, token_hash = :token_hash, accepted_by = :accepted_by,
+		expires_at = :expires_at, responded_at = :responded_at, updated_at = :updated_at
+		WHERE id = :id AND status = 'pending'`
+	result, err := sqlx.NamedExecContext(ctx, db, query, inv)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("invitation is no longer pending")
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+const loginLockoutColumns = `l.id, l.scope, l.subject, l.user_id, l.ip_address, l.failures, l.locked_until, l.cleared_at, l.cleared_by, l.created_at`
+
+func CreateLoginLockout(ctx context.Context, db sqlx.ExtContext, lockout *domain.LoginLockout) error {
+	query := `INSERT INTO tbl_login_lockout (id, scope, subject, user_id, ip_address, failures, locked_until, created_at)
+		VALUES (:id, :scope, :subject, :user_id, :ip_address, :failures, :locked_until, :created_at)`
+	_, err := sqlx.NamedExecContext(ctx, db, query, lockout)
+	return err
+}
+
+// GetClinicLoginLockouts returns the account lockouts of a clinic's current members, newest first.
+func GetClinicLoginLockouts(ctx context.Context, db *sqlx.DB, clinicID uuid.UUID, limit int) ([]domain.LoginLockout, error) {
+	query := `SELECT ` + loginLockoutColumns + ` FROM tbl_login_lockout l
+		INNER JOIN tbl_user_clinic uc ON uc.user_id = l.user_id AND uc.deleted_at IS NULL
+		WHERE uc.clinic_id = $1 AND l.scope = 'account'
+		ORDER BY l.created_at DESC LIMIT $2`
+	lockouts := []domain.LoginLockout{}
+	if err := db.SelectContext(ctx, &lockouts, query, clinicID, limit); err != nil {
+		return nil, errors.New("failed to list login lockouts")
+	}
+	return lockouts, nil
+}
+
+// ClearUserLoginLockouts marks the user's uncleared account lockouts as cleared by clearedBy and
+// returns them as they were before clearing.
+func ClearUserLoginLockouts(ctx context.Context, db sqlx.ExtContext, userID, clearedBy uuid.UUID) ([]domain.LoginLockout, error) {
+	lockouts := []domain.LoginLockout{}
+	err := sqlx.SelectContext(ctx, db, &lockouts, `SELECT `+loginLockoutColumns+` FROM tbl_login_lockout l
+		WHERE l.user_id = $1 AND l.scope = 'account' AND l.cleared_at IS NULL FOR UPDATE`, userID)
+	if err != nil {
+		return nil, errors.New("failed to get login lockouts")
+	}
+	if len(lockouts) == 0 {
+		return lockouts, nil
+	}
+	_, err = db.ExecContext(ctx, `UPDATE tbl_login_lockout SET cleared_at = $1, cleared_by = $2
+		WHERE user_id = $3 AND scope = 'account' AND cleared_at IS NULL`, time.Now(), clearedBy, userID)
+	if err != nil {
+		return nil, err
+	}
+	return lockouts, nil
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+// GetUserMFA returns the user's TOTP enrolment, or nil when they have none.
+func GetUserMFA(ctx context.Context, db *sqlx.DB, userID uuid.UUID) (*domain.UserMFA, error) {
+	query := `SELECT user_id, secret, enabled_at, last_used_step, created_at, updated_at FROM tbl_user_mfa WHERE user_id = $1`
+	var m domain.UserMFA
+	err := db.GetContext(ctx, &m, query, userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to get two-factor settings")
+	}
+	return &m, nil
+}
+
+// StartUserMFAEnrollment stores a new, not yet enabled secret, replacing any pending one.
+func StartUserMFAEnrollment(ctx context.Context, db *sqlx.DB, userID uuid.UUID, secret string) error {
+	now := time.Now()
+	_, err := db.ExecContext(ctx, `INSERT INTO tbl_user_mfa (user_id, secret, created_at, updated_at)
+		VALUES ($1, $2, $3, $3)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, enabled_at = NULL, last_used_step = 0, updated_at = EXCLUDED.updated_at`,
+		userID, secret, now)
+	return err
+}
+
+func EnableUserMFA(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, step int64) error {
+	now := time.Now()
+	_, err := db.ExecContext(ctx, `UPDATE tbl_user_mfa SET enabled_at = $1, last_used_step = $2, updated_at = $1 WHERE user_id = $3`,
+		now, step, userID)
+	return err
+}
+
+// AdvanceMFAStep records a step as used. It reports false when that step, or a later one, was
+// already used, so a code cannot be replayed.
+func AdvanceMFAStep(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, step int64) (bool, error) {
+	res, err := db.ExecContext(ctx, `UPDATE tbl_user_mfa SET last_used_step = $1, updated_at = $2
+		WHERE user_id = $3 AND last_used_step < $1`, step, time.Now(), userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// DeleteUserMFA removes the user's enrolment and recovery codes.
+func DeleteUserMFA(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM tbl_mfa_recovery_code WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, `DELETE FROM tbl_user_mfa WHERE user_id = $1`, userID)
+	return err
+}
+
+// ReplaceRecoveryCodes discards the user's recovery codes and stores the given hashes.
+func ReplaceRecoveryCodes(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, hashes []string) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM tbl_mfa_recovery_code WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, h := range hashes {
+		if _, err := db.ExecContext(ctx, `INSERT INTO tbl_mfa_recovery_code (id, user_id, code_hash, created_at) VALUES ($1, $2, $3, $4)`,
+			uuid.New(), userID, h, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConsumeRecoveryCode marks an unused recovery code as used, reporting whether one matched.
+func ConsumeRecoveryCode(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, hash string) (bool, error) {
+	res, err := db.ExecContext(ctx, `UPDATE tbl_mfa_recovery_code SET used_at = $1
+		WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL`, time.Now(), userID, hash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func CountRecoveryCodes(ctx context.Context, db *sqlx.DB, userID uuid.UUID) (int, error) {
+	var n int
+	err := db.GetContext(ctx, &n, `SELECT COUNT(*) FROM tbl_mfa_recovery_code WHERE user_id = $1 AND used_at IS NULL`, userID)
+	return n, err
+}
+
+// ClinicMFABlocksUser reports whether the clinic requires two-factor authentication and the user
+// has not enabled it.
+func ClinicMFABlocksUser(ctx context.Context, db *sqlx.DB, clinicID, userID uuid.UUID) (bool, error) {
+	var blocked bool
+	err := db.GetContext(ctx, &blocked, `SELECT c.require_mfa AND NOT EXISTS (
+			SELECT 1 FROM tbl_user_mfa m WHERE m.user_id = $2 AND m.enabled_at IS NOT NULL)
+		FROM tbl_clinic c WHERE c.id = $1`, clinicID, userID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.New("failed to check clinic two-factor requirement")
+	}
+	return blocked, nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -43,21 +44,73 @@ func UpdateOrCreateOAuthProvider(ctx context.Context, db *sqlx.DB, oauthProvider
 	return false, nil
 }
 
-func CreateOAuthProvider(ctx context.Context, db *sqlx.DB, oauthProvider *domain.OAuthProvider) error {
+func CreateOAuthProvider(ctx context.Context, db sqlx.ExtContext, oauthProvider *domain.OAuthProvider) error {
 	query := `INSERT INTO tbl_auth_provider (id, user_id, provider, provider_user_id, provider_email, access_token, refresh_token, token_expires_at, created_at, updated_at)
 		VALUES (:id, :user_id, :provider, :provider_user_id, :provider_email, :access_token, :refresh_token, :token_expires_at, :created_at, :updated_at)`
 
-	_, err := db.NamedExecContext(ctx, query, oauthProvider)
+	_, err := sqlx.NamedExecContext(ctx, db, query, oauthProvider)
 	return err
 }
 
+// GetOAuthProvider returns the link for a provider identity, or nil when it is not linked.
 func GetOAuthProvider(ctx context.Context, db *sqlx.DB, provider string, providerUserId string) (*domain.OAuthProvider, error) {
 	var oauthProvider domain.OAuthProvider
 	err := db.GetContext(ctx, &oauthProvider,
 		"SELECT id, user_id, provider, provider_user_id, provider_email, access_token, refresh_token, token_expires_at, created_at, updated_at FROM tbl_auth_provider WHERE provider = $1 AND provider_user_id = $2 AND deleted_at IS NULL",
 		provider, providerUserId)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &oauthProvider, nil
 }
+
+// GetUserOAuthIdentities lists the providers linked to a user.
+func GetUserOAuthIdentities(ctx context.Context, db *sqlx.DB, userID uuid.UUID) ([]domain.OAuthIdentity, error) {
+	identities := []domain.OAuthIdentity{}
+	err := db.SelectContext(ctx, &identities, `SELECT provider, COALESCE(provider_email, '') AS provider_email, created_at
+		FROM tbl_auth_provider WHERE user_id = $1 AND deleted_at IS NULL ORDER BY provider`, userID)
+	if err != nil {
+		return nil, errors.New("failed to list linked providers")
+	}
+	return identities, nil
+}
+
+// DeleteUserOAuthProvider unlinks a provider from a user, reporting whether one was linked.
+func DeleteUserOAuthProvider(ctx context.Context, db *sqlx.DB, userID uuid.UUID, provider string) (bool, error) {
+	res, err := db.ExecContext(ctx, `UPDATE tbl_auth_provider SET deleted_at = $1, access_token = NULL, refresh_token = NULL
+		WHERE user_id = $2 AND provider = $3 AND deleted_at IS NULL`, time.Now(), userID, provider)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// CreateOAuthState stores an authorisation in flight, discarding any that have expired.
+func CreateOAuthState(ctx context.Context, db *sqlx.DB, state *domain.OAuthState) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM tbl_oauth_state WHERE expires_at <= $1`, time.Now()); err != nil {
+		return err
+	}
+	query := `INSERT INTO tbl_oauth_state (state_hash, provider, code_verifier, nonce, user_id, expires_at, created_at)
+		VALUES (:state_hash, :provider, :code_verifier, :nonce, :user_id, :expires_at, :created_at)`
+	_, err := db.NamedExecContext(ctx, query, state)
+	return err
+}
+
+// ConsumeOAuthState deletes and returns the unexpired authorisation for a state hash, so each state
+// is redeemed at most once. It returns nil when there is none.
+func ConsumeOAuthState(ctx context.Context, db *sqlx.DB, stateHash string) (*domain.OAuthState, error) {
+	var state domain.OAuthState
+	err := db.GetContext(ctx, &state, `DELETE FROM tbl_oauth_state WHERE state_hash = $1 AND expires_at > $2
+		RETURNING state_hash, provider, code_verifier, nonce, user_id, expires_at, created_at`, stateHash, time.Now())
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to get oauth state")
+	}
+	return &state, nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+const pdfTemplateColumns = `id, clinic_id, name, config, is_default, logo_key, logo_content_type, created_by, created_at, updated_at, deleted_at`
+
+func CreatePDFTemplate(ctx context.Context, db sqlx.ExtContext, t *domain.PDFTemplate) error {
+	query := `INSERT INTO tbl_pdf_template (id, clinic_id, name, config, is_default, created_by, created_at, updated_at)
+		VALUES (:id, :clinic_id, :name, :config, :is_default, :created_by, :created_at, :updated_at)`
+	_, err := sqlx.NamedExecContext(ctx, db, query, t)
+	return err
+}
+
+// GetPDFTemplate returns a clinic's template, or nil when it has none with that ID.
+func GetPDFTemplate(ctx context.Context, db *sqlx.DB, clinicID, id uuid.UUID) (*domain.PDFTemplate, error) {
+	query := `SELECT ` + pdfTemplateColumns + ` FROM tbl_pdf_template WHERE clinic_id = $1 AND id = $2 AND deleted_at IS NULL`
+	var t domain.PDFTemplate
+	err := db.GetContext(ctx, &t, query, clinicID, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to get pdf template")
+	}
+	return &t, nil
+}
+
+// GetDefaultPDFTemplate returns the clinic's default template, or nil when none is marked.
+func GetDefaultPDFTemplate(ctx context.Context, db *sqlx.DB, clinicID uuid.UUID) (*domain.PDFTemplate, error) {
+	query := `SELECT ` + pdfTemplateColumns + ` FROM tbl_pdf_template WHERE clinic_id = $1 AND is_default AND deleted_at IS NULL`
+	var t domain.PDFTemplate
+	err := db.GetContext(ctx, &t, query, clinicID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to get default pdf template")
+	}
+	return &t, nil
+}
+
+func GetPDFTemplatesByClinicID(ctx context.Context, db *sqlx.DB, clinicID uuid.UUID) ([]domain.PDFTemplate, error) {
+	query := `SELECT ` + pdfTemplateColumns + ` FROM tbl_pdf_template WHERE clinic_id = $1 AND deleted_at IS NULL ORDER BY is_default DESC, name`
+	templates := []domain.PDFTemplate{}
+	if err := db.SelectContext(ctx, &templates, query, clinicID); err != nil {
+		return nil, errors.New("failed to list pdf templates")
+	}
+	return templates, nil
+}
+
+func UpdatePDFTemplate(ctx context.Context, db sqlx.ExtContext, t *domain.PDFTemplate) error {
+	query := `UPDATE tbl_pdf_template SET name = :name, config = :config, updated_at = :updated_at
+		WHERE id = :id AND deleted_at IS NULL`
+	_, err := sqlx.NamedExecContext(ctx, db, query, t)
+	return err
+}
+
+func UpdatePDFTemplateLogo(ctx context.Context, db *sqlx.DB, id uuid.UUID, logoKey, contentType *string) error {
+	query := `UPDATE tbl_pdf_template SET logo_key = $1, logo_content_type = $2, updated_at = $3 WHERE id = $4 AND deleted_at IS NULL`
+	_, err := db.ExecContext(ctx, query, logoKey, contentType, time.Now(), id)
+	return err
+}
+
+// SetDefaultPDFTemplate makes id the clinic's only default template.
+func SetDefaultPDFTemplate(ctx context.Context, db sqlx.ExtContext, clinicID, id uuid.UUID) error {
+	if _, err := db.ExecContext(ctx, `UPDATE tbl_pdf_template SET is_default = FALSE
+		WHERE clinic_id = $1 AND is_default AND id <> $2 AND deleted_at IS NULL`, clinicID, id); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, `UPDATE tbl_pdf_template SET is_default = TRUE, updated_at = $1
+		WHERE clinic_id = $2 AND id = $3 AND deleted_at IS NULL`, time.Now(), clinicID, id)
+	return err
+}
+
+func DeletePDFTemplate(ctx context.Context, db *sqlx.DB, id uuid.UUID) error {
+	query := `UPDATE tbl_pdf_template SET deleted_at = $1, is_default = FALSE WHERE id = $2 AND deleted_at IS NULL`
+	_, err := db.ExecContext(ctx, query, time.Now(), id)
+	return err
+}
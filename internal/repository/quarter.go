@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"time"
 
@@ -10,20 +11,30 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
-func CreateQuarter(ctx context.Context, db *sqlx.DB, quart *domain.Quarter) error {
-	query := `INSERT INTO tbl_quarter (id, name, start_date, end_date, created_at, updated_at)
-		VALUES (:id, :name, :start_date, :start_date, :created_at, :updated_at)`
+const quarterColumns = `q.id, q.clinic_id, q.name, q.period_type, q.financial_year, q.start_date, q.end_date, q.created_at, q.updated_at, q.deleted_at`
+
+// clinicPeriodScope restricts q to the periods a clinic reports against: its own when it has generated
+// any, otherwise the shared ones. The clinic ID is always bound to $1.
+const clinicPeriodScope = `(q.clinic_id = $1 OR (q.clinic_id IS NULL AND NOT EXISTS (
+		SELECT 1 FROM tbl_quarter own WHERE own.clinic_id = $1 AND own.deleted_at IS NULL)))`
+
+func CreateQuarter(ctx context.Context, db sqlx.ExtContext, quart *domain.Quarter) error {
+	query := `INSERT INTO tbl_quarter (id, clinic_id, name, period_type, financial_year, start_date, end_date, created_at, updated_at)
+		VALUES (:id, :clinic_id, :name, :period_type, :financial_year, :start_date, :end_date, :created_at, :updated_at)`
 
 	args := map[string]interface{}{
-		"id":         quart.ID,
-		"name":       quart.Name,
-		"start_date": quart.StartDate,
-		"end_date":   quart.EndDate,
-		"created_at": quart.CreatedAt,
-		"updated_at": quart.UpdatedAt,
+		"id":             quart.ID,
+		"clinic_id":      quart.ClinicID,
+		"name":           quart.Name,
+		"period_type":    quart.PeriodType,
+		"financial_year": quart.FinancialYear,
+		"start_date":     quart.StartDate,
+		"end_date":       quart.EndDate,
+		"created_at":     quart.CreatedAt,
+		"updated_at":     quart.UpdatedAt,
 	}
 
-	_, err := db.NamedExecContext(ctx, query, args)
+	_, err := sqlx.NamedExecContext(ctx, db, query, args)
 	if err != nil {
 		return err
 	}
@@ -31,16 +42,18 @@ func CreateQuarter(ctx context.Context, db *sqlx.DB, quart *domain.Quarter) erro
 }
 
 func UpdateQuarter(ctx context.Context, db *sqlx.DB, quart *domain.Quarter) error {
-	query := `UPDATE tbl_quarter SET name = :name, start_date = :start_date, end_date = :end_date, 
-		created_at = :created_at, updated_at = :updated_at WHERE id = :id`
+	query := `UPDATE tbl_quarter SET name = :name, period_type = :period_type, financial_year = :financial_year,
+		start_date = :start_date, end_date = :end_date, created_at = :created_at, updated_at = :updated_at WHERE id = :id`
 
 	args := map[string]interface{}{
-		"id":         quart.ID,
-		"name":       quart.Name,
-		"start_date": quart.StartDate,
-		"end_date":   quart.EndDate,
-		"created_at": quart.CreatedAt,
-		"updated_at": quart.UpdatedAt,
+		"id":             quart.ID,
+		"name":           quart.Name,
+		"period_type":    quart.PeriodType,
+		"financial_year": quart.FinancialYear,
+		"start_date":     quart.StartDate,
+		"end_date":       quart.EndDate,
+		"created_at":     quart.CreatedAt,
+		"updated_at":     quart.UpdatedAt,
 	}
 
 	_, err := db.NamedExecContext(ctx, query, args)
@@ -59,54 +72,80 @@ func DeleteQuarter(ctx context.Context, db *sqlx.DB, id uuid.UUID) error {
 	return nil
 }
 
+// ListQuarter returns the shared periods (those not generated for a particular clinic).
 func ListQuarter(ctx context.Context, db *sqlx.DB) ([]domain.Quarter, error) {
-	type formRow struct {
-		ID        uuid.UUID `db:"id"`
-		Name      string    `db:"name"`
-		StartDate time.Time `db:"start_date"`
-		EndDate   time.Time `db:"end_date"`
-
-		CreatedAt time.Time  `db:"created_at"`
-		UpdatedAt *time.Time `db:"updated_at"`
-		DeletedAt *time.Time `db:"deleted_at"`
-	}
+	query := `SELECT ` + quarterColumns + `
+		FROM tbl_quarter q WHERE q.clinic_id IS NULL AND q.deleted_at IS NULL ORDER BY q.start_date DESC`
 
-	query := `SELECT id, name, start_date, end_date, created_at, updated_at, deleted_at
-		FROM tbl_quarter WHERE deleted_at IS NULL ORDER BY created_at DESC`
-
-	var rows []formRow
-	err := db.SelectContext(ctx, &rows, query)
+	forms := []domain.Quarter{}
+	err := db.SelectContext(ctx, &forms, query)
 	if err != nil {
-		return nil, errors.New("failed to get financial forms")
-	}
-
-	forms := make([]domain.Quarter, len(rows))
-	for i, row := range rows {
-		forms[i] = domain.Quarter{
-			ID:        row.ID,
-			Name:      row.Name,
-			StartDate: row.StartDate,
-			EndDate:   row.EndDate,
-			CreatedAt: row.CreatedAt,
-			UpdatedAt: row.UpdatedAt,
-			DeletedAt: row.DeletedAt,
-		}
+		return nil, errors.New("failed to get financial quarters")
 	}
-
 	return forms, nil
 }
 
-func GetQuarterByID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (domain.Quarter, error) {
-	query := `
-		SELECT id, name, start_date, end_date, created_at, updated_at, deleted_at
-		FROM tbl_quarter
-		WHERE id = $1 AND deleted_at IS NULL
-	`
+func GetQuarterByID(ctx context.Context, db sqlx.QueryerContext, id uuid.UUID) (domain.Quarter, error) {
+	query := `SELECT ` + quarterColumns + ` FROM tbl_quarter q WHERE q.id = $1 AND q.deleted_at IS NULL`
 
 	var quart domain.Quarter
-	if err := db.GetContext(ctx, &quart, query, id); err != nil {
+	if err := sqlx.GetContext(ctx, db, &quart, query, id); err != nil {
 		return domain.Quarter{}, err
 	}
 
 	return quart, nil
 }
+
+// ListClinicPeriods returns the periods a clinic reports against, oldest first.
+func ListClinicPeriods(ctx context.Context, db *sqlx.DB, clinicID uuid.UUID) ([]domain.Quarter, error) {
+	query := `SELECT ` + quarterColumns + ` FROM tbl_quarter q
+		WHERE ` + clinicPeriodScope + ` AND q.deleted_at IS NULL ORDER BY q.start_date`
+	periods := []domain.Quarter{}
+	if err := db.SelectContext(ctx, &periods, query, clinicID); err != nil {
+		return nil, errors.New("failed to get clinic periods")
+	}
+	return periods, nil
+}
+
+// GetPeriodsInScope returns the live periods sharing a scope: one clinic's own periods, or the shared
+// ones when clinicID is nil.
+func GetPeriodsInScope(ctx context.Context, db sqlx.QueryerContext, clinicID *uuid.UUID) ([]domain.Quarter, error) {
+	query := `SELECT ` + quarterColumns + ` FROM tbl_quarter q
+		WHERE q.clinic_id IS NOT DISTINCT FROM $1 AND q.deleted_at IS NULL ORDER BY q.start_date`
+	periods := []domain.Quarter{}
+	if err := sqlx.SelectContext(ctx, db, &periods, query, clinicID); err != nil {
+		return nil, errors.New("failed to get periods")
+	}
+	return periods, nil
+}
+
+// GetClinicPeriodByID returns a period the clinic reports against, or nil when id is not one of them.
+func GetClinicPeriodByID(ctx context.Context, db sqlx.QueryerContext, clinicID, id uuid.UUID) (*domain.Quarter, error) {
+	query := `SELECT ` + quarterColumns + ` FROM tbl_quarter q
+		WHERE ` + clinicPeriodScope + ` AND q.id = $2 AND q.deleted_at IS NULL`
+	var quart domain.Quarter
+	err := sqlx.GetContext(ctx, db, &quart, query, clinicID, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to get period")
+	}
+	return &quart, nil
+}
+
+// GetPeriodForDate returns the clinic period containing date, or nil when no period covers it.
+func GetPeriodForDate(ctx context.Context, db sqlx.QueryerContext, clinicID uuid.UUID, date time.Time) (*domain.Quarter, error) {
+	query := `SELECT ` + quarterColumns + ` FROM tbl_quarter q
+		WHERE ` + clinicPeriodScope + ` AND q.deleted_at IS NULL AND $2::date BETWEEN q.start_date AND q.end_date
+		ORDER BY q.start_date LIMIT 1`
+	var quart domain.Quarter
+	err := sqlx.GetContext(ctx, db, &quart, query, clinicID, date)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to get period for date")
+	}
+	return &quart, nil
+}
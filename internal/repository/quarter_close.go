@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+const quarterCloseColumns = `id, clinic_id, quarter_id, closed_by, closed_at, reopened_by, reopened_at, reopen_reason`
+
+func CreateQuarterClose(ctx context.Context, db sqlx.ExtContext, qc *domain.QuarterClose) error {
+	query := `INSERT INTO tbl_clinic_quarter_close (` + quarterCloseColumns + `)
+		VALUES (:id, :clinic_id, :quarter_id, :closed_by, :closed_at, :reopened_by, :reopened_at, :reopen_reason)`
+	_, err := sqlx.NamedExecContext(ctx, db, query, qc)
+	return err
+}
+
+// ReopenQuarterClose stamps an active close as reopened. It fails if the close was already reopened.
+func ReopenQuarterClose(ctx context.Context, db sqlx.ExtContext, qc *domain.QuarterClose) error {
+	query := `UPDATE tbl_clinic_quarter_close SET reopened_by = :reopened_by, reopened_at = :reopened_at, reopen_reason = :reopen_reason
+		WHERE id = :id AND reopened_at IS NULL`
+	result, err := sqlx.NamedExecContext(ctx, db, query, qc)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("quarter is not closed")
+	}
+	return nil
+}
+
+// GetActiveQuarterClose returns the close currently in force for a clinic's quarter, or nil when it is open.
+func GetActiveQuarterClose(ctx context.Context, db sqlx.QueryerContext, clinicID, quarterID uuid.UUID) (*domain.QuarterClose, error) {
+	query := `SELECT ` + quarterCloseColumns + ` FROM tbl_clinic_quarter_close
+		WHERE clinic_id = $1 AND quarter_id = $2 AND reopened_at IS NULL`
+	var qc domain.QuarterClose
+	err := sqlx.GetContext(ctx, db, &qc, query, clinicID, quarterID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to get quarter close")
+	}
+	return &qc, nil
+}
+
+// GetQuarterCloses returns a clinic's close and reopen history, newest first.
+func GetQuarterCloses(ctx context.Context, db *sqlx.DB, clinicID uuid.UUID, quarterID *uuid.UUID) ([]domain.QuarterClose, error) {
+	query := `SELECT ` + quarterCloseColumns + ` FROM tbl_clinic_quarter_close
+		WHERE clinic_id = $1 AND ($2::uuid IS NULL OR quarter_id = $2) ORDER BY closed_at DESC`
+	closes := []domain.QuarterClose{}
+	if err := db.SelectContext(ctx, &closes, query, clinicID, quarterID); err != nil {
+		return nil, errors.New("failed to get quarter closes")
+	}
+	return closes, nil
+}
+
+// GetClosedQuarterForDate returns the closed quarter of a clinic that contains date, or nil when the date
+// falls in an open period.
+func GetClosedQuarterForDate(ctx context.Context, db sqlx.QueryerContext, clinicID uuid.UUID, date time.Time) (*domain.Quarter, error) {
+	query := `SELECT ` + quarterColumns + `
+		FROM tbl_quarter q
+		JOIN tbl_clinic_quarter_close c ON c.quarter_id = q.id AND c.clinic_id = $1 AND c.reopened_at IS NULL
+		WHERE q.deleted_at IS NULL AND $2::date BETWEEN q.start_date AND q.end_date
+		ORDER BY q.start_date LIMIT 1`
+	var quart domain.Quarter
+	err := sqlx.GetContext(ctx, db, &quart, query, clinicID, date)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to check closed periods")
+	}
+	return &quart, nil
+}
+
+// GetNextOpenQuarter returns the earliest quarter starting after date that is not closed for the clinic.
+func GetNextOpenQuarter(ctx context.Context, db sqlx.QueryerContext, clinicID uuid.UUID, date time.Time) (*domain.Quarter, error) {
+	query := `SELECT ` + quarterColumns + `
+		FROM tbl_quarter q
+		WHERE ` + clinicPeriodScope + ` AND q.deleted_at IS NULL AND q.start_date > $2::date
+			AND NOT EXISTS (SELECT 1 FROM tbl_clinic_quarter_close c
+				WHERE c.quarter_id = q.id AND c.clinic_id = $1 AND c.reopened_at IS NULL)
+		ORDER BY q.start_date LIMIT 1`
+	var quart domain.Quarter
+	err := sqlx.GetContext(ctx, db, &quart, query, clinicID, date)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to get next open quarter")
+	}
+	return &quart, nil
+}
+
+// QuarterHasActiveCloses reports whether any clinic currently has the quarter closed.
+func QuarterHasActiveCloses(ctx context.Context, db *sqlx.DB, quarterID uuid.UUID) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM tbl_clinic_quarter_close WHERE quarter_id = $1 AND reopened_at IS NULL)`
+	if err := db.GetContext(ctx, &exists, query, quarterID); err != nil {
+		return false, errors.New("failed to check quarter closes")
+	}
+	return exists, nil
+}
@@ -11,10 +11,10 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
-func CreateUserClinic(ctx context.Context, db *sqlx.DB, userClinic *domain.UserClinic) error {
+func CreateUserClinic(ctx context.Context, db sqlx.ExtContext, userClinic *domain.UserClinic) error {
 	query := `INSERT INTO tbl_user_clinic (id, user_id, clinic_id, role, created_at, updated_at)
 		VALUES (:id, :user_id, :clinic_id, :role, :created_at, :updated_at)`
-	_, err := db.NamedExecContext(ctx, query, userClinic)
+	_, err := sqlx.NamedExecContext(ctx, db, query, userClinic)
 	if err != nil {
 		return err
 	}
@@ -57,6 +57,8 @@ type userClinicWithClinicRow struct {
 	CShareType   string    `db:"c_share_type"`
 	CClinicShare int       `db:"c_clinic_share"`
 	COwnerShare  int       `db:"c_owner_share"`
+	CBASCycle    string    `db:"c_bas_cycle"`
+	CRequireMFA  bool      `db:"c_require_mfa"`
 	CCreatedAt   time.Time `db:"c_created_at"`
 	CUpdatedAt   time.Time `db:"c_updated_at"`
 }
@@ -67,7 +69,7 @@ func GetUserClinics(ctx context.Context, db *sqlx.DB, userID uuid.UUID) ([]domai
 		c.id as c_id, c.name as c_name, c.abn_number as c_abn_number, c.address as c_address,
 		c.city as c_city, c.state as c_state, c.postcode as c_postcode, c.phone as c_phone,
 		c.email as c_email, c.website as c_website, c.logo_url as c_logo_url, c.description as c_description,
-		c.share_type as c_share_type, c.clinic_share as c_clinic_share, c.owner_share as c_owner_share,
+		c.share_type as c_share_type, c.clinic_share as c_clinic_share, c.owner_share as c_owner_share, c.bas_cycle as c_bas_cycle, c.require_mfa as c_require_mfa,
 		c.created_at as c_created_at, c.updated_at as c_updated_at
 		FROM tbl_user_clinic uc
 		INNER JOIN tbl_clinic c ON uc.clinic_id = c.id
@@ -106,6 +108,8 @@ func GetUserClinics(ctx context.Context, db *sqlx.DB, userID uuid.UUID) ([]domai
 				ShareType:   r.CShareType,
 				ClinicShare: r.CClinicShare,
 				OwnerShare:  r.COwnerShare,
+				BASCycle:    r.CBASCycle,
+				RequireMFA:  r.CRequireMFA,
 				CreatedAt:   r.CCreatedAt,
 				UpdatedAt:   r.CUpdatedAt,
 			},
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+func CreateUserToken(ctx context.Context, db sqlx.ExtContext, token *domain.UserToken) error {
+	query := `INSERT INTO tbl_user_token (id, user_id, purpose, token_hash, expires_at, created_at)
+		VALUES (:id, :user_id, :purpose, :token_hash, :expires_at, :created_at)`
+	_, err := sqlx.NamedExecContext(ctx, db, query, token)
+	return err
+}
+
+// InvalidateUserTokens marks a user's outstanding tokens for the purpose as used, so only the most
+// recently issued link works.
+func InvalidateUserTokens(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, purpose domain.UserTokenPurpose) error {
+	_, err := db.ExecContext(ctx, `UPDATE tbl_user_token SET used_at = $1
+		WHERE user_id = $2 AND purpose = $3 AND used_at IS NULL`, time.Now(), userID, purpose)
+	return err
+}
+
+// ConsumeUserToken redeems an unused, unexpired token and returns its user. It returns nil when the
+// token is unknown, used, expired or issued for another purpose.
+func ConsumeUserToken(ctx context.Context, db sqlx.ExtContext, tokenHash string, purpose domain.UserTokenPurpose) (*uuid.UUID, error) {
+	now := time.Now()
+	var userID uuid.UUID
+	err := sqlx.GetContext(ctx, db, &userID, `UPDATE tbl_user_token SET used_at = $1
+		WHERE token_hash = $2 AND purpose = $3 AND used_at IS NULL AND expires_at > $1
+		RETURNING user_id`, now, tokenHash, purpose)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to redeem token")
+	}
+	return &userID, nil
+}
+
+// GetLatestUserTokenTime returns when the user was last issued a token for the purpose, or nil.
+func GetLatestUserTokenTime(ctx context.Context, db *sqlx.DB, userID uuid.UUID, purpose domain.UserTokenPurpose) (*time.Time, error) {
+	var createdAt *time.Time
+	err := db.GetContext(ctx, &createdAt, `SELECT MAX(created_at) FROM tbl_user_token WHERE user_id = $1 AND purpose = $2`, userID, purpose)
+	if err != nil {
+		return nil, err
+	}
+	return createdAt, nil
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/mailer"
+	"github.com/iamarpitzala/aca-reca-backend/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// userTokenCooldown stops repeated requests from flooding a mailbox with links.
+const userTokenCooldown = time.Minute
+
+// ForgotPassword emails a password reset link. It succeeds whether or not the email belongs to an
+// account so the endpoint cannot be used to discover who is registered; for the same reason a failed
+// delivery is only logged.
+func (as *AuthService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := repository.GetUserByEmail(ctx, as.db, email)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+	token, err := as.issueUserToken(ctx, user.ID, domain.UserTokenPasswordReset, as.accountCfg.PasswordResetTTL)
+	if err != nil || token == "" {
+		return err
+	}
+	body := fmt.Sprintf("We received a request to reset the password for your account.\n\n"+
+		"Choose a new password here:\n%s\n\n"+
+		"This link expires in %s and can be used once. If you did not ask for it, you can ignore this email.\n",
+		as.accountCfg.PasswordResetURL+"?token="+url.QueryEscape(token), as.accountCfg.PasswordResetTTL)
+	if err := as.mailer.Send(ctx, mailer.Message{To: user.Email, Subject: "Reset your password", Body: body}); err != nil {
+		log.Printf("Failed to send password reset email to user %s: %v", user.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword redeems a reset token and sets a new password. Every session is revoked, and the
+// email address counts as verified since the link reached its inbox.
+func (as *AuthService) ResetPassword(ctx context.Context, req *domain.ResetPasswordRequest) error {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+	return runInTx(ctx, as.db, func(tx *sqlx.Tx) error {
+		userID, err := repository.ConsumeUserToken(ctx, tx, hashToken(req.Token), domain.UserTokenPasswordReset)
+		if err != nil {
+			return err
+		}
+		if userID == nil {
+			return errors.New("password reset link is invalid or has expired")
+		}
+		if err := repository.UpdateUserPassword(ctx, tx, *userID, string(hashed)); err != nil {
+			return err
+		}
+		if err := repository.SetUserEmailVerified(ctx, tx, *userID); err != nil {
+			return err
+		}
+		return repository.RevokeUserSessions(ctx, tx, *userID, nil)
+	})
+}
+
+// ChangePassword replaces a signed-in user's password and signs out every other session.
+func (as *AuthService) ChangePassword(ctx context.Context, userID, sessionID uuid.UUID, req *domain.ChangePasswordRequest) error {
+	user, err := repository.GetUserByID(ctx, as.db, userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		return errors.New("current password is incorrect")
+	}
+	session, err := repository.GetSessionByID(ctx, as.db, sessionID)
+	if err != nil {
+		return err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+	return runInTx(ctx, as.db, func(tx *sqlx.Tx) error {
+		if err := repository.UpdateUserPassword(ctx, tx, userID, string(hashed)); err != nil {
+			return err
+		}
+		if err := repository.InvalidateUserTokens(ctx, tx, userID, domain.UserTokenPasswordReset); err != nil {
+			return err
+		}
+		return repository.RevokeUserSessions(ctx, tx, userID, &session.FamilyID)
+	})
+}
+
+// ResendVerification emails a fresh verification link to an unverified account. Like
+// ForgotPassword it does not reveal whether the email is registered, even when delivery fails.
+func (as *AuthService) ResendVerification(ctx context.Context, email string) error {
+	user, err := repository.GetUserByEmail(ctx, as.db, email)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.IsEmailVerified {
+		return nil
+	}
+	if err := as.sendVerification(ctx, user); err != nil {
+		log.Printf("Failed to send verification email to user %s: %v", user.ID, err)
+	}
+	return nil
+}
+
+func (as *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	return runInTx(ctx, as.db, func(tx *sqlx.Tx) error {
+		userID, err := repository.ConsumeUserToken(ctx, tx, hashToken(token), domain.UserTokenEmailVerification)
+		if err != nil {
+			return err
+		}
+		if userID == nil {
+			return errors.New("verification link is invalid or has expired")
+		}
+		return repository.SetUserEmailVerified(ctx, tx, *userID)
+	})
+}
+
+func (as *AuthService) sendVerification(ctx context.Context, user *domain.User) error {
+	token, err := as.issueUserToken(ctx, user.ID, domain.UserTokenEmailVerification, as.accountCfg.EmailVerificationTTL)
+	if err != nil || token == "" {
+		return err
+	}
+	body := fmt.Sprintf("Please confirm your email address by opening this link:\n%s\n\n"+
+		"This link expires in %s.\n",
+		as.accountCfg.EmailVerificationURL+"?token="+url.QueryEscape(token), as.accountCfg.EmailVerificationTTL)
+	if err := as.mailer.Send(ctx, mailer.Message{To: user.Email, Subject: "Verify your email address", Body: body}); err != nil {
+		return errors.New("failed to send verification email")
+	}
+	return nil
+}
+
+// issueUserToken stores a new single-use token for the purpose, replacing any outstanding one. It
+// returns an empty token when one was issued too recently to send another.
+func (as *AuthService) issueUserToken(ctx context.Context, userID uuid.UUID, purpose domain.UserTokenPurpose, ttl time.Duration) (string, error) {
+	latest, err := repository.GetLatestUserTokenTime(ctx, as.db, userID, purpose)
+	if err != nil {
+		return "", err
+	}
+	if latest != nil && time.Since(*latest) < userTokenCooldown {
+		return "", nil
+	}
+	token, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	t := &domain.UserToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+	err = runInTx(ctx, as.db, func(tx *sqlx.Tx) error {
+		if err := repository.InvalidateUserTokens(ctx, tx, userID, purpose); err != nil {
+			return err
+		}
+		return repository.CreateUserToken(ctx, tx, t)
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// newOpaqueToken returns 256 random bits, URL-safe encoded.
+func newOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.New("failed to generate token")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
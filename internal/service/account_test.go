@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/mailer"
+	"github.com/jmoiron/sqlx"
+)
+
+func newMockDB(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	return sqlx.NewDb(db, "postgres"), mock
+}
+
+// stmt matches a statement by a fragment of its text.
+func stmt(fragment string) string { return regexp.QuoteMeta(fragment) }
+
+// capture records the value bound to a statement argument.
+type capture struct{ value string }
+
+func (c *capture) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	c.value = s
+	return ok
+}
+
+// equals matches an argument against a value captured earlier.
+type equals struct{ c *capture }
+
+func (e equals) Match(v driver.Value) bool { return v == e.c.value }
+
+type failingMailer struct{}
+
+func (failingMailer) Send(context.Context, mailer.Message) error {
+	return errors.New("smtp unavailable")
+}
+
+var userColumns = []string{"id", "email", "password", "first_name", "last_name", "phone", "is_email_verified", "created_at", "updated_at"}
+
+func expectUserByEmail(mock sqlmock.Sqlmock, user *domain.User) {
+	rows := sqlmock.NewRows(userColumns)
+	if user != nil {
+		rows.AddRow(user.ID, user.Email, user.Password, user.FirstName, user.LastName, user.Phone, user.IsEmailVerified, user.CreatedAt, user.UpdatedAt)
+	}
+	mock.ExpectQuery(stmt("FROM tbl_user")).WithArgs(sqlmock.AnyArg()).WillReturnRows(rows)
+}
+
+// expectIssueUserToken expects a token to be stored for the user and captures its hash.
+func expectIssueUserToken(mock sqlmock.Sqlmock, userID uuid.UUID, purpose domain.UserTokenPurpose) *capture {
+	mock.ExpectQuery(stmt("SELECT MAX(created_at) FROM tbl_user_token")).WithArgs(userID, string(purpose)).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+	mock.ExpectBegin()
+	mock.ExpectExec(stmt("UPDATE tbl_user_token SET used_at")).WillReturnResult(sqlmock.NewResult(0, 0))
+	hash := &capture{}
+	mock.ExpectExec(stmt("INSERT INTO tbl_user_token")).
+		WithArgs(sqlmock.AnyArg(), userID, string(purpose), hash, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	return hash
+}
+
+// expectConsumeUserToken expects one redemption of the captured token, succeeding only while it is unused.
+func expectConsumeUserToken(mock sqlmock.Sqlmock, hash *capture, purpose domain.UserTokenPurpose, userID *uuid.UUID) {
+	rows := sqlmock.NewRows([]string{"user_id"})
+	if userID != nil {
+		rows.AddRow(*userID)
+	}
+	mock.ExpectQuery(stmt("UPDATE tbl_user_token SET used_at")).
+		WithArgs(sqlmock.AnyArg(), equals{hash}, string(purpose)).
+		WillReturnRows(rows)
+}
+
+func newAccountService(t *testing.T, db *sqlx.DB, m mailer.Mailer) *AuthService {
+	t.Helper()
+	tokens := NewTokenService(config.JWTConfig{SecretKey: "test-secret", AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour, Issuer: "test"})
+	return NewAuthService(db, tokens, nil, m, config.AccountConfig{
+		PasswordResetTTL:     time.Hour,
+		PasswordResetURL:     "http://app.test/reset-password",
+		EmailVerificationTTL: time.Hour,
+		EmailVerificationURL: "http://app.test/verify-email",
+	})
+}
+
+// outboxToken reads the only message in the outbox and returns the token in its link.
+func outboxToken(t *testing.T, dir, to, pageURL string) string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("outbox holds %d messages, want 1 (err %v)", len(files), err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(data)
+	if !strings.Contains(msg, "To: "+to+"\r\n") {
+		t.Fatalf("message is not addressed to %s:\n%s", to, msg)
+	}
+	link := regexp.MustCompile(regexp.QuoteMeta(pageURL) + `\?token=\S+`).FindString(msg)
+	if link == "" {
+		t.Fatalf("message has no %s link:\n%s", pageURL, msg)
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query().Get("token")
+}
+
+func testUser(verified bool) *domain.User {
+	now := time.Now()
+	return &domain.User{ID: uuid.New(), Email: "dentist@example.com", Password: "x", IsEmailVerified: verified, CreatedAt: now, UpdatedAt: now}
+}
+
+func TestPasswordResetThroughOutbox(t *testing.T) {
+	db, mock := newMockDB(t)
+	outbox := t.TempDir()
+	as := newAccountService(t, db, mailer.NewFileMailer("no-reply@example.com", outbox))
+	ctx := context.Background()
+	user := testUser(false)
+
+	expectUserByEmail(mock, user)
+	hash := expectIssueUserToken(mock, user.ID, domain.UserTokenPasswordReset)
+	if err := as.ForgotPassword(ctx, user.Email); err != nil {
+		t.Fatalf("ForgotPassword: %v", err)
+	}
+	token := outboxToken(t, outbox, user.Email, "http://app.test/reset-password")
+	if hashToken(token) != hash.value {
+		t.Fatal("emailed token does not match the stored hash")
+	}
+
+	mock.ExpectBegin()
+	expectConsumeUserToken(mock, hash, domain.UserTokenPasswordReset, &user.ID)
+	mock.ExpectExec(stmt("UPDATE tbl_user SET password")).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), user.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(stmt("UPDATE tbl_user SET is_email_verified = TRUE")).WithArgs(sqlmock.AnyArg(), user.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(stmt("UPDATE tbl_session SET deleted_at")).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	if err := as.ResetPassword(ctx, &domain.ResetPasswordRequest{Token: token, Password: "a-new-password"}); err != nil {
+		t.Fatalf("first redemption: %v", err)
+	}
+
+	mock.ExpectBegin()
+	expectConsumeUserToken(mock, hash, domain.UserTokenPasswordReset, nil)
+	mock.ExpectRollback()
+	if err := as.ResetPassword(ctx, &domain.ResetPasswordRequest{Token: token, Password: "another-password"}); err == nil {
+		t.Fatal("second redemption succeeded, want an error")
+	}
+}
+
+func TestEmailVerificationThroughOutbox(t *testing.T) {
+	db, mock := newMockDB(t)
+	outbox := t.TempDir()
+	as := newAccountService(t, db, mailer.NewFileMailer("no-reply@example.com", outbox))
+	ctx := context.Background()
+	user := testUser(false)
+
+	expectUserByEmail(mock, user)
+	hash := expectIssueUserToken(mock, user.ID, domain.UserTokenEmailVerification)
+	if err := as.ResendVerification(ctx, user.Email); err != nil {
+		t.Fatalf("ResendVerification: %v", err)
+	}
+	token := outboxToken(t, outbox, user.Email, "http://app.test/verify-email")
+
+	mock.ExpectBegin()
+	expectConsumeUserToken(mock, hash, domain.UserTokenEmailVerification, &user.ID)
+	mock.ExpectExec(stmt("UPDATE tbl_user SET is_email_verified = TRUE")).WithArgs(sqlmock.AnyArg(), user.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := as.VerifyEmail(ctx, token); err != nil {
+		t.Fatalf("first redemption: %v", err)
+	}
+
+	mock.ExpectBegin()
+	expectConsumeUserToken(mock, hash, domain.UserTokenEmailVerification, nil)
+	mock.ExpectRollback()
+	if err := as.VerifyEmail(ctx, token); err == nil {
+		t.Fatal("second redemption succeeded, want an error")
+	}
+}
+
+// Unknown emails and failed deliveries must look the same to the caller.
+func TestForgotPasswordDoesNotRevealAccounts(t *testing.T) {
+	db, mock := newMockDB(t)
+	ctx := context.Background()
+
+	outbox := t.TempDir()
+	as := newAccountService(t, db, mailer.NewFileMailer("no-reply@example.com", outbox))
+	expectUserByEmail(mock, nil)
+	if err := as.ForgotPassword(ctx, "nobody@example.com"); err != nil {
+		t.Fatalf("unknown email: %v", err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(outbox, "*.eml")); len(files) != 0 {
+		t.Fatalf("unknown email was sent %d messages", len(files))
+	}
+
+	as = newAccountService(t, db, failingMailer{})
+	user := testUser(true)
+	expectUserByEmail(mock, user)
+	expectIssueUserToken(mock, user.ID, domain.UserTokenPasswordReset)
+	if err := as.ForgotPassword(ctx, user.Email); err != nil {
+		t.Fatalf("failed delivery: %v", err)
+	}
+}
@@ -21,7 +21,7 @@ func NewAOSService(db *sqlx.DB) *AOSService {
 	}
 }
 
-func (as *AOSService) CreateAOC(ctx context.Context, aoc *domain.AOCRequest) error {
+func (as *AOSService) CreateAOC(ctx context.Context, aoc *domain.AOCRequest, actorID uuid.UUID) error {
 	aocRepo := aoc.ToRepo()
 
 	existing, err := repository.GetAOCByCode(ctx, as.db, aocRepo.Code)
@@ -48,7 +48,12 @@ func (as *AOSService) CreateAOC(ctx context.Context, aoc *domain.AOCRequest) err
 		return errors.New("account tax not found")
 	}
 
-	return repository.CreateAOC(ctx, as.db, aocRepo)
+	return runInTx(ctx, as.db, func(tx *sqlx.Tx) error {
+		if err := repository.CreateAOC(ctx, tx, aocRepo); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, actorID, accountAudit(aocRepo.ID, domain.AuditActionCreate, nil, aocRepo))
+	})
 }
 
 func (as *AOSService) GetAOCByID(ctx context.Context, id uuid.UUID) (*domain.AOCResponse, error) {
@@ -115,27 +120,50 @@ func (as *AOSService) GetAllAOCs(ctx context.Context) ([]domain.AOCResponse, err
 	return responses, nil
 }
 
-func (as *AOSService) UpdateAOC(ctx context.Context, aoc *domain.AOC) error {
+func (as *AOSService) UpdateAOC(ctx context.Context, aoc *domain.AOC, actorID uuid.UUID) error {
 	aocRepo, err := repository.GetAOCByID(ctx, as.db, aoc.ID)
 	if err != nil {
 		return err
 	}
+	if aocRepo.ID == uuid.Nil {
+		return errors.New("aoc not found")
+	}
+	before := *aocRepo
 	aocRepo.AccountTypeID = aoc.AccountTypeID
 	aocRepo.AccountTaxID = aoc.AccountTaxID
 	aocRepo.Code = aoc.Code
 	aocRepo.Name = aoc.Name
 	aocRepo.Description = aoc.Description
-	return repository.UpdateAOC(ctx, as.db, aocRepo)
+	return runInTx(ctx, as.db, func(tx *sqlx.Tx) error {
+		if err := repository.UpdateAOC(ctx, tx, aocRepo); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, actorID, accountAudit(aocRepo.ID, domain.AuditActionUpdate, &before, aocRepo))
+	})
 }
 
-func (as *AOSService) DeleteAOC(ctx context.Context, ids []uuid.UUID) error {
+func (as *AOSService) DeleteAOC(ctx context.Context, ids []uuid.UUID, actorID uuid.UUID) error {
 	if len(ids) == 0 {
 		return nil
 	}
-	return repository.DeleteAOC(ctx, as.db, ids)
+	existing, err := as.existingAOCs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	return runInTx(ctx, as.db, func(tx *sqlx.Tx) error {
+		if err := repository.DeleteAOC(ctx, tx, ids); err != nil {
+			return err
+		}
+		for _, before := range existing {
+			if err := recordAudit(ctx, tx, actorID, accountAudit(before.ID, domain.AuditActionDelete, before, nil)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
-func (as *AOSService) BulkUpdateTax(ctx context.Context, ids []uuid.UUID, accountTaxID int) error {
+func (as *AOSService) BulkUpdateTax(ctx context.Context, ids []uuid.UUID, accountTaxID int, actorID uuid.UUID) error {
 	if len(ids) == 0 {
 		return nil
 	}
@@ -143,7 +171,54 @@ func (as *AOSService) BulkUpdateTax(ctx context.Context, ids []uuid.UUID, accoun
 	if err != nil || tax == nil {
 		return errors.New("account tax not found")
 	}
-	return repository.BulkUpdateAccountTax(ctx, as.db, ids, accountTaxID)
+	existing, err := as.existingAOCs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	return runInTx(ctx, as.db, func(tx *sqlx.Tx) error {
+		if err := repository.BulkUpdateAccountTax(ctx, tx, ids, accountTaxID); err != nil {
+			return err
+		}
+		for _, before := range existing {
+			after := *before
+			after.AccountTaxID = accountTaxID
+			if err := recordAudit(ctx, tx, actorID, accountAudit(before.ID, domain.AuditActionUpdate, before, &after)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// existingAOCs loads the live accounts among ids; unknown or already deleted ids are skipped.
+func (as *AOSService) existingAOCs(ctx context.Context, ids []uuid.UUID) ([]*domain.AOC, error) {
+	aocs := make([]*domain.AOC, 0, len(ids))
+	for _, id := range ids {
+		aoc, err := repository.GetAOCByID(ctx, as.db, id)
+		if err != nil {
+			return nil, err
+		}
+		if aoc.ID != uuid.Nil {
+			aocs = append(aocs, aoc)
+		}
+	}
+	return aocs, nil
+}
+
+// accountAudit describes a change to the shared chart of accounts, which has no owning clinic.
+func accountAudit(id uuid.UUID, action string, before, after *domain.AOC) auditChange {
+	change := auditChange{
+		EntityType: domain.AuditEntityAccount,
+		EntityID:   id,
+		Action:     action,
+	}
+	if before != nil {
+		change.Before = before.ToResponse()
+	}
+	if after != nil {
+		change.After = after.ToResponse()
+	}
+	return change
 }
 
 func (as *AOSService) GetAOCType(ctx context.Context) ([]domain.AccountType, error) {
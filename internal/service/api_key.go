@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/repository"
+)
+
+// maxAPIKeys caps how many live keys one user may hold.
+const maxAPIKeys = 25
+
+// apiKeyUsedInterval is how stale a key's last-used time may get before a request updates it.
+const apiKeyUsedInterval = time.Minute
+
+// apiKeyPrefixLength is how much of a key is kept in clear to identify it in listings.
+const apiKeyPrefixLength = 12
+
+var (
+	// ErrAPIKeyInvalid is returned for an unknown, revoked or expired API key.
+	ErrAPIKeyInvalid  = errors.New("invalid or expired API key")
+	ErrAPIKeyNotFound = errors.New("API key not found")
+)
+
+// CreateAPIKey issues a key for the user. The key itself is returned only here; afterwards only its
+// prefix is shown. A clinic-bound key needs the user to be a member of that clinic.
+func (as *AuthService) CreateAPIKey(ctx context.Context, userID uuid.UUID, req *domain.CreateAPIKeyRequest) (*domain.CreatedAPIKey, error) {
+	for _, scope := range req.Scopes {
+		if !domain.IsValidAPIKeyScope(scope) {
+			return nil, fmt.Errorf("unknown scope: %s", scope)
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("expiry must be in the future")
+	}
+	if req.ClinicID != nil {
+		member, err := repository.GetUserClinicByUserAndClinic(ctx, as.db, userID, *req.ClinicID)
+		if err != nil {
+			return nil, err
+		}
+		if member == nil {
+			return nil, errors.New("user is not a member of this clinic")
+		}
+	}
+	existing, err := repository.GetUserAPIKeys(ctx, as.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxAPIKeys {
+		return nil, fmt.Errorf("a user may hold at most %d API keys", maxAPIKeys)
+	}
+
+	token, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	secret := domain.APIKeyPrefix + token
+	key := domain.APIKey{
+		ID:        uuid.New(),
+		UserID:    userID,
+		ClinicID:  req.ClinicID,
+		Name:      req.Name,
+		Prefix:    secret[:apiKeyPrefixLength],
+		KeyHash:   hashToken(secret),
+		Scopes:    uniqueStrings(req.Scopes),
+		ExpiresAt: req.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+	if err := repository.CreateAPIKey(ctx, as.db, &key); err != nil {
+		return nil, errors.New("failed to create API key")
+	}
+	return &domain.CreatedAPIKey{APIKey: key, Key: secret}, nil
+}
+
+func (as *AuthService) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]domain.APIKey, error) {
+	return repository.GetUserAPIKeys(ctx, as.db, userID)
+}
+
+func (as *AuthService) RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error {
+	revoked, err := repository.RevokeAPIKey(ctx, as.db, userID, keyID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// ValidateAPIKey returns a live key and the user it acts for, keeping the key's last-used time to
+// within apiKeyUsedInterval.
+func (as *AuthService) ValidateAPIKey(ctx context.Context, secret string) (*domain.APIKey, *domain.User, error) {
+	key, err := repository.GetAPIKeyByHash(ctx, as.db, hashToken(secret))
+	if err != nil {
+		return nil, nil, err
+	}
+	if key == nil || key.IsExpired() {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+	user, err := repository.GetUserByID(ctx, as.db, key.UserID)
+	if err != nil {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+	if now := time.Now(); key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyUsedInterval {
+		// Last-used is informational; a failed write must not reject the request
+		_ = repository.TouchAPIKey(ctx, as.db, key.ID, now)
+	}
+	return key, user, nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/repository"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+type AuditService struct {
+	db *sqlx.DB
+}
+
+func NewAuditService(db *sqlx.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// List returns audit records matching filter, newest first, paging by Limit/Offset.
+func (as *AuditService) List(ctx context.Context, filter domain.AuditLogFilter) ([]domain.AuditLog, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	if filter.Limit > maxAuditLimit {
+		filter.Limit = maxAuditLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return repository.GetAuditLogs(ctx, as.db, filter)
+}
+
+// auditChange describes one audited mutation. Before is nil for creates and After for deletes.
+type auditChange struct {
+	ClinicID   *uuid.UUID
+	EntityType string
+	EntityID   uuid.UUID
+	Action     string
+	Before     interface{}
+	After      interface{}
+}
+
+// recordAudit appends change to the audit log inside tx, so the record exists exactly when the change commits.
+func recordAudit(ctx context.Context, tx sqlx.ExtContext, actorID uuid.UUID, change auditChange) error {
+	before, err := auditSnapshot(change.Before)
+	if err != nil {
+		return err
+	}
+	after, err := auditSnapshot(change.After)
+	if err != nil {
+		return err
+	}
+	return repository.CreateAuditLog(ctx, tx, &domain.AuditLog{
+		ID:         uuid.New(),
+		ClinicID:   change.ClinicID,
+		ActorID:    actorID,
+		EntityType: change.EntityType,
+		EntityID:   change.EntityID,
+		Action:     change.Action,
+		Before:     before,
+		After:      after,
+		CreatedAt:  time.Now(),
+	})
+}
+
+func auditSnapshot(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
@@ -7,27 +7,59 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/config"
 	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/mailer"
 	"github.com/iamarpitzala/aca-reca-backend/internal/repository"
+	"github.com/iamarpitzala/aca-reca-backend/internal/useragent"
 	"github.com/jmoiron/sqlx"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// sessionSeenInterval is how stale a session's last-seen time may get before a request updates it,
+// so busy sessions do not write on every request.
+const sessionSeenInterval = 5 * time.Minute
+
+var (
+	// ErrRefreshTokenReused is returned when an already-rotated refresh token is presented again.
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected; all sessions from this sign-in have been revoked")
+	// ErrInvalidCredentials is returned by Login for an unknown email or a wrong password alike.
+	ErrInvalidCredentials = errors.New("invalid email or password")
+)
+
 type AuthService struct {
 	db           *sqlx.DB
 	tokenService *TokenService
 	oauthService *OAuthService
+	mailer       mailer.Mailer
+	accountCfg   config.AccountConfig
 }
 
-func NewAuthService(db *sqlx.DB, tokenService *TokenService, oauthService *OAuthService) *AuthService {
+func NewAuthService(db *sqlx.DB, tokenService *TokenService, oauthService *OAuthService, m mailer.Mailer, accountCfg config.AccountConfig) *AuthService {
 	return &AuthService{
 		db:           db,
 		tokenService: tokenService,
 		oauthService: oauthService,
+		mailer:       m,
+		accountCfg:   accountCfg,
 	}
 }
 
 func (as *AuthService) Register(ctx context.Context, req *domain.RegisterRequest) (*domain.AuthResponse, error) {
+	user, err := as.createUser(ctx, as.db, req, false)
+	if err != nil {
+		return nil, err
+	}
+	// The account is usable before it is verified, and the user can ask for another link, so a
+	// delivery failure does not fail registration
+	_ = as.sendVerification(ctx, user)
+
+	return as.sessionResponse(ctx, user)
+}
+
+// createUser inserts an account for req with a hashed password. emailVerified is set when the
+// caller has already proven the user owns the email, e.g. by redeeming an emailed invitation.
+func (as *AuthService) createUser(ctx context.Context, db sqlx.ExtContext, req *domain.RegisterRequest, emailVerified bool) (*domain.User, error) {
 	// Check if user already exists
 	emailExists, err := repository.EmailExists(ctx, as.db, req.Email)
 	if err != nil {
@@ -45,46 +77,34 @@ func (as *AuthService) Register(ctx context.Context, req *domain.RegisterRequest
 
 	// Create user
 	user := domain.User{
-		ID:        uuid.New(),
-		Email:     req.Email,
-		Password:  string(hashedPassword),
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		Phone:     req.Phone,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:              uuid.New(),
+		Email:           req.Email,
+		Password:        string(hashedPassword),
+		FirstName:       req.FirstName,
+		LastName:        req.LastName,
+		Phone:           req.Phone,
+		IsEmailVerified: emailVerified,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
 	}
 
-	err = repository.CreateUser(ctx, as.db, &user)
+	err = repository.CreateUser(ctx, db, &user)
 	if err != nil {
 		return nil, err
 	}
+	return &user, nil
+}
 
-	// Generate tokens
-	sessionID := uuid.New()
-	tokenPair, err := as.tokenService.GenerateTokenPair(user.ID, user.Email, sessionID)
-	if err != nil {
-		return nil, errors.New("failed to generate tokens")
-	}
-
-	// Create session in database
-	session := domain.Session{
-		ID:           sessionID,
-		UserID:       user.ID,
-		RefreshToken: tokenPair.RefreshToken,
-		ExpiresAt:    time.Now().Add(as.tokenService.refreshTokenTTL),
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
-	}
-
-	err = repository.CreateSession(ctx, as.db, &session)
+// sessionResponse signs in a newly created user, returning their first token pair.
+func (as *AuthService) sessionResponse(ctx context.Context, user *domain.User) (*domain.AuthResponse, error) {
+	tokenPair, err := as.beginSession(ctx, user)
 	if err != nil {
 		return nil, err
 	}
 
 	user.Password = ""
 	return &domain.AuthResponse{
-		User:         &user,
+		User:         user,
 		AccessToken:  tokenPair.AccessToken,
 		RefreshToken: tokenPair.RefreshToken,
 		TokenType:    tokenPair.TokenType,
@@ -99,101 +119,104 @@ func (as *AuthService) Login(ctx context.Context, req *domain.LoginRequest) (*do
 		return nil, err
 	}
 	if user == nil || user.ID == uuid.Nil {
-		return nil, errors.New("invalid email or password")
+		return nil, ErrInvalidCredentials
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return nil, errors.New("invalid email or password")
+		return nil, ErrInvalidCredentials
 	}
 
-	// Generate tokens
-	sessionID := uuid.New()
-	tokenPair, err := as.tokenService.GenerateTokenPair(user.ID, user.Email, sessionID)
-	if err != nil {
-		return nil, errors.New("failed to generate tokens")
-	}
-
-	// Create session in database
-	session := domain.Session{
-		ID:           sessionID,
-		UserID:       user.ID,
-		RefreshToken: tokenPair.RefreshToken,
-		ExpiresAt:    time.Now().Add(as.tokenService.refreshTokenTTL),
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
-	}
-
-	err = repository.CreateSession(ctx, as.db, &session)
-	if err != nil {
-		return nil, err
-	}
-
-	user.Password = ""
-	return &domain.AuthResponse{
-		User:         user,
-		AccessToken:  tokenPair.AccessToken,
-		RefreshToken: tokenPair.RefreshToken,
-		TokenType:    tokenPair.TokenType,
-		ExpiresIn:    tokenPair.ExpiresIn,
-	}, nil
+	return as.signIn(ctx, user)
 }
 
+// RefreshToken rotates a refresh token. Presenting a token that was already rotated means it has
+// leaked, so the whole session family is revoked and every token issued from that login stops working.
 func (as *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*domain.AuthResponse, error) {
 	// Validate refresh token
-	claims, err := as.tokenService.ValidateToken(refreshToken)
+	claims, err := as.tokenService.ValidateRefreshToken(refreshToken)
 	if err != nil {
 		return nil, errors.New("invalid refresh token")
 	}
 
-	// Find session in database
-	session, err := repository.GetSessionByRefreshToken(ctx, as.db, refreshToken)
+	session, err := repository.GetSessionByRefreshTokenHash(ctx, as.db, hashToken(refreshToken))
 	if err != nil {
 		return nil, err
 	}
-
-	// Check if session is expired
+	if session == nil || session.UserID != claims.UserID {
+		return nil, errors.New("invalid refresh token")
+	}
+	if session.DeletedAt != nil {
+		return nil, errors.New("session has been revoked")
+	}
+	if session.RotatedAt != nil {
+		if err := repository.RevokeSessionFamily(ctx, as.db, session.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
 	if session.IsExpired() {
 		return nil, errors.New("session expired")
 	}
 
-	// Find user
 	user, err := repository.GetUserByID(ctx, as.db, claims.UserID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Generate new token pair
-	newTokenPair, err := as.tokenService.GenerateTokenPair(user.ID, user.Email, session.ID)
-	if err != nil {
-		return nil, errors.New("failed to generate new tokens")
-	}
-
-	// Update session with new refresh token
-	session.RefreshToken = newTokenPair.RefreshToken
-	session.ExpiresAt = time.Now().Add(as.tokenService.refreshTokenTTL)
-	session.UpdatedAt = time.Now()
-
-	err = repository.UpdateSession(ctx, as.db, session)
+	var tokenPair *domain.TokenPair
+	reused := false
+	err = runInTx(ctx, as.db, func(tx *sqlx.Tx) error {
+		rotated, err := repository.MarkSessionRotated(ctx, tx, session.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		if !rotated {
+			// A concurrent request rotated this token first
+			reused = true
+			return repository.RevokeSessionFamily(ctx, tx, session.FamilyID)
+		}
+		tokenPair, err = as.startSession(ctx, tx, user, session.FamilyID)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
+	if reused {
+		return nil, ErrRefreshTokenReused
+	}
 
 	return &domain.AuthResponse{
-		AccessToken:  newTokenPair.AccessToken,
-		RefreshToken: newTokenPair.RefreshToken,
-		TokenType:    newTokenPair.TokenType,
-		ExpiresIn:    newTokenPair.ExpiresIn,
+		AccessToken:  tokenPair.AccessToken,
+		RefreshToken: tokenPair.RefreshToken,
+		TokenType:    tokenPair.TokenType,
+		ExpiresIn:    tokenPair.ExpiresIn,
 	}, nil
 }
 
+// Logout revokes the session and the rest of its family, which also invalidates its access tokens.
 func (as *AuthService) Logout(ctx context.Context, sessionID uuid.UUID) error {
-	// Deactivate session in database
-	err := repository.DeleteSession(ctx, as.db, sessionID)
+	return as.revokeFamily(ctx, sessionID)
+}
+
+// ValidateSession reports whether access tokens issued for the session are still honoured, and
+// keeps the session's last-seen time to within sessionSeenInterval. Once a session is rotated only
+// the access tokens it already issued remain, so it stops being honoured when those have expired.
+func (as *AuthService) ValidateSession(ctx context.Context, sessionID uuid.UUID) error {
+	session, err := repository.GetSessionByID(ctx, as.db, sessionID)
 	if err != nil {
 		return err
 	}
-
+	if session.IsExpired() {
+		return errors.New("session expired")
+	}
+	if session.RotatedAt != nil && time.Since(*session.RotatedAt) > as.tokenService.AccessTokenTTL() {
+		return errors.New("session has been rotated")
+	}
+	if now := time.Now(); now.Sub(session.LastSeenAt) > sessionSeenInterval {
+		// Last-seen is informational; a failed write must not reject the request
+		_ = repository.TouchSession(ctx, as.db, sessionID, now)
+	}
 	return nil
 }
 
@@ -226,22 +249,32 @@ func (as *AuthService) UpdateUser(ctx context.Context, userID uuid.UUID, firstNa
 	return user, nil
 }
 
+// GetUserSessions lists the user's signed-in devices, each labelled with its browser and platform.
 func (as *AuthService) GetUserSessions(ctx context.Context, userID uuid.UUID) ([]domain.Session, error) {
 	sessions, err := repository.GetUserSessions(ctx, as.db, userID)
 	if err != nil {
-		return sessions, nil
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Device = useragent.Label(sessions[i].UserAgent)
 	}
-
 	return sessions, nil
 }
 
-func (as *AuthService) RevokeSession(ctx context.Context, sessionID uuid.UUID) error {
-	err := repository.RevokeSession(ctx, as.db, sessionID)
+// RevokeOtherSessions signs the user out everywhere except the device holding sessionID.
+func (as *AuthService) RevokeOtherSessions(ctx context.Context, userID, sessionID uuid.UUID) error {
+	session, err := repository.GetSessionByID(ctx, as.db, sessionID)
 	if err != nil {
 		return err
 	}
+	if session.UserID != userID {
+		return errors.New("session not found")
+	}
+	return repository.RevokeUserSessions(ctx, as.db, userID, &session.FamilyID)
+}
 
-	return nil
+func (as *AuthService) RevokeSession(ctx context.Context, sessionID uuid.UUID) error {
+	return as.revokeFamily(ctx, sessionID)
 }
 
 // OAuthLogin creates a session for an OAuth-authenticated user
@@ -252,30 +285,29 @@ func (as *AuthService) OAuthLogin(ctx context.Context, userID uuid.UUID) (*domai
 		return nil, err
 	}
 
-	// Generate tokens
-	sessionID := uuid.New()
-	tokenPair, err := as.tokenService.GenerateTokenPair(user.ID, user.Email, sessionID)
+	return as.signIn(ctx, user)
+}
+
+// signIn finishes a password or OAuth login: it starts a session, or, when the user has
+// two-factor authentication enabled, returns a challenge to complete with MFAService.CompleteLogin.
+func (as *AuthService) signIn(ctx context.Context, user *domain.User) (*domain.AuthResponse, error) {
+	mfa, err := repository.GetUserMFA(ctx, as.db, user.ID)
 	if err != nil {
-		return nil, errors.New("failed to generate tokens")
+		return nil, err
 	}
-
-	// Create session in database
-	session := domain.Session{
-		ID:           sessionID,
-		UserID:       user.ID,
-		RefreshToken: tokenPair.RefreshToken,
-		ExpiresAt:    time.Now().Add(as.tokenService.refreshTokenTTL),
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+	if mfa.Enabled() {
+		token, err := as.tokenService.GenerateMFAChallengeToken(user.ID, mfaChallengeTTL)
+		if err != nil {
+			return nil, errors.New("failed to generate two-factor challenge")
+		}
+		return &domain.AuthResponse{MFARequired: true, MFAToken: token}, nil
 	}
 
-	if err := repository.CreateSession(ctx, as.db, &session); err != nil {
-		return nil, fmt.Errorf("failed to create session: %w", err)
+	tokenPair, err := as.beginSession(ctx, user)
+	if err != nil {
+		return nil, err
 	}
-
-	// Remove password from response
 	user.Password = ""
-
 	return &domain.AuthResponse{
 		User:         user,
 		AccessToken:  tokenPair.AccessToken,
@@ -284,3 +316,77 @@ func (as *AuthService) OAuthLogin(ctx context.Context, userID uuid.UUID) (*domai
 		ExpiresIn:    tokenPair.ExpiresIn,
 	}, nil
 }
+
+// beginSession starts the session family of a new sign-in and, when enabled, tells the user by
+// email if it came from a browser their account has not been used from before.
+func (as *AuthService) beginSession(ctx context.Context, user *domain.User) (*domain.TokenPair, error) {
+	sessionID := uuid.New()
+	tokenPair, err := as.startSessionWithID(ctx, as.db, user, sessionID, uuid.New())
+	if err != nil {
+		return nil, err
+	}
+	if as.accountCfg.NewDeviceAlerts {
+		// The user is signed in either way; a failed alert must not undo that
+		_ = as.alertNewDevice(ctx, user, sessionID)
+	}
+	return tokenPair, nil
+}
+
+// alertNewDevice emails the user when the session is their first from its browser while they are
+// signed in elsewhere. A user's very first session is not worth an alert.
+func (as *AuthService) alertNewDevice(ctx context.Context, user *domain.User, sessionID uuid.UUID) error {
+	client := clientInfoFrom(ctx)
+	others, sameAgent, err := repository.HasOtherSessions(ctx, as.db, user.ID, sessionID, client.UserAgent)
+	if err != nil || !others || sameAgent {
+		return err
+	}
+	ip := client.IPAddress
+	if ip == "" {
+		ip = "an unknown address"
+	}
+	body := fmt.Sprintf("Your account was just signed in to from a new device.\n\n"+
+		"Device: %s\nIP address: %s\nTime: %s\n\n"+
+		"If this was you, there is nothing to do. If not, change your password and sign out of your other devices.\n",
+		useragent.Label(client.UserAgent), ip, time.Now().UTC().Format(time.RFC1123))
+	return as.mailer.Send(ctx, mailer.Message{To: user.Email, Subject: "New sign-in to your account", Body: body})
+}
+
+// startSession issues a token pair and records its session in the given family, with the client
+// details carried by ctx. Only the hash of the refresh token is stored.
+func (as *AuthService) startSession(ctx context.Context, db sqlx.ExtContext, user *domain.User, familyID uuid.UUID) (*domain.TokenPair, error) {
+	return as.startSessionWithID(ctx, db, user, uuid.New(), familyID)
+}
+
+func (as *AuthService) startSessionWithID(ctx context.Context, db sqlx.ExtContext, user *domain.User, sessionID, familyID uuid.UUID) (*domain.TokenPair, error) {
+	tokenPair, err := as.tokenService.GenerateTokenPair(user.ID, user.Email, sessionID)
+	if err != nil {
+		return nil, errors.New("failed to generate tokens")
+	}
+
+	now := time.Now()
+	client := clientInfoFrom(ctx)
+	session := domain.Session{
+		ID:               sessionID,
+		UserID:           user.ID,
+		FamilyID:         familyID,
+		RefreshTokenHash: hashToken(tokenPair.RefreshToken),
+		UserAgent:        client.UserAgent,
+		IPAddress:        client.IPAddress,
+		ExpiresAt:        now.Add(as.tokenService.refreshTokenTTL),
+		LastSeenAt:       now,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if err := repository.CreateSession(ctx, db, &session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return tokenPair, nil
+}
+
+func (as *AuthService) revokeFamily(ctx context.Context, sessionID uuid.UUID) error {
+	session, err := repository.GetSessionByID(ctx, as.db, sessionID)
+	if err != nil {
+		return err
+	}
+	return repository.RevokeSessionFamily(ctx, as.db, session.FamilyID)
+}
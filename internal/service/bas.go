@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/calculation"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/money"
+	"github.com/iamarpitzala/aca-reca-backend/internal/repository"
+	"github.com/jmoiron/sqlx"
+)
+
+type BASService struct {
+	db *sqlx.DB
+}
+
+func NewBASService(db *sqlx.DB) *BASService {
+	return &BASService{db: db}
+}
+
+// entryCalcSummary is the subset of a stored entry's calculations needed for BAS.
+type entryCalcSummary struct {
+	FieldTotals []struct {
+		FieldID      string       `json:"fieldId"`
+		GstAmount    money.Amount `json:"gstAmount"`
+		TotalAmount  money.Amount `json:"totalAmount"`
+		TaxTreatment string       `json:"taxTreatment"`
+	} `json:"fieldTotals"`
+	BasMapping struct {
+		GstOnSales1A money.Amount `json:"gstOnSales1A"`
+		GstCredit1B  money.Amount `json:"gstCredit1B"`
+		TotalSalesG1 money.Amount `json:"totalSalesG1"`
+		ExpensesG11  money.Amount `json:"expensesG11"`
+	} `json:"basMapping"`
+}
+
+// GenerateWorksheet sums every custom form entry and expense entry dated within the quarter
+// into the GST section of a BAS, keeping the entries behind each label for drill-down.
+// Export sales are not captured by any form yet, so G2 is always reported as zero.
+func (s *BASService) GenerateWorksheet(ctx context.Context, clinicID, quarterID uuid.UUID) (*domain.BASWorksheet, error) {
+	if _, err := repository.GetClinicByID(ctx, s.db, clinicID); err != nil {
+		return nil, errors.New("clinic not found")
+	}
+	quarter, err := repository.GetClinicPeriodByID(ctx, s.db, clinicID, quarterID)
+	if err != nil {
+		return nil, err
+	}
+	if quarter == nil {
+		return nil, errors.New("quarter not found")
+	}
+
+	ws := &domain.BASWorksheet{
+		ClinicID:    clinicID.String(),
+		QuarterID:   quarter.ID.String(),
+		QuarterName: quarter.Name,
+		PeriodStart: quarter.StartDate,
+		PeriodEnd:   quarter.EndDate,
+		G1:          domain.BASLabel{Label: domain.BASLabelG1, Lines: []domain.BASLine{}},
+		G2:          domain.BASLabel{Label: domain.BASLabelG2, Lines: []domain.BASLine{}},
+		G3:          domain.BASLabel{Label: domain.BASLabelG3, Lines: []domain.BASLine{}},
+		G10:         domain.BASLabel{Label: domain.BASLabelG10, Lines: []domain.BASLine{}},
+		G11:         domain.BASLabel{Label: domain.BASLabelG11, Lines: []domain.BASLine{}},
+		GST1A:       domain.BASLabel{Label: domain.BASLabel1A, Lines: []domain.BASLine{}},
+		GST1B:       domain.BASLabel{Label: domain.BASLabel1B, Lines: []domain.BASLine{}},
+		GeneratedAt: time.Now(),
+	}
+
+	if err := s.addCustomFormEntries(ctx, ws, clinicID, quarter.StartDate, quarter.EndDate); err != nil {
+		return nil, err
+	}
+	if err := s.addExpenseEntries(ctx, ws, clinicID, quarter.StartDate, quarter.EndDate); err != nil {
+		return nil, err
+	}
+
+	ws.NetAmount = ws.GST1A.Amount - ws.GST1B.Amount
+	switch {
+	case ws.NetAmount > 0:
+		ws.Position = "payable"
+	case ws.NetAmount < 0:
+		ws.Position = "refundable"
+	default:
+		ws.Position = "nil"
+	}
+	return ws, nil
+}
+
+func (s *BASService) addCustomFormEntries(ctx context.Context, ws *domain.BASWorksheet, clinicID uuid.UUID, from, to time.Time) error {
+	entries, err := repository.GetCustomFormEntriesByDateRange(ctx, s.db, clinicID, from, to)
+	if err != nil {
+		return err
+	}
+	incomeFields := newIncomeFieldResolver(ctx, s.db)
+
+	for i := range entries {
+		e := &entries[i]
+		var calc entryCalcSummary
+		if len(e.Calculations) == 0 || json.Unmarshal(e.Calculations, &calc) != nil {
+			continue
+		}
+		addEntryBASLines(ws, e, calc)
+
+		if e.FormType == string(domain.FormTypeExpense) {
+			continue
+		}
+		income, err := incomeFields.resolve(e)
+		if err != nil {
+			return err
+		}
+		addBASLine(&ws.G3, customFormEntryLine(e, gstFreeIncome(calc, income)))
+	}
+	return nil
+}
+
+// addEntryBASLines adds an entry's stored BAS mapping to the G1, G11, 1A and 1B labels.
+func addEntryBASLines(ws *domain.BASWorksheet, e *domain.CustomFormEntry, calc entryCalcSummary) {
+	bas := calc.BasMapping
+	addBASLine(&ws.G1, customFormEntryLine(e, bas.TotalSalesG1))
+	addBASLine(&ws.GST1A, customFormEntryLine(e, bas.GstOnSales1A))
+	addBASLine(&ws.GST1B, customFormEntryLine(e, bas.GstCredit1B))
+	// basMapping.expensesG11 is GST-exclusive; the BAS reports purchases including GST
+	addBASLine(&ws.G11, customFormEntryLine(e, bas.ExpensesG11+bas.GstCredit1B))
+}
+
+func customFormEntryLine(e *domain.CustomFormEntry, amount money.Amount) domain.BASLine {
+	return domain.BASLine{
+		Source:      domain.BASSourceCustomFormEntry,
+		EntryID:     e.ID.String(),
+		FormID:      e.FormID.String(),
+		FormName:    e.FormName,
+		Date:        e.EntryDate,
+		Description: e.Description,
+		Amount:      amount,
+	}
+}
+
+func (s *BASService) addExpenseEntries(ctx context.Context, ws *domain.BASWorksheet, clinicID uuid.UUID, from, to time.Time) error {
+	entries, err := repository.GetExpenseEntriesByDateRange(ctx, s.db, clinicID, from, to)
+	if err != nil {
+		return err
+	}
+	categories, err := repository.GetExpenseCategoriesByClinicID(ctx, s.db, clinicID)
+	if err != nil {
+		return err
+	}
+	capital := make(map[uuid.UUID]bool, len(categories))
+	for _, c := range categories {
+		capital[c.ID] = c.IsCapital
+	}
+
+	for i := range entries {
+		e := &entries[i]
+		gst, total := expenseGST(e)
+		line := func(amount money.Amount) domain.BASLine {
+			return domain.BASLine{
+				Source:      domain.BASSourceExpenseEntry,
+				EntryID:     e.ID.String(),
+				Date:        e.ExpenseDate,
+				Description: e.SupplierName,
+				Amount:      amount,
+			}
+		}
+		if capital[e.CategoryID] {
+			addBASLine(&ws.G10, line(total))
+		} else {
+			addBASLine(&ws.G11, line(total))
+		}
+		addBASLine(&ws.GST1B, line(gst))
+	}
+	return nil
+}
+
+// incomeFieldResolver finds the income-section fields of the form version each entry was captured on,
+// caching per form version.
+type incomeFieldResolver struct {
+	ctx   context.Context
+	db    *sqlx.DB
+	cache map[incomeFieldKey]map[string]bool
+}
+
+type incomeFieldKey struct {
+	formID  uuid.UUID
+	version int
+}
+
+func newIncomeFieldResolver(ctx context.Context, db *sqlx.DB) *incomeFieldResolver {
+	return &incomeFieldResolver{ctx: ctx, db: db, cache: make(map[incomeFieldKey]map[string]bool)}
+}
+
+// resolve falls back to the live form only for entries captured before forms were versioned.
+func (r *incomeFieldResolver) resolve(e *domain.CustomFormEntry) (map[string]bool, error) {
+	key := incomeFieldKey{e.FormID, e.FormVersion}
+	if m, ok := r.cache[key]; ok {
+		return m, nil
+	}
+	m := make(map[string]bool)
+	var fieldsJSON []byte
+	v, err := repository.GetCustomFormVersion(r.ctx, r.db, e.FormID, e.FormVersion)
+	switch {
+	case err == nil:
+		fieldsJSON = v.Fields
+	case errors.Is(err, repository.ErrFormVersionNotFound):
+		// A deleted form leaves its pre-versioning entries with no known income fields
+		if form, err := repository.GetCustomFormByID(r.ctx, r.db, e.FormID); err == nil {
+			fieldsJSON = form.Fields
+		}
+	default:
+		return nil, err
+	}
+	fields, _ := calculation.ParseFields(fieldsJSON)
+	for _, f := range fields {
+		if e.FormType == string(domain.FormTypeIncome) ||
+			(e.FormType == string(domain.FormTypeBoth) && !calculation.IsExpenseSection(f.Section)) {
+			m[f.ID] = true
+		}
+	}
+	r.cache[key] = m
+	return m, nil
+}
+
+// gstFreeIncome totals the income fields of an entry that carried no GST. Input-taxed and
+// BAS-excluded income is not GST-free.
+func gstFreeIncome(calc entryCalcSummary, income map[string]bool) money.Amount {
+	var gstFree money.Amount
+	for _, ft := range calc.FieldTotals {
+		if ft.TaxTreatment == domain.TaxTreatmentInputTaxed || ft.TaxTreatment == domain.TaxTreatmentBASExcluded {
+			continue
+		}
+		if income[ft.FieldID] && ft.GstAmount == 0 {
+			gstFree += ft.TotalAmount
+		}
+	}
+	return gstFree
+}
+
+// expenseGST splits an expense entry into its GST component and GST-inclusive total.
+func expenseGST(e *domain.ExpenseEntry) (gst, total money.Amount) {
+	rate := 0.0
+	if e.GSTRate != nil {
+		rate = *e.GSTRate
+	}
+	if e.IsGSTInclusive != nil && *e.IsGSTInclusive {
+		return e.Amount.GSTIncluded(rate), e.Amount
+	}
+	gst = e.Amount.GST(rate)
+	return gst, e.Amount + gst
+}
+
+// addBASLine records a non-zero contribution to a label.
+func addBASLine(l *domain.BASLabel, line domain.BASLine) {
+	if line.Amount == 0 {
+		return
+	}
+	l.Amount += line.Amount
+	l.Lines = append(l.Lines, line)
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/calculation"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/money"
+)
+
+// randomCalculatedEntry runs the calculation engine on a random entry, as saving an entry does.
+func randomCalculatedEntry(t *testing.T, rng *rand.Rand) *domain.CustomFormEntry {
+	formType := []string{"income", "expense", "both"}[rng.Intn(3)]
+	var fields []domain.CustomFormField
+	var values []map[string]string
+	for i := 0; i < 1+rng.Intn(4); i++ {
+		rate := float64(rng.Intn(1501)) / 100
+		fields = append(fields, domain.CustomFormField{
+			ID:             fmt.Sprintf("f%d", i),
+			Type:           domain.FieldTypeCurrency,
+			Section:        []string{"income", "expense"}[rng.Intn(2)],
+			IncludeInTotal: true,
+			GstConfig:      &domain.FieldGSTConfig{Enabled: rng.Intn(3) > 0, Rate: &rate, Type: []string{"inclusive", "exclusive"}[rng.Intn(2)]},
+		})
+		values = append(values, map[string]string{"fieldId": fields[i].ID, "value": fmt.Sprintf("%d.%02d", rng.Intn(50000), rng.Intn(100))})
+	}
+	fieldsJSON, _ := json.Marshal(fields)
+	valuesJSON, _ := json.Marshal(values)
+	calc, err := calculation.RunEntryCalculation(fieldsJSON, formType, nil, false, nil, valuesJSON, nil, calculation.TaxSettings{GSTRate: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &domain.CustomFormEntry{ID: uuid.New(), FormID: uuid.New(), FormType: formType, Calculations: calc}
+}
+
+// A worksheet's labels, and a listing's totals, are exactly the sums of the amounts stored on each entry.
+func TestBASAndListingTotalsReconcileWithEntries(t *testing.T) {
+	f := func(seed int64) bool {
+		rng := rand.New(rand.NewSource(seed))
+		ws := &domain.BASWorksheet{}
+		var g1, g11, gst1A, gst1B money.Amount
+		var listed domain.CustomFormEntryTotals
+		sqlSum := new(big.Rat) // SUM over the stored totalAmount decimals, as the listing query does
+		for i := 0; i < rng.Intn(40); i++ {
+			e := randomCalculatedEntry(t, rng)
+			var calc entryCalcSummary
+			var totals domain.CustomFormEntryTotals
+			if json.Unmarshal(e.Calculations, &calc) != nil || json.Unmarshal(e.Calculations, &totals) != nil {
+				return false
+			}
+			addEntryBASLines(ws, e, calc)
+			g1 += calc.BasMapping.TotalSalesG1
+			g11 += calc.BasMapping.ExpensesG11 + calc.BasMapping.GstCredit1B
+			gst1A += calc.BasMapping.GstOnSales1A
+			gst1B += calc.BasMapping.GstCredit1B
+
+			listed.TotalBaseAmount += totals.TotalBaseAmount
+			listed.TotalGSTAmount += totals.TotalGSTAmount
+			listed.TotalAmount += totals.TotalAmount
+			var stored struct {
+				TotalAmount json.Number `json:"totalAmount"`
+			}
+			if json.Unmarshal(e.Calculations, &stored) != nil {
+				return false
+			}
+			d, ok := new(big.Rat).SetString(stored.TotalAmount.String())
+			if !ok {
+				return false
+			}
+			sqlSum.Add(sqlSum, d)
+		}
+		var summed money.Amount
+		if summed.Scan([]byte(sqlSum.FloatString(2))) != nil {
+			return false
+		}
+		for _, l := range []domain.BASLabel{ws.G1, ws.G11, ws.GST1A, ws.GST1B} {
+			var lines money.Amount
+			for _, line := range l.Lines {
+				lines += line.Amount
+			}
+			if lines != l.Amount {
+				return false
+			}
+		}
+		return ws.G1.Amount == g1 && ws.G11.Amount == g11 && ws.GST1A.Amount == gst1A && ws.GST1B.Amount == gst1B &&
+			listed.TotalBaseAmount+listed.TotalGSTAmount == listed.TotalAmount && summed == listed.TotalAmount
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 300}); err != nil {
+		t.Error(err)
+	}
+}
+
+// Expense entries split into GST and total exactly, whichever way the amount was entered.
+func TestExpenseGSTReconciles(t *testing.T) {
+	f := func(c int64, r uint16, inclusive bool) bool {
+		rate := float64(r%2001) / 100
+		e := &domain.ExpenseEntry{Amount: money.Cents(c % 1_000_000_000_000), GSTRate: &rate, IsGSTInclusive: &inclusive}
+		gst, total := expenseGST(e)
+		if inclusive {
+			return total == e.Amount && gst == e.Amount.GSTIncluded(rate)
+		}
+		return total-gst == e.Amount && gst == e.Amount.GST(rate)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
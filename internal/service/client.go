@@ -0,0 +1,23 @@
+package service
+
+import "context"
+
+type clientInfoKey struct{}
+
+// ClientInfo describes the client behind a request, recorded on the sessions it starts.
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// WithClientInfo returns a context carrying the requesting client's details.
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// clientInfoFrom returns the client details stored by WithClientInfo, or zero values for work not
+// started by a request.
+func clientInfoFrom(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
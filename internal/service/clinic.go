@@ -14,16 +14,18 @@ import (
 var ErrDuplicateABN = errors.New("a clinic with this ABN already exists")
 
 type ClinicService struct {
-	db *sqlx.DB
+	db    *sqlx.DB
+	logos *ClinicLogoService
 }
 
-func NewClinicService(db *sqlx.DB) *ClinicService {
+func NewClinicService(db *sqlx.DB, logos *ClinicLogoService) *ClinicService {
 	return &ClinicService{
-		db: db,
+		db:    db,
+		logos: logos,
 	}
 }
 
-func (cs *ClinicService) CreateClinic(ctx context.Context, clinic *domain.Clinic) error {
+func (cs *ClinicService) CreateClinic(ctx context.Context, clinic *domain.Clinic, actorID uuid.UUID) error {
 	// Validate state
 	if err := ValidateState(clinic.State); err != nil {
 		return err
@@ -34,8 +36,29 @@ func (cs *ClinicService) CreateClinic(ctx context.Context, clinic *domain.Clinic
 		return err
 	}
 
+	if clinic.BASCycle == "" {
+		clinic.BASCycle = domain.BASCycleQuarterly
+	}
+	if err := ValidateBASCycle(clinic.BASCycle); err != nil {
+		return err
+	}
+
 	clinic.ID = uuid.New()
-	err := repository.CreateClinic(ctx, cs.db, clinic)
+	logoChanged, err := cs.importLogo(ctx, clinic.ID, nil, clinic.LogoURL)
+	if err != nil {
+		return err
+	}
+	err = runInTx(ctx, cs.db, func(tx *sqlx.Tx) error {
+		if err := repository.CreateClinic(ctx, tx, clinic); err != nil {
+			return err
+		}
+		if logoChanged {
+			if err := repository.UpdateClinicLogoSource(ctx, tx, clinic.ID, clinic.LogoURL); err != nil {
+				return err
+			}
+		}
+		return recordAudit(ctx, tx, actorID, clinicAudit(clinic.ID, domain.AuditActionCreate, nil, clinic))
+	})
 	if err != nil {
 		if strings.Contains(err.Error(), "ux_clinic_abn_active") || strings.Contains(err.Error(), "duplicate key") {
 			return ErrDuplicateABN
@@ -49,7 +72,7 @@ func (cs *ClinicService) GetClinicByID(ctx context.Context, id uuid.UUID) (*doma
 	return repository.GetClinicByID(ctx, cs.db, id)
 }
 
-func (cs *ClinicService) UpdateClinic(ctx context.Context, clinic *domain.Clinic) error {
+func (cs *ClinicService) UpdateClinic(ctx context.Context, clinic *domain.Clinic, actorID uuid.UUID) error {
 	// Validate state
 	if err := ValidateState(clinic.State); err != nil {
 		return err
@@ -60,15 +83,30 @@ func (cs *ClinicService) UpdateClinic(ctx context.Context, clinic *domain.Clinic
 		return err
 	}
 
-	return repository.UpdateClinic(ctx, cs.db, clinic)
+	if err := ValidateBASCycle(clinic.BASCycle); err != nil {
+		return err
+	}
+
+	before, err := repository.GetClinicByID(ctx, cs.db, clinic.ID)
+	if err != nil {
+		return err
+	}
+	if before.ID == uuid.Nil {
+		return ErrClinicNotFound
+	}
+	return cs.updateClinic(ctx, before, clinic, actorID)
 }
 
 // UpdateClinicPartial merges partial updates into existing clinic and saves
-func (cs *ClinicService) UpdateClinicPartial(ctx context.Context, id uuid.UUID, req *domain.UpdateClinicRequest) (*domain.Clinic, error) {
+func (cs *ClinicService) UpdateClinicPartial(ctx context.Context, id uuid.UUID, req *domain.UpdateClinicRequest, actorID uuid.UUID) (*domain.Clinic, error) {
 	existing, err := repository.GetClinicByID(ctx, cs.db, id)
 	if err != nil {
 		return nil, err
 	}
+	if existing.ID == uuid.Nil {
+		return nil, ErrClinicNotFound
+	}
+	before := *existing
 	if req.Name != nil {
 		existing.Name = *req.Name
 	}
@@ -114,17 +152,98 @@ func (cs *ClinicService) UpdateClinicPartial(ctx context.Context, id uuid.UUID,
 	if req.OwnerShare != nil {
 		existing.OwnerShare = *req.OwnerShare
 	}
-	if err := repository.UpdateClinic(ctx, cs.db, existing); err != nil {
-		if strings.Contains(err.Error(), "ux_clinic_abn_active") || strings.Contains(err.Error(), "duplicate key") {
-			return nil, ErrDuplicateABN
+	if req.BASCycle != nil {
+		if err := ValidateBASCycle(*req.BASCycle); err != nil {
+			return nil, err
+		}
+		existing.BASCycle = *req.BASCycle
+	}
+	if req.RequireMFA != nil {
+		if *req.RequireMFA && !existing.RequireMFA {
+			// Otherwise the owner would lock themselves out of the clinic
+			mfa, err := repository.GetUserMFA(ctx, cs.db, actorID)
+			if err != nil {
+				return nil, err
+			}
+			if !mfa.Enabled() {
+				return nil, errors.New("enable two-factor authentication on your own account before requiring it for the clinic")
+			}
 		}
+		existing.RequireMFA = *req.RequireMFA
+	}
+	if err := cs.updateClinic(ctx, &before, existing, actorID); err != nil {
 		return nil, err
 	}
 	return existing, nil
 }
 
-func (cs *ClinicService) DeleteClinic(ctx context.Context, id uuid.UUID) error {
-	return repository.DeleteClinic(ctx, cs.db, id)
+func (cs *ClinicService) updateClinic(ctx context.Context, before, clinic *domain.Clinic, actorID uuid.UUID) error {
+	logoChanged, err := cs.importLogo(ctx, clinic.ID, before.LogoURL, clinic.LogoURL)
+	if err != nil {
+		return err
+	}
+	err = runInTx(ctx, cs.db, func(tx *sqlx.Tx) error {
+		if err := repository.UpdateClinic(ctx, tx, clinic); err != nil {
+			return err
+		}
+		if logoChanged {
+			if err := repository.UpdateClinicLogoSource(ctx, tx, clinic.ID, logoSource(clinic.LogoURL)); err != nil {
+				return err
+			}
+		}
+		return recordAudit(ctx, tx, actorID, clinicAudit(clinic.ID, domain.AuditActionUpdate, before, clinic))
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "ux_clinic_abn_active") || strings.Contains(err.Error(), "duplicate key") {
+			return ErrDuplicateABN
+		}
+		return err
+	}
+	if logoChanged && logoSource(clinic.LogoURL) == nil {
+		// The clinic no longer has a logo; an orphaned file is harmless
+		_ = cs.logos.Remove(ctx, clinic.ID)
+	}
+	return nil
+}
+
+// importLogo downloads a new or changed logo URL before the clinic is saved, so a URL that cannot be
+// used as a logo is refused rather than left for documents to trip over. It reports whether the
+// logo's source must be saved with the clinic.
+func (cs *ClinicService) importLogo(ctx context.Context, clinicID uuid.UUID, before, after *string) (bool, error) {
+	old, source := logoSource(before), logoSource(after)
+	switch {
+	case old == nil && source == nil:
+		return false, nil
+	case old != nil && source != nil && *old == *source:
+		return false, nil
+	case source == nil:
+		return true, nil
+	}
+	return true, cs.logos.Import(ctx, clinicID, *source)
+}
+
+// logoSource returns the logo URL a clinic's stored logo is imported from, or nil for no logo.
+func logoSource(logoURL *string) *string {
+	if logoURL == nil || strings.TrimSpace(*logoURL) == "" {
+		return nil
+	}
+	return logoURL
+}
+
+func (cs *ClinicService) DeleteClinic(ctx context.Context, id uuid.UUID, actorID uuid.UUID) error {
+	before, err := repository.GetClinicByID(ctx, cs.db, id)
+	if err != nil {
+		return err
+	}
+	if before.ID == uuid.Nil {
+		return ErrClinicNotFound
+	}
+	return runInTx(ctx, cs.db, func(tx *sqlx.Tx) error {
+		if err := repository.DeleteClinic(ctx, tx, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, actorID, clinicAudit(id, domain.AuditActionDelete, before, nil))
+	})
 }
 
 func (cs *ClinicService) GetAllClinics(ctx context.Context) ([]domain.Clinic, error) {
@@ -134,3 +253,19 @@ func (cs *ClinicService) GetAllClinics(ctx context.Context) ([]domain.Clinic, er
 func (cs *ClinicService) GetClinicByABNNumber(ctx context.Context, abnNumber string) (*domain.Clinic, error) {
 	return repository.GetClinicByABNNumber(ctx, cs.db, abnNumber)
 }
+
+func clinicAudit(id uuid.UUID, action string, before, after *domain.Clinic) auditChange {
+	change := auditChange{
+		ClinicID:   &id,
+		EntityType: domain.AuditEntityClinic,
+		EntityID:   id,
+		Action:     action,
+	}
+	if before != nil {
+		change.Before = before
+	}
+	if after != nil {
+		change.After = after
+	}
+	return change
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/repository"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// ErrNotFound is wrapped by every "<resource> not found" error returned while resolving clinic ownership.
+	ErrNotFound           = errors.New("not found")
+	ErrClinicNotFound     = fmt.Errorf("clinic %w", ErrNotFound)
+	ErrClinicAccessDenied = errors.New("access denied: you do not have access to this clinic")
+	ErrMFARequired        = errors.New("this clinic requires two-factor authentication; enable it on your account to continue")
+)
+
+// ClinicAccessService enforces tenant isolation: every clinic-owned resource is resolved to its
+// clinic and the requesting user must be a member of that clinic.
+type ClinicAccessService struct {
+	db *sqlx.DB
+}
+
+func NewClinicAccessService(db *sqlx.DB) *ClinicAccessService {
+	return &ClinicAccessService{
+		db: db,
+	}
+}
+
+// Authorize returns the user's role in the clinic. It fails with ErrClinicNotFound when the clinic
+// does not exist, ErrClinicAccessDenied when the user is not associated with it and ErrMFARequired
+// when the clinic requires two-factor authentication the user has not enabled.
+func (cas *ClinicAccessService) Authorize(ctx context.Context, userID, clinicID uuid.UUID) (string, error) {
+	exists, err := repository.ClinicExists(ctx, cas.db, clinicID)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", ErrClinicNotFound
+	}
+	userClinic, err := repository.GetUserClinicByUserAndClinic(ctx, cas.db, userID, clinicID)
+	if err != nil {
+		return "", err
+	}
+	if userClinic == nil {
+		return "", ErrClinicAccessDenied
+	}
+	blocked, err := repository.ClinicMFABlocksUser(ctx, cas.db, clinicID, userID)
+	if err != nil {
+		return "", err
+	}
+	if blocked {
+		return "", ErrMFARequired
+	}
+	return userClinic.Role, nil
+}
+
+// HasPermissionInAnyClinic reports whether any of the user's clinic roles grants the permission.
+// It guards actions that are not scoped to a clinic but touch no shared data, such as exporting
+// figures supplied in the request. Data shared by every clinic needs IsSystemAdmin instead.
+func (cas *ClinicAccessService) HasPermissionInAnyClinic(ctx context.Context, userID uuid.UUID, permission domain.Permission) (bool, error) {
+	userClinics, err := repository.GetUserClinics(ctx, cas.db, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, uc := range userClinics {
+		if domain.RoleHasPermission(uc.Role, permission) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// IsSystemAdmin reports whether the user may manage data shared by every clinic, such as the chart
+// of accounts. Clinic roles never grant this, since any user can create a clinic and own it.
+func (cas *ClinicAccessService) IsSystemAdmin(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return repository.IsSystemAdmin(ctx, cas.db, userID)
+}
+
+func (cas *ClinicAccessService) CustomFormClinic(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	return ownerOrNotFound("custom form")(repository.GetCustomFormClinicID(ctx, cas.db, id))
+}
+
+func (cas *ClinicAccessService) CustomFormEntryClinic(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	return ownerOrNotFound("custom form entry")(repository.GetCustomFormEntryClinicID(ctx, cas.db, id))
+}
+
+func (cas *ClinicAccessService) FinancialFormClinic(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	return ownerOrNotFound("financial form")(repository.GetFinancialFormClinicID(ctx, cas.db, id))
+}
+
+func (cas *ClinicAccessService) ExpenseTypeClinic(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	return ownerOrNotFound("expense type")(repository.GetExpenseTypeClinicID(ctx, cas.db, id))
+}
+
+func (cas *ClinicAccessService) ExpenseCategoryClinic(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	return ownerOrNotFound("expense category")(repository.GetExpenseCategoryClinicID(ctx, cas.db, id))
+}
+
+func (cas *ClinicAccessService) ExpenseCategoryTypeClinic(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	return ownerOrNotFound("expense category type")(repository.GetExpenseCategoryTypeClinicID(ctx, cas.db, id))
+}
+
+func (cas *ClinicAccessService) ExpenseEntryClinic(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	return ownerOrNotFound("expense entry")(repository.GetExpenseEntryClinicID(ctx, cas.db, id))
+}
+
+func (cas *ClinicAccessService) UserClinicClinic(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	return ownerOrNotFound("user clinic association")(repository.GetUserClinicClinicID(ctx, cas.db, id))
+}
+
+func (cas *ClinicAccessService) InvitationClinic(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	return ownerOrNotFound("invitation")(repository.GetInvitationClinicID(ctx, cas.db, id))
+}
+
+func ownerOrNotFound(resource string) func(uuid.UUID, bool, error) (uuid.UUID, error) {
+	return func(clinicID uuid.UUID, ok bool, err error) (uuid.UUID, error) {
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if !ok {
+			return uuid.Nil, fmt.Errorf("%s %w", resource, ErrNotFound)
+		}
+		return clinicID, nil
+	}
+}
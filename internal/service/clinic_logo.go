@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/repository"
+	"github.com/iamarpitzala/aca-reca-backend/internal/storage"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrLogoAddressNotPublic is returned for a logo URL that resolves to a private, loopback or other
+// non-public address, which the server must never be made to request.
+var ErrLogoAddressNotPublic = errors.New("logo URL must point to a public address")
+
+// ClinicLogoService keeps a copy of each clinic's logo URL in storage, downloaded when the URL is
+// saved, so documents draw the logo without the server requesting the URL every time.
+type ClinicLogoService struct {
+	db     *sqlx.DB
+	store  storage.Storage
+	client *http.Client
+}
+
+func NewClinicLogoService(db *sqlx.DB, store storage.Storage) *ClinicLogoService {
+	return &ClinicLogoService{
+		db:     db,
+		store:  store,
+		client: publicOnlyClient(),
+	}
+}
+
+func clinicLogoKey(clinicID uuid.UUID) string {
+	return "clinics/" + clinicID.String() + "/logo"
+}
+
+// Import downloads logoURL into the clinic's stored logo. The caller records logoURL as the logo's
+// source once the clinic is saved; until then Get keeps treating the stored copy as stale.
+func (s *ClinicLogoService) Import(ctx context.Context, clinicID uuid.UUID, logoURL string) error {
+	data, err := s.download(ctx, strings.TrimSpace(logoURL))
+	if err != nil {
+		return fmt.Errorf("logo URL could not be imported: %w", err)
+	}
+	if _, _, ok := logoImageType(data); !ok {
+		return errors.New("logo URL could not be imported: it must be a PNG or JPEG image")
+	}
+	if err := s.store.Put(ctx, clinicLogoKey(clinicID), data); err != nil {
+		return fmt.Errorf("failed to store logo: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the clinic's stored logo once its logo URL has been cleared.
+func (s *ClinicLogoService) Remove(ctx context.Context, clinicID uuid.UUID) error {
+	return s.store.Delete(ctx, clinicLogoKey(clinicID))
+}
+
+// Get returns the stored copy of the clinic's logo and its gofpdf image type, or nil when the clinic
+// has no logo URL. A URL saved before logos were imported, or whose copy is missing, is imported now.
+func (s *ClinicLogoService) Get(ctx context.Context, clinic *domain.Clinic) ([]byte, string, error) {
+	if clinic.LogoURL == nil || strings.TrimSpace(*clinic.LogoURL) == "" {
+		return nil, "", nil
+	}
+	source, err := repository.GetClinicLogoSource(ctx, s.db, clinic.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	var data []byte
+	if source != nil && *source == *clinic.LogoURL {
+		data, err = s.store.Get(ctx, clinicLogoKey(clinic.ID))
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return nil, "", err
+		}
+	}
+	if data == nil {
+		if err := s.Import(ctx, clinic.ID, *clinic.LogoURL); err != nil {
+			return nil, "", err
+		}
+		if err := repository.UpdateClinicLogoSource(ctx, s.db, clinic.ID, clinic.LogoURL); err != nil {
+			return nil, "", err
+		}
+		if data, err = s.store.Get(ctx, clinicLogoKey(clinic.ID)); err != nil {
+			return nil, "", err
+		}
+	}
+	_, imageType, _ := logoImageType(data)
+	return data, imageType, nil
+}
+
+func (s *ClinicLogoService) download(ctx context.Context, logoURL string) ([]byte, error) {
+	u, err := url.Parse(logoURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("logo URL must be an http or https address")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrLogoAddressNotPublic) {
+			return nil, ErrLogoAddressNotPublic
+		}
+		return nil, errors.New("logo could not be downloaded")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("logo download failed: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxLogoBytes+1))
+	if err != nil {
+		return nil, errors.New("logo could not be downloaded")
+	}
+	if len(data) > MaxLogoBytes {
+		return nil, errors.New("logo must be 2 MB or smaller")
+	}
+	return data, nil
+}
+
+// publicOnlyClient is an HTTP client that refuses to connect to non-public addresses. The check runs
+// on the address actually dialled, after DNS resolution and on every redirect, so neither a hostname
+// pointing inside the network nor a redirect to one gets through. Proxies are not used, since the
+// proxy, not this client, would choose the address.
+func publicOnlyClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: refuseNonPublicAddress}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 3 {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return errors.New("logo URL must be an http or https address")
+			}
+			return nil
+		},
+	}
+}
+
+// nonPublicPrefixes are ranges that are not reachable on the public internet but that the netip
+// predicates below do not cover.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "this network"; 0.0.0.0 reaches the local host
+	netip.MustParsePrefix("100.64.0.0/10"),  // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  // benchmarking
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64, which can embed any IPv4 address
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local-use NAT64
+}
+
+func refuseNonPublicAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return ErrLogoAddressNotPublic
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return ErrLogoAddressNotPublic
+	}
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return ErrLogoAddressNotPublic
+	}
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(ip) {
+			return ErrLogoAddressNotPublic
+		}
+	}
+	return nil
+}
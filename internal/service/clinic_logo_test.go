@@ -0,0 +1,136 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/storage"
+)
+
+var pngLogo = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestRefuseNonPublicAddress(t *testing.T) {
+	for address, public := range map[string]bool{
+		"93.184.215.14:443":          true,
+		"[2606:2800:21f:cb07::1]:80": true,
+		"127.0.0.1:80":               false,
+		"[::1]:80":                   false,
+		"10.1.2.3:80":                false,
+		"172.16.0.1:80":              false,
+		"192.168.1.1:80":             false,
+		"169.254.169.254:80":         false, // cloud metadata
+		"0.0.0.0:80":                 false,
+		"100.64.0.1:80":              false,
+		"224.0.0.1:80":               false,
+		"[fd00::1]:80":               false,
+		"[fe80::1]:80":               false,
+		"[::ffff:127.0.0.1]:80":      false,
+		"[64:ff9b::a9fe:a9fe]:80":    false,
+	} {
+		err := refuseNonPublicAddress("tcp", address, nil)
+		if public && err != nil {
+			t.Errorf("%s: refused, want allowed", address)
+		}
+		if !public && !errors.Is(err, ErrLogoAddressNotPublic) {
+			t.Errorf("%s: %v, want ErrLogoAddressNotPublic", address, err)
+		}
+	}
+}
+
+// The real client never reaches a server on the loopback address
+func TestImportClinicLogoRefusesLoopback(t *testing.T) {
+	requested := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		w.Write(pngLogo)
+	}))
+	defer srv.Close()
+
+	store := storage.NewMemoryStorage()
+	s := NewClinicLogoService(nil, store)
+	if err := s.Import(context.Background(), uuid.New(), srv.URL+"/logo.png"); !errors.Is(err, ErrLogoAddressNotPublic) {
+		t.Fatalf("import: %v, want ErrLogoAddressNotPublic", err)
+	}
+	if requested {
+		t.Error("the loopback server was requested")
+	}
+}
+
+func TestImportClinicLogo(t *testing.T) {
+	body := pngLogo
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write(body) }))
+	defer srv.Close()
+	store := storage.NewMemoryStorage()
+	// The test server is on the loopback address, so it is reached through its own client
+	s := &ClinicLogoService{store: store, client: srv.Client()}
+	clinicID := uuid.New()
+
+	if err := s.Import(context.Background(), clinicID, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := store.Get(context.Background(), clinicLogoKey(clinicID)); err != nil || !bytes.Equal(data, pngLogo) {
+		t.Fatalf("stored logo: %q, %v", data, err)
+	}
+
+	for name, url := range map[string]string{"not an image": srv.URL, "not http": "file:///etc/passwd"} {
+		body = []byte("<html>not a logo</html>")
+		if err := s.Import(context.Background(), uuid.New(), url); err == nil {
+			t.Errorf("%s: imported", name)
+		}
+	}
+	body = append(append([]byte{}, pngLogo...), make([]byte, MaxLogoBytes)...)
+	if err := s.Import(context.Background(), uuid.New(), srv.URL); err == nil {
+		t.Error("oversized logo: imported")
+	}
+}
+
+// A clinic whose logo URL was saved before logos were imported has it imported on first use; after
+// that the stored copy is drawn without requesting the URL again.
+func TestGetClinicLogoImportsLegacyURL(t *testing.T) {
+	db, mock := newMockDB(t)
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write(pngLogo)
+	}))
+	defer srv.Close()
+	s := &ClinicLogoService{db: db, store: storage.NewMemoryStorage(), client: srv.Client()}
+	logoURL := srv.URL + "/logo.png"
+	clinic := &domain.Clinic{ID: uuid.New(), LogoURL: &logoURL}
+	expectSource := func(source any) {
+		mock.ExpectQuery(stmt("SELECT logo_source_url FROM tbl_clinic")).WithArgs(clinic.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"logo_source_url"}).AddRow(source))
+	}
+
+	expectSource(nil)
+	mock.ExpectExec(stmt("UPDATE tbl_clinic SET logo_source_url = $1")).WithArgs(logoURL, clinic.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectSource(logoURL)
+	for range 2 {
+		data, imageType, err := s.Get(context.Background(), clinic)
+		if err != nil || !bytes.Equal(data, pngLogo) || imageType != "PNG" {
+			t.Fatalf("logo: %q, %q, %v", data, imageType, err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("logo URL requested %d times, want 1", requests)
+	}
+}
+
+// A logo URL the server must not request is refused before the clinic is saved
+func TestCreateClinicRefusesPrivateLogoURL(t *testing.T) {
+	db, _ := newMockDB(t)
+	s := NewClinicService(db, NewClinicLogoService(db, storage.NewMemoryStorage()))
+	logoURL := "http://169.254.169.254/latest/meta-data/"
+	clinic := &domain.Clinic{Name: "Smile Dental", ABNNumber: "51 824 753 556", State: "NSW", LogoURL: &logoURL}
+	if err := s.CreateClinic(context.Background(), clinic, uuid.New()); !errors.Is(err, ErrLogoAddressNotPublic) {
+		t.Fatalf("create: %v, want ErrLogoAddressNotPublic", err)
+	}
+}
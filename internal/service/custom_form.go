@@ -13,6 +13,9 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
+// ErrCustomFormModified is returned when another edit saved the form after it was read.
+var ErrCustomFormModified = errors.New("the form was changed by someone else; reload it and try again")
+
 type CustomFormService struct {
 	db *sqlx.DB
 }
@@ -62,12 +65,27 @@ func (s *CustomFormService) Create(ctx context.Context, req *domain.CreateCustom
 		CreatedAt:                    now,
 		UpdatedAt:                    now,
 	}
-	if err := repository.CreateCustomForm(ctx, s.db, form); err != nil {
+	if err := s.createForm(ctx, form, userID); err != nil {
 		return nil, err
 	}
 	return customFormToResponse(form), nil
 }
 
+func (s *CustomFormService) createForm(ctx context.Context, form *domain.CustomForm, userID uuid.UUID) error {
+	return runInTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		if err := repository.CreateCustomForm(ctx, tx, form); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, userID, auditChange{
+			ClinicID:   &form.ClinicID,
+			EntityType: domain.AuditEntityCustomForm,
+			EntityID:   form.ID,
+			Action:     domain.AuditActionCreate,
+			After:      customFormToResponse(form),
+		})
+	})
+}
+
 func (s *CustomFormService) GetByID(ctx context.Context, id uuid.UUID) (*domain.CustomFormResponse, error) {
 	form, err := repository.GetCustomFormByID(ctx, s.db, id)
 	if err != nil {
@@ -100,13 +118,14 @@ func (s *CustomFormService) GetPublishedByClinicID(ctx context.Context, clinicID
 	return out, nil
 }
 
-func (s *CustomFormService) Update(ctx context.Context, id uuid.UUID, req *domain.UpdateCustomFormRequest) (*domain.CustomFormResponse, error) {
+func (s *CustomFormService) Update(ctx context.Context, id uuid.UUID, req *domain.UpdateCustomFormRequest, userID uuid.UUID) (*domain.CustomFormResponse, error) {
 	form, err := repository.GetCustomFormByID(ctx, s.db, id)
 	if err != nil {
 		return nil, err
 	}
+	before := *form
 	if form.Status == "published" {
-		// Published: only allow updating field section if needed; for simplicity we allow full update of fields for section only
+		// Published: only the field definitions may change; each change becomes a new version
 		if req.Fields != nil {
 			form.Fields = req.Fields
 		}
@@ -133,14 +152,49 @@ func (s *CustomFormService) Update(ctx context.Context, id uuid.UUID, req *domai
 			form.OutworkRatePercent = req.OutworkRatePercent
 		}
 	}
+
+	// Once a form has been published, entries are pinned to its versions, so any change to
+	// the definition is written as a new immutable version instead of editing in place.
+	newVersion := form.PublishedAt != nil && formDefinitionChanged(&before, form)
+	if newVersion {
+		if err := calculation.ValidateFormFields(form.Fields); err != nil {
+			return nil, err
+		}
+		if err := checkLinkedAccounts(ctx, s.db, form.Fields); err != nil {
+			return nil, err
+		}
+		form.Version++
+	}
 	form.UpdatedAt = time.Now()
-	if err := repository.UpdateCustomForm(ctx, s.db, form); err != nil {
+	err = runInTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		saved, err := repository.UpdateCustomForm(ctx, tx, form, before.Version)
+		if err != nil {
+			return err
+		}
+		if !saved {
+			return ErrCustomFormModified
+		}
+		if newVersion {
+			if err := repository.CreateCustomFormVersion(ctx, tx, formVersionSnapshot(form, userID)); err != nil {
+				return err
+			}
+		}
+		return recordAudit(ctx, tx, userID, auditChange{
+			ClinicID:   &form.ClinicID,
+			EntityType: domain.AuditEntityCustomForm,
+			EntityID:   form.ID,
+			Action:     domain.AuditActionUpdate,
+			Before:     customFormToResponse(&before),
+			After:      customFormToResponse(form),
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 	return customFormToResponse(form), nil
 }
 
-func (s *CustomFormService) Publish(ctx context.Context, id uuid.UUID) (*domain.CustomFormResponse, error) {
+func (s *CustomFormService) Publish(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*domain.CustomFormResponse, error) {
 	form, err := repository.GetCustomFormByID(ctx, s.db, id)
 	if err != nil {
 		return nil, err
@@ -151,31 +205,128 @@ func (s *CustomFormService) Publish(ctx context.Context, id uuid.UUID) (*domain.
 	if len(form.Fields) == 0 || string(form.Fields) == "[]" {
 		return nil, errors.New("cannot publish a form with no fields")
 	}
-	if err := repository.PublishCustomForm(ctx, s.db, id); err != nil {
+	if err := calculation.ValidateFormFields(form.Fields); err != nil {
+		return nil, err
+	}
+	if err := checkLinkedAccounts(ctx, s.db, form.Fields); err != nil {
 		return nil, err
 	}
-	form.Status = "published"
+	// Re-publishing an archived form reuses its existing snapshot for the current version
+	_, versionErr := repository.GetCustomFormVersion(ctx, s.db, form.ID, form.Version)
+	if versionErr != nil && !errors.Is(versionErr, repository.ErrFormVersionNotFound) {
+		return nil, versionErr
+	}
+	before := customFormToResponse(form)
 	now := time.Now()
-	form.PublishedAt = &now
-	form.UpdatedAt = now
-	return customFormToResponse(form), nil
+	published := *form
+	published.Status = "published"
+	published.PublishedAt = &now
+	published.UpdatedAt = now
+	err = runInTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		if err := repository.PublishCustomForm(ctx, tx, id); err != nil {
+			return err
+		}
+		if versionErr != nil {
+			if err := repository.CreateCustomFormVersion(ctx, tx, formVersionSnapshot(form, userID)); err != nil {
+				return err
+			}
+		}
+		return recordAudit(ctx, tx, userID, auditChange{
+			ClinicID:   &form.ClinicID,
+			EntityType: domain.AuditEntityCustomForm,
+			EntityID:   form.ID,
+			Action:     domain.AuditActionPublish,
+			Before:     before,
+			After:      customFormToResponse(&published),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return customFormToResponse(&published), nil
 }
 
-func (s *CustomFormService) Archive(ctx context.Context, id uuid.UUID) (*domain.CustomFormResponse, error) {
-	form, err := repository.GetCustomFormByID(ctx, s.db, id)
+// ListVersions returns all published snapshots of a form, newest first.
+func (s *CustomFormService) ListVersions(ctx context.Context, formID uuid.UUID) ([]domain.CustomFormVersionResponse, error) {
+	if _, err := repository.GetCustomFormByID(ctx, s.db, formID); err != nil {
+		return nil, err
+	}
+	versions, err := repository.GetCustomFormVersions(ctx, s.db, formID)
 	if err != nil {
 		return nil, err
 	}
-	if err := repository.ArchiveCustomForm(ctx, s.db, id); err != nil {
+	out := make([]domain.CustomFormVersionResponse, len(versions))
+	for i := range versions {
+		out[i] = *customFormVersionToResponse(&versions[i])
+	}
+	return out, nil
+}
+
+func (s *CustomFormService) GetVersion(ctx context.Context, formID uuid.UUID, version int) (*domain.CustomFormVersionResponse, error) {
+	v, err := repository.GetCustomFormVersion(ctx, s.db, formID, version)
+	if err != nil {
 		return nil, err
 	}
+	return customFormVersionToResponse(v), nil
+}
+
+// DiffVersions compares two versions of a form field by field.
+func (s *CustomFormService) DiffVersions(ctx context.Context, formID uuid.UUID, from, to int) (*domain.CustomFormVersionDiff, error) {
+	a, err := repository.GetCustomFormVersion(ctx, s.db, formID, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := repository.GetCustomFormVersion(ctx, s.db, formID, to)
+	if err != nil {
+		return nil, err
+	}
+	return diffFormVersions(a, b)
+}
+
+func (s *CustomFormService) Archive(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*domain.CustomFormResponse, error) {
+	form, err := repository.GetCustomFormByID(ctx, s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	before := customFormToResponse(form)
 	form.Status = "archived"
 	form.UpdatedAt = time.Now()
+	err = runInTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		if err := repository.ArchiveCustomForm(ctx, tx, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, userID, auditChange{
+			ClinicID:   &form.ClinicID,
+			EntityType: domain.AuditEntityCustomForm,
+			EntityID:   form.ID,
+			Action:     domain.AuditActionArchive,
+			Before:     before,
+			After:      customFormToResponse(form),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
 	return customFormToResponse(form), nil
 }
 
-func (s *CustomFormService) Delete(ctx context.Context, id uuid.UUID) error {
-	return repository.DeleteCustomForm(ctx, s.db, id)
+func (s *CustomFormService) Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
+	form, err := repository.GetCustomFormByID(ctx, s.db, id)
+	if err != nil {
+		return err
+	}
+	return runInTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		if err := repository.DeleteCustomForm(ctx, tx, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, userID, auditChange{
+			ClinicID:   &form.ClinicID,
+			EntityType: domain.AuditEntityCustomForm,
+			EntityID:   form.ID,
+			Action:     domain.AuditActionDelete,
+			Before:     customFormToResponse(form),
+		})
+	})
 }
 
 func (s *CustomFormService) Duplicate(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*domain.CustomFormResponse, error) {
@@ -202,7 +353,7 @@ func (s *CustomFormService) Duplicate(ctx context.Context, id uuid.UUID, userID
 		CreatedAt:                    now,
 		UpdatedAt:                    now,
 	}
-	if err := repository.CreateCustomForm(ctx, s.db, newForm); err != nil {
+	if err := s.createForm(ctx, newForm, userID); err != nil {
 		return nil, err
 	}
 	return customFormToResponse(newForm), nil
@@ -260,18 +411,28 @@ func (s *CustomFormService) CreateEntry(ctx context.Context, req *domain.CreateE
 		}
 	}
 
-	var quarterID *uuid.UUID
-	if req.QuarterID != nil && *req.QuarterID != "" {
-		q, err := uuid.Parse(*req.QuarterID)
+	var correctsEntryID *uuid.UUID
+	if req.CorrectsEntryID != nil && *req.CorrectsEntryID != "" {
+		id, err := uuid.Parse(*req.CorrectsEntryID)
 		if err != nil {
-			return nil, errors.New("invalid quarter ID")
+			return nil, errors.New("invalid correctsEntryId")
 		}
-		quarterID = &q
+		original, err := repository.GetCustomFormEntryByID(ctx, s.db, id)
+		if err != nil {
+			return nil, errors.New("entry being corrected not found")
+		}
+		if original.ClinicID != clinicID || original.FormID != formID {
+			return nil, errors.New("a correcting entry must use the same clinic and form as the entry it corrects")
+		}
+		correctsEntryID = &id
 	}
 
 	if len(req.Values) == 0 {
 		req.Values = []byte("[]")
 	}
+	if err := calculation.ValidateEntryValues(form.Fields, req.Values); err != nil {
+		return nil, err
+	}
 	deductions := req.Deductions
 	deductionsForCalc := mergeEntryPaymentResponsibilityIntoDeductions(deductions, req.PaymentResponsibility)
 	if len(deductionsForCalc) == 0 {
@@ -281,6 +442,10 @@ func (s *CustomFormService) CreateEntry(ctx context.Context, req *domain.CreateE
 		deductions = nil
 	}
 
+	tax, err := entryTaxSettings(ctx, s.db, clinicID, entryDate, form.Fields)
+	if err != nil {
+		return nil, err
+	}
 	// Run calculations on backend (single source of truth)
 	calculations, err := calculation.RunEntryCalculation(
 		form.Fields,
@@ -290,6 +455,7 @@ func (s *CustomFormService) CreateEntry(ctx context.Context, req *domain.CreateE
 		form.OutworkRatePercent,
 		req.Values,
 		deductionsForCalc,
+		tax,
 	)
 	if err != nil {
 		return nil, err
@@ -301,8 +467,8 @@ func (s *CustomFormService) CreateEntry(ctx context.Context, req *domain.CreateE
 		FormID:                formID,
 		FormName:              form.Name,
 		FormType:              form.FormType,
+		FormVersion:           form.Version,
 		ClinicID:              clinicID,
-		QuarterID:             quarterID,
 		Values:                req.Values,
 		Calculations:          calculations,
 		EntryDate:             entryDate,
@@ -310,11 +476,35 @@ func (s *CustomFormService) CreateEntry(ctx context.Context, req *domain.CreateE
 		Remarks:               req.Remarks,
 		PaymentResponsibility: req.PaymentResponsibility,
 		Deductions:            deductions,
+		CorrectsEntryID:       correctsEntryID,
 		CreatedBy:             userID,
 		CreatedAt:             now,
 		UpdatedAt:             now,
 	}
-	if err := repository.CreateCustomFormEntry(ctx, s.db, entry); err != nil {
+	err = runInTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		// The reporting period always follows the entry date; any client-supplied quarterId is ignored
+		period, err := repository.GetPeriodForDate(ctx, tx, clinicID, entryDate)
+		if err != nil {
+			return err
+		}
+		if period != nil {
+			entry.QuarterID = &period.ID
+		}
+		if err := checkPeriodOpen(ctx, tx, entry.ClinicID, entry.EntryDate, entry.QuarterID); err != nil {
+			return err
+		}
+		if err := repository.CreateCustomFormEntry(ctx, tx, entry); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, userID, auditChange{
+			ClinicID:   &entry.ClinicID,
+			EntityType: domain.AuditEntityCustomFormEntry,
+			EntityID:   entry.ID,
+			Action:     domain.AuditActionCreate,
+			After:      customFormEntryToResponse(entry),
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 	return customFormEntryToResponse(entry), nil
@@ -325,40 +515,36 @@ func (s *CustomFormService) GetEntryByID(ctx context.Context, id uuid.UUID) (*do
 	if err != nil {
 		return nil, err
 	}
-	return customFormEntryToResponse(entry), nil
+	resp := customFormEntryToResponse(entry)
+	if form, err := repository.GetCustomFormByID(ctx, s.db, entry.FormID); err == nil {
+		def, err := s.resolveEntryFormVersion(ctx, form, entry.FormVersion)
+		if err != nil {
+			return nil, err
+		}
+		resp.Fields = def.Fields
+	}
+	return resp, nil
 }
 
-func (s *CustomFormService) GetEntriesByFormID(ctx context.Context, formID uuid.UUID) ([]domain.CustomFormEntryResponse, error) {
-	entries, err := repository.GetCustomFormEntriesByFormID(ctx, s.db, formID)
+func (s *CustomFormService) UpdateEntry(ctx context.Context, id uuid.UUID, req *domain.UpdateEntryRequest, userID uuid.UUID) (*domain.CustomFormEntryResponse, error) {
+	entry, err := repository.GetCustomFormEntryByID(ctx, s.db, id)
 	if err != nil {
 		return nil, err
 	}
-	out := make([]domain.CustomFormEntryResponse, len(entries))
-	for i := range entries {
-		out[i] = *customFormEntryToResponse(&entries[i])
-	}
-	return out, nil
-}
-
-func (s *CustomFormService) GetEntriesByClinicID(ctx context.Context, clinicID uuid.UUID) ([]domain.CustomFormEntryResponse, error) {
-	entries, err := repository.GetCustomFormEntriesByClinicID(ctx, s.db, clinicID)
+	before := customFormEntryToResponse(entry)
+	form, err := repository.GetCustomFormByID(ctx, s.db, entry.FormID)
 	if err != nil {
 		return nil, err
 	}
-	out := make([]domain.CustomFormEntryResponse, len(entries))
-	for i := range entries {
-		out[i] = *customFormEntryToResponse(&entries[i])
-	}
-	return out, nil
-}
-
-func (s *CustomFormService) UpdateEntry(ctx context.Context, id uuid.UUID, req *domain.UpdateEntryRequest) (*domain.CustomFormEntryResponse, error) {
-	entry, err := repository.GetCustomFormEntryByID(ctx, s.db, id)
+	// Validate and calculate against the version the entry was captured on, not the live form
+	def, err := s.resolveEntryFormVersion(ctx, form, entry.FormVersion)
 	if err != nil {
 		return nil, err
 	}
-	form, err := repository.GetCustomFormByID(ctx, s.db, entry.FormID)
-	if err != nil {
+	if len(req.Values) == 0 {
+		req.Values = []byte("[]")
+	}
+	if err := calculation.ValidateEntryValues(def.Fields, req.Values); err != nil {
 		return nil, err
 	}
 	entry.Values = req.Values
@@ -370,47 +556,106 @@ func (s *CustomFormService) UpdateEntry(ctx context.Context, id uuid.UUID, req *
 	if len(deductions) == 0 {
 		deductions = nil
 	}
+	// The GST rate is the one in force on the entry date, so editing an old entry keeps its rate
+	tax, err := entryTaxSettings(ctx, s.db, entry.ClinicID, entry.EntryDate, def.Fields)
+	if err != nil {
+		return nil, err
+	}
 	calculations, err := calculation.RunEntryCalculation(
-		form.Fields,
-		form.FormType,
-		form.ServiceFacilityFeePercent,
-		form.OutworkEnabled,
-		form.OutworkRatePercent,
+		def.Fields,
+		def.FormType,
+		def.ServiceFacilityFeePercent,
+		def.OutworkEnabled,
+		def.OutworkRatePercent,
 		req.Values,
 		deductionsForCalc,
+		tax,
 	)
 	if err != nil {
 		return nil, err
 	}
 	entry.Calculations = calculations
 	entry.UpdatedAt = time.Now()
-	if err := repository.UpdateCustomFormEntry(ctx, s.db, entry); err != nil {
+	err = runInTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		if err := checkPeriodOpen(ctx, tx, entry.ClinicID, entry.EntryDate, entry.QuarterID); err != nil {
+			return err
+		}
+		if err := repository.UpdateCustomFormEntry(ctx, tx, entry); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, userID, auditChange{
+			ClinicID:   &entry.ClinicID,
+			EntityType: domain.AuditEntityCustomFormEntry,
+			EntityID:   entry.ID,
+			Action:     domain.AuditActionUpdate,
+			Before:     before,
+			After:      customFormEntryToResponse(entry),
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
-	return customFormEntryToResponse(entry), nil
+	resp := customFormEntryToResponse(entry)
+	resp.Fields = def.Fields
+	return resp, nil
 }
 
-func (s *CustomFormService) DeleteEntry(ctx context.Context, id uuid.UUID) error {
-	return repository.DeleteCustomFormEntry(ctx, s.db, id)
+func (s *CustomFormService) DeleteEntry(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
+	entry, err := repository.GetCustomFormEntryByID(ctx, s.db, id)
+	if err != nil {
+		return err
+	}
+	return runInTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		if err := checkPeriodOpen(ctx, tx, entry.ClinicID, entry.EntryDate, entry.QuarterID); err != nil {
+			return err
+		}
+		if err := repository.DeleteCustomFormEntry(ctx, tx, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, userID, auditChange{
+			ClinicID:   &entry.ClinicID,
+			EntityType: domain.AuditEntityCustomFormEntry,
+			EntityID:   entry.ID,
+			Action:     domain.AuditActionDelete,
+			Before:     customFormEntryToResponse(entry),
+		})
+	})
 }
 
 // PreviewCalculations returns calculations for the given form and values (for live display; no save).
-func (s *CustomFormService) PreviewCalculations(ctx context.Context, formID uuid.UUID, valuesJSON, deductionsJSON []byte) ([]byte, error) {
+// When formVersion is set the preview uses that version's definition (e.g. while editing an older entry).
+// GST follows the clinic's rate on entryDate.
+func (s *CustomFormService) PreviewCalculations(ctx context.Context, formID uuid.UUID, formVersion *int, entryDate time.Time, valuesJSON, deductionsJSON []byte) ([]byte, error) {
 	form, err := repository.GetCustomFormByID(ctx, s.db, formID)
 	if err != nil {
 		return nil, err
 	}
+	def := formVersionSnapshot(form, form.CreatedBy)
+	if formVersion != nil && *formVersion != form.Version {
+		def, err = repository.GetCustomFormVersion(ctx, s.db, formID, *formVersion)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if len(valuesJSON) == 0 {
 		valuesJSON = []byte("[]")
 	}
+	if err := calculation.ValidateEntryValues(def.Fields, valuesJSON); err != nil {
+		return nil, err
+	}
+	tax, err := entryTaxSettings(ctx, s.db, form.ClinicID, entryDate, def.Fields)
+	if err != nil {
+		return nil, err
+	}
 	return calculation.RunEntryCalculation(
-		form.Fields,
-		form.FormType,
-		form.ServiceFacilityFeePercent,
-		form.OutworkEnabled,
-		form.OutworkRatePercent,
+		def.Fields,
+		def.FormType,
+		def.ServiceFacilityFeePercent,
+		def.OutworkEnabled,
+		def.OutworkRatePercent,
 		valuesJSON,
 		deductionsJSON,
+		tax,
 	)
 }
 
@@ -437,11 +682,17 @@ func customFormEntryToResponse(e *domain.CustomFormEntry) *domain.CustomFormEntr
 		s := e.QuarterID.String()
 		quarterID = &s
 	}
+	var correctsEntryID *string
+	if e.CorrectsEntryID != nil {
+		s := e.CorrectsEntryID.String()
+		correctsEntryID = &s
+	}
 	return &domain.CustomFormEntryResponse{
 		ID:                    e.ID.String(),
 		FormID:                e.FormID.String(),
 		FormName:              e.FormName,
 		FormType:              e.FormType,
+		FormVersion:           e.FormVersion,
 		ClinicID:              e.ClinicID.String(),
 		QuarterID:             quarterID,
 		Values:                e.Values,
@@ -451,6 +702,7 @@ func customFormEntryToResponse(e *domain.CustomFormEntry) *domain.CustomFormEntr
 		Remarks:               e.Remarks,
 		PaymentResponsibility: e.PaymentResponsibility,
 		Deductions:            e.Deductions,
+		CorrectsEntryID:       correctsEntryID,
 		CreatedBy:             e.CreatedBy.String(),
 		CreatedAt:             e.CreatedAt,
 		UpdatedAt:             e.UpdatedAt,
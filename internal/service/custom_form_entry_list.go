@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/money"
+	"github.com/iamarpitzala/aca-reca-backend/internal/repository"
+)
+
+const (
+	defaultEntryPageSize = 50
+	maxEntryPageSize     = 500
+)
+
+var (
+	ErrInvalidEntryCursor   = errors.New("cursor is invalid or was issued for a different sort")
+	ErrEntryQuarterNotFound = errors.New("quarter not found")
+)
+
+// ListEntries returns a page of a clinic's entries matching filter, with the count and totals of every
+// matching entry. A quarter narrows the date range to the clinic period, as the BAS does.
+func (s *CustomFormService) ListEntries(ctx context.Context, filter domain.CustomFormEntryFilter) (*domain.CustomFormEntryPage, error) {
+	if filter.Sort == "" {
+		filter.Sort = domain.EntrySortEntryDate
+	}
+	if filter.Order == "" {
+		filter.Order = domain.SortDesc
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultEntryPageSize
+	}
+	if filter.Limit > maxEntryPageSize {
+		filter.Limit = maxEntryPageSize
+	}
+	if filter.Cursor != "" {
+		after, err := decodeEntryCursor(filter.Cursor, filter.Sort)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = after
+	}
+	if filter.QuarterID != nil {
+		period, err := repository.GetClinicPeriodByID(ctx, s.db, filter.ClinicID, *filter.QuarterID)
+		if err != nil {
+			return nil, err
+		}
+		if period == nil {
+			return nil, ErrEntryQuarterNotFound
+		}
+		if filter.From == nil || filter.From.Before(period.StartDate) {
+			filter.From = &period.StartDate
+		}
+		if filter.To == nil || filter.To.After(period.EndDate) {
+			filter.To = &period.EndDate
+		}
+	}
+
+	total, totals, err := repository.GetCustomFormEntryTotals(ctx, s.db, filter)
+	if err != nil {
+		return nil, err
+	}
+	// One extra row tells whether there is a next page
+	limit := filter.Limit
+	filter.Limit++
+	entries, err := repository.ListCustomFormEntries(ctx, s.db, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.CustomFormEntryPage{
+		Entries: make([]domain.CustomFormEntryResponse, 0, len(entries)),
+		Total:   total,
+		Totals:  *totals,
+	}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		page.NextCursor = encodeEntryCursor(&entries[limit-1], filter.Sort)
+	}
+	for i := range entries {
+		page.Entries = append(page.Entries, *customFormEntryToResponse(&entries[i]))
+	}
+	return page, nil
+}
+
+// encodeEntryCursor records where a page ended: the last entry's sort value and ID.
+func encodeEntryCursor(e *domain.CustomFormEntry, sort string) string {
+	c := domain.CustomFormEntryCursor{Sort: sort, ID: e.ID}
+	if sort == domain.EntrySortTotalAmount {
+		c.Value = entryTotalAmount(e)
+	} else {
+		c.Value = e.EntryDate.Format("2006-01-02")
+	}
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeEntryCursor(cursor, sort string) (*domain.CustomFormEntryCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidEntryCursor
+	}
+	var c domain.CustomFormEntryCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Sort != sort || c.Value == "" {
+		return nil, ErrInvalidEntryCursor
+	}
+	// The value is passed to SQL as a parameter; reject anything the cast would fail on
+	if sort == domain.EntrySortTotalAmount {
+		if _, err := money.Parse(c.Value); err != nil {
+			return nil, ErrInvalidEntryCursor
+		}
+	} else if _, err := time.Parse("2006-01-02", c.Value); err != nil {
+		return nil, ErrInvalidEntryCursor
+	}
+	return &c, nil
+}
+
+// entryTotalAmount is the calculated totalAmount of an entry exactly as stored, so that it compares
+// equal to the value the listing sorted on; "0" when the entry has none.
+func entryTotalAmount(e *domain.CustomFormEntry) string {
+	var calc struct {
+		TotalAmount json.Number `json:"totalAmount"`
+	}
+	if json.Unmarshal(e.Calculations, &calc) != nil || calc.TotalAmount == "" {
+		return "0"
+	}
+	return calc.TotalAmount.String()
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+)
+
+// Two edits of the same published version cannot both become the next version: the second save
+// finds the form already moved on and fails without writing a snapshot.
+func TestUpdatePublishedFormDetectsConcurrentEdit(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := NewCustomFormService(db)
+	now := time.Now()
+	formID, userID := uuid.New(), uuid.New()
+	fields := func(name string) json.RawMessage {
+		return json.RawMessage(`[{"id":"fee","name":"` + name + `","type":"currency","section":"income"}]`)
+	}
+	expectForm := func() {
+		mock.ExpectQuery(stmt("FROM tbl_custom_form WHERE id = $1")).WithArgs(formID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "clinic_id", "name", "description", "calculation_method", "form_type", "status", "fields",
+				"default_payment_responsibility", "service_facility_fee_percent", "outwork_enabled", "outwork_rate_percent", "version",
+				"created_by", "created_at", "updated_at", "published_at", "deleted_at"}).
+				AddRow(formID, uuid.New(), "Fees", "", "net", "income", "published", []byte(fields("Fee")),
+					nil, nil, false, nil, 3, userID, now, now, now, nil))
+	}
+	updated := func(rows int64) {
+		mock.ExpectExec(stmt("UPDATE tbl_custom_form SET")).
+			WithArgs("Fees", "", sqlmock.AnyArg(), nil, nil, false, nil, 4, sqlmock.AnyArg(), formID, 3).
+			WillReturnResult(sqlmock.NewResult(0, rows))
+	}
+
+	expectForm()
+	mock.ExpectBegin()
+	updated(1)
+	mock.ExpectExec(stmt("INSERT INTO tbl_custom_form_version")).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(stmt("INSERT INTO tbl_audit_log")).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	resp, err := s.Update(context.Background(), formID, &domain.UpdateCustomFormRequest{Fields: fields("Patient fee")}, userID)
+	if err != nil || resp.Version != 4 {
+		t.Fatalf("first edit: %+v, %v", resp, err)
+	}
+
+	expectForm()
+	mock.ExpectBegin()
+	updated(0)
+	mock.ExpectRollback()
+	if _, err := s.Update(context.Background(), formID, &domain.UpdateCustomFormRequest{Fields: fields("Gross fee")}, userID); !errors.Is(err, ErrCustomFormModified) {
+		t.Fatalf("concurrent edit: %v, want ErrCustomFormModified", err)
+	}
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/calculation"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/repository"
+)
+
+// formVersionSnapshot captures the calculation-relevant definition of a form at its current version.
+func formVersionSnapshot(f *domain.CustomForm, userID uuid.UUID) *domain.CustomFormVersion {
+	return &domain.CustomFormVersion{
+		ID:                           uuid.New(),
+		FormID:                       f.ID,
+		Version:                      f.Version,
+		Name:                         f.Name,
+		CalculationMethod:            f.CalculationMethod,
+		FormType:                     f.FormType,
+		Fields:                       f.Fields,
+		DefaultPaymentResponsibility: f.DefaultPaymentResponsibility,
+		ServiceFacilityFeePercent:    f.ServiceFacilityFeePercent,
+		OutworkEnabled:               f.OutworkEnabled,
+		OutworkRatePercent:           f.OutworkRatePercent,
+		CreatedBy:                    userID,
+		CreatedAt:                    time.Now(),
+	}
+}
+
+// resolveEntryFormVersion returns the form definition an entry was captured on. Only entries created
+// before versioning existed, which have no snapshot, fall back to the live form row; any other error
+// is returned rather than reading the entry against a definition it was not captured on.
+func (s *CustomFormService) resolveEntryFormVersion(ctx context.Context, form *domain.CustomForm, version int) (*domain.CustomFormVersion, error) {
+	v, err := repository.GetCustomFormVersion(ctx, s.db, form.ID, version)
+	if errors.Is(err, repository.ErrFormVersionNotFound) {
+		return formVersionSnapshot(form, form.CreatedBy), nil
+	}
+	return v, err
+}
+
+func formDefinitionChanged(a, b *domain.CustomForm) bool {
+	return !jsonEqual(a.Fields, b.Fields) ||
+		!reflect.DeepEqual(a.DefaultPaymentResponsibility, b.DefaultPaymentResponsibility) ||
+		!reflect.DeepEqual(a.ServiceFacilityFeePercent, b.ServiceFacilityFeePercent) ||
+		a.OutworkEnabled != b.OutworkEnabled ||
+		!reflect.DeepEqual(a.OutworkRatePercent, b.OutworkRatePercent)
+}
+
+// jsonEqual compares two JSON documents semantically (ignoring whitespace and key order).
+func jsonEqual(a, b json.RawMessage) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	var x, y interface{}
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+func diffFormVersions(a, b *domain.CustomFormVersion) (*domain.CustomFormVersionDiff, error) {
+	before, err := calculation.ParseFields(a.Fields)
+	if err != nil {
+		return nil, err
+	}
+	after, err := calculation.ParseFields(b.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &domain.CustomFormVersionDiff{
+		FormID:        a.FormID.String(),
+		FromVersion:   a.Version,
+		ToVersion:     b.Version,
+		AddedFields:   []domain.CustomFormField{},
+		RemovedFields: []domain.CustomFormField{},
+		ChangedFields: []domain.FieldChange{},
+		Settings:      []domain.SettingChange{},
+	}
+
+	beforeByID := make(map[string]domain.CustomFormField, len(before))
+	for _, f := range before {
+		beforeByID[f.ID] = f
+	}
+	afterByID := make(map[string]bool, len(after))
+	for _, f := range after {
+		afterByID[f.ID] = true
+		old, ok := beforeByID[f.ID]
+		if !ok {
+			diff.AddedFields = append(diff.AddedFields, f)
+			continue
+		}
+		if attrs := changedFieldAttributes(old, f); len(attrs) > 0 {
+			diff.ChangedFields = append(diff.ChangedFields, domain.FieldChange{
+				FieldID:    f.ID,
+				FieldName:  f.Name,
+				Attributes: attrs,
+				Before:     old,
+				After:      f,
+			})
+		}
+	}
+	for _, f := range before {
+		if !afterByID[f.ID] {
+			diff.RemovedFields = append(diff.RemovedFields, f)
+		}
+	}
+
+	addSetting := func(name string, from, to interface{}) {
+		if !reflect.DeepEqual(from, to) {
+			diff.Settings = append(diff.Settings, domain.SettingChange{Setting: name, From: from, To: to})
+		}
+	}
+	addSetting("name", a.Name, b.Name)
+	addSetting("defaultPaymentResponsibility", a.DefaultPaymentResponsibility, b.DefaultPaymentResponsibility)
+	addSetting("serviceFacilityFeePercent", a.ServiceFacilityFeePercent, b.ServiceFacilityFeePercent)
+	addSetting("outworkEnabled", a.OutworkEnabled, b.OutworkEnabled)
+	addSetting("outworkRatePercent", a.OutworkRatePercent, b.OutworkRatePercent)
+	return diff, nil
+}
+
+func changedFieldAttributes(a, b domain.CustomFormField) []string {
+	var attrs []string
+	check := func(name string, x, y interface{}) {
+		if !reflect.DeepEqual(x, y) {
+			attrs = append(attrs, name)
+		}
+	}
+	check("name", a.Name, b.Name)
+	check("type", a.Type, b.Type)
+	check("section", a.Section, b.Section)
+	check("required", a.Required, b.Required)
+	check("includeInTotal", a.IncludeInTotal, b.IncludeInTotal)
+	check("gstConfig", a.GstConfig, b.GstConfig)
+	check("options", a.Options, b.Options)
+	check("validation", a.Validation, b.Validation)
+	check("paymentResponsibility", a.PaymentResponsibility, b.PaymentResponsibility)
+	check("conditionalLogic", a.ConditionalLogic, b.ConditionalLogic)
+	check("formula", a.Formula, b.Formula)
+	check("accountId", a.AccountID, b.AccountID)
+	return attrs
+}
+
+func customFormVersionToResponse(v *domain.CustomFormVersion) *domain.CustomFormVersionResponse {
+	return &domain.CustomFormVersionResponse{
+		FormID:                       v.FormID.String(),
+		Version:                      v.Version,
+		Name:                         v.Name,
+		CalculationMethod:            v.CalculationMethod,
+		FormType:                     v.FormType,
+		Fields:                       v.Fields,
+		DefaultPaymentResponsibility: v.DefaultPaymentResponsibility,
+		ServiceFacilityFeePercent:    v.ServiceFacilityFeePercent,
+		OutworkEnabled:               v.OutworkEnabled,
+		OutworkRatePercent:           v.OutworkRatePercent,
+		CreatedBy:                    v.CreatedBy.String(),
+		CreatedAt:                    v.CreatedAt,
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 
 	"github.com/google/uuid"
 	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
@@ -20,19 +21,100 @@ func NewExpensesService(db *sqlx.DB) *ExpensesService {
 }
 
 func (es *ExpensesService) CreateExpenseType(ctx context.Context, expenseType *domain.ExpenseType) error {
-	return repository.CreateExpenseType(ctx, es.db, expenseType)
+	return runInTx(ctx, es.db, func(tx *sqlx.Tx) error {
+		if err := repository.CreateExpenseType(ctx, tx, expenseType); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, expenseType.CreatedBy, auditChange{
+			ClinicID:   &expenseType.ClinicID,
+			EntityType: domain.AuditEntityExpenseType,
+			EntityID:   expenseType.ID,
+			Action:     domain.AuditActionCreate,
+			After:      expenseType,
+		})
+	})
 }
 
 func (es *ExpensesService) CreateExpenseCategory(ctx context.Context, expenseCategory *domain.ExpenseCategory) error {
-	return repository.CreateExpenseCategory(ctx, es.db, expenseCategory)
+	return runInTx(ctx, es.db, func(tx *sqlx.Tx) error {
+		if err := repository.CreateExpenseCategory(ctx, tx, expenseCategory); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, expenseCategory.CreatedBy, auditChange{
+			ClinicID:   &expenseCategory.ClinicID,
+			EntityType: domain.AuditEntityExpenseCategory,
+			EntityID:   expenseCategory.ID,
+			Action:     domain.AuditActionCreate,
+			After:      expenseCategory,
+		})
+	})
 }
 
 func (es *ExpensesService) CreateExpenseCategoryType(ctx context.Context, expenseCategoryType *domain.ExpenseCategoryType) error {
-	return repository.CreateExpenseCategoryType(ctx, es.db, expenseCategoryType)
+	if err := es.checkTypeAndCategory(ctx, expenseCategoryType.ClinicID, expenseCategoryType.TypeID, expenseCategoryType.CategoryID); err != nil {
+		return err
+	}
+	return runInTx(ctx, es.db, func(tx *sqlx.Tx) error {
+		if err := repository.CreateExpenseCategoryType(ctx, tx, expenseCategoryType); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, expenseCategoryType.CreatedBy, auditChange{
+			ClinicID:   &expenseCategoryType.ClinicID,
+			EntityType: domain.AuditEntityExpenseCategoryType,
+			EntityID:   expenseCategoryType.ID,
+			Action:     domain.AuditActionCreate,
+			After:      expenseCategoryType,
+		})
+	})
 }
 
 func (es *ExpensesService) CreateExpenseEntry(ctx context.Context, expenseEntry *domain.ExpenseEntry) error {
-	return repository.CreateExpenseEntry(ctx, es.db, expenseEntry)
+	if err := es.checkTypeAndCategory(ctx, expenseEntry.ClinicID, expenseEntry.TypeID, expenseEntry.CategoryID); err != nil {
+		return err
+	}
+	if expenseEntry.CorrectsEntryID != nil {
+		original, err := repository.GetExpenseEntryByID(ctx, es.db, *expenseEntry.CorrectsEntryID)
+		if err != nil {
+			return err
+		}
+		if original.ID == uuid.Nil || original.ClinicID != expenseEntry.ClinicID {
+			return errors.New("expense entry being corrected not found for this clinic")
+		}
+	}
+	return runInTx(ctx, es.db, func(tx *sqlx.Tx) error {
+		if err := checkPeriodOpen(ctx, tx, expenseEntry.ClinicID, expenseEntry.ExpenseDate, nil); err != nil {
+			return err
+		}
+		if err := repository.CreateExpenseEntry(ctx, tx, expenseEntry); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, expenseEntry.CreatedBy, auditChange{
+			ClinicID:   &expenseEntry.ClinicID,
+			EntityType: domain.AuditEntityExpenseEntry,
+			EntityID:   expenseEntry.ID,
+			Action:     domain.AuditActionCreate,
+			After:      expenseEntry,
+		})
+	})
+}
+
+// checkTypeAndCategory ensures the referenced expense type and category belong to the same clinic.
+func (es *ExpensesService) checkTypeAndCategory(ctx context.Context, clinicID, typeID, categoryID uuid.UUID) error {
+	typeClinicID, ok, err := repository.GetExpenseTypeClinicID(ctx, es.db, typeID)
+	if err != nil {
+		return err
+	}
+	if !ok || typeClinicID != clinicID {
+		return errors.New("expense type not found for this clinic")
+	}
+	categoryClinicID, ok, err := repository.GetExpenseCategoryClinicID(ctx, es.db, categoryID)
+	if err != nil {
+		return err
+	}
+	if !ok || categoryClinicID != clinicID {
+		return errors.New("expense category not found for this clinic")
+	}
+	return nil
 }
 
 func (es *ExpensesService) GetExpenseTypesByClinicID(ctx context.Context, clinicID uuid.UUID) ([]domain.ExpenseType, error) {
@@ -63,10 +145,44 @@ func (es *ExpensesService) GetExpenseCategoriesByClinicID(ctx context.Context, c
 	return repository.GetExpenseCategoriesByClinicID(ctx, es.db, clinicID)
 }
 
-func (es *ExpensesService) UpdateExpenseCategory(ctx context.Context, category *domain.ExpenseCategory) error {
-	return repository.UpdateExpenseCategory(ctx, es.db, category)
+func (es *ExpensesService) UpdateExpenseCategory(ctx context.Context, category *domain.ExpenseCategory, updatedBy uuid.UUID) error {
+	before, err := repository.GetExpenseCategoryByID(ctx, es.db, category.ID)
+	if err != nil {
+		return err
+	}
+	return runInTx(ctx, es.db, func(tx *sqlx.Tx) error {
+		if err := repository.UpdateExpenseCategory(ctx, tx, category); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, updatedBy, auditChange{
+			ClinicID:   &category.ClinicID,
+			EntityType: domain.AuditEntityExpenseCategory,
+			EntityID:   category.ID,
+			Action:     domain.AuditActionUpdate,
+			Before:     before,
+			After:      category,
+		})
+	})
 }
 
 func (es *ExpensesService) DeleteExpenseCategory(ctx context.Context, id uuid.UUID, deletedBy uuid.UUID) error {
-	return repository.DeleteExpenseCategory(ctx, es.db, id, deletedBy)
+	before, err := repository.GetExpenseCategoryByID(ctx, es.db, id)
+	if err != nil {
+		return err
+	}
+	if before.ID == uuid.Nil {
+		return errors.New("expense category not found")
+	}
+	return runInTx(ctx, es.db, func(tx *sqlx.Tx) error {
+		if err := repository.DeleteExpenseCategory(ctx, tx, id, deletedBy); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, deletedBy, auditChange{
+			ClinicID:   &before.ClinicID,
+			EntityType: domain.AuditEntityExpenseCategory,
+			EntityID:   id,
+			Action:     domain.AuditActionDelete,
+			Before:     before,
+		})
+	})
 }
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/money"
+	"github.com/iamarpitzala/aca-reca-backend/internal/repository"
+	"github.com/jmoiron/sqlx"
+)
+
+// exportDateLayout is how dates are written into export rows.
+const exportDateLayout = "02/01/2006"
+
+type ExportService struct {
+	db *sqlx.DB
+}
+
+func NewExportService(db *sqlx.DB) *ExportService {
+	return &ExportService{db: db}
+}
+
+// ExportRange is the inclusive date range an export covers.
+type ExportRange struct {
+	From time.Time
+	To   time.Time
+	// Label names the range in file names, e.g. "Q1 FY2025" or "2024-07-01_2024-09-30".
+	Label string
+}
+
+// entryExportSummary is the subset of a stored entry's calculations needed for the income export.
+type entryExportSummary struct {
+	entryCalcSummary
+	TotalAmount         money.Amount  `json:"totalAmount"`
+	NetReceivable       money.Amount  `json:"netReceivable"`
+	NetFee              *money.Amount `json:"netFee"`
+	ServiceFeeBase      *money.Amount `json:"serviceFeeBase"`
+	GstOnServiceFee     *money.Amount `json:"gstOnServiceFee"`
+	TotalServiceFee     *money.Amount `json:"totalServiceFee"`
+	TotalReductions     *money.Amount `json:"totalReductions"`
+	TotalReimbursements *money.Amount `json:"totalReimbursements"`
+	RemittedAmount      *money.Amount `json:"remittedAmount"`
+}
+
+// ResolveRange picks the export range from a clinic period or an explicit from/to pair.
+func (s *ExportService) ResolveRange(ctx context.Context, clinicID uuid.UUID, quarterID *uuid.UUID, from, to *time.Time) (*ExportRange, error) {
+	if quarterID != nil {
+		period, err := repository.GetClinicPeriodByID(ctx, s.db, clinicID, *quarterID)
+		if err != nil {
+			return nil, err
+		}
+		if period == nil {
+			return nil, errors.New("quarter not found")
+		}
+		return &ExportRange{From: period.StartDate, To: period.EndDate, Label: period.Name}, nil
+	}
+	if from == nil || to == nil {
+		return nil, errors.New("either quarterId or both from and to are required")
+	}
+	if to.Before(*from) {
+		return nil, errors.New("to must not be before from")
+	}
+	return &ExportRange{From: *from, To: *to, Label: from.Format("2006-01-02") + "_" + to.Format("2006-01-02")}, nil
+}
+
+// IncomeRows builds one income export row per income (or mixed) custom form entry in the range.
+func (s *ExportService) IncomeRows(ctx context.Context, clinicID uuid.UUID, rng *ExportRange) ([]domain.ExportIncome, error) {
+	clinic, err := repository.GetClinicByID(ctx, s.db, clinicID)
+	if err != nil {
+		return nil, err
+	}
+	if clinic.ID == uuid.Nil {
+		return nil, ErrClinicNotFound
+	}
+	entries, err := repository.GetCustomFormEntriesByDateRange(ctx, s.db, clinicID, rng.From, rng.To)
+	if err != nil {
+		return nil, err
+	}
+	periodName, err := s.periodNamer(ctx, clinicID)
+	if err != nil {
+		return nil, err
+	}
+	incomeFields := newIncomeFieldResolver(ctx, s.db)
+
+	rows := make([]domain.ExportIncome, 0, len(entries))
+	for i := range entries {
+		e := &entries[i]
+		if e.FormType == string(domain.FormTypeExpense) {
+			continue
+		}
+		var calc entryExportSummary
+		if len(e.Calculations) == 0 || json.Unmarshal(e.Calculations, &calc) != nil {
+			continue
+		}
+		gross := calc.BasMapping.TotalSalesG1
+		// Mixed forms net their expense section (lab fees) out of the entry total
+		var labFees money.Amount
+		if e.FormType == string(domain.FormTypeBoth) {
+			labFees = gross - calc.TotalAmount
+		}
+		percentage := 0.0
+		if calc.NetFee != nil && *calc.NetFee != 0 && calc.ServiceFeeBase != nil {
+			percentage = math.Round(float64(*calc.ServiceFeeBase)/float64(*calc.NetFee)*10000) / 100
+		}
+		income, err := incomeFields.resolve(e)
+		if err != nil {
+			return nil, err
+		}
+		net := calc.NetReceivable
+		if calc.RemittedAmount != nil {
+			net = *calc.RemittedAmount
+		} else if e.FormType == string(domain.FormTypeBoth) {
+			net = calc.TotalAmount
+		}
+
+		rows = append(rows, domain.ExportIncome{
+			Q:                 periodName(e.EntryDate),
+			IncomeType:        e.FormName,
+			LabRecord:         e.Description,
+			PaymentDate:       e.EntryDate.Format(exportDateLayout),
+			DentalPractice:    clinic.Name,
+			Adjustments:       valueOrZero(calc.TotalReductions),
+			GrossIncomeG1:     gross,
+			LabFees:           labFees,
+			GrossNetLabFees:   gross - labFees,
+			GSTPayable1A:      calc.BasMapping.GstOnSales1A,
+			GSTFree:           gstFreeIncome(calc.entryCalcSummary, income),
+			ManagementFeesG11: valueOrZero(calc.TotalServiceFee),
+			Percentage:        percentage,
+			GSTRefundable1B:   valueOrZero(calc.GstOnServiceFee),
+			NetPayment:        net,
+		})
+	}
+	return rows, nil
+}
+
+// ExpenseRows builds one expense export row per expense entry in the range.
+func (s *ExportService) ExpenseRows(ctx context.Context, clinicID uuid.UUID, rng *ExportRange) ([]domain.ExportExpenses, error) {
+	entries, err := repository.GetExpenseEntriesByDateRange(ctx, s.db, clinicID, rng.From, rng.To)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := repository.GetExpenseCategoriesByClinicID(ctx, s.db, clinicID)
+	if err != nil {
+		return nil, err
+	}
+	categoryNames := make(map[uuid.UUID]string, len(categories))
+	for _, c := range categories {
+		categoryNames[c.ID] = c.Name
+	}
+	periodName, err := s.periodNamer(ctx, clinicID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]domain.ExportExpenses, 0, len(entries))
+	for i := range entries {
+		e := &entries[i]
+		gst, total := expenseGST(e)
+		rows = append(rows, domain.ExportExpenses{
+			Q:        periodName(e.ExpenseDate),
+			Date:     e.ExpenseDate.Format(exportDateLayout),
+			Supplier: e.SupplierName,
+			Category: categoryNames[e.CategoryID],
+			Amount:   total,
+			// Business-use apportionment is not recorded on expense entries, so the whole amount counts
+			Bas:     100,
+			Net:     total - gst,
+			GST:     gst,
+			Remarks: e.Notes,
+		})
+	}
+	return rows, nil
+}
+
+// periodNamer returns a lookup from a date to the name of the clinic period containing it.
+func (s *ExportService) periodNamer(ctx context.Context, clinicID uuid.UUID) (func(time.Time) string, error) {
+	periods, err := repository.ListClinicPeriods(ctx, s.db, clinicID)
+	if err != nil {
+		return nil, err
+	}
+	return func(date time.Time) string {
+		day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+		for _, p := range periods {
+			start := time.Date(p.StartDate.Year(), p.StartDate.Month(), p.StartDate.Day(), 0, 0, 0, 0, time.UTC)
+			end := time.Date(p.EndDate.Year(), p.EndDate.Month(), p.EndDate.Day(), 0, 0, 0, 0, time.UTC)
+			if !day.Before(start) && !day.After(end) {
+				return p.Name
+			}
+		}
+		return ""
+	}, nil
+}
+
+func valueOrZero(v *money.Amount) money.Amount {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
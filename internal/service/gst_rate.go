@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/calculation"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/repository"
+	"github.com/jmoiron/sqlx"
+)
+
+// GetGSTRates returns a clinic's GST rate history and the rate in force today.
+func (cs *ClinicService) GetGSTRates(ctx context.Context, clinicID uuid.UUID) (*domain.ClinicGSTRates, error) {
+	rates, err := repository.GetClinicGSTRates(ctx, cs.db, clinicID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := gstRateOn(ctx, cs.db, clinicID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &domain.ClinicGSTRates{CurrentRate: current, Rates: rates}, nil
+}
+
+// SetGSTRate sets the rate a clinic charges from a date. Entries keep the rate they were calculated
+// with, so a rate cannot take effect inside a closed period.
+func (cs *ClinicService) SetGSTRate(ctx context.Context, clinicID uuid.UUID, req *domain.SetGSTRateRequest, actorID uuid.UUID) (*domain.ClinicGSTRate, error) {
+	from, err := time.Parse("2006-01-02", req.EffectiveFrom)
+	if err != nil {
+		return nil, errors.New("effectiveFrom must be YYYY-MM-DD")
+	}
+	rate := &domain.ClinicGSTRate{
+		ID:            uuid.New(),
+		ClinicID:      clinicID,
+		Rate:          *req.Rate,
+		EffectiveFrom: from,
+		CreatedBy:     actorID,
+		CreatedAt:     time.Now(),
+	}
+	err = runInTx(ctx, cs.db, func(tx *sqlx.Tx) error {
+		if err := checkPeriodOpen(ctx, tx, clinicID, from, nil); err != nil {
+			return err
+		}
+		before, err := repository.GetClinicGSTRateForDate(ctx, tx, clinicID, from)
+		if err != nil {
+			return err
+		}
+		if err := repository.SetClinicGSTRate(ctx, tx, rate); err != nil {
+			return err
+		}
+		change := auditChange{
+			ClinicID:   &clinicID,
+			EntityType: domain.AuditEntityClinicGSTRate,
+			EntityID:   rate.ID,
+			Action:     domain.AuditActionCreate,
+			After:      rate,
+		}
+		if before != nil && before.EffectiveFrom.Equal(from) {
+			change.Action, change.Before = domain.AuditActionUpdate, before
+		}
+		return recordAudit(ctx, tx, actorID, change)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+// gstRateOn returns the clinic's GST rate on a date, or DefaultGSTRate when it has not set one.
+func gstRateOn(ctx context.Context, db sqlx.QueryerContext, clinicID uuid.UUID, date time.Time) (float64, error) {
+	r, err := repository.GetClinicGSTRateForDate(ctx, db, clinicID, date)
+	if err != nil {
+		return 0, err
+	}
+	if r == nil {
+		return domain.DefaultGSTRate, nil
+	}
+	return r.Rate, nil
+}
+
+// entryTaxSettings gathers the GST rules for an entry of a clinic dated entryDate: the clinic's rate
+// on that date and the tax codes of the accounts the fields are linked to.
+func entryTaxSettings(ctx context.Context, db sqlx.QueryerContext, clinicID uuid.UUID, entryDate time.Time, fieldsJSON []byte) (calculation.TaxSettings, error) {
+	rate, err := gstRateOn(ctx, db, clinicID, entryDate)
+	if err != nil {
+		return calculation.TaxSettings{}, err
+	}
+	codes, err := linkedAccountTaxCodes(ctx, db, fieldsJSON)
+	if err != nil {
+		return calculation.TaxSettings{}, err
+	}
+	return calculation.TaxSettings{GSTRate: rate, AccountTaxes: codes}, nil
+}
+
+// linkedAccountTaxCodes returns the tax codes of the live accounts the fields are linked to, by
+// account ID.
+func linkedAccountTaxCodes(ctx context.Context, db sqlx.QueryerContext, fieldsJSON []byte) (map[string]domain.AccountTaxCode, error) {
+	fields, err := calculation.ParseFields(fieldsJSON)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uuid.UUID
+	for _, f := range fields {
+		if id, err := uuid.Parse(f.AccountID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	codes, err := repository.GetAccountTaxCodes(ctx, db, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]domain.AccountTaxCode, len(codes))
+	for _, c := range codes {
+		byID[c.AccountID] = c
+	}
+	// Keyed by the ID as the field spells it, which is how the calculation looks it up
+	out := make(map[string]domain.AccountTaxCode, len(codes))
+	for _, f := range fields {
+		if id, err := uuid.Parse(f.AccountID); err == nil {
+			if c, ok := byID[id]; ok {
+				out[f.AccountID] = c
+			}
+		}
+	}
+	return out, nil
+}
+
+// checkLinkedAccounts reports fields linked to accounts that do not exist, so a form cannot be
+// published with GST rules that would silently fall back to the field's own settings.
+func checkLinkedAccounts(ctx context.Context, db sqlx.QueryerContext, fieldsJSON []byte) error {
+	codes, err := linkedAccountTaxCodes(ctx, db, fieldsJSON)
+	if err != nil {
+		return err
+	}
+	fields, _ := calculation.ParseFields(fieldsJSON)
+	var errs []domain.FieldError
+	for _, f := range fields {
+		if _, ok := codes[f.AccountID]; f.AccountID != "" && !ok {
+			errs = append(errs, domain.FieldError{FieldID: f.ID, FieldName: f.Name, Message: "linked account not found"})
+		}
+	}
+	if len(errs) > 0 {
+		return &domain.FieldValidationError{Errors: errs}
+	}
+	return nil
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/mailer"
+	"github.com/iamarpitzala/aca-reca-backend/internal/repository"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrInvitationEmailMismatch = errors.New("this invitation was sent to a different email address")
+	ErrInvitationLoginRequired = errors.New("an account already exists for this email; sign in to accept the invitation")
+	// ErrInvitationNotSent is returned with an invitation that was saved but whose email could not be
+	// sent; resending it tries again.
+	ErrInvitationNotSent = errors.New("the invitation was saved but its email could not be sent; resend it to try again")
+)
+
+type InvitationService struct {
+	db           *sqlx.DB
+	tokenService *TokenService
+	authService  *AuthService
+	mailer       mailer.Mailer
+	cfg          config.InvitationConfig
+}
+
+func NewInvitationService(db *sqlx.DB, tokenService *TokenService, authService *AuthService, m mailer.Mailer, cfg config.InvitationConfig) *InvitationService {
+	return &InvitationService{
+		db:           db,
+		tokenService: tokenService,
+		authService:  authService,
+		mailer:       m,
+		cfg:          cfg,
+	}
+}
+
+// Create records a pending invitation and then emails its token. If the email cannot be sent, the
+// invitation is returned with ErrInvitationNotSent so it can be resent.
+func (is *InvitationService) Create(ctx context.Context, req *domain.CreateInvitationRequest, invitedBy uuid.UUID) (*domain.ClinicInvitation, error) {
+	clinicID, err := uuid.Parse(req.ClinicID)
+	if err != nil {
+		return nil, errors.New("invalid clinic ID")
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	role := strings.ToLower(strings.TrimSpace(req.Role))
+	if role == "" {
+		role = domain.RoleViewer
+	}
+	if !domain.IsValidRole(role) {
+		return nil, errors.New("invalid role. Must be one of: owner, accountant, practitioner, viewer")
+	}
+
+	clinic, err := repository.GetClinicByID(ctx, is.db, clinicID)
+	if err != nil || clinic.ID == uuid.Nil {
+		return nil, ErrClinicNotFound
+	}
+	if user, err := repository.GetUserByEmail(ctx, is.db, email); err != nil {
+		return nil, err
+	} else if user != nil {
+		member, err := repository.GetUserClinicByUserAndClinic(ctx, is.db, user.ID, clinicID)
+		if err != nil {
+			return nil, err
+		}
+		if member != nil {
+			return nil, errors.New("user is already associated with this clinic")
+		}
+	}
+	pending, err := repository.GetPendingInvitation(ctx, is.db, clinicID, email)
+	if err != nil {
+		return nil, err
+	}
+	if pending != nil {
+		if !pending.IsExpired() {
+			return nil, errors.New("an invitation is already pending for this email; resend it instead")
+		}
+		// An expired invitation no longer blocks a new one
+		now := time.Now()
+		pending.Status = domain.InvitationRevoked
+		pending.RespondedAt = &now
+		pending.UpdatedAt = now
+		if err := repository.UpdateInvitation(ctx, is.db, pending); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	inv := &domain.ClinicInvitation{
+		ID:        uuid.New(),
+		ClinicID:  clinicID,
+		Email:     email,
+		Role:      role,
+		Status:    domain.InvitationPending,
+		InvitedBy: invitedBy,
+		ExpiresAt: now.Add(is.cfg.TTL),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	token, err := is.issueToken(inv)
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.CreateInvitation(ctx, is.db, inv); err != nil {
+		return nil, err
+	}
+	if err := is.sendInvitation(ctx, inv, clinic.Name, token); err != nil {
+		return inv, err
+	}
+	return inv, nil
+}
+
+func (is *InvitationService) ListByClinicID(ctx context.Context, clinicID uuid.UUID) ([]domain.ClinicInvitation, error) {
+	return repository.GetInvitationsByClinicID(ctx, is.db, clinicID)
+}
+
+// Resend issues a fresh token with a new expiry; any previously emailed token stops working. As with
+// Create, the invitation is returned with ErrInvitationNotSent if the email cannot be sent.
+func (is *InvitationService) Resend(ctx context.Context, id uuid.UUID) (*domain.ClinicInvitation, error) {
+	inv, err := repository.GetInvitationByID(ctx, is.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if inv.Status != domain.InvitationPending {
+		return nil, fmt.Errorf("invitation has already been %s", inv.Status)
+	}
+	clinic, err := repository.GetClinicByID(ctx, is.db, inv.ClinicID)
+	if err != nil || clinic.ID == uuid.Nil {
+		return nil, ErrClinicNotFound
+	}
+
+	now := time.Now()
+	inv.ExpiresAt = now.Add(is.cfg.TTL)
+	inv.UpdatedAt = now
+	token, err := is.issueToken(inv)
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.UpdateInvitation(ctx, is.db, inv); err != nil {
+		return nil, err
+	}
+	if err := is.sendInvitation(ctx, inv, clinic.Name, token); err != nil {
+		return inv, err
+	}
+	return inv, nil
+}
+
+func (is *InvitationService) Revoke(ctx context.Context, id uuid.UUID) (*domain.ClinicInvitation, error) {
+	inv, err := repository.GetInvitationByID(ctx, is.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if inv.Status != domain.InvitationPending {
+		return nil, fmt.Errorf("invitation has already been %s", inv.Status)
+	}
+	now := time.Now()
+	inv.Status = domain.InvitationRevoked
+	inv.RespondedAt = &now
+	inv.UpdatedAt = now
+	if err := repository.UpdateInvitation(ctx, is.db, inv); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// Preview describes a pending invitation to the invitee without accepting it.
+func (is *InvitationService) Preview(ctx context.Context, token string) (*domain.InvitationPreview, error) {
+	inv, err := is.pendingInvitationForToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	clinic, err := repository.GetClinicByID(ctx, is.db, inv.ClinicID)
+	if err != nil || clinic.ID == uuid.Nil {
+		return nil, ErrClinicNotFound
+	}
+	hasAccount, err := repository.EmailExists(ctx, is.db, inv.Email)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.InvitationPreview{
+		ClinicName: clinic.Name,
+		Email:      inv.Email,
+		Role:       inv.Role,
+		ExpiresAt:  inv.ExpiresAt,
+		HasAccount: hasAccount,
+	}, nil
+}
+
+// Accept adds the invitee to the clinic with the invited role. A signed-in caller must own the invited
+// email. Without a session, an account is registered for the invited email first, unless one exists;
+// redeeming the emailed token proves the address, so that account starts out verified. The account,
+// the accepted invitation and the membership are created together or not at all.
+func (is *InvitationService) Accept(ctx context.Context, req *domain.AcceptInvitationRequest, userID *uuid.UUID) (*domain.AcceptInvitationResponse, error) {
+	inv, err := is.pendingInvitationForToken(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &domain.AcceptInvitationResponse{}
+	var user *domain.User
+	var existing *domain.UserClinic
+	if userID != nil {
+		user, err = repository.GetUserByID(ctx, is.db, *userID)
+		if err != nil {
+			return nil, errors.New("user not found")
+		}
+		if !strings.EqualFold(user.Email, inv.Email) {
+			return nil, ErrInvitationEmailMismatch
+		}
+		existing, err = repository.GetUserClinicByUserAndClinic(ctx, is.db, user.ID, inv.ClinicID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		registered, err := repository.GetUserByEmail(ctx, is.db, inv.Email)
+		if err != nil {
+			return nil, err
+		}
+		if registered != nil {
+			return nil, ErrInvitationLoginRequired
+		}
+		if len(req.Password) < 8 {
+			return nil, errors.New("password of at least 8 characters is required to create your account")
+		}
+	}
+
+	now := time.Now()
+	err = runInTx(ctx, is.db, func(tx *sqlx.Tx) error {
+		if user == nil {
+			var err error
+			user, err = is.authService.createUser(ctx, tx, &domain.RegisterRequest{
+				Email:     inv.Email,
+				Password:  req.Password,
+				FirstName: req.FirstName,
+				LastName:  req.LastName,
+				Phone:     req.Phone,
+			}, true)
+			if err != nil {
+				return err
+			}
+		}
+		inv.Status = domain.InvitationAccepted
+		inv.AcceptedBy = &user.ID
+		inv.RespondedAt = &now
+		inv.UpdatedAt = now
+		if err := repository.UpdateInvitation(ctx, tx, inv); err != nil {
+			return err
+		}
+		if existing != nil {
+			// Added directly while the invitation was pending; keep the existing membership
+			resp.UserClinic = existing
+			return nil
+		}
+		resp.UserClinic = &domain.UserClinic{
+			ID:        uuid.New(),
+			UserID:    user.ID,
+			ClinicID:  inv.ClinicID,
+			Role:      inv.Role,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		return repository.CreateUserClinic(ctx, tx, resp.UserClinic)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if userID == nil {
+		if resp.Auth, err = is.authService.sessionResponse(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func (is *InvitationService) Decline(ctx context.Context, token string) error {
+	inv, err := is.pendingInvitationForToken(ctx, token)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	inv.Status = domain.InvitationDeclined
+	inv.RespondedAt = &now
+	inv.UpdatedAt = now
+	return repository.UpdateInvitation(ctx, is.db, inv)
+}
+
+// pendingInvitationForToken verifies the token signature and that it is the latest token issued
+// for an invitation that is still pending and unexpired.
+func (is *InvitationService) pendingInvitationForToken(ctx context.Context, token string) (*domain.ClinicInvitation, error) {
+	claims, err := is.tokenService.ValidateInvitationToken(token)
+	if err != nil {
+		return nil, err
+	}
+	inv, err := repository.GetInvitationByID(ctx, is.db, claims.InvitationID)
+	if err != nil {
+		return nil, err
+	}
+	if inv.TokenHash != hashInvitationToken(token) {
+		return nil, errors.New("invitation link has been replaced by a newer one")
+	}
+	if inv.Status != domain.InvitationPending {
+		return nil, fmt.Errorf("invitation has already been %s", inv.Status)
+	}
+	if inv.IsExpired() {
+		return nil, errors.New("invitation has expired")
+	}
+	return inv, nil
+}
+
+// issueToken signs a token for the invitation and stores its hash; only the hash is persisted.
+func (is *InvitationService) issueToken(inv *domain.ClinicInvitation) (string, error) {
+	token, err := is.tokenService.GenerateInvitationToken(inv.ID, inv.ExpiresAt)
+	if err != nil {
+		return "", errors.New("failed to generate invitation token")
+	}
+	inv.TokenHash = hashInvitationToken(token)
+	return token, nil
+}
+
+func (is *InvitationService) sendInvitation(ctx context.Context, inv *domain.ClinicInvitation, clinicName, token string) error {
+	link := is.cfg.AcceptURL + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("You have been invited to join %s as %s.\n\n"+
+		"Accept or decline the invitation here:\n%s\n\n"+
+		"This invitation expires on %s.\n",
+		clinicName, inv.Role, link, inv.ExpiresAt.Format("2 January 2006 15:04 MST"))
+	err := is.mailer.Send(ctx, mailer.Message{
+		To:      inv.Email,
+		Subject: fmt.Sprintf("Invitation to join %s", clinicName),
+		Body:    body,
+	})
+	if err != nil {
+		log.Printf("Failed to send invitation %s: %v", inv.ID, err)
+		return ErrInvitationNotSent
+	}
+	return nil
+}
+
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
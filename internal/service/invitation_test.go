@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/mailer"
+)
+
+// An invitation is saved before it is emailed, so a failed email leaves an invitation that resending
+// delivers, rather than an email for an invitation that was never stored.
+func TestInvitationEmailFailureCanBeResent(t *testing.T) {
+	db, mock := newMockDB(t)
+	tokens := NewTokenService(config.JWTConfig{SecretKey: "test-secret", AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour, Issuer: "test"})
+	cfg := config.InvitationConfig{TTL: time.Hour, AcceptURL: "http://app.test/invitation"}
+	clinicID, ownerID := uuid.New(), uuid.New()
+	expectClinic := func() {
+		mock.ExpectQuery(stmt("FROM tbl_clinic WHERE id = $1")).WithArgs(clinicID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(clinicID, "Smile Dental"))
+	}
+
+	expectClinic()
+	expectUserByEmail(mock, nil)
+	mock.ExpectQuery(stmt("FROM tbl_clinic_invitation")).WithArgs(clinicID, "nurse@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec(stmt("INSERT INTO tbl_clinic_invitation")).WillReturnResult(sqlmock.NewResult(0, 1))
+	failing := NewInvitationService(db, tokens, nil, failingMailer{}, cfg)
+	inv, err := failing.Create(context.Background(), &domain.CreateInvitationRequest{ClinicID: clinicID.String(), Email: "Nurse@example.com", Role: domain.RoleViewer}, ownerID)
+	if !errors.Is(err, ErrInvitationNotSent) || inv == nil {
+		t.Fatalf("create: %+v, %v, want the saved invitation and ErrInvitationNotSent", inv, err)
+	}
+
+	mock.ExpectQuery(stmt("FROM tbl_clinic_invitation WHERE id = $1")).WithArgs(inv.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "clinic_id", "email", "role", "status", "token_hash", "invited_by", "accepted_by",
+			"expires_at", "responded_at", "created_at", "updated_at"}).
+			AddRow(inv.ID, clinicID, inv.Email, inv.Role, inv.Status, inv.TokenHash, ownerID, nil, inv.ExpiresAt, nil, inv.CreatedAt, inv.UpdatedAt))
+	expectClinic()
+	mock.ExpectExec(stmt("UPDATE tbl_clinic_invitation")).WillReturnResult(sqlmock.NewResult(0, 1))
+	outbox := t.TempDir()
+	working := NewInvitationService(db, tokens, nil, mailer.NewFileMailer("clinic@example.com", outbox), cfg)
+	resent, err := working.Resend(context.Background(), inv.ID)
+	if err != nil {
+		t.Fatalf("resend: %v", err)
+	}
+	if token := outboxToken(t, outbox, "nurse@example.com", cfg.AcceptURL); hashInvitationToken(token) != resent.TokenHash {
+		t.Error("the emailed token is not the one stored for the invitation")
+	}
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/ratelimit"
+	"github.com/iamarpitzala/aca-reca-backend/internal/repository"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	maxLockoutHistory = 100
+	// maxChallengeAttempts is how many wrong second factors one MFA challenge token may take.
+	maxChallengeAttempts = 5
+)
+
+// ErrTooManyAttempts is wrapped by the error returned while an account or address must wait before
+// trying to sign in again.
+var ErrTooManyAttempts = errors.New("too many failed attempts")
+
+// ErrChallengeSpent is returned for an MFA challenge token that has taken too many wrong codes.
+var ErrChallengeSpent = fmt.Errorf("%w on this two-factor challenge; sign in again", ErrTooManyAttempts)
+
+// LoginBlockedError says how long the caller must wait before the next attempt.
+type LoginBlockedError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginBlockedError) Error() string {
+	seconds := int(math.Ceil(e.RetryAfter.Seconds()))
+	if e.Locked {
+		return fmt.Sprintf("too many failed attempts; sign-in is locked for %d seconds", seconds)
+	}
+	return fmt.Sprintf("too many failed attempts; try again in %d seconds", seconds)
+}
+
+func (e *LoginBlockedError) Unwrap() error { return ErrTooManyAttempts }
+
+// LoginLimitService slows down password guessing. Failed logins are counted per account (email)
+// and per client IP address: after a few failures each attempt must wait a growing delay, and an
+// account or address that keeps failing is locked for a while. Lockouts are kept in
+// tbl_login_lockout, and an admin of a clinic can clear a member's account lockout. Wrong second
+// factors count the same way, and each MFA challenge token is also capped on its own.
+type LoginLimitService struct {
+	db        *sqlx.DB
+	limiter   ratelimit.Limiter
+	account   ratelimit.Policy
+	ip        ratelimit.Policy
+	challenge ratelimit.Policy
+}
+
+func NewLoginLimitService(db *sqlx.DB, limiter ratelimit.Limiter, cfg config.LoginLimitConfig) *LoginLimitService {
+	policy := func(lockoutAfter int) ratelimit.Policy {
+		return ratelimit.Policy{
+			FreeAttempts:    cfg.FreeAttempts,
+			BaseDelay:       cfg.BaseDelay,
+			MaxDelay:        cfg.MaxDelay,
+			LockoutAfter:    lockoutAfter,
+			LockoutDuration: cfg.LockoutDuration,
+			Window:          cfg.Window,
+		}
+	}
+	return &LoginLimitService{
+		db:      db,
+		limiter: limiter,
+		account: policy(cfg.AccountLockoutAfter),
+		ip:      policy(cfg.IPLockoutAfter),
+		// A spent challenge stays locked for longer than the token lives
+		challenge: ratelimit.Policy{
+			LockoutAfter:    maxChallengeAttempts,
+			LockoutDuration: mfaChallengeTTL,
+			Window:          mfaChallengeTTL,
+		},
+	}
+}
+
+// Allow returns a *LoginBlockedError when the account or the address must still wait. An empty
+// email checks only the address.
+func (s *LoginLimitService) Allow(ctx context.Context, email, ip string) error {
+	now := time.Now()
+	var blocked *LoginBlockedError
+	for _, key := range s.keys(email, ip) {
+		status, err := s.limiter.Status(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to check login attempts: %w", err)
+		}
+		wait := status.RetryAfter(now)
+		if wait > 0 && (blocked == nil || wait > blocked.RetryAfter) {
+			blocked = &LoginBlockedError{RetryAfter: wait, Locked: status.Locked}
+		}
+	}
+	if blocked != nil {
+		return blocked
+	}
+	return nil
+}
+
+// RecordFailure counts a failed attempt against the account and the address, recording a lockout
+// for whichever of them this failure locks. Attempts are refused by Allow while a lock lasts, so each
+// locking failure starts a new lockout. An empty email counts only against the address.
+func (s *LoginLimitService) RecordFailure(ctx context.Context, email, ip string) error {
+	email = normalizeEmail(email)
+	if email != "" {
+		status, err := s.limiter.Fail(ctx, accountKey(email), s.account)
+		if err != nil {
+			return fmt.Errorf("failed to record login attempt: %w", err)
+		}
+		if status.Locked {
+			if err := s.recordLockout(ctx, domain.LockoutScopeAccount, email, ip, status); err != nil {
+				return err
+			}
+		}
+	}
+	status, err := s.limiter.Fail(ctx, ipKey(ip), s.ip)
+	if err != nil {
+		return fmt.Errorf("failed to record login attempt: %w", err)
+	}
+	if status.Locked {
+		return s.recordLockout(ctx, domain.LockoutScopeIP, ip, ip, status)
+	}
+	return nil
+}
+
+// AllowChallenge is Allow for the second step of a login: it also refuses an MFA challenge that has
+// used up its attempts, so the user must sign in with their password again.
+func (s *LoginLimitService) AllowChallenge(ctx context.Context, email, ip, challengeID string) error {
+	status, err := s.limiter.Status(ctx, challengeKey(challengeID))
+	if err != nil {
+		return fmt.Errorf("failed to check login attempts: %w", err)
+	}
+	if status.Locked {
+		return ErrChallengeSpent
+	}
+	return s.Allow(ctx, email, ip)
+}
+
+// RecordChallengeFailure counts a wrong second factor against the account, the address and the
+// challenge token.
+func (s *LoginLimitService) RecordChallengeFailure(ctx context.Context, email, ip, challengeID string) error {
+	if _, err := s.limiter.Fail(ctx, challengeKey(challengeID), s.challenge); err != nil {
+		return fmt.Errorf("failed to record login attempt: %w", err)
+	}
+	return s.RecordFailure(ctx, email, ip)
+}
+
+// RecordSuccess forgets the account's failures. Call it only once the login is complete, after the
+// second factor when the account has one. The address keeps its count, so an attacker cannot
+// reset it by signing in to an account of their own between guesses.
+func (s *LoginLimitService) RecordSuccess(ctx context.Context, email string) error {
+	return s.limiter.Reset(ctx, accountKey(normalizeEmail(email)))
+}
+
+// ListClinicLockouts returns recent account lockouts of the clinic's members, newest first.
+func (s *LoginLimitService) ListClinicLockouts(ctx context.Context, clinicID uuid.UUID) ([]domain.LoginLockout, error) {
+	return repository.GetClinicLoginLockouts(ctx, s.db, clinicID, maxLockoutHistory)
+}
+
+// ClearUserLockout lifts any delay or lockout on a clinic member's account. Each lockout cleared is
+// recorded in the clinic's audit log against actorID.
+func (s *LoginLimitService) ClearUserLockout(ctx context.Context, clinicID, actorID, userID uuid.UUID) error {
+	member, err := repository.GetUserClinicByUserAndClinic(ctx, s.db, userID, clinicID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return errors.New("user is not a member of this clinic")
+	}
+	user, err := repository.GetUserByID(ctx, s.db, userID)
+	if err != nil {
+		return err
+	}
+	err = runInTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		cleared, err := repository.ClearUserLoginLockouts(ctx, tx, userID, actorID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for i := range cleared {
+			after := cleared[i]
+			after.ClearedAt = &now
+			after.ClearedBy = &actorID
+			if err := recordAudit(ctx, tx, actorID, auditChange{
+				ClinicID:   &clinicID,
+				EntityType: domain.AuditEntityLoginLockout,
+				EntityID:   cleared[i].ID,
+				Action:     domain.AuditActionUpdate,
+				Before:     cleared[i],
+				After:      after,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.limiter.Reset(ctx, accountKey(normalizeEmail(user.Email)))
+}
+
+func (s *LoginLimitService) recordLockout(ctx context.Context, scope, subject, ip string, status ratelimit.Status) error {
+	lockout := &domain.LoginLockout{
+		ID:          uuid.New(),
+		Scope:       scope,
+		Subject:     subject,
+		IPAddress:   ip,
+		Failures:    status.Failures,
+		LockedUntil: status.RetryAt,
+		CreatedAt:   time.Now(),
+	}
+	if scope == domain.LockoutScopeAccount {
+		user, err := repository.GetUserByEmail(ctx, s.db, subject)
+		if err != nil {
+			return err
+		}
+		if user != nil {
+			lockout.UserID = &user.ID
+		}
+	}
+	return repository.CreateLoginLockout(ctx, s.db, lockout)
+}
+
+func (s *LoginLimitService) keys(email, ip string) []string {
+	keys := []string{ipKey(ip)}
+	if email = normalizeEmail(email); email != "" {
+		keys = append(keys, accountKey(email))
+	}
+	return keys
+}
+
+func accountKey(email string) string { return "login:account:" + email }
+
+func ipKey(ip string) string { return "login:ip:" + ip }
+
+func challengeKey(id string) string { return "login:mfa:" + id }
+
+// normalizeEmail matches the case-insensitive lookup used by GetUserByEmail.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
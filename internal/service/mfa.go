@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/repository"
+	"github.com/iamarpitzala/aca-reca-backend/internal/totp"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// mfaChallengeTTL is how long a user has to enter their code after the password step.
+	mfaChallengeTTL = 5 * time.Minute
+	// mfaSkew accepts codes one period either side of now to allow for clock drift.
+	mfaSkew           = 1
+	recoveryCodeCount = 10
+)
+
+var ErrInvalidMFACode = errors.New("invalid two-factor code")
+
+// MFAService manages TOTP enrolment and recovery codes, and completes two-step logins.
+type MFAService struct {
+	db           *sqlx.DB
+	tokenService *TokenService
+	authService  *AuthService
+	loginLimit   *LoginLimitService
+}
+
+func NewMFAService(db *sqlx.DB, tokenService *TokenService, authService *AuthService, loginLimit *LoginLimitService) *MFAService {
+	return &MFAService{
+		db:           db,
+		tokenService: tokenService,
+		authService:  authService,
+		loginLimit:   loginLimit,
+	}
+}
+
+func (ms *MFAService) Status(ctx context.Context, userID uuid.UUID) (*domain.MFAStatus, error) {
+	m, err := repository.GetUserMFA(ctx, ms.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &domain.MFAStatus{Enabled: m.Enabled()}
+	if status.Enabled {
+		if status.RecoveryCodesRemaining, err = repository.CountRecoveryCodes(ctx, ms.db, userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// Enroll creates a new TOTP secret for the user. It takes effect once confirmed through Activate.
+func (ms *MFAService) Enroll(ctx context.Context, userID uuid.UUID) (*domain.MFAEnrollment, error) {
+	m, err := repository.GetUserMFA(ctx, ms.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if m.Enabled() {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	user, err := repository.GetUserByID(ctx, ms.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, errors.New("failed to generate two-factor secret")
+	}
+	sealed, err := ms.sealSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.StartUserMFAEnrollment(ctx, ms.db, userID, sealed); err != nil {
+		return nil, err
+	}
+	return &domain.MFAEnrollment{
+		Secret:     secret,
+		OTPAuthURL: totp.URI(ms.tokenService.issuer, user.Email, secret),
+	}, nil
+}
+
+// Activate confirms enrolment with a first code, returns the recovery codes and signs out every
+// other session, since those were opened with a password alone.
+func (ms *MFAService) Activate(ctx context.Context, userID, sessionID uuid.UUID, code string) (*domain.MFARecoveryCodes, error) {
+	m, err := repository.GetUserMFA(ctx, ms.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, errors.New("start two-factor enrolment first")
+	}
+	if m.Enabled() {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	secret, err := ms.openSecret(m.Secret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := totp.Validate(secret, code, time.Now(), mfaSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+	session, err := repository.GetSessionByID(ctx, ms.db, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = runInTx(ctx, ms.db, func(tx *sqlx.Tx) error {
+		if err := repository.EnableUserMFA(ctx, tx, userID, step); err != nil {
+			return err
+		}
+		if err := repository.ReplaceRecoveryCodes(ctx, tx, userID, hashes); err != nil {
+			return err
+		}
+		return repository.RevokeUserSessions(ctx, tx, userID, &session.FamilyID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &domain.MFARecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// Disable turns two-factor authentication off after checking a current code or a recovery code.
+func (ms *MFAService) Disable(ctx context.Context, userID uuid.UUID, code string) error {
+	if err := ms.verify(ctx, userID, code); err != nil {
+		return err
+	}
+	return runInTx(ctx, ms.db, func(tx *sqlx.Tx) error {
+		return repository.DeleteUserMFA(ctx, tx, userID)
+	})
+}
+
+// RegenerateRecoveryCodes replaces every recovery code, used or not.
+func (ms *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) (*domain.MFARecoveryCodes, error) {
+	if err := ms.verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = runInTx(ctx, ms.db, func(tx *sqlx.Tx) error {
+		return repository.ReplaceRecoveryCodes(ctx, tx, userID, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &domain.MFARecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// CompleteLogin exchanges an MFA challenge token and a second factor for a token pair. Wrong codes
+// count as failed logins for the account and for ip, and each challenge takes only a few of them;
+// a *LoginBlockedError or ErrChallengeSpent is returned once the caller must stop guessing.
+func (ms *MFAService) CompleteLogin(ctx context.Context, req *domain.MFALoginRequest, ip string) (*domain.AuthResponse, error) {
+	claims, err := ms.tokenService.ValidateMFAChallengeToken(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+	user, err := repository.GetUserByID(ctx, ms.db, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ms.loginLimit.AllowChallenge(ctx, user.Email, ip, claims.ID); err != nil {
+		return nil, err
+	}
+	if err := ms.verify(ctx, claims.UserID, req.Code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if limitErr := ms.loginLimit.RecordChallengeFailure(ctx, user.Email, ip, claims.ID); limitErr != nil {
+				return nil, limitErr
+			}
+		}
+		return nil, err
+	}
+	tokenPair, err := ms.authService.beginSession(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	// The session already exists; failures that could not be reset still expire with their window
+	_ = ms.loginLimit.RecordSuccess(ctx, user.Email)
+	user.Password = ""
+	return &domain.AuthResponse{
+		User:         user,
+		AccessToken:  tokenPair.AccessToken,
+		RefreshToken: tokenPair.RefreshToken,
+		TokenType:    tokenPair.TokenType,
+		ExpiresIn:    tokenPair.ExpiresIn,
+	}, nil
+}
+
+// verify accepts an authenticator code, each time step at most once, or an unused recovery code.
+func (ms *MFAService) verify(ctx context.Context, userID uuid.UUID, code string) error {
+	m, err := repository.GetUserMFA(ctx, ms.db, userID)
+	if err != nil {
+		return err
+	}
+	if !m.Enabled() {
+		return errors.New("two-factor authentication is not enabled")
+	}
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		secret, err := ms.openSecret(m.Secret)
+		if err != nil {
+			return err
+		}
+		step, ok := totp.Validate(secret, code, time.Now(), mfaSkew)
+		if !ok {
+			return ErrInvalidMFACode
+		}
+		fresh, err := repository.AdvanceMFAStep(ctx, ms.db, userID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return errors.New("this code has already been used; wait for the next one")
+		}
+		return nil
+	}
+	used, err := repository.ConsumeRecoveryCode(ctx, ms.db, userID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// sealSecret encrypts a TOTP secret with AES-GCM under a key derived from the signing secret.
+func (ms *MFAService) sealSecret(secret string) (string, error) {
+	gcm, err := ms.cipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+func (ms *MFAService) openSecret(sealed string) (string, error) {
+	gcm, err := ms.cipher()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < gcm.NonceSize() {
+		return "", errors.New("stored two-factor secret is invalid")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("stored two-factor secret is invalid")
+	}
+	return string(plain), nil
+}
+
+func (ms *MFAService) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(ms.tokenService.deriveKey("mfa-secret"))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// newRecoveryCodes returns codes like "k3j9x-4mq2p" for display and their hashes for storage.
+func newRecoveryCodes() ([]string, []string, error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, errors.New("failed to generate recovery codes")
+		}
+		raw := strings.ToLower(enc.EncodeToString(b))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
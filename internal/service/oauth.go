@@ -2,11 +2,18 @@ package service
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/iamarpitzala/aca-reca-backend/config"
 	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
@@ -15,13 +22,30 @@ import (
 	"golang.org/x/oauth2"
 )
 
+const (
+	oauthStateTTL     = 10 * time.Minute
+	oauthLinkTokenTTL = 5 * time.Minute
+	// idTokenLeeway tolerates clock skew between us and the provider when checking id_token expiry
+	idTokenLeeway = time.Minute
+)
+
+var (
+	ErrOAuthStateInvalid     = errors.New("sign-in request has expired or was already used; start again")
+	ErrOAuthEmailNotVerified = errors.New("an account with this email already exists; sign in with your password and link this provider from your profile")
+	ErrOAuthIdentityInUse    = errors.New("this identity is already linked to another account")
+	ErrOAuthProviderLinked   = errors.New("another identity from this provider is already linked to your account; unlink it first")
+	ErrOAuthNotLinked        = errors.New("provider is not linked to your account")
+	ErrOAuthLastSignInMethod = errors.New("set a password or link another provider before unlinking your only sign-in method")
+)
+
 type OAuthService struct {
-	config    config.OAuthConfig
-	db        *sqlx.DB
-	providers map[string]*oauth2.Config
+	config       config.OAuthConfig
+	db           *sqlx.DB
+	tokenService *TokenService
+	providers    map[string]*oauth2.Config
 }
 
-func NewOAuthService(cfg config.OAuthConfig, db *sqlx.DB) *OAuthService {
+func NewOAuthService(cfg config.OAuthConfig, db *sqlx.DB, tokenService *TokenService) *OAuthService {
 	providers := make(map[string]*oauth2.Config)
 
 	for name, providerCfg := range cfg.Providers {
@@ -40,19 +64,195 @@ func NewOAuthService(cfg config.OAuthConfig, db *sqlx.DB) *OAuthService {
 	}
 
 	return &OAuthService{
-		config:    cfg,
-		db:        db,
-		providers: providers,
+		config:       cfg,
+		db:           db,
+		tokenService: tokenService,
+		providers:    providers,
 	}
 }
 
-func (os *OAuthService) GetAuthURL(provider string, state string) (string, error) {
+// Begin starts an authorisation with provider and returns the URL to send the browser to and the
+// state the callback must echo. The PKCE verifier and id_token nonce are kept server side with the
+// state. A non-empty linkToken, from LinkURL, links the identity to that user instead of signing in.
+func (os *OAuthService) Begin(ctx context.Context, provider, linkToken string) (authURL, state string, err error) {
 	oauthConfig, ok := os.providers[provider]
 	if !ok {
-		return "", fmt.Errorf("oauth provider %s not configured", provider)
+		return "", "", fmt.Errorf("oauth provider %s not configured", provider)
+	}
+	var linkUserID *uuid.UUID
+	if linkToken != "" {
+		claims, err := os.tokenService.ValidateOAuthLinkToken(linkToken)
+		if err != nil {
+			return "", "", err
+		}
+		if claims.Provider != provider {
+			return "", "", errors.New("link request was issued for another provider")
+		}
+		linkUserID = &claims.UserID
+	}
+
+	if state, err = newOpaqueToken(); err != nil {
+		return "", "", err
+	}
+	nonce, err := newOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+	now := time.Now()
+	err = repository.CreateOAuthState(ctx, os.db, &domain.OAuthState{
+		StateHash:    hashToken(state),
+		Provider:     provider,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		UserID:       linkUserID,
+		ExpiresAt:    now.Add(oauthStateTTL),
+		CreatedAt:    now,
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to start oauth sign-in: %w", err)
+	}
+
+	authURL = oauthConfig.AuthCodeURL(state,
+		oauth2.AccessTypeOffline,
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	)
+	return authURL, state, nil
+}
+
+// LinkURL returns the URL a signed-in user's browser opens to link provider to their account.
+func (os *OAuthService) LinkURL(userID uuid.UUID, provider string) (*domain.OAuthLinkResponse, error) {
+	if _, ok := os.providers[provider]; !ok {
+		return nil, fmt.Errorf("oauth provider %s not configured", provider)
+	}
+	token, err := os.tokenService.GenerateOAuthLinkToken(userID, provider, oauthLinkTokenTTL)
+	if err != nil {
+		return nil, errors.New("failed to generate link token")
+	}
+	linkURL := fmt.Sprintf("%s/%s?link_token=%s", strings.TrimSuffix(os.config.RedirectURL, "/"), provider, url.QueryEscape(token))
+	return &domain.OAuthLinkResponse{URL: linkURL}, nil
+}
+
+// Complete redeems the callback of an authorisation started by Begin. It returns the user to sign
+// in, or, for a link request, the user the identity was linked to with linked set. An identity whose
+// email matches an existing account is merged into it only when both the provider and our account
+// have verified that email; otherwise the user must sign in and link it from their profile.
+func (os *OAuthService) Complete(ctx context.Context, provider, state, code string) (user *domain.User, linked bool, err error) {
+	pending, err := repository.ConsumeOAuthState(ctx, os.db, hashToken(state))
+	if err != nil {
+		return nil, false, err
+	}
+	if pending == nil || pending.Provider != provider {
+		return nil, false, ErrOAuthStateInvalid
+	}
+
+	token, err := os.ExchangeCode(ctx, provider, code, pending.CodeVerifier)
+	if err != nil {
+		return nil, false, err
+	}
+	idClaims, err := os.verifyIDToken(provider, token, pending.Nonce)
+	if err != nil {
+		return nil, false, err
+	}
+	userInfo, err := os.GetUserInfo(ctx, provider, token)
+	if err != nil {
+		return nil, false, err
+	}
+	if provider == "microsoft" {
+		// Graph does not say whether the address was verified; only the optional xms_edov claim does
+		userInfo.EmailVerified = idClaims.EmailDomainVerified != nil && *idClaims.EmailDomainVerified
+	}
+	if userInfo.ID == "" {
+		return nil, false, errors.New("provider did not return a user ID")
+	}
+
+	existing, err := repository.GetOAuthProvider(ctx, os.db, provider, userInfo.ID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if pending.UserID != nil {
+		if existing != nil && existing.UserID != *pending.UserID {
+			return nil, false, ErrOAuthIdentityInUse
+		}
+		if existing == nil {
+			identities, err := repository.GetUserOAuthIdentities(ctx, os.db, *pending.UserID)
+			if err != nil {
+				return nil, false, err
+			}
+			for _, identity := range identities {
+				if identity.Provider == provider {
+					return nil, false, ErrOAuthProviderLinked
+				}
+			}
+		}
+		if err := os.LinkProvider(ctx, *pending.UserID, provider, userInfo.ID, userInfo.Email, token); err != nil {
+			return nil, false, err
+		}
+		user, err := repository.GetUserByID(ctx, os.db, *pending.UserID)
+		return user, true, err
+	}
+
+	if existing != nil {
+		// A returning identity: refresh its stored tokens
+		if err := os.LinkProvider(ctx, existing.UserID, provider, userInfo.ID, userInfo.Email, token); err != nil {
+			return nil, false, err
+		}
+		user, err := repository.GetUserByID(ctx, os.db, existing.UserID)
+		return user, false, err
 	}
 
-	return oauthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline), nil
+	if userInfo.Email == "" {
+		return nil, false, errors.New("provider did not share an email address")
+	}
+	byEmail, err := repository.GetUserByEmail(ctx, os.db, userInfo.Email)
+	if err != nil {
+		return nil, false, err
+	}
+	if byEmail != nil {
+		if !userInfo.EmailVerified || !byEmail.IsEmailVerified {
+			return nil, false, ErrOAuthEmailNotVerified
+		}
+		if err := os.LinkProvider(ctx, byEmail.ID, provider, userInfo.ID, userInfo.Email, token); err != nil {
+			return nil, false, err
+		}
+		return byEmail, false, nil
+	}
+
+	user, err = os.createUserFromOAuth(ctx, provider, userInfo, token)
+	return user, false, err
+}
+
+// Identities lists the providers linked to a user.
+func (os *OAuthService) Identities(ctx context.Context, userID uuid.UUID) ([]domain.OAuthIdentity, error) {
+	return repository.GetUserOAuthIdentities(ctx, os.db, userID)
+}
+
+// Unlink removes a provider from a user's account. A user without a password must keep at least
+// one linked provider to sign in with.
+func (os *OAuthService) Unlink(ctx context.Context, userID uuid.UUID, provider string) error {
+	user, err := repository.GetUserByID(ctx, os.db, userID)
+	if err != nil {
+		return err
+	}
+	if user.Password == "" {
+		identities, err := repository.GetUserOAuthIdentities(ctx, os.db, userID)
+		if err != nil {
+			return err
+		}
+		if len(identities) <= 1 {
+			return ErrOAuthLastSignInMethod
+		}
+	}
+	ok, err := repository.DeleteUserOAuthProvider(ctx, os.db, userID, provider)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrOAuthNotLinked
+	}
+	return nil
 }
 
 // GetRedirectURI returns the configured redirect URI for a provider
@@ -65,13 +265,14 @@ func (os *OAuthService) GetRedirectURI(provider string) (string, error) {
 	return oauthConfig.RedirectURL, nil
 }
 
-func (os *OAuthService) ExchangeCode(ctx context.Context, provider string, code string) (*oauth2.Token, error) {
+// ExchangeCode redeems an authorisation code, proving possession of the PKCE verifier.
+func (os *OAuthService) ExchangeCode(ctx context.Context, provider string, code string, verifier string) (*oauth2.Token, error) {
 	oauthConfig, ok := os.providers[provider]
 	if !ok {
 		return nil, fmt.Errorf("oauth provider %s not configured", provider)
 	}
 
-	token, err := oauthConfig.Exchange(ctx, code)
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(verifier))
 	if err != nil {
 		return nil, fmt.Errorf("failed to exchange code for token: %w", err)
 	}
@@ -79,6 +280,46 @@ func (os *OAuthService) ExchangeCode(ctx context.Context, provider string, code
 	return token, nil
 }
 
+// idTokenClaims are the id_token claims checked after a code exchange.
+type idTokenClaims struct {
+	Nonce               string `json:"nonce"`
+	TenantID            string `json:"tid"`
+	EmailDomainVerified *bool  `json:"xms_edov"`
+	jwt.RegisteredClaims
+}
+
+// verifyIDToken checks the id_token returned with the access token. It comes straight from the
+// provider's token endpoint over TLS, which OpenID Connect Core 3.1.3.7 accepts in place of checking
+// its signature; the audience, issuer, expiry and nonce are still verified.
+func (os *OAuthService) verifyIDToken(provider string, token *oauth2.Token, nonce string) (*idTokenClaims, error) {
+	raw, _ := token.Extra("id_token").(string)
+	if raw == "" {
+		return nil, errors.New("provider did not return an id_token")
+	}
+	claims := &idTokenClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(raw, claims); err != nil {
+		return nil, errors.New("invalid id_token")
+	}
+	providerCfg := os.config.Providers[provider]
+	if claims.ExpiresAt == nil || time.Now().After(claims.ExpiresAt.Add(idTokenLeeway)) {
+		return nil, errors.New("id_token has expired")
+	}
+	if !slices.Contains(claims.Audience, providerCfg.ClientID) {
+		return nil, errors.New("id_token was issued to another client")
+	}
+	if providerCfg.Issuer != "" {
+		expected := strings.ReplaceAll(providerCfg.Issuer, "{tenantid}", claims.TenantID)
+		// Google issues with and without the scheme
+		if strings.TrimPrefix(claims.Issuer, "https://") != strings.TrimPrefix(expected, "https://") {
+			return nil, errors.New("id_token was issued by an unexpected issuer")
+		}
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, errors.New("id_token nonce does not match")
+	}
+	return claims, nil
+}
+
 func (os *OAuthService) GetUserInfo(ctx context.Context, provider string, token *oauth2.Token) (*domain.OAuthUserInfo, error) {
 	providerCfg, ok := os.config.Providers[provider]
 	if !ok {
@@ -91,6 +332,9 @@ func (os *OAuthService) GetUserInfo(ctx context.Context, provider string, token
 		return nil, fmt.Errorf("failed to get user info: %w", err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get user info: provider returned %s", resp.Status)
+	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -136,14 +380,33 @@ func (os *OAuthService) GetUserInfo(ctx context.Context, provider string, token
 			email = msUser.UserPrincipalName
 		}
 		userInfo = domain.OAuthUserInfo{
-			ID:            msUser.ID,
-			Email:         email,
-			FirstName:     msUser.GivenName,
-			LastName:      msUser.Surname,
-			EmailVerified: true, // Microsoft accounts are typically verified
+			ID:        msUser.ID,
+			Email:     email,
+			FirstName: msUser.GivenName,
+			LastName:  msUser.Surname,
+			// Set from the id_token by Complete; Graph's mail is not necessarily verified
 		}
 	default:
-		return nil, fmt.Errorf("unsupported provider: %s", provider)
+		// Any other provider is expected to serve a standard OpenID Connect userinfo response
+		var oidcUser struct {
+			Subject       string `json:"sub"`
+			Email         string `json:"email"`
+			EmailVerified bool   `json:"email_verified"`
+			GivenName     string `json:"given_name"`
+			FamilyName    string `json:"family_name"`
+			Picture       string `json:"picture"`
+		}
+		if err := json.Unmarshal(body, &oidcUser); err != nil {
+			return nil, fmt.Errorf("failed to parse %s user info: %w", provider, err)
+		}
+		userInfo = domain.OAuthUserInfo{
+			ID:            oidcUser.Subject,
+			Email:         oidcUser.Email,
+			FirstName:     oidcUser.GivenName,
+			LastName:      oidcUser.FamilyName,
+			AvatarURL:     oidcUser.Picture,
+			EmailVerified: oidcUser.EmailVerified,
+		}
 	}
 
 	return &userInfo, nil
@@ -173,19 +436,7 @@ func (os *OAuthService) LinkProvider(ctx context.Context, userID uuid.UUID, prov
 	}
 
 	// Create new link
-	expiresAt := token.Expiry
-	oauthProvider = domain.OAuthProvider{
-		ID:             uuid.New(),
-		UserID:         userID,
-		Provider:       provider,
-		ProviderUserID: providerUserID,
-		ProviderEmail:  providerEmail,
-		AccessToken:    token.AccessToken,
-		RefreshToken:   token.RefreshToken,
-		TokenExpiresAt: &expiresAt,
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
-	}
+	oauthProvider = *oauthProviderLink(userID, provider, &domain.OAuthUserInfo{ID: providerUserID, Email: providerEmail}, token)
 
 	// Create new OAuth provider link
 	err = repository.CreateOAuthProvider(ctx, os.db, &oauthProvider)
@@ -195,21 +446,9 @@ func (os *OAuthService) LinkProvider(ctx context.Context, userID uuid.UUID, prov
 	return nil
 }
 
-func (os *OAuthService) FindUserByProvider(ctx context.Context, provider string, providerUserID string) (*domain.User, error) {
-	oauthProvider, err := repository.GetOAuthProvider(ctx, os.db, provider, providerUserID)
-	if err != nil {
-		return nil, err
-	}
-
-	user, err := repository.GetUserByID(ctx, os.db, oauthProvider.UserID)
-	if err != nil {
-		return nil, err
-	}
-
-	return user, nil
-}
-
-func (os *OAuthService) CreateUserFromOAuth(ctx context.Context, userInfo *domain.OAuthUserInfo) (*domain.User, error) {
+// createUserFromOAuth signs up a new user from a provider identity and links it.
+func (os *OAuthService) createUserFromOAuth(ctx context.Context, provider string, userInfo *domain.OAuthUserInfo, token *oauth2.Token) (*domain.User, error) {
+	now := time.Now()
 	user := domain.User{
 		ID:              uuid.New(),
 		Email:           userInfo.Email,
@@ -217,14 +456,35 @@ func (os *OAuthService) CreateUserFromOAuth(ctx context.Context, userInfo *domai
 		LastName:        userInfo.LastName,
 		AvatarURL:       userInfo.AvatarURL,
 		IsEmailVerified: userInfo.EmailVerified,
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
+		CreatedAt:       now,
+		UpdatedAt:       now,
 	}
-
-	err := repository.CreateUser(ctx, os.db, &user)
+	link := oauthProviderLink(user.ID, provider, userInfo, token)
+	err := runInTx(ctx, os.db, func(tx *sqlx.Tx) error {
+		if err := repository.CreateUser(ctx, tx, &user); err != nil {
+			return err
+		}
+		return repository.CreateOAuthProvider(ctx, tx, link)
+	})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
-
 	return &user, nil
 }
+
+func oauthProviderLink(userID uuid.UUID, provider string, userInfo *domain.OAuthUserInfo, token *oauth2.Token) *domain.OAuthProvider {
+	now := time.Now()
+	expiresAt := token.Expiry
+	return &domain.OAuthProvider{
+		ID:             uuid.New(),
+		UserID:         userID,
+		Provider:       provider,
+		ProviderUserID: userInfo.ID,
+		ProviderEmail:  userInfo.Email,
+		AccessToken:    token.AccessToken,
+		RefreshToken:   token.RefreshToken,
+		TokenExpiresAt: &expiresAt,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+}
@@ -0,0 +1,503 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"golang.org/x/oauth2"
+)
+
+// fakeProvider is an OpenID Connect provider. Its authorize endpoint redirects back with a code bound
+// to the PKCE challenge and nonce, its token endpoint redeems each code once for an access token and
+// an id_token, and its userinfo endpoint describes identity.
+type fakeProvider struct {
+	*httptest.Server
+	clientID, clientSecret string
+	identity               oidcIdentity
+	// nonce, when set, replaces the nonce requested at authorisation in the id_tokens issued
+	nonce string
+
+	mu     sync.Mutex
+	codes  map[string]authorization
+	tokens map[string]bool
+}
+
+type oidcIdentity struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
+}
+
+type authorization struct{ challenge, nonce, redirectURI string }
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	p := &fakeProvider{
+		clientID:     "client-id",
+		clientSecret: "client-secret",
+		identity:     oidcIdentity{Subject: "provider-user-1", Email: "dentist@example.com", EmailVerified: true, GivenName: "Dana"},
+		codes:        map[string]authorization{},
+		tokens:       map[string]bool{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/userinfo", p.userinfo)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *fakeProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != p.clientID || q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" ||
+		q.Get("code_challenge") == "" || q.Get("nonce") == "" || q.Get("state") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	code := uuid.NewString()
+	p.mu.Lock()
+	p.codes[code] = authorization{challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), redirectURI: q.Get("redirect_uri")}
+	p.mu.Unlock()
+	back := q.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+	http.Redirect(w, r, back, http.StatusFound)
+}
+
+func (p *fakeProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if id != p.clientID || secret != p.clientSecret {
+		oauthError(w, "invalid_client")
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	code := r.PostForm.Get("code")
+	auth, ok := p.codes[code]
+	delete(p.codes, code)
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != auth.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != auth.challenge {
+		oauthError(w, "invalid_grant")
+		return
+	}
+
+	nonce := auth.nonce
+	if p.nonce != "" {
+		nonce = p.nonce
+	}
+	now := time.Now()
+	idToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &idTokenClaims{
+		Nonce: nonce,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    p.URL,
+			Subject:   p.identity.Subject,
+			Audience:  jwt.ClaimStrings{p.clientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		},
+	}).SignedString([]byte("provider-signing-key"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	accessToken := uuid.NewString()
+	p.tokens[accessToken] = true
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func (p *fakeProvider) userinfo(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	ok := p.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+	p.mu.Unlock()
+	if !ok {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p.identity)
+}
+
+func oauthError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+// sendBrowser follows an authorisation URL as the browser would and returns the code and state the
+// provider sends it back with.
+func (p *fakeProvider) sendBrowser(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("provider refused the authorisation request: %s", resp.Status)
+	}
+	back, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.Path != "/auth/oauth/oidc/callback" {
+		t.Fatalf("provider redirected to %s", back)
+	}
+	return back.Query().Get("code"), back.Query().Get("state")
+}
+
+func newOAuthService(t *testing.T, p *fakeProvider) (*OAuthService, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock := newMockDB(t)
+	tokens := NewTokenService(config.JWTConfig{SecretKey: "test-secret", AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour, Issuer: "test"})
+	return NewOAuthService(config.OAuthConfig{
+		RedirectURL: "http://api.test/auth/oauth",
+		Providers: map[string]config.OAuthProviderConfig{
+			"oidc": {
+				ClientID:     p.clientID,
+				ClientSecret: p.clientSecret,
+				AuthURL:      p.URL + "/authorize",
+				TokenURL:     p.URL + "/token",
+				UserInfoURL:  p.URL + "/userinfo",
+				Scopes:       []string{"openid", "email", "profile"},
+				Issuer:       p.URL,
+			},
+		},
+	}, db, tokens), mock
+}
+
+// anything captures an argument of any type.
+type anything struct{ value driver.Value }
+
+func (a *anything) Match(v driver.Value) bool {
+	a.value = v
+	return true
+}
+
+// recent matches a time close to now.
+type recent struct{}
+
+func (recent) Match(v driver.Value) bool {
+	at, ok := v.(time.Time)
+	return ok && time.Since(at).Abs() < time.Minute
+}
+
+// begin starts an authorisation, for linkUser when set, expecting its state to be stored, and returns
+// the URL to send the browser to, the state, and the authorisation as stored.
+func begin(t *testing.T, os *OAuthService, mock sqlmock.Sqlmock, linkUser *uuid.UUID) (string, string, *domain.OAuthState) {
+	t.Helper()
+	linkToken := ""
+	if linkUser != nil {
+		link, err := os.LinkURL(*linkUser, "oidc")
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, _ := url.Parse(link.URL)
+		linkToken = u.Query().Get("link_token")
+	}
+	mock.ExpectExec(stmt("DELETE FROM tbl_oauth_state WHERE expires_at <= $1")).WithArgs(recent{}).WillReturnResult(sqlmock.NewResult(0, 0))
+	hash, verifier, nonce, userID := &capture{}, &capture{}, &capture{}, &anything{}
+	mock.ExpectExec(stmt("INSERT INTO tbl_oauth_state")).
+		WithArgs(hash, "oidc", verifier, nonce, userID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	authURL, state, err := os.Begin(context.Background(), "oidc", linkToken)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if hash.value != hashToken(state) {
+		t.Fatal("state is not stored by its hash")
+	}
+	if (linkUser == nil) != (userID.value == nil) {
+		t.Fatalf("stored link user %v, want %v", userID.value, linkUser)
+	}
+	now := time.Now()
+	return authURL, state, &domain.OAuthState{StateHash: hash.value, Provider: "oidc", CodeVerifier: verifier.value, Nonce: nonce.value,
+		UserID: linkUser, ExpiresAt: now.Add(oauthStateTTL), CreatedAt: now}
+}
+
+// expectConsumeState expects the state to be redeemed, finding stored, or nothing when nil: the
+// store only returns a state that has not expired and deletes it as it does.
+func expectConsumeState(mock sqlmock.Sqlmock, state string, stored *domain.OAuthState) {
+	rows := sqlmock.NewRows([]string{"state_hash", "provider", "code_verifier", "nonce", "user_id", "expires_at", "created_at"})
+	if stored != nil {
+		var userID driver.Value
+		if stored.UserID != nil {
+			userID = stored.UserID.String()
+		}
+		rows.AddRow(stored.StateHash, stored.Provider, stored.CodeVerifier, stored.Nonce, userID, stored.ExpiresAt, stored.CreatedAt)
+	}
+	mock.ExpectQuery(stmt("DELETE FROM tbl_oauth_state WHERE state_hash = $1 AND expires_at > $2")).
+		WithArgs(hashToken(state), recent{}).WillReturnRows(rows)
+}
+
+var providerColumns = []string{"id", "user_id", "provider", "provider_user_id", "provider_email", "access_token", "refresh_token", "token_expires_at", "created_at", "updated_at"}
+
+// expectProviderLink expects the identity's link to be looked up, finding one to owner when set.
+func expectProviderLink(mock sqlmock.Sqlmock, p *fakeProvider, owner *uuid.UUID) *uuid.UUID {
+	rows := sqlmock.NewRows(providerColumns)
+	var id *uuid.UUID
+	if owner != nil {
+		linkID := uuid.New()
+		id = &linkID
+		rows.AddRow(linkID, *owner, "oidc", p.identity.Subject, p.identity.Email, "old-access", "", nil, time.Now(), time.Now())
+	}
+	mock.ExpectQuery(stmt("FROM tbl_auth_provider WHERE provider = $1 AND provider_user_id = $2")).
+		WithArgs("oidc", p.identity.Subject).WillReturnRows(rows)
+	return id
+}
+
+// expectNewLink expects the identity to be linked to userID for the first time.
+func expectNewLink(mock sqlmock.Sqlmock, p *fakeProvider, userID uuid.UUID) {
+	expectProviderLink(mock, p, nil)
+	mock.ExpectExec(stmt("INSERT INTO tbl_auth_provider")).
+		WithArgs(sqlmock.AnyArg(), userID, "oidc", p.identity.Subject, p.identity.Email, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func expectUserByID(mock sqlmock.Sqlmock, user *domain.User) {
+	mock.ExpectQuery(stmt("FROM tbl_user WHERE id = $1")).WithArgs(user.ID).
+		WillReturnRows(sqlmock.NewRows(userColumns).AddRow(user.ID, user.Email, user.Password, user.FirstName, user.LastName, user.Phone, user.IsEmailVerified, user.CreatedAt, user.UpdatedAt))
+}
+
+func expectIdentities(mock sqlmock.Sqlmock, userID uuid.UUID, providers ...string) {
+	rows := sqlmock.NewRows([]string{"provider", "provider_email", "created_at"})
+	for _, p := range providers {
+		rows.AddRow(p, "", time.Now())
+	}
+	mock.ExpectQuery(stmt("FROM tbl_auth_provider WHERE user_id = $1")).WithArgs(userID).WillReturnRows(rows)
+}
+
+func TestOAuthRejectsWrongCodeVerifier(t *testing.T) {
+	p := newFakeProvider(t)
+	os, mock := newOAuthService(t, p)
+	authURL, state, stored := begin(t, os, mock, nil)
+	code, _ := p.sendBrowser(t, authURL)
+
+	stored.CodeVerifier = oauth2.GenerateVerifier()
+	expectConsumeState(mock, state, stored)
+	if _, _, err := os.Complete(context.Background(), "oidc", state, code); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Fatalf("Complete with another verifier: %v, want invalid_grant", err)
+	}
+}
+
+func TestOAuthRejectsNonceMismatch(t *testing.T) {
+	p := newFakeProvider(t)
+	os, mock := newOAuthService(t, p)
+	authURL, state, stored := begin(t, os, mock, nil)
+	code, _ := p.sendBrowser(t, authURL)
+
+	p.nonce = "nonce-of-another-sign-in"
+	expectConsumeState(mock, state, stored)
+	if _, _, err := os.Complete(context.Background(), "oidc", state, code); err == nil || !strings.Contains(err.Error(), "nonce does not match") {
+		t.Fatalf("Complete with another nonce: %v", err)
+	}
+}
+
+func TestOAuthStateIsSingleUse(t *testing.T) {
+	p := newFakeProvider(t)
+	os, mock := newOAuthService(t, p)
+	ctx := context.Background()
+	user := testUser(true)
+
+	authURL, state, stored := begin(t, os, mock, nil)
+	code, returned := p.sendBrowser(t, authURL)
+	if returned != state {
+		t.Fatal("provider did not echo the state")
+	}
+	expectConsumeState(mock, state, stored)
+	expectProviderLink(mock, p, &user.ID)
+	linkID := expectProviderLink(mock, p, &user.ID)
+	mock.ExpectExec(stmt("UPDATE tbl_auth_provider SET user_id = $1")).
+		WithArgs(user.ID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), *linkID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectUserByID(mock, user)
+	got, linked, err := os.Complete(ctx, "oidc", state, code)
+	if err != nil || linked || got.ID != user.ID {
+		t.Fatalf("returning identity: user %v, linked %v, %v", got, linked, err)
+	}
+
+	expectConsumeState(mock, state, nil)
+	if _, _, err := os.Complete(ctx, "oidc", state, code); !errors.Is(err, ErrOAuthStateInvalid) {
+		t.Fatalf("replayed state: %v, want ErrOAuthStateInvalid", err)
+	}
+}
+
+func TestOAuthRejectsExpiredOrForeignState(t *testing.T) {
+	p := newFakeProvider(t)
+	os, mock := newOAuthService(t, p)
+	ctx := context.Background()
+
+	authURL, state, _ := begin(t, os, mock, nil)
+	code, _ := p.sendBrowser(t, authURL)
+	expectConsumeState(mock, state, nil) // expired, so the store no longer returns it
+	if _, _, err := os.Complete(ctx, "oidc", state, code); !errors.Is(err, ErrOAuthStateInvalid) {
+		t.Fatalf("expired state: %v, want ErrOAuthStateInvalid", err)
+	}
+
+	authURL, state, stored := begin(t, os, mock, nil)
+	code, _ = p.sendBrowser(t, authURL)
+	expectConsumeState(mock, state, stored)
+	if _, _, err := os.Complete(ctx, "google", state, code); !errors.Is(err, ErrOAuthStateInvalid) {
+		t.Fatalf("state of another provider: %v, want ErrOAuthStateInvalid", err)
+	}
+}
+
+func TestOAuthMergesVerifiedEmail(t *testing.T) {
+	p := newFakeProvider(t)
+	os, mock := newOAuthService(t, p)
+	user := testUser(true)
+
+	authURL, state, stored := begin(t, os, mock, nil)
+	code, _ := p.sendBrowser(t, authURL)
+	expectConsumeState(mock, state, stored)
+	expectProviderLink(mock, p, nil)
+	expectUserByEmail(mock, user)
+	expectNewLink(mock, p, user.ID)
+	got, linked, err := os.Complete(context.Background(), "oidc", state, code)
+	if err != nil || linked || got.ID != user.ID {
+		t.Fatalf("verified email: user %v, linked %v, %v", got, linked, err)
+	}
+}
+
+func TestOAuthRefusesUnverifiedEmail(t *testing.T) {
+	tests := []struct {
+		name                     string
+		providerVerified, ourOwn bool
+	}{
+		{"provider has not verified the email", false, true},
+		{"account has not verified the email", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newFakeProvider(t)
+			p.identity.EmailVerified = tt.providerVerified
+			os, mock := newOAuthService(t, p)
+
+			authURL, state, stored := begin(t, os, mock, nil)
+			code, _ := p.sendBrowser(t, authURL)
+			expectConsumeState(mock, state, stored)
+			expectProviderLink(mock, p, nil)
+			expectUserByEmail(mock, testUser(tt.ourOwn))
+			if _, _, err := os.Complete(context.Background(), "oidc", state, code); !errors.Is(err, ErrOAuthEmailNotVerified) {
+				t.Fatalf("Complete: %v, want ErrOAuthEmailNotVerified", err)
+			}
+		})
+	}
+}
+
+func TestOAuthLinksToSignedInUser(t *testing.T) {
+	p := newFakeProvider(t)
+	p.identity.Email = "personal@example.com" // linking does not depend on the email
+	os, mock := newOAuthService(t, p)
+	user := testUser(true)
+
+	authURL, state, stored := begin(t, os, mock, &user.ID)
+	code, _ := p.sendBrowser(t, authURL)
+	expectConsumeState(mock, state, stored)
+	expectProviderLink(mock, p, nil)
+	expectIdentities(mock, user.ID, "google")
+	expectNewLink(mock, p, user.ID)
+	expectUserByID(mock, user)
+	got, linked, err := os.Complete(context.Background(), "oidc", state, code)
+	if err != nil || !linked || got.ID != user.ID {
+		t.Fatalf("link: user %v, linked %v, %v", got, linked, err)
+	}
+}
+
+func TestOAuthLinkRefusals(t *testing.T) {
+	p := newFakeProvider(t)
+	os, mock := newOAuthService(t, p)
+	ctx := context.Background()
+	user := testUser(true)
+
+	authURL, state, stored := begin(t, os, mock, &user.ID)
+	code, _ := p.sendBrowser(t, authURL)
+	expectConsumeState(mock, state, stored)
+	someoneElse := uuid.New()
+	expectProviderLink(mock, p, &someoneElse)
+	if _, _, err := os.Complete(ctx, "oidc", state, code); !errors.Is(err, ErrOAuthIdentityInUse) {
+		t.Fatalf("identity of another account: %v, want ErrOAuthIdentityInUse", err)
+	}
+
+	authURL, state, stored = begin(t, os, mock, &user.ID)
+	code, _ = p.sendBrowser(t, authURL)
+	expectConsumeState(mock, state, stored)
+	expectProviderLink(mock, p, nil)
+	expectIdentities(mock, user.ID, "oidc")
+	if _, _, err := os.Complete(ctx, "oidc", state, code); !errors.Is(err, ErrOAuthProviderLinked) {
+		t.Fatalf("second identity from the provider: %v, want ErrOAuthProviderLinked", err)
+	}
+
+	link, err := os.tokenService.GenerateOAuthLinkToken(user.ID, "google", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := os.Begin(ctx, "oidc", link); err == nil {
+		t.Fatal("Begin accepted a link request issued for another provider")
+	}
+}
+
+func TestOAuthUnlink(t *testing.T) {
+	ctx := context.Background()
+	passwordless := testUser(true)
+	passwordless.Password = ""
+	withPassword := testUser(true)
+	tests := []struct {
+		name       string
+		user       *domain.User
+		identities []string // listed only for users without a password
+		unlinked   int64
+		want       error
+	}{
+		{"only sign-in method", passwordless, []string{"oidc"}, 0, ErrOAuthLastSignInMethod},
+		{"another provider remains", passwordless, []string{"google", "oidc"}, 1, nil},
+		{"password remains", withPassword, nil, 1, nil},
+		{"provider not linked", withPassword, nil, 0, ErrOAuthNotLinked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os, mock := newOAuthService(t, newFakeProvider(t))
+			expectUserByID(mock, tt.user)
+			if tt.user.Password == "" {
+				expectIdentities(mock, tt.user.ID, tt.identities...)
+			}
+			if tt.want != ErrOAuthLastSignInMethod {
+				mock.ExpectExec(stmt("UPDATE tbl_auth_provider SET deleted_at")).
+					WithArgs(sqlmock.AnyArg(), tt.user.ID, "oidc").WillReturnResult(sqlmock.NewResult(0, tt.unlinked))
+			}
+			if err := os.Unlink(ctx, tt.user.ID, "oidc"); !errors.Is(err, tt.want) {
+				t.Fatalf("Unlink: %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/repository"
+	"github.com/iamarpitzala/aca-reca-backend/internal/storage"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrPDFTemplateNotFound = errors.New("pdf template not found")
+
+type PDFTemplateService struct {
+	db    *sqlx.DB
+	store storage.Storage
+}
+
+func NewPDFTemplateService(db *sqlx.DB, store storage.Storage) *PDFTemplateService {
+	return &PDFTemplateService{db: db, store: store}
+}
+
+func (s *PDFTemplateService) List(ctx context.Context, clinicID uuid.UUID) ([]domain.PDFTemplate, error) {
+	return repository.GetPDFTemplatesByClinicID(ctx, s.db, clinicID)
+}
+
+func (s *PDFTemplateService) Get(ctx context.Context, clinicID, id uuid.UUID) (*domain.PDFTemplate, error) {
+	t, err := repository.GetPDFTemplate(ctx, s.db, clinicID, id)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrPDFTemplateNotFound
+	}
+	return t, nil
+}
+
+// Create saves a template. A clinic's first template becomes its default.
+func (s *PDFTemplateService) Create(ctx context.Context, clinicID, userID uuid.UUID, req domain.CreatePDFTemplateRequest) (*domain.PDFTemplate, error) {
+	if err := ValidatePDFConfig(&req.Config); err != nil {
+		return nil, err
+	}
+	config, err := json.Marshal(req.Config)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := repository.GetDefaultPDFTemplate(ctx, s.db, clinicID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	t := &domain.PDFTemplate{
+		ID:        uuid.New(),
+		ClinicID:  clinicID,
+		Name:      strings.TrimSpace(req.Name),
+		Config:    config,
+		CreatedBy: userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	makeDefault := req.IsDefault || existing == nil
+	err = runInTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		if err := repository.CreatePDFTemplate(ctx, tx, t); err != nil {
+			return err
+		}
+		if makeDefault {
+			return repository.SetDefaultPDFTemplate(ctx, tx, clinicID, t.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, templateNameConflict(err)
+	}
+	t.IsDefault = makeDefault
+	return t, nil
+}
+
+func (s *PDFTemplateService) Update(ctx context.Context, clinicID, id uuid.UUID, req domain.UpdatePDFTemplateRequest) (*domain.PDFTemplate, error) {
+	t, err := s.Get(ctx, clinicID, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		t.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Config != nil {
+		if err := ValidatePDFConfig(req.Config); err != nil {
+			return nil, err
+		}
+		if t.Config, err = json.Marshal(req.Config); err != nil {
+			return nil, err
+		}
+	}
+	t.UpdatedAt = time.Now()
+	if err := repository.UpdatePDFTemplate(ctx, s.db, t); err != nil {
+		return nil, templateNameConflict(err)
+	}
+	return t, nil
+}
+
+// SetDefault makes the template the one used when a document names none.
+func (s *PDFTemplateService) SetDefault(ctx context.Context, clinicID, id uuid.UUID) (*domain.PDFTemplate, error) {
+	t, err := s.Get(ctx, clinicID, id)
+	if err != nil {
+		return nil, err
+	}
+	err = runInTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		return repository.SetDefaultPDFTemplate(ctx, tx, clinicID, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.IsDefault = true
+	return t, nil
+}
+
+// Delete removes a template and its logo. Deleting the default leaves the clinic without one until
+// another is marked.
+func (s *PDFTemplateService) Delete(ctx context.Context, clinicID, id uuid.UUID) error {
+	t, err := s.Get(ctx, clinicID, id)
+	if err != nil {
+		return err
+	}
+	if err := repository.DeletePDFTemplate(ctx, s.db, id); err != nil {
+		return err
+	}
+	if t.LogoKey != nil {
+		// The template row is already gone; an orphaned file is harmless
+		_ = s.store.Delete(ctx, *t.LogoKey)
+	}
+	return nil
+}
+
+// UploadLogo stores a PNG or JPEG logo for the template, replacing any previous one.
+func (s *PDFTemplateService) UploadLogo(ctx context.Context, clinicID, id uuid.UUID, data []byte) (*domain.PDFTemplate, error) {
+	t, err := s.Get(ctx, clinicID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxLogoBytes {
+		return nil, errors.New("logo must be 2 MB or smaller")
+	}
+	contentType, _, ok := logoImageType(data)
+	if !ok {
+		return nil, errors.New("logo must be a PNG or JPEG image")
+	}
+	key := "clinics/" + clinicID.String() + "/pdf-templates/" + id.String() + "/logo"
+	if err := s.store.Put(ctx, key, data); err != nil {
+		return nil, err
+	}
+	if err := repository.UpdatePDFTemplateLogo(ctx, s.db, id, &key, &contentType); err != nil {
+		return nil, err
+	}
+	t.LogoKey = &key
+	t.LogoContentType = &contentType
+	return t, nil
+}
+
+// GetLogo returns the template's logo and its content type.
+func (s *PDFTemplateService) GetLogo(ctx context.Context, clinicID, id uuid.UUID) ([]byte, string, error) {
+	t, err := s.Get(ctx, clinicID, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if t.LogoKey == nil {
+		return nil, "", errors.New("template has no logo")
+	}
+	data, err := s.store.Get(ctx, *t.LogoKey)
+	if err != nil {
+		return nil, "", err
+	}
+	contentType := ""
+	if t.LogoContentType != nil {
+		contentType = *t.LogoContentType
+	}
+	return data, contentType, nil
+}
+
+func (s *PDFTemplateService) DeleteLogo(ctx context.Context, clinicID, id uuid.UUID) error {
+	t, err := s.Get(ctx, clinicID, id)
+	if err != nil {
+		return err
+	}
+	if t.LogoKey == nil {
+		return nil
+	}
+	if err := repository.UpdatePDFTemplateLogo(ctx, s.db, id, nil, nil); err != nil {
+		return err
+	}
+	return s.store.Delete(ctx, *t.LogoKey)
+}
+
+// Resolve returns the config and logo a document should use: the named template, or the clinic's
+// default when templateID is nil. It returns a nil config when the clinic has no template to use.
+func (s *PDFTemplateService) Resolve(ctx context.Context, clinicID uuid.UUID, templateID *uuid.UUID) (*domain.PDFConfig, []byte, string, error) {
+	var t *domain.PDFTemplate
+	var err error
+	if templateID != nil {
+		t, err = s.Get(ctx, clinicID, *templateID)
+	} else {
+		t, err = repository.GetDefaultPDFTemplate(ctx, s.db, clinicID)
+	}
+	if err != nil || t == nil {
+		return nil, nil, "", err
+	}
+	var config domain.PDFConfig
+	if err := json.Unmarshal(t.Config, &config); err != nil {
+		return nil, nil, "", errors.New("stored pdf template is invalid")
+	}
+	if t.LogoKey == nil {
+		return &config, nil, "", nil
+	}
+	logo, err := s.store.Get(ctx, *t.LogoKey)
+	if err != nil {
+		// Documents still render without the logo
+		return &config, nil, "", nil
+	}
+	_, imageType, _ := logoImageType(logo)
+	return &config, logo, imageType, nil
+}
+
+// logoImageType sniffs a logo, returning its MIME type and gofpdf image type.
+func logoImageType(data []byte) (contentType, imageType string, ok bool) {
+	switch http.DetectContentType(data) {
+	case "image/png":
+		return "image/png", "PNG", true
+	case "image/jpeg":
+		return "image/jpeg", "JPG", true
+	}
+	return "", "", false
+}
+
+func templateNameConflict(err error) error {
+	if strings.Contains(err.Error(), "ux_pdf_template_name") {
+		return errors.New("a template with this name already exists")
+	}
+	return err
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/repository"
+	"github.com/jmoiron/sqlx"
+)
+
+// FinancialYearPeriods returns the BAS periods of an Australian financial year (1 July fy-1 to
+// 30 June fy): the four ATO quarters for a quarterly cycle, or twelve calendar months for a monthly one.
+func FinancialYearPeriods(fy int, cycle string) []domain.Quarter {
+	start := time.Date(fy-1, time.July, 1, 0, 0, 0, 0, time.UTC)
+	months, periodType := 3, domain.PeriodTypeQuarter
+	if cycle == domain.BASCycleMonthly {
+		months, periodType = 1, domain.PeriodTypeMonth
+	}
+
+	year := fy
+	periods := make([]domain.Quarter, 0, 12/months)
+	for i := 0; i < 12/months; i++ {
+		periodStart := start.AddDate(0, i*months, 0)
+		name := fmt.Sprintf("Q%d FY%d", i+1, fy)
+		if periodType == domain.PeriodTypeMonth {
+			name = periodStart.Format("Jan 2006")
+		}
+		periods = append(periods, domain.Quarter{
+			Name:          name,
+			PeriodType:    periodType,
+			FinancialYear: &year,
+			StartDate:     periodStart,
+			EndDate:       periodStart.AddDate(0, months, -1),
+		})
+	}
+	return periods
+}
+
+// checkPeriodsFit rejects added periods that overlap, or leave a gap next to, the periods already in
+// their scope. Gaps elsewhere in the existing chain are not the caller's concern.
+func checkPeriodsFit(existing, added []domain.Quarter) error {
+	all := make([]domain.Quarter, 0, len(existing)+len(added))
+	isAdded := make(map[int]bool, len(added))
+	all = append(all, existing...)
+	for _, p := range added {
+		if !p.EndDate.After(p.StartDate) {
+			return fmt.Errorf("%s must end after it starts", p.Name)
+		}
+		isAdded[len(all)] = true
+		all = append(all, p)
+	}
+
+	order := make([]int, len(all))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return all[order[a]].StartDate.Before(all[order[b]].StartDate) })
+
+	for i := 1; i < len(order); i++ {
+		if !isAdded[order[i-1]] && !isAdded[order[i]] {
+			continue
+		}
+		prev, next := all[order[i-1]], all[order[i]]
+		if !next.StartDate.After(prev.EndDate) {
+			return fmt.Errorf("%s overlaps %s", next.Name, prev.Name)
+		}
+		if !sameDay(next.StartDate, prev.EndDate.AddDate(0, 0, 1)) {
+			return fmt.Errorf("gap between %s and %s; periods must be contiguous", prev.Name, next.Name)
+		}
+	}
+	return nil
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// withoutPeriod returns periods minus the one with the given ID.
+func withoutPeriod(periods []domain.Quarter, id uuid.UUID) []domain.Quarter {
+	out := make([]domain.Quarter, 0, len(periods))
+	for _, p := range periods {
+		if p.ID != id {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// GenerateFinancialYear creates the clinic's own BAS periods for a financial year using its BAS cycle.
+// Once a clinic has periods of its own, entries and BAS worksheets use those instead of the shared ones.
+func (qs *QuarterService) GenerateFinancialYear(ctx context.Context, clinicID uuid.UUID, fy int) ([]domain.Quarter, error) {
+	clinic, err := repository.GetClinicByID(ctx, qs.db, clinicID)
+	if err != nil {
+		return nil, err
+	}
+	if clinic.ID == uuid.Nil {
+		return nil, ErrClinicNotFound
+	}
+	cycle := clinic.BASCycle
+	if cycle == "" {
+		cycle = domain.BASCycleQuarterly
+	}
+
+	periods := FinancialYearPeriods(fy, cycle)
+	now := time.Now()
+	for i := range periods {
+		periods[i].ID = uuid.New()
+		periods[i].ClinicID = &clinicID
+		periods[i].CreatedAt = now
+		periods[i].UpdatedAt = &now
+	}
+
+	err = runInTx(ctx, qs.db, func(tx *sqlx.Tx) error {
+		existing, err := repository.GetPeriodsInScope(ctx, tx, &clinicID)
+		if err != nil {
+			return err
+		}
+		if err := checkPeriodsFit(existing, periods); err != nil {
+			return err
+		}
+		for i := range periods {
+			if err := repository.CreateQuarter(ctx, tx, &periods[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return periods, nil
+}
+
+// DeleteClinicPeriod removes one of the clinic's own periods. Only the first or last period can go,
+// so the remaining chain stays contiguous.
+func (qs *QuarterService) DeleteClinicPeriod(ctx context.Context, clinicID, id uuid.UUID) error {
+	period, err := repository.GetClinicPeriodByID(ctx, qs.db, clinicID, id)
+	if err != nil {
+		return err
+	}
+	if period == nil || period.ClinicID == nil {
+		return errors.New("period not found")
+	}
+	return qs.deletePeriod(ctx, *period)
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/repository"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrPeriodClosed is matched (errors.Is) by every PeriodClosedError.
+var ErrPeriodClosed = errors.New("period is closed")
+
+// PeriodClosedErrorCode is returned to API clients alongside a PeriodClosedError.
+const PeriodClosedErrorCode = "PERIOD_CLOSED"
+
+// PeriodClosedError rejects a change to an entry dated inside a closed quarter. NextOpen, when known,
+// is the period a correcting entry should be recorded in instead.
+type PeriodClosedError struct {
+	Quarter  domain.Quarter
+	NextOpen *domain.Quarter
+}
+
+func (e *PeriodClosedError) Error() string {
+	msg := fmt.Sprintf("%s is closed for this clinic; record a correcting entry in an open period instead", e.Quarter.Name)
+	if e.NextOpen != nil {
+		msg += fmt.Sprintf(" (next open period: %s)", e.NextOpen.Name)
+	}
+	return msg
+}
+
+func (e *PeriodClosedError) Is(target error) bool {
+	return target == ErrPeriodClosed
+}
+
+// checkPeriodOpen fails with a PeriodClosedError when an entry dated date, or assigned to quarterID,
+// falls in a quarter that is closed for the clinic.
+func checkPeriodOpen(ctx context.Context, db sqlx.QueryerContext, clinicID uuid.UUID, date time.Time, quarterID *uuid.UUID) error {
+	closed, err := repository.GetClosedQuarterForDate(ctx, db, clinicID, date)
+	if err != nil {
+		return err
+	}
+	if closed == nil && quarterID != nil {
+		qc, err := repository.GetActiveQuarterClose(ctx, db, clinicID, *quarterID)
+		if err != nil {
+			return err
+		}
+		if qc != nil {
+			quart, err := repository.GetQuarterByID(ctx, db, *quarterID)
+			if err != nil {
+				return err
+			}
+			closed = &quart
+		}
+	}
+	if closed == nil {
+		return nil
+	}
+	next, err := repository.GetNextOpenQuarter(ctx, db, clinicID, closed.EndDate)
+	if err != nil {
+		return err
+	}
+	return &PeriodClosedError{Quarter: *closed, NextOpen: next}
+}
+
+// CloseQuarter freezes a quarter for a clinic: entries dated inside it can no longer be created,
+// edited or deleted until it is reopened.
+func (qs *QuarterService) CloseQuarter(ctx context.Context, clinicID, quarterID, userID uuid.UUID) (*domain.QuarterClose, error) {
+	quart, err := repository.GetClinicPeriodByID(ctx, qs.db, clinicID, quarterID)
+	if err != nil {
+		return nil, err
+	}
+	if quart == nil {
+		return nil, errors.New("quarter not found")
+	}
+	existing, err := repository.GetActiveQuarterClose(ctx, qs.db, clinicID, quarterID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("quarter is already closed")
+	}
+	qc := &domain.QuarterClose{
+		ID:        uuid.New(),
+		ClinicID:  clinicID,
+		QuarterID: quarterID,
+		ClosedBy:  userID,
+		ClosedAt:  time.Now(),
+	}
+	if err := repository.CreateQuarterClose(ctx, qs.db, qc); err != nil {
+		if strings.Contains(err.Error(), "ux_clinic_quarter_close_active") {
+			return nil, errors.New("quarter is already closed")
+		}
+		return nil, err
+	}
+	return qc, nil
+}
+
+// ReopenQuarter lifts a close. The reason is kept with the close record.
+func (qs *QuarterService) ReopenQuarter(ctx context.Context, clinicID, quarterID, userID uuid.UUID, reason string) (*domain.QuarterClose, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("a reason is required to reopen a quarter")
+	}
+	qc, err := repository.GetActiveQuarterClose(ctx, qs.db, clinicID, quarterID)
+	if err != nil {
+		return nil, err
+	}
+	if qc == nil {
+		return nil, errors.New("quarter is not closed")
+	}
+	now := time.Now()
+	qc.ReopenedBy = &userID
+	qc.ReopenedAt = &now
+	qc.ReopenReason = &reason
+	if err := repository.ReopenQuarterClose(ctx, qs.db, qc); err != nil {
+		return nil, err
+	}
+	return qc, nil
+}
+
+// ListClinicQuarters returns the periods the clinic reports against with their close status.
+func (qs *QuarterService) ListClinicQuarters(ctx context.Context, clinicID uuid.UUID) ([]domain.ClinicQuarterStatus, error) {
+	quarters, err := repository.ListClinicPeriods(ctx, qs.db, clinicID)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]domain.ClinicQuarterStatus, len(quarters))
+	for i, q := range quarters {
+		qc, err := repository.GetActiveQuarterClose(ctx, qs.db, clinicID, q.ID)
+		if err != nil {
+			return nil, err
+		}
+		statuses[i] = domain.ClinicQuarterStatus{
+			QuarterID:     q.ID,
+			Name:          q.Name,
+			PeriodType:    q.PeriodType,
+			FinancialYear: q.FinancialYear,
+			StartDate:     q.StartDate,
+			EndDate:       q.EndDate,
+			Closed:        qc != nil,
+			Close:         qc,
+		}
+	}
+	return statuses, nil
+}
+
+// GetCloseHistory returns the clinic's close and reopen records, optionally for one quarter.
+func (qs *QuarterService) GetCloseHistory(ctx context.Context, clinicID uuid.UUID, quarterID *uuid.UUID) ([]domain.QuarterClose, error) {
+	return repository.GetQuarterCloses(ctx, qs.db, clinicID, quarterID)
+}
@@ -24,10 +24,22 @@ func NewQuarterService(db *sqlx.DB) *QuarterService {
 func (qs *QuarterService) CreateQuarter(ctx context.Context, form *domain.Quarter) error {
 
 	form.ID = uuid.New()
+	form.ClinicID = nil
+	if form.PeriodType == "" {
+		form.PeriodType = domain.PeriodTypeQuarter
+	}
 	form.CreatedAt = time.Now()
 	form.UpdatedAt = &form.CreatedAt
 	form.DeletedAt = nil
 
+	existing, err := repository.GetPeriodsInScope(ctx, qs.db, nil)
+	if err != nil {
+		return err
+	}
+	if err := checkPeriodsFit(existing, []domain.Quarter{*form}); err != nil {
+		return err
+	}
+
 	return repository.CreateQuarter(ctx, qs.db, form)
 }
 
@@ -45,6 +57,24 @@ func (qs *QuarterService) UpdateQuarter(ctx context.Context, form *domain.Quarte
 	if existing.DeletedAt != nil {
 		return errors.New("cannot update deleted quarter")
 	}
+	if existing.ClinicID != nil {
+		return errors.New("quarter belongs to a clinic and cannot be changed here")
+	}
+	if err := qs.ensureNotClosed(ctx, form.ID); err != nil {
+		return err
+	}
+
+	form.ClinicID = nil
+	if form.PeriodType == "" {
+		form.PeriodType = existing.PeriodType
+	}
+	scope, err := repository.GetPeriodsInScope(ctx, qs.db, nil)
+	if err != nil {
+		return err
+	}
+	if err := checkPeriodsFit(withoutPeriod(scope, form.ID), []domain.Quarter{*form}); err != nil {
+		return err
+	}
 
 	updatedAt := time.Now()
 
@@ -55,14 +85,51 @@ func (qs *QuarterService) UpdateQuarter(ctx context.Context, form *domain.Quarte
 }
 
 func (qs *QuarterService) DeleteQuarter(ctx context.Context, id uuid.UUID) error {
-	_, err := repository.GetQuarterByID(ctx, qs.db, id)
+	existing, err := repository.GetQuarterByID(ctx, qs.db, id)
 	if err != nil {
 		return errors.New("quarter not found")
 	}
+	if existing.ClinicID != nil {
+		return errors.New("quarter belongs to a clinic and cannot be deleted here")
+	}
+	return qs.deletePeriod(ctx, existing)
+}
+
+// deletePeriod soft-deletes a period unless a clinic has closed it or it sits in the middle of its
+// chain, where removing it would leave a gap.
+func (qs *QuarterService) deletePeriod(ctx context.Context, period domain.Quarter) error {
+	if err := qs.ensureNotClosed(ctx, period.ID); err != nil {
+		return err
+	}
 
-	return repository.DeleteQuarter(ctx, qs.db, id)
+	scope, err := repository.GetPeriodsInScope(ctx, qs.db, period.ClinicID)
+	if err != nil {
+		return err
+	}
+	var before, after bool
+	for _, p := range scope {
+		before = before || p.EndDate.Before(period.StartDate)
+		after = after || p.StartDate.After(period.EndDate)
+	}
+	if before && after {
+		return errors.New("only the first or last period can be deleted; periods must stay contiguous")
+	}
+
+	return repository.DeleteQuarter(ctx, qs.db, period.ID)
 }
 
 func (qs *QuarterService) ListQuarter(ctx context.Context) ([]domain.Quarter, error) {
 	return repository.ListQuarter(ctx, qs.db)
 }
+
+// ensureNotClosed stops a quarter's dates from moving under a clinic that has closed it.
+func (qs *QuarterService) ensureNotClosed(ctx context.Context, id uuid.UUID) error {
+	closed, err := repository.QuarterHasActiveCloses(ctx, qs.db, id)
+	if err != nil {
+		return err
+	}
+	if closed {
+		return errors.New("quarter is closed for at least one clinic and cannot be changed")
+	}
+	return nil
+}
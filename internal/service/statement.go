@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/iamarpitzala/aca-reca-backend/internal/money"
+	"github.com/iamarpitzala/aca-reca-backend/internal/repository"
+	"github.com/iamarpitzala/aca-reca-backend/pkg"
+	"github.com/jmoiron/sqlx"
+)
+
+// MaxLogoBytes caps the size of a logo uploaded to a template or imported from a clinic's logo URL.
+const MaxLogoBytes = 2 << 20
+
+// Custom fields with these keys fill the statement header instead of being listed under it.
+const (
+	statementFieldSupplierCode = "supplierCode"
+	statementFieldGLCode       = "glCode"
+	statementFieldReference    = "reference"
+)
+
+type StatementService struct {
+	db        *sqlx.DB
+	templates *PDFTemplateService
+	logos     *ClinicLogoService
+}
+
+func NewStatementService(db *sqlx.DB, templates *PDFTemplateService, logos *ClinicLogoService) *StatementService {
+	return &StatementService{
+		db:        db,
+		templates: templates,
+		logos:     logos,
+	}
+}
+
+// GenerateStatement renders a practitioner's service-fee statement for the range as PDF bytes. It covers
+// the income entries the practitioner recorded for the clinic, using the amounts the calculation engine
+// stored on each entry. Layout comes from config when given, else from the named or default clinic
+// template, else the built-in defaults.
+func (s *StatementService) GenerateStatement(ctx context.Context, clinicID, practitionerID uuid.UUID, rng *ExportRange, notes string, templateID *uuid.UUID, config *domain.PDFConfig) ([]byte, error) {
+	clinic, err := repository.GetClinicByID(ctx, s.db, clinicID)
+	if err != nil {
+		return nil, err
+	}
+	if clinic.ID == uuid.Nil {
+		return nil, ErrClinicNotFound
+	}
+	member, err := repository.GetUserClinicByUserAndClinic(ctx, s.db, practitionerID, clinicID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, errors.New("practitioner is not a member of this clinic")
+	}
+	practitioner, err := repository.GetUserByID(ctx, s.db, practitionerID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := repository.GetCustomFormEntriesByDateRange(ctx, s.db, clinicID, rng.From, rng.To)
+	if err != nil {
+		return nil, err
+	}
+
+	tmplConfig, logo, logoType, err := s.templates.Resolve(ctx, clinicID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if config != nil {
+		if err := ValidatePDFConfig(config); err != nil {
+			return nil, err
+		}
+	} else if tmplConfig != nil {
+		config = tmplConfig
+	} else {
+		config = pkg.GetDefaultPDFConfig()
+	}
+	cfg := *config
+	providerName := strings.TrimSpace(practitioner.FirstName + " " + practitioner.LastName)
+	st := domain.Statement{
+		CompanyName:  clinic.Name,
+		Date:         rng.To.Format("01/2006"),
+		Reference:    rng.To.Format("200601") + strings.ToUpper(practitionerID.String()[:4]),
+		ProviderName: providerName,
+		Notes:        notes,
+	}
+	cfg.CustomFields = nil
+	for _, f := range config.CustomFields {
+		switch f.Key {
+		case statementFieldSupplierCode:
+			st.SupplierCode = f.Value
+		case statementFieldGLCode:
+			st.GLCode = f.Value
+		case statementFieldReference:
+			st.Reference = f.Value
+		default:
+			cfg.CustomFields = append(cfg.CustomFields, f)
+		}
+	}
+
+	var t statementTotals
+	for i := range entries {
+		e := &entries[i]
+		if e.CreatedBy != practitionerID || e.FormType == string(domain.FormTypeExpense) {
+			continue
+		}
+		var calc entryExportSummary
+		if len(e.Calculations) == 0 || json.Unmarshal(e.Calculations, &calc) != nil {
+			continue
+		}
+		collection := calc.BasMapping.TotalSalesG1
+		var lab money.Amount
+		if e.FormType == string(domain.FormTypeBoth) {
+			lab = collection - calc.TotalAmount
+		}
+		t.add(calc, collection, lab)
+		st.Rows = append(st.Rows, domain.Row{
+			Provider:    providerName,
+			Code:        e.FormName,
+			GST:         formatMoney(calc.BasMapping.GstOnSales1A),
+			Collection:  formatMoney(collection),
+			ExternalLab: formatMoney(-lab),
+			Implant:     formatMoney(0),
+			InternalLab: formatMoney(0),
+			Net:         formatMoney(collection - lab),
+		})
+	}
+	t.fill(&st)
+
+	// A logo uploaded to the template wins; otherwise the copy of the clinic's logo URL imported into
+	// storage is drawn
+	if cfg.ShowLogo {
+		if logo == nil {
+			if logo, logoType, err = s.logos.Get(ctx, clinic); err != nil {
+				return nil, err
+			}
+		}
+		st.Logo, st.LogoType = logo, logoType
+	}
+	return pkg.RenderStatement(st, &cfg)
+}
+
+// statementTotals accumulates the engine's per-entry amounts across a statement.
+type statementTotals struct {
+	collected, lab, gstOnCollection   money.Amount
+	netFee, serviceFee, gstOnService  money.Amount
+	reductions, reimbursements, remit money.Amount
+}
+
+func (t *statementTotals) add(calc entryExportSummary, collection, lab money.Amount) {
+	t.collected += collection
+	t.lab += lab
+	t.gstOnCollection += calc.BasMapping.GstOnSales1A
+	t.netFee += valueOrZero(calc.NetFee)
+	t.serviceFee += valueOrZero(calc.ServiceFeeBase)
+	t.gstOnService += valueOrZero(calc.GstOnServiceFee)
+	t.reductions += valueOrZero(calc.TotalReductions)
+	t.reimbursements += valueOrZero(calc.TotalReimbursements)
+	t.remit += valueOrZero(calc.RemittedAmount)
+}
+
+func (t *statementTotals) fill(st *domain.Statement) {
+	feePct := 0.0
+	if t.netFee != 0 {
+		feePct = float64(t.serviceFee) / float64(t.netFee) * 100
+	}
+	remaining := t.netFee - t.serviceFee
+	st.CollectedFees = formatMoney(t.collected)
+	st.DirectCostExternalLab = formatMoney(-t.lab)
+	st.NetCollectedFees = formatMoney(t.collected - t.lab)
+	st.RemainingAmountPercent = formatPercent(100 - feePct)
+	st.RAPercentNetCollected = formatMoney(remaining)
+	st.GSTOnCollection = formatMoney(t.gstOnCollection)
+	st.DirectCostItemsGST = formatMoney(-t.reductions)
+	st.RemainingAmount = formatMoney(remaining + t.reimbursements - t.reductions)
+	st.GSTOnServiceFee = formatMoney(-t.gstOnService)
+	st.RemittedAmount = formatMoney(t.remit)
+	st.TotalPayable = st.RemittedAmount
+	st.ServiceFee = formatMoney(t.serviceFee)
+	st.TotalChargeExclGST = formatMoney(t.serviceFee)
+	st.GST = formatMoney(t.gstOnService)
+	st.TotalChargeInclGST = formatMoney(t.serviceFee + t.gstOnService)
+	st.ServiceFeePercent = formatPercent(feePct)
+	st.DentalDrawPercent = formatPercent(100 - feePct)
+}
+
+// formatMoney renders an amount the way statements show it, e.g. $16,129.78 or -$665.00.
+func formatMoney(n money.Amount) string {
+	cents := n.Cents()
+	if cents < 0 {
+		cents = -cents
+	}
+	whole := fmt.Sprintf("%d", cents/100)
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	sign := ""
+	if n < 0 && cents != 0 {
+		sign = "-"
+	}
+	return fmt.Sprintf("%s$%s.%02d", sign, whole, cents%100)
+}
+
+func formatPercent(n float64) string {
+	return fmt.Sprintf("%.2f%%", n)
+}
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -49,7 +52,8 @@ func (ts *TokenService) GenerateTokenPair(userID uuid.UUID, email string, sessio
 		return nil, err
 	}
 
-	// Refresh token (longer TTL, stored in database)
+	// Refresh token (longer TTL, its hash is stored in database), signed with its own key so it is
+	// never accepted as an access token
 	refreshClaims := &domain.TokenClaims{
 		UserID:    userID,
 		Email:     email,
@@ -60,11 +64,12 @@ func (ts *TokenService) GenerateTokenPair(userID uuid.UUID, email string, sessio
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    ts.issuer,
 			Subject:   userID.String(),
+			ID:        uuid.NewString(),
 		},
 	}
 
 	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString(ts.secretKey)
+	refreshTokenString, err := refreshToken.SignedString(ts.refreshKey())
 	if err != nil {
 		return nil, err
 	}
@@ -77,12 +82,27 @@ func (ts *TokenService) GenerateTokenPair(userID uuid.UUID, email string, sessio
 	}, nil
 }
 
+// ValidateToken validates an access token. Refresh tokens are signed with another key and fail here.
 func (ts *TokenService) ValidateToken(tokenString string) (*domain.TokenClaims, error) {
+	return ts.validateSessionToken(tokenString, ts.secretKey)
+}
+
+// ValidateRefreshToken validates a refresh token; access tokens fail here.
+func (ts *TokenService) ValidateRefreshToken(tokenString string) (*domain.TokenClaims, error) {
+	return ts.validateSessionToken(tokenString, ts.refreshKey())
+}
+
+// AccessTokenTTL is how long an access token stays valid after it is issued.
+func (ts *TokenService) AccessTokenTTL() time.Duration {
+	return ts.accessTokenTTL
+}
+
+func (ts *TokenService) validateSessionToken(tokenString string, key []byte) (*domain.TokenClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &domain.TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
-		return ts.secretKey, nil
+		return key, nil
 	})
 
 	if err != nil {
@@ -114,3 +134,138 @@ func (ts *TokenService) ExtractTokenFromHeader(authHeader string) (string, error
 
 	return authHeader[len(bearerPrefix):], nil
 }
+
+// deriveKey derives a separate key for one purpose so tokens signed for it can never be replayed
+// as access tokens (and vice versa).
+func (ts *TokenService) deriveKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, ts.secretKey)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+func (ts *TokenService) refreshKey() []byte {
+	return ts.deriveKey("refresh-token")
+}
+
+func (ts *TokenService) invitationKey() []byte {
+	return ts.deriveKey("clinic-invitation")
+}
+
+func (ts *TokenService) GenerateInvitationToken(invitationID uuid.UUID, expiresAt time.Time) (string, error) {
+	now := time.Now()
+	claims := &domain.InvitationClaims{
+		InvitationID: invitationID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    ts.issuer,
+			Subject:   invitationID.String(),
+			ID:        uuid.NewString(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ts.invitationKey())
+}
+
+func (ts *TokenService) ValidateInvitationToken(tokenString string) (*domain.InvitationClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &domain.InvitationClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return ts.invitationKey(), nil
+	})
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, errors.New("invitation has expired")
+		}
+		return nil, errors.New("invalid invitation token")
+	}
+	claims, ok := token.Claims.(*domain.InvitationClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid invitation token")
+	}
+	return claims, nil
+}
+
+// GenerateMFAChallengeToken signs the short-lived token a user exchanges, with a second factor, for
+// a token pair.
+func (ts *TokenService) GenerateMFAChallengeToken(userID uuid.UUID, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := &domain.MFAChallengeClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    ts.issuer,
+			Subject:   userID.String(),
+			ID:        uuid.NewString(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ts.deriveKey("mfa-challenge"))
+}
+
+func (ts *TokenService) ValidateMFAChallengeToken(tokenString string) (*domain.MFAChallengeClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &domain.MFAChallengeClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return ts.deriveKey("mfa-challenge"), nil
+	})
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, errors.New("two-factor challenge has expired; sign in again")
+		}
+		return nil, errors.New("invalid two-factor challenge")
+	}
+	claims, ok := token.Claims.(*domain.MFAChallengeClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid two-factor challenge")
+	}
+	return claims, nil
+}
+
+// GenerateOAuthLinkToken signs the short-lived token that starts linking provider to a signed-in
+// user's account.
+func (ts *TokenService) GenerateOAuthLinkToken(userID uuid.UUID, provider string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := &domain.OAuthLinkClaims{
+		UserID:   userID,
+		Provider: provider,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    ts.issuer,
+			Subject:   userID.String(),
+			ID:        uuid.NewString(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ts.deriveKey("oauth-link"))
+}
+
+func (ts *TokenService) ValidateOAuthLinkToken(tokenString string) (*domain.OAuthLinkClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &domain.OAuthLinkClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return ts.deriveKey("oauth-link"), nil
+	})
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, errors.New("link request has expired; start again from your profile")
+		}
+		return nil, errors.New("invalid link request")
+	}
+	claims, ok := token.Claims.(*domain.OAuthLinkClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid link request")
+	}
+	return claims, nil
+}
+
+// hashToken returns the hex SHA-256 digest stored in place of a bearer token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,20 @@
+package service
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// runInTx executes fn inside a transaction, committing on success and rolling back on error.
+func runInTx(ctx context.Context, db *sqlx.DB, fn func(tx *sqlx.Tx) error) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
@@ -39,8 +40,12 @@ func (ucs *UserClinicService) AssociateUserWithClinic(ctx context.Context, userI
 		return nil, errors.New("user not found")
 	}
 
+	role = strings.ToLower(strings.TrimSpace(role))
 	if role == "" {
-		role = "owner"
+		role = domain.RoleViewer
+	}
+	if !domain.IsValidRole(role) {
+		return nil, errors.New("invalid role. Must be one of: owner, accountant, practitioner, viewer")
 	}
 
 	userClinic := &domain.UserClinic{
@@ -2,9 +2,11 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
 	"strings"
 
+	constants "github.com/iamarpitzala/aca-reca-backend/constant"
 	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
 )
 
@@ -19,6 +21,78 @@ func ValidateState(state string) error {
 	return errors.New("invalid state. Must be one of: NSW, VIC, QLD, SA, WA, TAS, NT, ACT")
 }
 
+// ValidateBASCycle validates a clinic's BAS reporting cycle
+func ValidateBASCycle(cycle string) error {
+	if cycle != domain.BASCycleMonthly && cycle != domain.BASCycleQuarterly {
+		return errors.New("invalid BAS cycle. Must be one of: monthly, quarterly")
+	}
+	return nil
+}
+
+// ValidatePDFConfig checks a PDF template: RGB triplets, a built-in font, sensible font sizes, known
+// section names and well-formed custom fields.
+func ValidatePDFConfig(cfg *domain.PDFConfig) error {
+	for name, c := range map[string][]int{"primaryColor": cfg.PrimaryColor, "secondaryColor": cfg.SecondaryColor, "accentColor": cfg.AccentColor} {
+		if c == nil {
+			continue
+		}
+		if len(c) != 3 {
+			return fmt.Errorf("%s must be an RGB triplet", name)
+		}
+		for _, v := range c {
+			if v < 0 || v > 255 {
+				return fmt.Errorf("%s values must be between 0 and 255", name)
+			}
+		}
+	}
+	if cfg.FontFamily != "" && !containsString(domain.PDFFontFamilies, cfg.FontFamily) {
+		return errors.New("invalid font family. Must be one of: " + strings.Join(domain.PDFFontFamilies, ", "))
+	}
+	for name, size := range map[string]float64{"headerFontSize": cfg.HeaderFontSize, "bodyFontSize": cfg.BodyFontSize, "tableFontSize": cfg.TableFontSize} {
+		if size != 0 && (size < 6 || size > 48) {
+			return fmt.Errorf("%s must be between 6 and 48", name)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, section := range cfg.ShowSections {
+		if !containsString(domain.StatementSections, section) {
+			return fmt.Errorf("unknown section %q. Must be one of: %s", section, strings.Join(domain.StatementSections, ", "))
+		}
+		if seen[section] {
+			return fmt.Errorf("section %q is listed twice", section)
+		}
+		seen[section] = true
+	}
+	if len(cfg.CustomFields) > 20 {
+		return errors.New("a template can have at most 20 custom fields")
+	}
+	keys := make(map[string]bool)
+	for _, f := range cfg.CustomFields {
+		if strings.TrimSpace(f.Key) == "" || strings.TrimSpace(f.Label) == "" {
+			return errors.New("custom fields need a key and a label")
+		}
+		if keys[f.Key] {
+			return fmt.Errorf("custom field key %q is used twice", f.Key)
+		}
+		keys[f.Key] = true
+		switch f.Type {
+		case "", "text", "number", "currency":
+		default:
+			return fmt.Errorf("custom field %q has invalid type. Must be one of: text, number, currency", f.Key)
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
 // ValidateABN validates ABN format (11 digits)
 func ValidateABN(abn string) error {
 	// Remove spaces and hyphens
@@ -54,8 +128,7 @@ func ValidateCalculationInput(config map[string]interface{}, input domain.Calcul
 }
 
 func validateNetMethodInput(config map[string]interface{}, input domain.CalculationInput) error {
-	// Check if lab fee is enabled
-	if labFeeEnabled, ok := config["labFeeEnabled"].(bool); ok && labFeeEnabled {
+	if labFees, ok := config["labFees"].(bool); ok && labFees {
 		if input.LabFee < 0 {
 			return errors.New("lab fee cannot be negative")
 		}
@@ -65,13 +138,9 @@ func validateNetMethodInput(config map[string]interface{}, input domain.Calculat
 }
 
 func validateGrossMethodInput(config map[string]interface{}, input domain.CalculationInput) error {
-	// B1: Standard - check lab fee if enabled
-	if labFeeEnabled, ok := config["labFeeEnabled"].(bool); ok && labFeeEnabled {
-		if labFeePaidBy, ok := config["labFeePaidBy"].(string); ok && labFeePaidBy == "clinic" {
-			if input.LabFee < 0 {
-				return errors.New("lab fee cannot be negative")
-			}
-		}
+	// B1: Standard
+	if input.LabFee < 0 {
+		return errors.New("lab fee cannot be negative")
 	}
 
 	// B2: With GST on Lab Fee
@@ -82,26 +151,25 @@ func validateGrossMethodInput(config map[string]interface{}, input domain.Calcul
 	}
 
 	// B3: With Merchant Fee
-	if merchantFeeEnabled, ok := config["merchantFeeEnabled"].(bool); ok && merchantFeeEnabled {
-		if input.MerchantFeeInclGST < 0 {
-			return errors.New("merchant fee cannot be negative")
-		}
-		if input.BankFee < 0 {
-			return errors.New("bank fee cannot be negative")
-		}
+	if input.MerchantFeeInclGST < 0 {
+		return errors.New("merchant fee cannot be negative")
+	}
+	if input.BankFee < 0 {
+		return errors.New("bank fee cannot be negative")
 	}
 
 	// B4: GST on Patient Fee + Lab Fee Paid by Dentist
-	if gstOnPatientFee, ok := config["gstOnPatientFee"].(bool); ok && gstOnPatientFee {
-		if labFeePaidBy, ok := config["labFeePaidBy"].(string); ok && labFeePaidBy == "dentist" {
-			if input.LabFeePaidByDentist < 0 {
-				return errors.New("lab fee paid by dentist cannot be negative")
-			}
+	if paidBy, ok := config["paidBy"].(string); ok && paidBy == constants.PAID_BY_OWNER {
+		if input.GSTOnPatientFee < 0 {
+			return errors.New("GST on patient fee cannot be negative")
+		}
+		if input.LabFeePaidByDentist < 0 {
+			return errors.New("lab fee paid by dentist cannot be negative")
 		}
 	}
 
 	// B5: Outwork Charge Rate
-	if outworkChargeRateEnabled, ok := config["outworkChargeRateEnabled"].(bool); ok && outworkChargeRateEnabled {
+	if outworkRateEnabled, ok := config["outworkRateEnabled"].(bool); ok && outworkRateEnabled {
 		if input.OutworkLabFee < 0 {
 			return errors.New("outwork lab fee cannot be negative")
 		}
@@ -0,0 +1,76 @@
+package useragent
+
+import "strings"
+
+// Label summarises a User-Agent header as "<browser> on <platform>", e.g. "Chrome on Windows" or
+// "Safari on iPhone", for showing a user where they are signed in. Parts that cannot be recognised
+// are left out; an empty or unrecognised header gives "Unknown device".
+func Label(ua string) string {
+	browser, platform := Browser(ua), Platform(ua)
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	}
+	return "Unknown device"
+}
+
+// browsers are matched in order: most browsers also claim to be the ones they are built on, so
+// Edge and Opera must be tested before Chrome, and Chrome before Safari.
+var browsers = []struct {
+	token string
+	name  string
+}{
+	{"Edg/", "Edge"},
+	{"Edge/", "Edge"},
+	{"EdgiOS/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"PostmanRuntime/", "Postman"},
+	{"curl/", "curl"},
+	{"okhttp/", "Android app"},
+	{"Go-http-client/", "Go client"},
+}
+
+// Browser returns the browser or client named by ua, or "" when it is not recognised.
+func Browser(ua string) string {
+	for _, b := range browsers {
+		if strings.Contains(ua, b.token) {
+			return b.name
+		}
+	}
+	return ""
+}
+
+// platforms are matched in order: iOS agents mention Mac OS X and Android agents mention Linux.
+var platforms = []struct {
+	token string
+	name  string
+}{
+	{"iPhone", "iPhone"},
+	{"iPad", "iPad"},
+	{"Android", "Android"},
+	{"Windows", "Windows"},
+	{"CrOS", "ChromeOS"},
+	{"Macintosh", "macOS"},
+	{"Mac OS X", "macOS"},
+	{"Linux", "Linux"},
+}
+
+// Platform returns the operating system or device named by ua, or "" when it is not recognised.
+func Platform(ua string) string {
+	for _, p := range platforms {
+		if strings.Contains(ua, p.token) {
+			return p.name
+		}
+	}
+	return ""
+}
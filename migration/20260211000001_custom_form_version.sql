@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Immutable snapshots of a custom form's definition, one row per published version
+CREATE TABLE IF NOT EXISTS tbl_custom_form_version (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    form_id UUID NOT NULL REFERENCES tbl_custom_form(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    calculation_method VARCHAR(50) NOT NULL,
+    form_type VARCHAR(50) NOT NULL,
+    fields JSONB NOT NULL DEFAULT '[]',
+    default_payment_responsibility VARCHAR(50),
+    service_facility_fee_percent NUMERIC(5,2),
+    outwork_enabled BOOLEAN NOT NULL DEFAULT false,
+    outwork_rate_percent NUMERIC(5,2),
+    created_by UUID NOT NULL REFERENCES tbl_user(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_custom_form_version UNIQUE (form_id, version)
+);
+
+CREATE INDEX idx_custom_form_version_form_id ON tbl_custom_form_version(form_id);
+
+-- Snapshot the current definition of every form that has already been published
+INSERT INTO tbl_custom_form_version (
+    form_id, version, name, calculation_method, form_type, fields,
+    default_payment_responsibility, service_facility_fee_percent, outwork_enabled, outwork_rate_percent,
+    created_by, created_at
+)
+SELECT id, version, name, calculation_method, form_type, fields,
+    default_payment_responsibility, service_facility_fee_percent, outwork_enabled, outwork_rate_percent,
+    created_by, COALESCE(published_at, updated_at)
+FROM tbl_custom_form
+WHERE published_at IS NOT NULL
+ON CONFLICT (form_id, version) DO NOTHING;
+
+-- Entries are pinned to the form version they were captured on
+ALTER TABLE tbl_custom_form_entry
+    ADD COLUMN IF NOT EXISTS form_version INTEGER;
+
+UPDATE tbl_custom_form_entry e
+SET form_version = f.version
+FROM tbl_custom_form f
+WHERE e.form_id = f.id AND e.form_version IS NULL;
+
+ALTER TABLE tbl_custom_form_entry
+    ALTER COLUMN form_version SET DEFAULT 1,
+    ALTER COLUMN form_version SET NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tbl_custom_form_entry
+    DROP COLUMN IF EXISTS form_version;
+DROP TABLE IF EXISTS tbl_custom_form_version;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Capital purchases are reported at BAS label G10 instead of G11
+ALTER TABLE tbl_expense_category
+    ADD COLUMN IF NOT EXISTS is_capital BOOLEAN NOT NULL DEFAULT false;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tbl_expense_category
+    DROP COLUMN IF EXISTS is_capital;
+-- +goose StatementEnd
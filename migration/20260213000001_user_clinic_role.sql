@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+UPDATE tbl_user_clinic SET role = LOWER(TRIM(role)) WHERE role IS NOT NULL;
+UPDATE tbl_user_clinic SET role = 'owner'
+WHERE role IS NULL OR role NOT IN ('owner', 'accountant', 'practitioner', 'viewer');
+
+ALTER TABLE tbl_user_clinic ALTER COLUMN role SET DEFAULT 'viewer';
+ALTER TABLE tbl_user_clinic ALTER COLUMN role SET NOT NULL;
+ALTER TABLE tbl_user_clinic ADD CONSTRAINT chk_user_clinic_role
+    CHECK (role IN ('owner', 'accountant', 'practitioner', 'viewer'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tbl_user_clinic DROP CONSTRAINT IF EXISTS chk_user_clinic_role;
+ALTER TABLE tbl_user_clinic ALTER COLUMN role DROP NOT NULL;
+ALTER TABLE tbl_user_clinic ALTER COLUMN role SET DEFAULT 'owner';
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS tbl_clinic_invitation (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    clinic_id UUID NOT NULL REFERENCES tbl_clinic(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(50) NOT NULL CHECK (role IN ('owner', 'accountant', 'practitioner', 'viewer')),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined', 'revoked')),
+    token_hash VARCHAR(64) NOT NULL,
+    invited_by UUID NOT NULL REFERENCES tbl_user(id),
+    accepted_by UUID NULL REFERENCES tbl_user(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    responded_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_clinic_invitation_pending ON tbl_clinic_invitation(clinic_id, LOWER(email)) WHERE status = 'pending';
+CREATE INDEX idx_clinic_invitation_clinic_id ON tbl_clinic_invitation(clinic_id);
+CREATE INDEX idx_clinic_invitation_email ON tbl_clinic_invitation(LOWER(email));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS tbl_clinic_invitation;
+-- +goose StatementEnd
@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS tbl_audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    clinic_id UUID NULL REFERENCES tbl_clinic(id),
+    actor_id UUID NOT NULL REFERENCES tbl_user(id),
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id UUID NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('create', 'update', 'delete', 'publish', 'archive')),
+    before JSONB NULL,
+    after JSONB NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_log_clinic_id ON tbl_audit_log(clinic_id, created_at DESC);
+CREATE INDEX idx_audit_log_entity ON tbl_audit_log(entity_type, entity_id, created_at DESC);
+CREATE INDEX idx_audit_log_actor_id ON tbl_audit_log(actor_id, created_at DESC);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Audit rows are append-only: reject any attempt to rewrite or remove history
+CREATE OR REPLACE FUNCTION fn_audit_log_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'tbl_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER trg_audit_log_append_only
+    BEFORE UPDATE OR DELETE ON tbl_audit_log
+    FOR EACH ROW EXECUTE FUNCTION fn_audit_log_append_only();
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER trg_audit_log_no_truncate
+    BEFORE TRUNCATE ON tbl_audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION fn_audit_log_append_only();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS tbl_audit_log;
+DROP FUNCTION IF EXISTS fn_audit_log_append_only();
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+-- One row per close of a quarter for a clinic. Reopening stamps the row instead of deleting it,
+-- so the table doubles as the close/reopen history.
+CREATE TABLE IF NOT EXISTS tbl_clinic_quarter_close (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    clinic_id UUID NOT NULL REFERENCES tbl_clinic(id) ON DELETE CASCADE,
+    quarter_id UUID NOT NULL REFERENCES tbl_quarter(id),
+    closed_by UUID NOT NULL REFERENCES tbl_user(id),
+    closed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reopened_by UUID NULL REFERENCES tbl_user(id),
+    reopened_at TIMESTAMP NULL,
+    reopen_reason TEXT NULL,
+    CONSTRAINT chk_quarter_close_reopen CHECK (
+        (reopened_at IS NULL AND reopened_by IS NULL AND reopen_reason IS NULL)
+        OR (reopened_at IS NOT NULL AND reopened_by IS NOT NULL AND LENGTH(TRIM(reopen_reason)) > 0)
+    )
+);
+
+CREATE UNIQUE INDEX ux_clinic_quarter_close_active ON tbl_clinic_quarter_close(clinic_id, quarter_id) WHERE reopened_at IS NULL;
+CREATE INDEX idx_clinic_quarter_close_clinic_id ON tbl_clinic_quarter_close(clinic_id, closed_at DESC);
+
+-- Adjustments to a closed period are recorded as new entries in an open period that point at the original
+ALTER TABLE tbl_custom_form_entry ADD COLUMN corrects_entry_id UUID NULL REFERENCES tbl_custom_form_entry(id);
+ALTER TABLE tbl_expense_entry ADD COLUMN corrects_entry_id UUID NULL REFERENCES tbl_expense_entry(id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tbl_expense_entry DROP COLUMN IF EXISTS corrects_entry_id;
+ALTER TABLE tbl_custom_form_entry DROP COLUMN IF EXISTS corrects_entry_id;
+DROP TABLE IF EXISTS tbl_clinic_quarter_close;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE tbl_clinic ADD COLUMN bas_cycle VARCHAR(10) NOT NULL DEFAULT 'quarterly';
+ALTER TABLE tbl_clinic ADD CONSTRAINT chk_clinic_bas_cycle CHECK (bas_cycle IN ('monthly', 'quarterly'));
+
+-- Periods are either shared (clinic_id NULL, the original seeded quarters) or generated for one clinic
+ALTER TABLE tbl_quarter ADD COLUMN clinic_id UUID NULL REFERENCES tbl_clinic(id) ON DELETE CASCADE;
+ALTER TABLE tbl_quarter ADD COLUMN period_type VARCHAR(10) NOT NULL DEFAULT 'quarter';
+ALTER TABLE tbl_quarter ADD CONSTRAINT chk_quarter_period_type CHECK (period_type IN ('month', 'quarter'));
+-- Australian financial year, named by the year it ends (FY2025 = 1 Jul 2024 to 30 Jun 2025)
+ALTER TABLE tbl_quarter ADD COLUMN financial_year INT NULL;
+
+UPDATE tbl_quarter
+SET financial_year = EXTRACT(YEAR FROM start_date)::INT + CASE WHEN EXTRACT(MONTH FROM start_date) >= 7 THEN 1 ELSE 0 END;
+
+CREATE UNIQUE INDEX ux_quarter_scope_start ON tbl_quarter(COALESCE(clinic_id, '00000000-0000-0000-0000-000000000000'::uuid), start_date)
+WHERE deleted_at IS NULL;
+CREATE INDEX idx_quarter_clinic_id ON tbl_quarter(clinic_id, start_date);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_quarter_clinic_id;
+DROP INDEX IF EXISTS ux_quarter_scope_start;
+DELETE FROM tbl_quarter WHERE clinic_id IS NOT NULL;
+ALTER TABLE tbl_quarter DROP COLUMN IF EXISTS financial_year;
+ALTER TABLE tbl_quarter DROP CONSTRAINT IF EXISTS chk_quarter_period_type;
+ALTER TABLE tbl_quarter DROP COLUMN IF EXISTS period_type;
+ALTER TABLE tbl_quarter DROP COLUMN IF EXISTS clinic_id;
+ALTER TABLE tbl_clinic DROP CONSTRAINT IF EXISTS chk_clinic_bas_cycle;
+ALTER TABLE tbl_clinic DROP COLUMN IF EXISTS bas_cycle;
+-- +goose StatementEnd
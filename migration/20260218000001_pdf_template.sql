@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS tbl_pdf_template (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    clinic_id UUID NOT NULL REFERENCES tbl_clinic(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    config JSONB NOT NULL DEFAULT '{}',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    logo_key VARCHAR(255) NULL,
+    logo_content_type VARCHAR(50) NULL,
+    created_by UUID NOT NULL REFERENCES tbl_user(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL
+);
+
+-- At most one default template per clinic, and template names are unique within a clinic
+CREATE UNIQUE INDEX ux_pdf_template_default ON tbl_pdf_template(clinic_id) WHERE is_default AND deleted_at IS NULL;
+CREATE UNIQUE INDEX ux_pdf_template_name ON tbl_pdf_template(clinic_id, LOWER(name)) WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS tbl_pdf_template;
+-- +goose StatementEnd
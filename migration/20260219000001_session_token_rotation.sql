@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Refresh tokens are kept only as SHA-256 hashes. Each refresh creates a new session row in the
+-- same family and marks the old one rotated, so a rotated token presented again is detectable.
+ALTER TABLE tbl_session
+    ADD COLUMN refresh_token_hash VARCHAR(64),
+    ADD COLUMN family_id UUID,
+    ADD COLUMN rotated_at TIMESTAMP NULL;
+
+UPDATE tbl_session
+SET refresh_token_hash = encode(sha256(convert_to(refresh_token, 'UTF8')), 'hex'),
+    family_id = id;
+
+ALTER TABLE tbl_session
+    ALTER COLUMN refresh_token_hash SET NOT NULL,
+    ALTER COLUMN family_id SET NOT NULL;
+
+DROP INDEX IF EXISTS ux_session_refresh_token_active;
+ALTER TABLE tbl_session DROP COLUMN refresh_token;
+
+-- Rotated and revoked rows keep their hash so replays can still be matched
+CREATE UNIQUE INDEX ux_session_refresh_token_hash ON tbl_session (refresh_token_hash);
+CREATE INDEX idx_session_family ON tbl_session (family_id);
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- Plaintext tokens cannot be recovered, so existing sessions are revoked on the way down
+
+DROP INDEX IF EXISTS idx_session_family;
+DROP INDEX IF EXISTS ux_session_refresh_token_hash;
+
+ALTER TABLE tbl_session ADD COLUMN refresh_token TEXT;
+UPDATE tbl_session SET refresh_token = refresh_token_hash, deleted_at = COALESCE(deleted_at, CURRENT_TIMESTAMP);
+ALTER TABLE tbl_session ALTER COLUMN refresh_token SET NOT NULL;
+
+CREATE UNIQUE INDEX ux_session_refresh_token_active
+ON tbl_session (refresh_token)
+WHERE deleted_at IS NULL;
+
+ALTER TABLE tbl_session
+    DROP COLUMN rotated_at,
+    DROP COLUMN family_id,
+    DROP COLUMN refresh_token_hash;
+
+-- +goose StatementEnd
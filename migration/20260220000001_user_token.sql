@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Single-use tokens emailed to users (password reset, email verification). Only the SHA-256 hash
+-- of each token is stored.
+CREATE TABLE IF NOT EXISTS tbl_user_token (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES tbl_user(id) ON DELETE CASCADE,
+    purpose VARCHAR(30) NOT NULL CHECK (purpose IN ('password_reset', 'email_verification')),
+    token_hash VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX ux_user_token_hash ON tbl_user_token (token_hash);
+CREATE INDEX idx_user_token_user_purpose ON tbl_user_token (user_id, purpose) WHERE used_at IS NULL;
+
+UPDATE tbl_user SET is_email_verified = FALSE WHERE is_email_verified IS NULL;
+ALTER TABLE tbl_user ALTER COLUMN is_email_verified SET NOT NULL;
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tbl_user ALTER COLUMN is_email_verified DROP NOT NULL;
+DROP TABLE IF EXISTS tbl_user_token;
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- TOTP enrolment per user. The secret is encrypted by the application; enabled_at stays NULL until
+-- the user confirms a first code. last_used_step stops a code being accepted twice.
+CREATE TABLE IF NOT EXISTS tbl_user_mfa (
+    user_id UUID PRIMARY KEY REFERENCES tbl_user(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    enabled_at TIMESTAMP NULL,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Single-use recovery codes, stored as SHA-256 hashes
+CREATE TABLE IF NOT EXISTS tbl_mfa_recovery_code (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES tbl_user(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX ux_mfa_recovery_code ON tbl_mfa_recovery_code (user_id, code_hash);
+
+-- Owners can require every member to have two-factor authentication before opening the clinic
+ALTER TABLE tbl_clinic ADD COLUMN require_mfa BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tbl_clinic DROP COLUMN IF EXISTS require_mfa;
+DROP TABLE IF EXISTS tbl_mfa_recovery_code;
+DROP TABLE IF EXISTS tbl_user_mfa;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- History of login lockouts. Live failure counters are kept by the rate limiter (memory or Redis);
+-- a row is written each time an account or IP address is locked, and marked when an admin clears it.
+CREATE TABLE IF NOT EXISTS tbl_login_lockout (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    scope VARCHAR(10) NOT NULL CHECK (scope IN ('account', 'ip')),
+    subject VARCHAR(255) NOT NULL,
+    user_id UUID NULL REFERENCES tbl_user(id) ON DELETE CASCADE,
+    ip_address VARCHAR(45) NOT NULL,
+    failures INT NOT NULL,
+    locked_until TIMESTAMP NOT NULL,
+    cleared_at TIMESTAMP NULL,
+    cleared_by UUID NULL REFERENCES tbl_user(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_login_lockout_user ON tbl_login_lockout (user_id, created_at DESC);
+CREATE INDEX idx_login_lockout_subject ON tbl_login_lockout (scope, subject, created_at DESC);
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS tbl_login_lockout;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- In-flight OAuth authorisations, keyed by the SHA-256 of the state parameter. Each row carries the
+-- PKCE verifier and OpenID nonce for the callback, and user_id when a signed-in user is linking an
+-- identity rather than signing in. Rows are deleted when the callback redeems them.
+CREATE TABLE IF NOT EXISTS tbl_oauth_state (
+    state_hash VARCHAR(64) PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    user_id UUID NULL REFERENCES tbl_user(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_oauth_state_expires_at ON tbl_oauth_state (expires_at);
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS tbl_oauth_state;
+-- +goose StatementEnd
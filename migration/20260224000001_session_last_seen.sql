@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- When a session was last used, for the signed-in devices view
+ALTER TABLE tbl_session ADD COLUMN last_seen_at TIMESTAMP NULL;
+UPDATE tbl_session SET last_seen_at = updated_at;
+ALTER TABLE tbl_session ALTER COLUMN last_seen_at SET NOT NULL;
+ALTER TABLE tbl_session ALTER COLUMN last_seen_at SET DEFAULT CURRENT_TIMESTAMP;
+
+-- New-device alerts look for an earlier session from the same browser
+CREATE INDEX idx_session_user_agent ON tbl_session (user_id, user_agent);
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_session_user_agent;
+ALTER TABLE tbl_session DROP COLUMN IF EXISTS last_seen_at;
+-- +goose StatementEnd
@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+-- How a tax code treats GST: taxable codes charge GST (at their own rate, or the clinic's rate when
+-- the code's rate is 0); the others never do. BAS-excluded amounts are also left out of the BAS.
+ALTER TABLE tbl_account_tax ADD COLUMN treatment VARCHAR(20) NOT NULL DEFAULT 'taxable';
+ALTER TABLE tbl_account_tax ADD CONSTRAINT chk_account_tax_treatment
+    CHECK (treatment IN ('taxable', 'gst_free', 'input_taxed', 'bas_excluded'));
+
+UPDATE tbl_account_tax SET treatment = 'gst_free' WHERE name IN ('GST Free Expenses', 'GST Free Income');
+UPDATE tbl_account_tax SET treatment = 'bas_excluded' WHERE name = 'BAS Excluded';
+
+INSERT INTO tbl_account_tax (name, rate, description, treatment)
+VALUES
+    ('Input Taxed', 0.00, 'Input taxed sales and purchases', 'input_taxed')
+ON CONFLICT (name) DO NOTHING;
+
+-- A clinic's GST rate history; the rate on a date is the latest one effective on or before it
+CREATE TABLE tbl_clinic_gst_rate (
+    id UUID PRIMARY KEY,
+    clinic_id UUID NOT NULL REFERENCES tbl_clinic(id) ON DELETE CASCADE,
+    rate NUMERIC(5,2) NOT NULL CHECK (rate >= 0 AND rate <= 100),
+    effective_from DATE NOT NULL,
+    created_by UUID NOT NULL REFERENCES tbl_user(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT uq_clinic_gst_rate_from UNIQUE (clinic_id, effective_from)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS tbl_clinic_gst_rate;
+DELETE FROM tbl_account_tax WHERE name = 'Input Taxed'
+    AND NOT EXISTS (SELECT 1 FROM tbl_account WHERE account_tax_id = tbl_account_tax.id);
+ALTER TABLE tbl_account_tax DROP CONSTRAINT IF EXISTS chk_account_tax_treatment;
+ALTER TABLE tbl_account_tax DROP COLUMN IF EXISTS treatment;
+-- +goose StatementEnd
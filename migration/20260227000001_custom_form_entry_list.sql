@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Entry listings page through a clinic's entries by date or total amount, with the entry ID as tie-breaker
+CREATE INDEX idx_custom_form_entry_clinic_date ON tbl_custom_form_entry (clinic_id, entry_date, id)
+    WHERE deleted_at IS NULL;
+CREATE INDEX idx_custom_form_entry_clinic_total ON tbl_custom_form_entry
+    (clinic_id, (COALESCE((calculations->>'totalAmount')::numeric, 0)), id)
+    WHERE deleted_at IS NULL;
+
+-- Full-text search over description and remarks
+CREATE INDEX idx_custom_form_entry_search ON tbl_custom_form_entry
+    USING GIN (to_tsvector('english', COALESCE(description, '') || ' ' || COALESCE(remarks, '')));
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_custom_form_entry_search;
+DROP INDEX IF EXISTS idx_custom_form_entry_clinic_total;
+DROP INDEX IF EXISTS idx_custom_form_entry_clinic_date;
+-- +goose StatementEnd
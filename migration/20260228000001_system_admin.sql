@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- System administrators manage data shared by every clinic, such as the chart of accounts.
+-- The flag is granted directly in the database; clinic roles never confer it.
+ALTER TABLE tbl_user ADD COLUMN is_system_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tbl_user DROP COLUMN IF EXISTS is_system_admin;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A field's GST rate now falls back to the clinic's rate only when it is absent. Fields saved before
+-- that charged no GST without a rate, so they are given an explicit rate of 0 to keep their figures.
+CREATE FUNCTION pg_temp.pin_field_gst_rate(fields JSONB) RETURNS JSONB AS $$
+    SELECT COALESCE(jsonb_agg(
+        CASE
+            WHEN jsonb_typeof(f->'gstConfig') = 'object'
+                AND jsonb_typeof(COALESCE(f->'gstConfig'->'rate', 'null'::jsonb)) = 'null'
+            THEN jsonb_set(f, '{gstConfig,rate}', '0'::jsonb)
+            ELSE f
+        END ORDER BY ord), '[]'::jsonb)
+    FROM jsonb_array_elements(fields) WITH ORDINALITY AS t(f, ord)
+$$ LANGUAGE SQL IMMUTABLE;
+
+UPDATE tbl_custom_form SET fields = pg_temp.pin_field_gst_rate(fields)
+WHERE jsonb_typeof(fields) = 'array';
+UPDATE tbl_custom_form_version SET fields = pg_temp.pin_field_gst_rate(fields)
+WHERE jsonb_typeof(fields) = 'array';
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+-- Explicit zero rates are also how fields charge no GST from now on, so they are left in place
+SELECT 1;
+-- +goose StatementEnd
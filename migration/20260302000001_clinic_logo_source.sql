@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- The clinic's logo URL is downloaded into storage when it is saved; this records which URL the
+-- stored copy came from, so a copy left over from an earlier URL is never drawn.
+ALTER TABLE tbl_clinic ADD COLUMN logo_source_url TEXT;
+
+-- +goose StatementEnd
+
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tbl_clinic DROP COLUMN logo_source_url;
+-- +goose StatementEnd
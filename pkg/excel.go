@@ -1,6 +1,8 @@
 package pkg
 
 import (
+	"bytes"
+
 	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
 	"github.com/jung-kurt/gofpdf"
 )
@@ -26,7 +28,7 @@ func SetHeaderStyle(pdf *gofpdf.Fpdf, config *domain.PDFConfig) {
 	if fontSize == 0 {
 		fontSize = 20
 	}
-	pdf.SetFont("Arial", "B", fontSize)
+	pdf.SetFont(fontFamily(config), "B", fontSize)
 }
 
 func GetPDFConfig(ShowLogo bool, PrimaryColor, SecondaryColor, AccentColor []int, FontFamily string, HeaderFontSize, BodyFontSize, TableFontSize float64) *domain.PDFConfig {
@@ -65,14 +67,21 @@ func SetTableRow(pdf *gofpdf.Fpdf, config *domain.PDFConfig, alt bool) {
 		pdf.SetFillColor(255, 255, 255)
 	}
 	pdf.SetTextColor(0, 0, 0)
-	pdf.SetFont("Arial", "", config.TableFontSize)
+	pdf.SetFont(fontFamily(config), "", config.TableFontSize)
 }
 
 func RenderHeader(pdf *gofpdf.Fpdf, d domain.Statement, config *domain.PDFConfig) {
-	// Add logo if enabled
-	if config.ShowLogo {
-		pdf.Image("logo.png", 15, 15, 30, 0, false, "", 0, "")
-		pdf.Ln(35)
+	// Add logo if enabled and the clinic has one
+	if config.ShowLogo && len(d.Logo) > 0 {
+		opts := gofpdf.ImageOptions{ImageType: d.LogoType, ReadDpi: true}
+		pdf.RegisterImageOptionsReader("logo", opts, bytes.NewReader(d.Logo))
+		if pdf.Ok() {
+			pdf.ImageOptions("logo", 15, 15, 30, 0, false, opts, 0, "")
+			pdf.Ln(35)
+		} else {
+			// An unreadable logo should not cost the whole statement
+			pdf.ClearError()
+		}
 	}
 
 	SetHeaderStyle(pdf, config)
@@ -101,7 +110,7 @@ func SetNormalText(pdf *gofpdf.Fpdf, config *domain.PDFConfig) {
 	if fontSize == 0 {
 		fontSize = 11
 	}
-	pdf.SetFont("Arial", "", fontSize)
+	pdf.SetFont(fontFamily(config), "", fontSize)
 }
 
 func SetBoldText(pdf *gofpdf.Fpdf, config *domain.PDFConfig, size float64) {
@@ -112,7 +121,7 @@ func SetBoldText(pdf *gofpdf.Fpdf, config *domain.PDFConfig, size float64) {
 			size = 11
 		}
 	}
-	pdf.SetFont("Arial", "B", size)
+	pdf.SetFont(fontFamily(config), "B", size)
 }
 
 func SetTableHeader(pdf *gofpdf.Fpdf, config *domain.PDFConfig) {
@@ -126,7 +135,7 @@ func SetTableHeader(pdf *gofpdf.Fpdf, config *domain.PDFConfig) {
 	if fontSize == 0 {
 		fontSize = 10
 	}
-	pdf.SetFont("Arial", "B", fontSize)
+	pdf.SetFont(fontFamily(config), "B", fontSize)
 }
 
 func setTableRow(pdf *gofpdf.Fpdf, config *domain.PDFConfig, alt bool) {
@@ -140,7 +149,7 @@ func setTableRow(pdf *gofpdf.Fpdf, config *domain.PDFConfig, alt bool) {
 	if fontSize == 0 {
 		fontSize = 9
 	}
-	pdf.SetFont("Arial", "", fontSize)
+	pdf.SetFont(fontFamily(config), "", fontSize)
 }
 
 func setSectionTitle(pdf *gofpdf.Fpdf, config *domain.PDFConfig) {
@@ -154,5 +163,13 @@ func setSectionTitle(pdf *gofpdf.Fpdf, config *domain.PDFConfig) {
 	if fontSize < 13 {
 		fontSize = 13
 	}
-	pdf.SetFont("Arial", "B", fontSize)
+	pdf.SetFont(fontFamily(config), "B", fontSize)
+}
+
+// fontFamily is the template's built-in font, Arial when unset.
+func fontFamily(config *domain.PDFConfig) string {
+	if config.FontFamily == "" {
+		return "Arial"
+	}
+	return config.FontFamily
 }
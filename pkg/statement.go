@@ -0,0 +1,133 @@
+package pkg
+
+import (
+	"bytes"
+
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Statement table columns and their widths (mm); they fill the 180mm between the margins.
+var statementColumns = []struct {
+	title string
+	width float64
+}{
+	{"Provider", 36}, {"Code", 22}, {"GST", 18}, {"Collection", 24},
+	{"External Lab", 22}, {"Implant", 18}, {"Internal Lab", 20}, {"Net", 20},
+}
+
+// RenderStatement draws a service-fee statement and returns the PDF bytes. Only the sections listed in
+// config.ShowSections are drawn; an empty list draws them all.
+func RenderStatement(d domain.Statement, config *domain.PDFConfig) ([]byte, error) {
+	if config == nil {
+		config = GetDefaultPDFConfig()
+	}
+	show := func(section string) bool {
+		if len(config.ShowSections) == 0 {
+			return true
+		}
+		for _, s := range config.ShowSections {
+			if s == section {
+				return true
+			}
+		}
+		return false
+	}
+
+	pdf := NewPDF()
+	// Core fonts are cp1252; translate names and notes entered as UTF-8
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	d.CompanyName = tr(d.CompanyName)
+	d.ProviderName = tr(d.ProviderName)
+
+	if show(domain.StatementSectionHeader) {
+		RenderHeader(pdf, d, config)
+		renderCustomFields(pdf, config, tr)
+	}
+	if show(domain.StatementSectionTable) {
+		renderStatementTable(pdf, d, config, tr)
+	}
+	if show(domain.StatementSectionReconciliation) {
+		renderSection(pdf, config, "Reconciliation", [][2]string{
+			{"Collected fees", d.CollectedFees},
+			{"Direct cost (external lab)", d.DirectCostExternalLab},
+			{"Net collected fees", d.NetCollectedFees},
+			{"Remaining amount (" + d.RemainingAmountPercent + " of net collected fees)", d.RAPercentNetCollected},
+			{"GST on collection", d.GSTOnCollection},
+			{"GST on direct cost items", d.DirectCostItemsGST},
+			{"Remaining amount", d.RemainingAmount},
+			{"GST on service fee", d.GSTOnServiceFee},
+			{"Remitted amount", d.RemittedAmount},
+		})
+	}
+	if show(domain.StatementSectionServiceFee) {
+		renderSection(pdf, config, "Service Fee", [][2]string{
+			{"Service fee (" + d.ServiceFeePercent + ")", d.ServiceFee},
+			{"Total charge excl. GST", d.TotalChargeExclGST},
+			{"GST", d.GST},
+			{"Total charge incl. GST", d.TotalChargeInclGST},
+			{"Dental draw", d.DentalDrawPercent},
+		})
+	}
+	if show(domain.StatementSectionNotes) && d.Notes != "" {
+		setSectionTitle(pdf, config)
+		pdf.CellFormat(0, 9, "Notes", "", 1, "L", true, 0, "")
+		pdf.Ln(2)
+		SetNormalText(pdf, config)
+		pdf.MultiCell(0, 6, tr(d.Notes), "", "L", false)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func renderCustomFields(pdf *gofpdf.Fpdf, config *domain.PDFConfig, tr func(string) string) {
+	if len(config.CustomFields) == 0 {
+		return
+	}
+	for _, f := range config.CustomFields {
+		SetBoldText(pdf, config, 0)
+		pdf.CellFormat(60, 7, tr(f.Label)+":", "", 0, "L", false, 0, "")
+		SetNormalText(pdf, config)
+		pdf.CellFormat(0, 7, tr(f.Value), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(6)
+}
+
+func renderStatementTable(pdf *gofpdf.Fpdf, d domain.Statement, config *domain.PDFConfig, tr func(string) string) {
+	SetTableHeader(pdf, config)
+	for _, col := range statementColumns {
+		pdf.CellFormat(col.width, 8, col.title, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	for i, r := range d.Rows {
+		setTableRow(pdf, config, i%2 == 1)
+		cells := []string{tr(r.Provider), tr(r.Code), r.GST, r.Collection, r.ExternalLab, r.Implant, r.InternalLab, r.Net}
+		for j, col := range statementColumns {
+			align := "R"
+			if j < 2 {
+				align = "L"
+			}
+			pdf.CellFormat(col.width, 7, cells[j], "1", 0, align, true, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	pdf.Ln(8)
+}
+
+// renderSection draws a titled block of label/amount lines.
+func renderSection(pdf *gofpdf.Fpdf, config *domain.PDFConfig, title string, lines [][2]string) {
+	setSectionTitle(pdf, config)
+	pdf.CellFormat(0, 9, title, "", 1, "L", true, 0, "")
+	pdf.Ln(2)
+	SetNormalText(pdf, config)
+	for _, l := range lines {
+		pdf.CellFormat(130, 7, l[0], "B", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 7, l[1], "B", 1, "R", false, 0, "")
+	}
+	pdf.Ln(8)
+}
@@ -8,12 +8,15 @@ import (
 	"github.com/iamarpitzala/aca-reca-backend/internal/service"
 )
 
-func RegisterAOCRoutes(e *gin.RouterGroup, aocHandler *httpHandler.AOCHandler) {
+func RegisterAOCRoutes(e *gin.RouterGroup, aocHandler *httpHandler.AOCHandler, access *service.ClinicAccessService, authService *service.AuthService) {
 	aoc := e.Group("/aoc")
 	cfg := config.Load()
 
 	tokenService := service.NewTokenService(cfg.JWT)
-	aoc.Use(middleware.AuthMiddleware(tokenService))
+	aoc.Use(middleware.AuthMiddleware(tokenService, authService))
+
+	// The chart of accounts is shared by every clinic, so only a system administrator may change it
+	canManageAccounts := middleware.RequireSystemAdmin(access)
 
 	aoc.GET("/type", aocHandler.GetAllAOCType)
 	aoc.GET("/tax", aocHandler.GetAllAccountTax)
@@ -21,13 +24,13 @@ func RegisterAOCRoutes(e *gin.RouterGroup, aocHandler *httpHandler.AOCHandler) {
 	aoc.GET("/account-type/:id", aocHandler.GetAOCByAccountTypeID)
 	aoc.GET("/account-tax/:id", aocHandler.GetAOCByAccountTaxID)
 	aoc.GET("/code/:code", aocHandler.GetAOCByCode)
-	aoc.POST("", aocHandler.CreateAOC)
-	aoc.POST("/", aocHandler.CreateAOC)
+	aoc.POST("", canManageAccounts, aocHandler.CreateAOC)
+	aoc.POST("/", canManageAccounts, aocHandler.CreateAOC)
 	aoc.GET("", aocHandler.GetAllAOCs)
 	aoc.GET("/", aocHandler.GetAllAOCs)
 	aoc.GET("/:id", aocHandler.GetAOCByID)
-	aoc.PUT("/:id", aocHandler.UpdateAOC)
-	aoc.PATCH("", aocHandler.DeleteAOC)
-	aoc.PATCH("/bulk-tax", aocHandler.BulkUpdateTax)
-	aoc.PATCH("/archive", aocHandler.ArchiveAOC)
+	aoc.PUT("/:id", canManageAccounts, aocHandler.UpdateAOC)
+	aoc.PATCH("", canManageAccounts, aocHandler.DeleteAOC)
+	aoc.PATCH("/bulk-tax", canManageAccounts, aocHandler.BulkUpdateTax)
+	aoc.PATCH("/archive", canManageAccounts, aocHandler.ArchiveAOC)
 }
@@ -0,0 +1,24 @@
+package audit
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	httpHandler "github.com/iamarpitzala/aca-reca-backend/internal/http"
+	"github.com/iamarpitzala/aca-reca-backend/internal/middleware"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+)
+
+func RegisterAuditRoutes(e *gin.RouterGroup, auditHandler *httpHandler.AuditHandler, access *service.ClinicAccessService, authService *service.AuthService) {
+	audit := e.Group("/audit")
+	cfg := config.Load()
+
+	tokenService := service.NewTokenService(cfg.JWT)
+	audit.Use(middleware.AuthMiddleware(tokenService, authService))
+
+	canExportReports := middleware.RequirePermission(access, domain.PermissionExportReports)
+
+	audit.GET("/clinic/:clinicId", middleware.RequireClinicAccess(access, middleware.ClinicParam("clinicId")), canExportReports, auditHandler.GetClinicAuditLogs)
+	// The chart of accounts is shared, so its history is visible only to those who may manage it
+	audit.GET("/accounts", middleware.RequireSystemAdmin(access), auditHandler.GetAccountAuditLogs)
+}
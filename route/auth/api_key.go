@@ -0,0 +1,22 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/iamarpitzala/aca-reca-backend/config"
+	httpHandler "github.com/iamarpitzala/aca-reca-backend/internal/http"
+	"github.com/iamarpitzala/aca-reca-backend/internal/middleware"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+)
+
+// RegisterAPIKeyRoutes registers API key management. These routes refuse API keys, so a key cannot
+// mint or revoke keys.
+func RegisterAPIKeyRoutes(e *gin.RouterGroup, apiKeyHandler *httpHandler.APIKeyHandler, authService *service.AuthService) {
+	keys := e.Group("/users/me/api-keys")
+	cfg := config.Load()
+	tokenService := service.NewTokenService(cfg.JWT)
+	keys.Use(middleware.AuthMiddleware(tokenService, authService))
+
+	keys.GET("", apiKeyHandler.List)
+	keys.POST("", apiKeyHandler.Create)
+	keys.DELETE("/:id", apiKeyHandler.Revoke)
+}
@@ -11,7 +11,12 @@ func RegisterAuthRoutes(e *gin.RouterGroup, authHandler *httpHandler.AuthHandler
 	auth.POST("/register", authHandler.Register)
 	auth.POST("/login", authHandler.Login)
 	auth.POST("/refresh", authHandler.RefreshToken)
+	auth.POST("/mfa/verify", authHandler.VerifyMFA)
 	auth.POST("/logout/:sessionId", authHandler.Logout)
+	auth.POST("/password/forgot", authHandler.ForgotPassword)
+	auth.POST("/password/reset", authHandler.ResetPassword)
+	auth.POST("/email/verify", authHandler.VerifyEmail)
+	auth.POST("/email/resend", authHandler.ResendVerification)
 	auth.GET("/oauth/:provider", authHandler.InitiateOAuth)
 	auth.GET("/oauth/:provider/callback", authHandler.OAuthCallback)
 }
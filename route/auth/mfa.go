@@ -0,0 +1,22 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/iamarpitzala/aca-reca-backend/config"
+	httpHandler "github.com/iamarpitzala/aca-reca-backend/internal/http"
+	"github.com/iamarpitzala/aca-reca-backend/internal/middleware"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+)
+
+func RegisterMFARoutes(e *gin.RouterGroup, mfaHandler *httpHandler.MFAHandler, authService *service.AuthService) {
+	mfa := e.Group("/users/me/mfa")
+	cfg := config.Load()
+	tokenService := service.NewTokenService(cfg.JWT)
+	mfa.Use(middleware.AuthMiddleware(tokenService, authService))
+
+	mfa.GET("", mfaHandler.GetStatus)
+	mfa.DELETE("", mfaHandler.Disable)
+	mfa.POST("/enroll", mfaHandler.Enroll)
+	mfa.POST("/activate", mfaHandler.Activate)
+	mfa.POST("/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+}
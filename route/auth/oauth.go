@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/iamarpitzala/aca-reca-backend/config"
+	httpHandler "github.com/iamarpitzala/aca-reca-backend/internal/http"
+	"github.com/iamarpitzala/aca-reca-backend/internal/middleware"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+)
+
+func RegisterOAuthLinkRoutes(e *gin.RouterGroup, linkHandler *httpHandler.OAuthLinkHandler, authService *service.AuthService) {
+	oauth := e.Group("/users/me/oauth")
+	cfg := config.Load()
+	tokenService := service.NewTokenService(cfg.JWT)
+	oauth.Use(middleware.AuthMiddleware(tokenService, authService))
+
+	oauth.GET("", linkHandler.List)
+	oauth.POST("/:provider/link", linkHandler.Link)
+	oauth.DELETE("/:provider", linkHandler.Unlink)
+}
@@ -8,14 +8,16 @@ import (
 	"github.com/iamarpitzala/aca-reca-backend/internal/service"
 )
 
-func RegisterUserRoutes(e *gin.RouterGroup, userHandler *httpHandler.UserHandler) {
+func RegisterUserRoutes(e *gin.RouterGroup, userHandler *httpHandler.UserHandler, authService *service.AuthService) {
 	user := e.Group("/users")
 	cfg := config.Load()
 	tokenService := service.NewTokenService(cfg.JWT)
-	user.Use(middleware.AuthMiddleware(tokenService))
+	user.Use(middleware.AuthMiddleware(tokenService, authService))
 
 	// /me must be before /:userId to avoid "me" being captured as userId
 	user.GET("/me", userHandler.GetMe)
+	user.PUT("/me/password", userHandler.ChangePassword)
+	user.DELETE("/me/sessions", userHandler.RevokeOtherSessions)
 	user.GET("/:userId", userHandler.GetCurrentUser)
 	user.PUT("/:userId", userHandler.UpdateCurrentUser)
 	user.GET("/:userId/sessions", userHandler.GetActiveSessions)
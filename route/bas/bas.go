@@ -0,0 +1,22 @@
+package bas
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	httpHandler "github.com/iamarpitzala/aca-reca-backend/internal/http"
+	"github.com/iamarpitzala/aca-reca-backend/internal/middleware"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+)
+
+func RegisterBASRoutes(e *gin.RouterGroup, basHandler *httpHandler.BASHandler, access *service.ClinicAccessService, authService *service.AuthService) {
+	bas := e.Group("/bas")
+	cfg := config.Load()
+
+	tokenService := service.NewTokenService(cfg.JWT)
+	bas.Use(middleware.AuthMiddleware(tokenService, authService))
+
+	canExport := middleware.RequirePermission(access, domain.PermissionExportReports)
+
+	bas.GET("/clinic/:clinicId/quarter/:quarterId", middleware.RequireClinicAccess(access, middleware.ClinicParam("clinicId")), canExport, basHandler.GetWorksheet)
+}
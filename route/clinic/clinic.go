@@ -3,22 +3,28 @@ package clinic
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
 	httpHandler "github.com/iamarpitzala/aca-reca-backend/internal/http"
 	"github.com/iamarpitzala/aca-reca-backend/internal/middleware"
 	"github.com/iamarpitzala/aca-reca-backend/internal/service"
 )
 
-func RegisterClinicRoutes(e *gin.RouterGroup, clinicHandler *httpHandler.ClinicHandler) {
+func RegisterClinicRoutes(e *gin.RouterGroup, clinicHandler *httpHandler.ClinicHandler, access *service.ClinicAccessService, authService *service.AuthService) {
 	clinic := e.Group("/clinic")
 	cfg := config.Load()
 
 	tokenService := service.NewTokenService(cfg.JWT)
-	clinic.Use(middleware.AuthMiddleware(tokenService))
+	clinic.Use(middleware.AuthMiddleware(tokenService, authService))
+
+	byClinic := middleware.RequireClinicAccess(access, middleware.ClinicParam("id"))
+	canManageClinic := middleware.RequirePermission(access, domain.PermissionManageClinic)
 
 	clinic.POST("", clinicHandler.CreateClinic)
-	clinic.GET("/:id", clinicHandler.GetClinic)
-	clinic.PUT("/:id", clinicHandler.UpdateClinic)
-	clinic.DELETE("/:id", clinicHandler.DeleteClinic)
+	clinic.GET("/:id", byClinic, clinicHandler.GetClinic)
+	clinic.PUT("/:id", byClinic, canManageClinic, clinicHandler.UpdateClinic)
+	clinic.DELETE("/:id", byClinic, canManageClinic, clinicHandler.DeleteClinic)
+	clinic.GET("/:id/gst-rates", byClinic, clinicHandler.GetGSTRates)
+	clinic.POST("/:id/gst-rates", byClinic, canManageClinic, clinicHandler.SetGSTRate)
 	clinic.GET("", clinicHandler.GetAllClinics)
 	clinic.GET("/abn/:abnNumber", clinicHandler.GetClinicByABNNumber)
 }
@@ -3,34 +3,44 @@ package custom_form
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
 	httpHandler "github.com/iamarpitzala/aca-reca-backend/internal/http"
 	"github.com/iamarpitzala/aca-reca-backend/internal/middleware"
 	"github.com/iamarpitzala/aca-reca-backend/internal/service"
 )
 
-func RegisterCustomFormRoutes(e *gin.RouterGroup, handler *httpHandler.CustomFormHandler) {
+func RegisterCustomFormRoutes(e *gin.RouterGroup, handler *httpHandler.CustomFormHandler, access *service.ClinicAccessService, authService *service.AuthService) {
 	g := e.Group("/custom-form")
 	cfg := config.Load()
 	tokenService := service.NewTokenService(cfg.JWT)
-	g.Use(middleware.AuthMiddleware(tokenService))
+	g.Use(middleware.AuthMiddleware(tokenService, authService))
 
-	g.POST("", handler.Create)
-	g.GET("/clinic/:clinicId", handler.GetByClinicID)
-	g.GET("/clinic/:clinicId/published", handler.GetPublishedByClinicID)
-	g.GET("/:id", handler.GetByID)
-	g.PUT("/:id", handler.Update)
-	g.POST("/:id/publish", handler.Publish)
-	g.POST("/:id/archive", handler.Archive)
-	g.DELETE("/:id", handler.Delete)
-	g.POST("/:id/duplicate", handler.Duplicate)
+	byClinic := middleware.RequireClinicAccess(access, middleware.ClinicParam("clinicId"))
+	byForm := middleware.RequireClinicAccess(access, middleware.ResourceParam("id", access.CustomFormClinic))
+	byEntry := middleware.RequireClinicAccess(access, middleware.ResourceParam("entryId", access.CustomFormEntryClinic))
+	canManageForms := middleware.RequirePermission(access, domain.PermissionManageForms)
+	canEditEntries := middleware.RequirePermission(access, domain.PermissionEditEntries)
+
+	g.POST("", middleware.RequireClinicAccess(access, middleware.ClinicField("clinicId")), canManageForms, handler.Create)
+	g.GET("/clinic/:clinicId", byClinic, handler.GetByClinicID)
+	g.GET("/clinic/:clinicId/published", byClinic, handler.GetPublishedByClinicID)
+	g.GET("/:id", byForm, handler.GetByID)
+	g.PUT("/:id", byForm, canManageForms, handler.Update)
+	g.POST("/:id/publish", byForm, middleware.RequirePermission(access, domain.PermissionPublishForms), handler.Publish)
+	g.POST("/:id/archive", byForm, canManageForms, handler.Archive)
+	g.DELETE("/:id", byForm, canManageForms, handler.Delete)
+	g.POST("/:id/duplicate", byForm, canManageForms, handler.Duplicate)
+	g.GET("/:id/versions", byForm, handler.ListVersions)
+	g.GET("/:id/versions/diff", byForm, handler.DiffVersions)
+	g.GET("/:id/versions/:version", byForm, handler.GetVersion)
 
 	// Entries under /entries to avoid conflicting with form :id
 	entries := g.Group("/entries")
-	entries.POST("/preview", handler.PreviewCalculations)
-	entries.POST("", handler.CreateEntry)
-	entries.GET("/form/:formId", handler.GetEntriesByFormID)
-	entries.GET("/clinic/:clinicId", handler.GetEntriesByClinicID)
-	entries.GET("/:entryId", handler.GetEntryByID)
-	entries.PUT("/:entryId", handler.UpdateEntry)
-	entries.DELETE("/:entryId", handler.DeleteEntry)
+	entries.POST("/preview", middleware.RequireClinicAccess(access, middleware.ResourceField("formId", access.CustomFormClinic)), handler.PreviewCalculations)
+	entries.POST("", middleware.RequireClinicAccess(access, middleware.ResourceField("formId", access.CustomFormClinic)), canEditEntries, handler.CreateEntry)
+	entries.GET("/form/:formId", middleware.RequireClinicAccess(access, middleware.ResourceParam("formId", access.CustomFormClinic)), handler.GetEntriesByFormID)
+	entries.GET("/clinic/:clinicId", byClinic, handler.GetEntriesByClinicID)
+	entries.GET("/:entryId", byEntry, handler.GetEntryByID)
+	entries.PUT("/:entryId", byEntry, canEditEntries, handler.UpdateEntry)
+	entries.DELETE("/:entryId", byEntry, canEditEntries, handler.DeleteEntry)
 }
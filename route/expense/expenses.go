@@ -3,29 +3,35 @@ package expense
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
 	httpHandler "github.com/iamarpitzala/aca-reca-backend/internal/http"
 	"github.com/iamarpitzala/aca-reca-backend/internal/middleware"
 	"github.com/iamarpitzala/aca-reca-backend/internal/service"
 )
 
-func RegisterExpensesRoutes(e *gin.RouterGroup, expensesHandler *httpHandler.ExpensesHandler) {
+func RegisterExpensesRoutes(e *gin.RouterGroup, expensesHandler *httpHandler.ExpensesHandler, access *service.ClinicAccessService, authService *service.AuthService) {
 	expense := e.Group("/expense")
 	cfg := config.Load()
 
 	tokenService := service.NewTokenService(cfg.JWT)
-	expense.Use(middleware.AuthMiddleware(tokenService))
+	expense.Use(middleware.AuthMiddleware(tokenService, authService))
 
-	expense.POST("/type", expensesHandler.CreateExpenseType)
-	expense.POST("/category", expensesHandler.CreateExpenseCategory)
-	expense.POST("/category-type", expensesHandler.CreateExpenseCategoryType)
-	expense.POST("/entry", expensesHandler.CreateExpenseEntry)
-	expense.GET("/type/clinic/:clinicId", expensesHandler.GetExpenseTypesByClinicID)
-	expense.GET("/type/:id", expensesHandler.GetExpenseTypeByID)
-	expense.GET("/category/clinic/:clinicId", expensesHandler.GetExpenseCategoriesByClinicID)
-	expense.GET("/category/:id", expensesHandler.GetExpenseCategoryByID)
-	expense.PUT("/category/:id", expensesHandler.UpdateExpenseCategory)
-	expense.DELETE("/category/:id", expensesHandler.DeleteExpenseCategory)
-	expense.GET("/category-type/:id", expensesHandler.GetExpenseCategoryTypeByID)
-	expense.GET("/entry/clinic/:clinicId", expensesHandler.GetExpenseEntriesByClinicID)
-	expense.GET("/entry/:id", expensesHandler.GetExpenseEntryByID)
+	byClinic := middleware.RequireClinicAccess(access, middleware.ClinicParam("clinicId"))
+	byBodyClinic := middleware.RequireClinicAccess(access, middleware.ClinicField("clinicId"))
+	byCategory := middleware.RequireClinicAccess(access, middleware.ResourceParam("id", access.ExpenseCategoryClinic))
+	canManageAccounts := middleware.RequirePermission(access, domain.PermissionManageAccounts)
+
+	expense.POST("/type", byBodyClinic, canManageAccounts, expensesHandler.CreateExpenseType)
+	expense.POST("/category", byBodyClinic, canManageAccounts, expensesHandler.CreateExpenseCategory)
+	expense.POST("/category-type", byBodyClinic, canManageAccounts, expensesHandler.CreateExpenseCategoryType)
+	expense.POST("/entry", byBodyClinic, middleware.RequirePermission(access, domain.PermissionEditEntries), expensesHandler.CreateExpenseEntry)
+	expense.GET("/type/clinic/:clinicId", byClinic, expensesHandler.GetExpenseTypesByClinicID)
+	expense.GET("/type/:id", middleware.RequireClinicAccess(access, middleware.ResourceParam("id", access.ExpenseTypeClinic)), expensesHandler.GetExpenseTypeByID)
+	expense.GET("/category/clinic/:clinicId", byClinic, expensesHandler.GetExpenseCategoriesByClinicID)
+	expense.GET("/category/:id", byCategory, expensesHandler.GetExpenseCategoryByID)
+	expense.PUT("/category/:id", byCategory, canManageAccounts, expensesHandler.UpdateExpenseCategory)
+	expense.DELETE("/category/:id", byCategory, canManageAccounts, expensesHandler.DeleteExpenseCategory)
+	expense.GET("/category-type/:id", middleware.RequireClinicAccess(access, middleware.ResourceParam("id", access.ExpenseCategoryTypeClinic)), expensesHandler.GetExpenseCategoryTypeByID)
+	expense.GET("/entry/clinic/:clinicId", byClinic, expensesHandler.GetExpenseEntriesByClinicID)
+	expense.GET("/entry/:id", middleware.RequireClinicAccess(access, middleware.ResourceParam("id", access.ExpenseEntryClinic)), expensesHandler.GetExpenseEntryByID)
 }
@@ -1,20 +1,21 @@
 package financial_calculation
 
-// import (
-// 	"github.com/gin-gonic/gin"
-// 	"github.com/iamarpitzala/aca-reca-backend/config"
-// 	httpHandler "github.com/iamarpitzala/aca-reca-backend/internal/http"
-// 	"github.com/iamarpitzala/aca-reca-backend/internal/middleware"
-// 	"github.com/iamarpitzala/aca-reca-backend/internal/service"
-// )
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	httpHandler "github.com/iamarpitzala/aca-reca-backend/internal/http"
+	"github.com/iamarpitzala/aca-reca-backend/internal/middleware"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+)
 
-// func RegisterFinancialCalculationRoutes(e *gin.RouterGroup, calculationHandler *httpHandler.FinancialCalculationHandler) {
-// 	calculation := e.Group("/financial-calculation")
-// 	cfg := config.Load()
+func RegisterFinancialCalculationRoutes(e *gin.RouterGroup, calculationHandler *httpHandler.FinancialCalculationHandler, access *service.ClinicAccessService, authService *service.AuthService) {
+	calculation := e.Group("/financial-calculation")
+	cfg := config.Load()
 
-// 	tokenService := service.NewTokenService(cfg.JWT)
-// 	calculation.Use(middleware.AuthMiddleware(tokenService))
+	tokenService := service.NewTokenService(cfg.JWT)
+	calculation.Use(middleware.AuthMiddleware(tokenService, authService))
 
-// 	calculation.POST("/calculate", calculationHandler.CalculateFinancial)
-// 	calculation.GET("/history/:formId", calculationHandler.GetCalculationHistory)
-// }
+	calculation.POST("/calculate", middleware.RequireClinicAccess(access, middleware.ResourceField("formId", access.FinancialFormClinic)), middleware.RequirePermission(access, domain.PermissionEditEntries), calculationHandler.CalculateFinancial)
+	calculation.GET("/history/:formId", middleware.RequireClinicAccess(access, middleware.ResourceParam("formId", access.FinancialFormClinic)), calculationHandler.GetCalculationHistory)
+}
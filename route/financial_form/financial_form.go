@@ -3,21 +3,25 @@ package financial_form
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
 	httpHandler "github.com/iamarpitzala/aca-reca-backend/internal/http"
 	"github.com/iamarpitzala/aca-reca-backend/internal/middleware"
 	"github.com/iamarpitzala/aca-reca-backend/internal/service"
 )
 
-func RegisterFinancialFormRoutes(e *gin.RouterGroup, financialFormHandler *httpHandler.FinancialFormHandler) {
+func RegisterFinancialFormRoutes(e *gin.RouterGroup, financialFormHandler *httpHandler.FinancialFormHandler, access *service.ClinicAccessService, authService *service.AuthService) {
 	financialForm := e.Group("/form")
 	cfg := config.Load()
 
 	tokenService := service.NewTokenService(cfg.JWT)
-	financialForm.Use(middleware.AuthMiddleware(tokenService))
+	financialForm.Use(middleware.AuthMiddleware(tokenService, authService))
 
-	financialForm.POST("/", financialFormHandler.CreateFinancialForm)
-	financialForm.GET("/:id", financialFormHandler.GetFinancialForm)
-	financialForm.GET("/clinic/:clinicId", financialFormHandler.GetFinancialFormsByClinic)
-	financialForm.PUT("/:id", financialFormHandler.UpdateFinancialForm)
-	financialForm.DELETE("/:id", financialFormHandler.DeleteFinancialForm)
+	byForm := middleware.RequireClinicAccess(access, middleware.ResourceParam("id", access.FinancialFormClinic))
+	canManageForms := middleware.RequirePermission(access, domain.PermissionManageForms)
+
+	financialForm.POST("/", middleware.RequireClinicAccess(access, middleware.ClinicField("clinicId")), canManageForms, financialFormHandler.CreateFinancialForm)
+	financialForm.GET("/:id", byForm, financialFormHandler.GetFinancialForm)
+	financialForm.GET("/clinic/:clinicId", middleware.RequireClinicAccess(access, middleware.ClinicParam("clinicId")), financialFormHandler.GetFinancialFormsByClinic)
+	financialForm.PUT("/:id", byForm, canManageForms, financialFormHandler.UpdateFinancialForm)
+	financialForm.DELETE("/:id", byForm, canManageForms, financialFormHandler.DeleteFinancialForm)
 }
@@ -0,0 +1,33 @@
+package invitation
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	httpHandler "github.com/iamarpitzala/aca-reca-backend/internal/http"
+	"github.com/iamarpitzala/aca-reca-backend/internal/middleware"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+)
+
+func RegisterInvitationRoutes(e *gin.RouterGroup, invitationHandler *httpHandler.InvitationHandler, access *service.ClinicAccessService, authService *service.AuthService) {
+	cfg := config.Load()
+	tokenService := service.NewTokenService(cfg.JWT)
+
+	// Invitee endpoints: the emailed token is the credential; a session is optional
+	public := e.Group("/invitation")
+	public.Use(middleware.OptionalAuthMiddleware(tokenService, authService))
+	public.POST("/preview", invitationHandler.PreviewInvitation)
+	public.POST("/accept", invitationHandler.AcceptInvitation)
+	public.POST("/decline", invitationHandler.DeclineInvitation)
+
+	invitation := e.Group("/invitation")
+	invitation.Use(middleware.AuthMiddleware(tokenService, authService))
+
+	canInviteUsers := middleware.RequirePermission(access, domain.PermissionInviteUsers)
+	byInvitation := middleware.RequireClinicAccess(access, middleware.ResourceParam("id", access.InvitationClinic))
+
+	invitation.POST("", middleware.RequireClinicAccess(access, middleware.ClinicField("clinicId")), canInviteUsers, invitationHandler.CreateInvitation)
+	invitation.GET("/clinic/:clinicId", middleware.RequireClinicAccess(access, middleware.ClinicParam("clinicId")), canInviteUsers, invitationHandler.GetClinicInvitations)
+	invitation.POST("/:id/resend", byInvitation, canInviteUsers, invitationHandler.ResendInvitation)
+	invitation.POST("/:id/revoke", byInvitation, canInviteUsers, invitationHandler.RevokeInvitation)
+}
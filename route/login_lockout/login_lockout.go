@@ -0,0 +1,25 @@
+package login_lockout
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	httpHandler "github.com/iamarpitzala/aca-reca-backend/internal/http"
+	"github.com/iamarpitzala/aca-reca-backend/internal/middleware"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+)
+
+func RegisterLoginLockoutRoutes(e *gin.RouterGroup, lockoutHandler *httpHandler.LoginLockoutHandler, access *service.ClinicAccessService, authService *service.AuthService) {
+	lockout := e.Group("/login-lockout")
+	cfg := config.Load()
+
+	tokenService := service.NewTokenService(cfg.JWT)
+	lockout.Use(middleware.AuthMiddleware(tokenService, authService))
+
+	// Whoever may add and remove a clinic's members may also unlock their accounts
+	clinicAccess := middleware.RequireClinicAccess(access, middleware.ClinicParam("clinicId"))
+	canInviteUsers := middleware.RequirePermission(access, domain.PermissionInviteUsers)
+
+	lockout.GET("/clinic/:clinicId", clinicAccess, canInviteUsers, lockoutHandler.List)
+	lockout.DELETE("/clinic/:clinicId/user/:userId", clinicAccess, canInviteUsers, lockoutHandler.Clear)
+}
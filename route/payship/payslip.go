@@ -2,12 +2,27 @@ package payslip
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
 	httpHandler "github.com/iamarpitzala/aca-reca-backend/internal/http"
+	"github.com/iamarpitzala/aca-reca-backend/internal/middleware"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
 )
 
-func RegisterPayslipRoutes(e *gin.RouterGroup, payslipHeander *httpHandler.PayslipHandler) {
+func RegisterPayslipRoutes(e *gin.RouterGroup, payslipHeander *httpHandler.PayslipHandler, access *service.ClinicAccessService, authService *service.AuthService) {
 	payslip := e.Group("/payslip")
-	payslip.POST("/export/income", payslipHeander.ExportExcelIncome)
-	payslip.POST("/export/expenses", payslipHeander.ExportExcelExpanses)
-	payslip.POST("/generate/pdf", payslipHeander.GeneratePdf)
+	cfg := config.Load()
+
+	tokenService := service.NewTokenService(cfg.JWT)
+	payslip.Use(middleware.AuthMiddleware(tokenService, authService))
+	canExport := middleware.RequirePermission(access, domain.PermissionExportReports)
+
+	payslip.POST("/export/income", canExport, payslipHeander.ExportExcelIncome)
+	payslip.POST("/export/expenses", canExport, payslipHeander.ExportExcelExpanses)
+
+	// Exports built from the clinic's stored entries
+	byClinic := middleware.RequireClinicAccess(access, middleware.ClinicParam("clinicId"))
+	payslip.GET("/export/clinic/:clinicId/income", byClinic, canExport, payslipHeander.ExportClinicIncome)
+	payslip.GET("/export/clinic/:clinicId/expenses", byClinic, canExport, payslipHeander.ExportClinicExpenses)
+	payslip.POST("/statement/clinic/:clinicId", byClinic, canExport, payslipHeander.GenerateStatement)
 }
@@ -0,0 +1,31 @@
+package pdf_template
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
+	httpHandler "github.com/iamarpitzala/aca-reca-backend/internal/http"
+	"github.com/iamarpitzala/aca-reca-backend/internal/middleware"
+	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+)
+
+func RegisterPDFTemplateRoutes(e *gin.RouterGroup, templateHandler *httpHandler.PDFTemplateHandler, access *service.ClinicAccessService, authService *service.AuthService) {
+	template := e.Group("/pdf-template")
+	cfg := config.Load()
+
+	tokenService := service.NewTokenService(cfg.JWT)
+	template.Use(middleware.AuthMiddleware(tokenService, authService))
+
+	byClinic := middleware.RequireClinicAccess(access, middleware.ClinicParam("clinicId"))
+	canManage := middleware.RequirePermission(access, domain.PermissionManageClinic)
+
+	template.GET("/clinic/:clinicId", byClinic, templateHandler.List)
+	template.POST("/clinic/:clinicId", byClinic, canManage, templateHandler.Create)
+	template.GET("/clinic/:clinicId/:id", byClinic, templateHandler.Get)
+	template.PUT("/clinic/:clinicId/:id", byClinic, canManage, templateHandler.Update)
+	template.DELETE("/clinic/:clinicId/:id", byClinic, canManage, templateHandler.Delete)
+	template.POST("/clinic/:clinicId/:id/default", byClinic, canManage, templateHandler.SetDefault)
+	template.GET("/clinic/:clinicId/:id/logo", byClinic, templateHandler.GetLogo)
+	template.PUT("/clinic/:clinicId/:id/logo", byClinic, canManage, templateHandler.UploadLogo)
+	template.DELETE("/clinic/:clinicId/:id/logo", byClinic, canManage, templateHandler.DeleteLogo)
+}
@@ -3,21 +3,36 @@ package quarter
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
 	httpHandler "github.com/iamarpitzala/aca-reca-backend/internal/http"
 	"github.com/iamarpitzala/aca-reca-backend/internal/middleware"
 	"github.com/iamarpitzala/aca-reca-backend/internal/service"
 )
 
-func RegisterQuarterRoutes(e *gin.RouterGroup, userClinicHandler *httpHandler.QuarterHandler) {
+func RegisterQuarterRoutes(e *gin.RouterGroup, userClinicHandler *httpHandler.QuarterHandler, access *service.ClinicAccessService, authService *service.AuthService) {
 	quarter := e.Group("/quarter")
 	cfg := config.Load()
 
 	tokenService := service.NewTokenService(cfg.JWT)
-	quarter.Use(middleware.AuthMiddleware(tokenService))
+	quarter.Use(middleware.AuthMiddleware(tokenService, authService))
 
-	quarter.POST("/", userClinicHandler.Create)
+	// The shared periods back every clinic's entry assignment, BAS and period close, so only a
+	// system administrator may change them
+	canManageSharedPeriods := middleware.RequireSystemAdmin(access)
+
+	quarter.POST("/", canManageSharedPeriods, userClinicHandler.Create)
 	quarter.GET("/:id", userClinicHandler.Get)
-	quarter.PUT("/:id", userClinicHandler.Update)
-	quarter.DELETE("/:id", userClinicHandler.Delete)
+	quarter.PUT("/:id", canManageSharedPeriods, userClinicHandler.Update)
+	quarter.DELETE("/:id", canManageSharedPeriods, userClinicHandler.Delete)
 	quarter.GET("/", userClinicHandler.List)
+
+	// Per-clinic periods and period close
+	byClinic := middleware.RequireClinicAccess(access, middleware.ClinicParam("clinicId"))
+	managePeriods := middleware.RequirePermission(access, domain.PermissionManageAccounts)
+	quarter.GET("/clinic/:clinicId", byClinic, userClinicHandler.ListClinicQuarters)
+	quarter.GET("/clinic/:clinicId/history", byClinic, userClinicHandler.GetCloseHistory)
+	quarter.POST("/clinic/:clinicId/generate", byClinic, managePeriods, userClinicHandler.GenerateFinancialYear)
+	quarter.DELETE("/clinic/:clinicId/:id", byClinic, managePeriods, userClinicHandler.DeleteClinicPeriod)
+	quarter.POST("/clinic/:clinicId/:id/close", byClinic, middleware.RequirePermission(access, domain.PermissionClosePeriods), userClinicHandler.Close)
+	quarter.POST("/clinic/:clinicId/:id/reopen", byClinic, middleware.RequirePermission(access, domain.PermissionReopenPeriods), userClinicHandler.Reopen)
 }
@@ -7,14 +7,23 @@ import (
 	"github.com/iamarpitzala/aca-reca-backend/config"
 	_ "github.com/iamarpitzala/aca-reca-backend/docs" // swagger docs
 	httpHandler "github.com/iamarpitzala/aca-reca-backend/internal/http"
+	"github.com/iamarpitzala/aca-reca-backend/internal/mailer"
+	"github.com/iamarpitzala/aca-reca-backend/internal/ratelimit"
 	"github.com/iamarpitzala/aca-reca-backend/internal/service"
+	"github.com/iamarpitzala/aca-reca-backend/internal/storage"
 	"github.com/iamarpitzala/aca-reca-backend/route/aoc"
+	"github.com/iamarpitzala/aca-reca-backend/route/audit"
 	"github.com/iamarpitzala/aca-reca-backend/route/auth"
+	"github.com/iamarpitzala/aca-reca-backend/route/bas"
 	"github.com/iamarpitzala/aca-reca-backend/route/clinic"
 	custom_form "github.com/iamarpitzala/aca-reca-backend/route/custom_form"
 	expense "github.com/iamarpitzala/aca-reca-backend/route/expense"
+	financial_calculation "github.com/iamarpitzala/aca-reca-backend/route/financial_calculation"
 	financial_form "github.com/iamarpitzala/aca-reca-backend/route/financial_form"
+	"github.com/iamarpitzala/aca-reca-backend/route/invitation"
+	login_lockout "github.com/iamarpitzala/aca-reca-backend/route/login_lockout"
 	payslip "github.com/iamarpitzala/aca-reca-backend/route/payship"
+	pdf_template "github.com/iamarpitzala/aca-reca-backend/route/pdf_template"
 	"github.com/iamarpitzala/aca-reca-backend/route/quarter"
 	user_clinic "github.com/iamarpitzala/aca-reca-backend/route/user_clinic"
 	swaggerFiles "github.com/swaggo/files"
@@ -29,42 +38,80 @@ func InitRouter(e *gin.Engine) {
 	}
 
 	tokenService := service.NewTokenService(cfg.JWT)
-	oauthService := service.NewOAuthService(cfg.OAuth, db.DB)
-	authService := service.NewAuthService(db.DB, tokenService, oauthService)
-	clinicService := service.NewClinicService(db.DB)
+	oauthService := service.NewOAuthService(cfg.OAuth, db.DB, tokenService)
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		log.Fatalf("Failed to configure mailer: %v", err)
+	}
+	authService := service.NewAuthService(db.DB, tokenService, oauthService, mail, cfg.Account)
+	limiter, err := ratelimit.New(cfg.Login, cfg.Redis)
+	if err != nil {
+		log.Fatalf("Failed to configure login rate limiting: %v", err)
+	}
+	loginLimitService := service.NewLoginLimitService(db.DB, limiter, cfg.Login)
+	mfaService := service.NewMFAService(db.DB, tokenService, authService, loginLimitService)
+	store, err := storage.New(cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to configure storage: %v", err)
+	}
+	clinicLogoService := service.NewClinicLogoService(db.DB, store)
+	clinicService := service.NewClinicService(db.DB, clinicLogoService)
 	userClinicService := service.NewUserClinicService(db.DB)
 	financialFormService := service.NewFinancialFormService(db.DB)
 	customFormService := service.NewCustomFormService(db.DB)
-	// financialCalculationService := service.NewFinancialCalculationService(db.DB)
+	financialCalculationService := service.NewFinancialCalculationService(db.DB)
 	expensesService := service.NewExpensesService(db.DB)
 	quarterService := service.NewQuarterService(db.DB)
 	aosService := service.NewAOSService(db.DB)
+	basService := service.NewBASService(db.DB)
+	clinicAccessService := service.NewClinicAccessService(db.DB)
+	auditService := service.NewAuditService(db.DB)
+	exportService := service.NewExportService(db.DB)
+	pdfTemplateService := service.NewPDFTemplateService(db.DB, store)
+	statementService := service.NewStatementService(db.DB, pdfTemplateService, clinicLogoService)
+	invitationService := service.NewInvitationService(db.DB, tokenService, authService, mail, cfg.Invite)
 
-	authHandler := httpHandler.NewAuthHandler(authService, oauthService, cfg.OAuth.FrontendURL)
+	authHandler := httpHandler.NewAuthHandler(authService, oauthService, mfaService, loginLimitService, cfg.OAuth.FrontendURL)
 	userHandler := httpHandler.NewUserHandler(authService)
-	payslipHandler := httpHandler.NewPayslipHandler()
+	mfaHandler := httpHandler.NewMFAHandler(mfaService)
+	oauthLinkHandler := httpHandler.NewOAuthLinkHandler(oauthService)
+	apiKeyHandler := httpHandler.NewAPIKeyHandler(authService)
+	payslipHandler := httpHandler.NewPayslipHandler(exportService, statementService)
 	clinicHandler := httpHandler.NewClinicHandler(clinicService, userClinicService)
 	userClinicHandler := httpHandler.NewUserClinicHandler(userClinicService)
 	financialFormHandler := httpHandler.NewFinancialFormHandler(financialFormService)
 	customFormHandler := httpHandler.NewCustomFormHandler(customFormService)
-	// financialCalculationHandler := httpHandler.NewFinancialCalculationHandler(financialCalculationService)
+	financialCalculationHandler := httpHandler.NewFinancialCalculationHandler(financialCalculationService)
 	expensesHandler := httpHandler.NewExpensesHandler(expensesService)
 	quarterHandler := httpHandler.NewQuarterHandler(quarterService)
 	aosHandler := httpHandler.NewAOCHandler(aosService)
+	basHandler := httpHandler.NewBASHandler(basService)
+	invitationHandler := httpHandler.NewInvitationHandler(invitationService)
+	auditHandler := httpHandler.NewAuditHandler(auditService)
+	pdfTemplateHandler := httpHandler.NewPDFTemplateHandler(pdfTemplateService)
+	loginLockoutHandler := httpHandler.NewLoginLockoutHandler(loginLimitService)
 	// Swagger documentation route
 	e.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	v1 := e.Group("/api/v1")
 	auth.RegisterAuthRoutes(v1, authHandler)
-	auth.RegisterUserRoutes(v1, userHandler)
-	clinic.RegisterClinicRoutes(v1, clinicHandler)
-	payslip.RegisterPayslipRoutes(v1, payslipHandler)
-	user_clinic.RegisterUserClinicRoutes(v1, userClinicHandler)
-	financial_form.RegisterFinancialFormRoutes(v1, financialFormHandler)
-	custom_form.RegisterCustomFormRoutes(v1, customFormHandler)
-	// financial_calculation.RegisterFinancialCalculationRoutes(v1, financialCalculationHandler)
-	quarter.RegisterQuarterRoutes(v1, quarterHandler)
-	expense.RegisterExpensesRoutes(v1, expensesHandler)
-	aoc.RegisterAOCRoutes(v1, aosHandler)
+	auth.RegisterUserRoutes(v1, userHandler, authService)
+	auth.RegisterMFARoutes(v1, mfaHandler, authService)
+	auth.RegisterOAuthLinkRoutes(v1, oauthLinkHandler, authService)
+	auth.RegisterAPIKeyRoutes(v1, apiKeyHandler, authService)
+	clinic.RegisterClinicRoutes(v1, clinicHandler, clinicAccessService, authService)
+	payslip.RegisterPayslipRoutes(v1, payslipHandler, clinicAccessService, authService)
+	user_clinic.RegisterUserClinicRoutes(v1, userClinicHandler, clinicAccessService, authService)
+	financial_form.RegisterFinancialFormRoutes(v1, financialFormHandler, clinicAccessService, authService)
+	custom_form.RegisterCustomFormRoutes(v1, customFormHandler, clinicAccessService, authService)
+	financial_calculation.RegisterFinancialCalculationRoutes(v1, financialCalculationHandler, clinicAccessService, authService)
+	quarter.RegisterQuarterRoutes(v1, quarterHandler, clinicAccessService, authService)
+	expense.RegisterExpensesRoutes(v1, expensesHandler, clinicAccessService, authService)
+	aoc.RegisterAOCRoutes(v1, aosHandler, clinicAccessService, authService)
+	bas.RegisterBASRoutes(v1, basHandler, clinicAccessService, authService)
+	invitation.RegisterInvitationRoutes(v1, invitationHandler, clinicAccessService, authService)
+	audit.RegisterAuditRoutes(v1, auditHandler, clinicAccessService, authService)
+	pdf_template.RegisterPDFTemplateRoutes(v1, pdfTemplateHandler, clinicAccessService, authService)
+	login_lockout.RegisterLoginLockoutRoutes(v1, loginLockoutHandler, clinicAccessService, authService)
 
 }
@@ -3,20 +3,23 @@ package user_clinic
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/iamarpitzala/aca-reca-backend/config"
+	"github.com/iamarpitzala/aca-reca-backend/internal/domain"
 	httpHandler "github.com/iamarpitzala/aca-reca-backend/internal/http"
 	"github.com/iamarpitzala/aca-reca-backend/internal/middleware"
 	"github.com/iamarpitzala/aca-reca-backend/internal/service"
 )
 
-func RegisterUserClinicRoutes(e *gin.RouterGroup, userClinicHandler *httpHandler.UserClinicHandler) {
+func RegisterUserClinicRoutes(e *gin.RouterGroup, userClinicHandler *httpHandler.UserClinicHandler, access *service.ClinicAccessService, authService *service.AuthService) {
 	userClinic := e.Group("/user-clinic")
 	cfg := config.Load()
 
 	tokenService := service.NewTokenService(cfg.JWT)
-	userClinic.Use(middleware.AuthMiddleware(tokenService))
+	userClinic.Use(middleware.AuthMiddleware(tokenService, authService))
 
-	userClinic.POST("/", userClinicHandler.AssociateUserWithClinic)
+	canInviteUsers := middleware.RequirePermission(access, domain.PermissionInviteUsers)
+
+	userClinic.POST("/", middleware.RequireClinicAccess(access, middleware.ClinicField("clinicId")), canInviteUsers, userClinicHandler.AssociateUserWithClinic)
 	userClinic.GET("/user/:userId", userClinicHandler.GetUserClinics)
-	userClinic.GET("/clinic/:clinicId", userClinicHandler.GetClinicUsers)
-	userClinic.DELETE("/:id", userClinicHandler.RemoveUserFromClinic)
+	userClinic.GET("/clinic/:clinicId", middleware.RequireClinicAccess(access, middleware.ClinicParam("clinicId")), userClinicHandler.GetClinicUsers)
+	userClinic.DELETE("/:id", middleware.RequireClinicAccess(access, middleware.ResourceParam("id", access.UserClinicClinic)), canInviteUsers, userClinicHandler.RemoveUserFromClinic)
 }